@@ -0,0 +1,32 @@
+package genji
+
+import (
+	"context"
+	"io"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/engine"
+)
+
+// Backup writes a consistent snapshot of the database to w.
+// It reads the database from a single read-only transaction and, depending on the engine,
+// doesn't prevent other transactions from writing during the backup.
+func (db *DB) Backup(ctx context.Context, w io.Writer) error {
+	return db.DB.Backup(ctx, w)
+}
+
+// Restore reads a backup created by DB.Backup, writes it to the given engine
+// and returns the restored database.
+// The engine must not contain any table. Backups can be restored on
+// a different engine than the one they were created with.
+func Restore(ctx context.Context, r io.Reader, ng engine.Engine, opts ...Option) (*DB, error) {
+	db, err := database.Restore(ctx, r, ng, options(opts...))
+	if err != nil {
+		return nil, err
+	}
+
+	return &DB{
+		DB:  db,
+		ctx: context.Background(),
+	}, nil
+}
@@ -0,0 +1,317 @@
+package dbutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+)
+
+// DumpOptions determines what is dumped by Dump.
+type DumpOptions struct {
+	// SchemaOnly only dumps the CREATE statements.
+	SchemaOnly bool
+	// DataOnly only dumps the INSERT statements.
+	DataOnly bool
+	// Tables to dump. If empty, all the tables are dumped.
+	Tables []string
+	// BatchSize is the maximum number of documents inserted by
+	// a single INSERT statement. Defaults to 1.
+	BatchSize int
+}
+
+// Dump writes the content of the database to w as SQL statements,
+// in a way that can be replayed by ExecSQL.
+// All the tables are read from the same transaction.
+// Triggers are created after the data is inserted to avoid firing
+// them when the dump is replayed.
+func Dump(db *genji.DB, w io.Writer, opts DumpOptions) error {
+	if opts.SchemaOnly && opts.DataOnly {
+		return errors.New("cannot dump only the schema and only the data at the same time")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1
+	}
+
+	tx, err := db.Begin(false)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tables := opts.Tables
+	if len(tables) == 0 {
+		tables, err = listTables(tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err = fmt.Fprintln(w, "BEGIN TRANSACTION;"); err != nil {
+		return err
+	}
+
+	for i, tableName := range tables {
+		// Blank separation between tables.
+		if i > 0 {
+			if _, err := fmt.Fprintln(w, ""); err != nil {
+				return err
+			}
+		}
+
+		if !opts.DataOnly {
+			err = dumpSchema(tx, tableName, w)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !opts.SchemaOnly {
+			err = dumpData(tx, tableName, w, opts.BatchSize)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if !opts.DataOnly {
+		err = dumpTriggers(tx, tables, w)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintln(w, "COMMIT;")
+	return err
+}
+
+// DumpSchema writes the CREATE statements of the given tables, of their indexes
+// and of their triggers to w. If no table is given, all the tables are written.
+func DumpSchema(db *genji.DB, w io.Writer, tables ...string) error {
+	tx, err := db.Begin(false)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if len(tables) == 0 {
+		tables, err = listTables(tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, tableName := range tables {
+		err = dumpSchema(tx, tableName, w)
+		if err != nil {
+			return err
+		}
+	}
+
+	return dumpTriggers(tx, tables, w)
+}
+
+// listTables returns the names of all the tables of the database.
+func listTables(tx *genji.Tx) ([]string, error) {
+	res, err := tx.Query("SELECT table_name FROM __genji_tables")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	var tables []string
+	err = res.Iterate(func(d document.Document) error {
+		var tableName string
+		if err := document.Scan(d, &tableName); err != nil {
+			return err
+		}
+
+		tables = append(tables, tableName)
+		return nil
+	})
+	return tables, err
+}
+
+// dumpSchema writes the CREATE TABLE and CREATE INDEX statements of the given table.
+func dumpSchema(tx *genji.Tx, tableName string, w io.Writer) error {
+	var buf bytes.Buffer
+
+	t, err := tx.GetTable(tableName)
+	if err != nil {
+		return err
+	}
+
+	buf.WriteString("CREATE TABLE " + t.Name())
+
+	ti, err := t.Info()
+	if err != nil {
+		return err
+	}
+
+	fcs := ti.FieldConstraints
+	// Fields constraints should be displayed between parenthesis.
+	if len(fcs) > 0 {
+		buf.WriteString(" (\n")
+	}
+
+	for i, fc := range fcs {
+		// Don't display the last comma.
+		if i > 0 {
+			buf.WriteString(",\n")
+		}
+
+		buf.WriteString("  " + fc.Path.String() + " ")
+		buf.WriteString(strings.ToUpper(fc.Type.String()))
+
+		if fc.IsPrimaryKey {
+			buf.WriteString(" PRIMARY KEY")
+		}
+
+		if fc.IsNotNull {
+			buf.WriteString(" NOT NULL")
+		}
+
+		if fc.HasDefaultValue() {
+			buf.WriteString(" DEFAULT " + fc.DefaultValue.String())
+		}
+	}
+
+	// Fields constraints close parenthesis.
+	if len(fcs) > 0 {
+		buf.WriteString("\n)")
+	}
+
+	if len(ti.TTLPath) > 0 {
+		buf.WriteString(" WITH TTL ON " + ti.TTLPath.String())
+	}
+
+	buf.WriteString(";\n")
+
+	// Indexes statements.
+	indexes, err := t.Indexes()
+	if err != nil {
+		return err
+	}
+
+	var list []database.Index
+	for _, idx := range indexes {
+		// indexes created by the database, like the one
+		// used by TTL, are recreated with the table.
+		if strings.HasPrefix(idx.Opts.IndexName, "__genji_") {
+			continue
+		}
+
+		list = append(list, idx)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Opts.IndexName < list[j].Opts.IndexName
+	})
+
+	for _, idx := range list {
+		u := ""
+		switch {
+		case idx.Opts.Unique:
+			u = " UNIQUE"
+		case idx.Opts.FullText:
+			u = " FULLTEXT"
+		case idx.Opts.Spatial:
+			u = " SPATIAL"
+		}
+
+		path := idx.Opts.Path.String()
+		if idx.Opts.Multikey {
+			path += "[*]"
+		}
+
+		fmt.Fprintf(&buf, "CREATE%s INDEX %s ON %s (%s);\n", u, idx.Opts.IndexName, idx.Opts.TableName, path)
+	}
+
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// dumpData writes the documents of the given table as INSERT statements
+// of at most batchSize documents.
+func dumpData(tx *genji.Tx, tableName string, w io.Writer, batchSize int) error {
+	t, err := tx.GetTable(tableName)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Query(fmt.Sprintf("SELECT * FROM %s", t.Name()))
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+
+	var buf bytes.Buffer
+	var n int
+	insert := fmt.Sprintf("INSERT INTO %s VALUES ", t.Name())
+
+	err = res.Iterate(func(d document.Document) error {
+		if n == 0 {
+			buf.WriteString(insert)
+		} else {
+			buf.WriteString(", ")
+		}
+
+		data, err := document.MarshalJSON(d)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		n++
+
+		if n < batchSize {
+			return nil
+		}
+
+		n = 0
+		buf.WriteString(";\n")
+		_, err = buf.WriteTo(w)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		buf.WriteString(";\n")
+	}
+
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// dumpTriggers writes the CREATE TRIGGER statements of the given tables.
+func dumpTriggers(tx *genji.Tx, tables []string, w io.Writer) error {
+	triggers, err := tx.ListTriggers()
+	if err != nil {
+		return err
+	}
+
+	dumped := make(map[string]bool, len(tables))
+	for _, tableName := range tables {
+		dumped[tableName] = true
+	}
+
+	for _, tr := range triggers {
+		if !dumped[tr.TableName] {
+			continue
+		}
+
+		_, err = fmt.Fprintf(w, "CREATE TRIGGER %s %s %s ON %s %s;\n",
+			tr.TriggerName, tr.Timing, strings.ToUpper(tr.Event.String()), tr.TableName, tr.Statement)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
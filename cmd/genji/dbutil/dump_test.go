@@ -0,0 +1,109 @@
+package dbutil
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDump(t *testing.T) {
+	newDB := func(t *testing.T) *genji.DB {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+
+		err = db.Exec(`
+			CREATE TABLE test(a INTEGER PRIMARY KEY, b TEXT NOT NULL DEFAULT "foo", c.d DOUBLE);
+			CREATE UNIQUE INDEX idx_b ON test (b);
+			CREATE FULLTEXT INDEX idx_b_text ON test (b);
+			CREATE INDEX idx_c ON test (c.e[*]);
+			CREATE SPATIAL INDEX idx_c_loc ON test (c.loc);
+			CREATE TABLE audit WITH TTL ON exp;
+			CREATE INDEX idx_audit_a ON audit (a);
+			CREATE TRIGGER on_insert AFTER INSERT ON test INSERT INTO audit (a) VALUES ($new.a);
+			INSERT INTO test (a, b) VALUES (1, 'a'), (2, 'b'), (3, 'c');
+		`)
+		require.NoError(t, err)
+		return db
+	}
+
+	schema := `CREATE TABLE test (
+  a INTEGER PRIMARY KEY,
+  b TEXT NOT NULL DEFAULT "foo",
+  c.d DOUBLE
+);
+CREATE UNIQUE INDEX idx_b ON test (b);
+CREATE FULLTEXT INDEX idx_b_text ON test (b);
+CREATE INDEX idx_c ON test (c.e[*]);
+CREATE SPATIAL INDEX idx_c_loc ON test (c.loc);
+`
+	data := `INSERT INTO test VALUES {"a": 1, "b": "a"}, {"a": 2, "b": "b"};
+INSERT INTO test VALUES {"a": 3, "b": "c"};
+`
+	trigger := "CREATE TRIGGER on_insert AFTER INSERT ON test INSERT INTO audit (a) VALUES ($new.a);\n"
+
+	tests := []struct {
+		name string
+		opts DumpOptions
+		want string
+	}{
+		{"All", DumpOptions{Tables: []string{"test"}, BatchSize: 2}, "BEGIN TRANSACTION;\n" + schema + data + trigger + "COMMIT;\n"},
+		{"Schema only", DumpOptions{Tables: []string{"test"}, SchemaOnly: true}, "BEGIN TRANSACTION;\n" + schema + trigger + "COMMIT;\n"},
+		{"Data only", DumpOptions{Tables: []string{"test"}, DataOnly: true, BatchSize: 2}, "BEGIN TRANSACTION;\n" + data + "COMMIT;\n"},
+		{"TTL", DumpOptions{Tables: []string{"audit"}}, "BEGIN TRANSACTION;\nCREATE TABLE audit WITH TTL ON exp;\nCREATE INDEX idx_audit_a ON audit (a);\n" + `INSERT INTO audit VALUES {"a": 1};
+INSERT INTO audit VALUES {"a": 2};
+INSERT INTO audit VALUES {"a": 3};
+COMMIT;
+`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := newDB(t)
+			defer db.Close()
+
+			var buf bytes.Buffer
+			err := Dump(db, &buf, test.opts)
+			require.NoError(t, err)
+			require.Equal(t, test.want, buf.String())
+		})
+	}
+
+	t.Run("Schema and data only", func(t *testing.T) {
+		db := newDB(t)
+		defer db.Close()
+
+		err := Dump(db, new(bytes.Buffer), DumpOptions{SchemaOnly: true, DataOnly: true})
+		require.Error(t, err)
+	})
+
+	t.Run("Unknown table", func(t *testing.T) {
+		db := newDB(t)
+		defer db.Close()
+
+		err := Dump(db, new(bytes.Buffer), DumpOptions{Tables: []string{"foo"}})
+		require.Error(t, err)
+	})
+
+	t.Run("Round trip", func(t *testing.T) {
+		db := newDB(t)
+		defer db.Close()
+
+		var dump bytes.Buffer
+		err := Dump(db, &dump, DumpOptions{BatchSize: 2})
+		require.NoError(t, err)
+
+		other, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer other.Close()
+
+		err = ExecSQL(other, bytes.NewReader(dump.Bytes()))
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		err = Dump(other, &buf, DumpOptions{BatchSize: 2})
+		require.NoError(t, err)
+		require.Equal(t, dump.String(), buf.String())
+	})
+}
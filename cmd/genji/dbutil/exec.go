@@ -0,0 +1,57 @@
+package dbutil
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// ExecSQL reads SQL statements from r and executes them one by one
+// in a single transaction, without loading the whole input in memory.
+// Transaction statements found in r, like the ones written by Dump, are ignored:
+// either every statement succeeds or nothing is written to the database.
+func ExecSQL(db *genji.DB, r io.Reader) error {
+	tx, err := db.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	p := parser.NewParser(bufio.NewReader(r))
+	for {
+		tok, _, _ := p.ScanIgnoreWhitespace()
+		if tok == scanner.EOF {
+			break
+		}
+		if tok == scanner.SEMICOLON {
+			continue
+		}
+		p.Unscan()
+
+		stmt, err := p.ParseStatement()
+		if err != nil {
+			return err
+		}
+
+		switch stmt.(type) {
+		case query.BeginStmt, query.CommitStmt, query.RollbackStmt:
+			continue
+		}
+
+		res, err := stmt.Run(tx.Transaction, nil)
+		if err != nil {
+			return err
+		}
+
+		err = res.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
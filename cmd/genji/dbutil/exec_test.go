@@ -0,0 +1,61 @@
+package dbutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecSQL(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = ExecSQL(db, strings.NewReader(`
+			BEGIN TRANSACTION;
+			CREATE TABLE test;
+			INSERT INTO test VALUES {"a": 1}, {"a": 2};;
+			COMMIT;
+		`))
+		require.NoError(t, err)
+
+		d, err := db.QueryDocument("SELECT COUNT(*) FROM test")
+		require.NoError(t, err)
+		var n int
+		err = d.Iterate(func(field string, v document.Value) error {
+			n = int(v.V.(int64))
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+	})
+
+	t.Run("Rollback on error", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = ExecSQL(db, strings.NewReader(`
+			CREATE TABLE test;
+			INSERT INTO test VALUES {"a": 1};
+			INSERT INTO unknown VALUES {"a": 1};
+		`))
+		require.Error(t, err)
+
+		err = db.Exec("SELECT * FROM test")
+		require.Error(t, err)
+	})
+
+	t.Run("Parse error", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = ExecSQL(db, strings.NewReader(`CREATE TABLE test; INSERT INTO`))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,151 @@
+package dbutil
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+)
+
+// Export runs the query and writes the documents it returns to w
+// in the given format, "csv" or "ndjson".
+// With csv, nested documents are flattened: the field b of the document a
+// is written in the column "a.b". Arrays are written as JSON.
+// The columns are the fields of all the documents, in the order they appear:
+// the rows are held in memory until all the documents are read.
+func Export(db *genji.DB, w io.Writer, q string, format string) error {
+	tx, err := db.Begin(false)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	switch strings.ToLower(format) {
+	case "csv":
+		return exportCSV(tx, w, q)
+	case "ndjson":
+		return exportNDJSON(tx, w, q)
+	}
+
+	return fmt.Errorf("unsupported format %q", format)
+}
+
+func exportNDJSON(tx *genji.Tx, w io.Writer, q string) error {
+	res, err := tx.Query(q)
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+
+	bw := bufio.NewWriter(w)
+	err = res.Iterate(func(d document.Document) error {
+		data, err := document.MarshalJSON(d)
+		if err != nil {
+			return err
+		}
+
+		if _, err := bw.Write(data); err != nil {
+			return err
+		}
+		return bw.WriteByte('\n')
+	})
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+func exportCSV(tx *genji.Tx, w io.Writer, q string) error {
+	// the columns are only known once all the documents are read:
+	// the rows are collected in a single pass, then written.
+	var columns []string
+	var rows [][]string
+	index := make(map[string]int)
+	err := iterateQuery(tx, q, func(d document.Document) error {
+		var row []string
+		err := Flatten(d, func(column string, v document.Value) error {
+			i, ok := index[column]
+			if !ok {
+				i = len(columns)
+				index[column] = i
+				columns = append(columns, column)
+			}
+
+			for len(row) <= i {
+				row = append(row, "")
+			}
+
+			if v.Type == document.NullValue {
+				return nil
+			}
+
+			tv, err := v.CastAsText()
+			if err != nil {
+				return err
+			}
+			row[i] = tv.V.(string)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	err = cw.Write(columns)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		for len(row) < len(columns) {
+			row = append(row, "")
+		}
+
+		err = cw.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func iterateQuery(tx *genji.Tx, q string, fn func(d document.Document) error) error {
+	res, err := tx.Query(q)
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+
+	return res.Iterate(fn)
+}
+
+// Flatten calls fn for each value of the document which isn't a document,
+// with the dotted path of the value.
+func Flatten(d document.Document, fn func(column string, v document.Value) error) error {
+	return flatten(d, "", fn)
+}
+
+func flatten(d document.Document, prefix string, fn func(column string, v document.Value) error) error {
+	return d.Iterate(func(field string, v document.Value) error {
+		column := prefix + field
+		if v.Type == document.DocumentValue {
+			return flatten(v.V.(document.Document), column+".", fn)
+		}
+
+		return fn(column, v)
+	})
+}
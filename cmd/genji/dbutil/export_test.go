@@ -0,0 +1,79 @@
+package dbutil
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	newDB := func(t *testing.T) *genji.DB {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+
+		err = db.Exec(`
+			CREATE TABLE test(a INTEGER);
+			INSERT INTO test (a, b) VALUES (1, 'foo');
+			INSERT INTO test (a, c) VALUES (2, {d: 1.5, e: [1, "x"]});
+			INSERT INTO test (a, b) VALUES (3, 'with, comma');
+		`)
+		require.NoError(t, err)
+		return db
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		format string
+		want   string
+		fails  bool
+	}{
+		{"CSV", "SELECT * FROM test", "csv", `a,b,c.d,c.e
+1,foo,,
+2,,1.5,"[1, ""x""]"
+3,"with, comma",,
+`, false},
+		{"CSV projection", "SELECT a, c.d FROM test WHERE a > 1", "csv", "a,c.d\n2,1.5\n3,\n", false},
+		{"NDJSON", "SELECT a FROM test", "ndjson", "{\"a\": 1}\n{\"a\": 2}\n{\"a\": 3}\n", false},
+		{"Write query", "DELETE FROM test", "csv", "", true},
+		{"Unknown format", "SELECT * FROM test", "xml", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := newDB(t)
+			defer db.Close()
+
+			var buf bytes.Buffer
+			err := Export(db, &buf, test.query, test.format)
+			if test.fails {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.want, buf.String())
+		})
+	}
+
+	t.Run("Round trip", func(t *testing.T) {
+		db := newDB(t)
+		defer db.Close()
+
+		var buf bytes.Buffer
+		err := Export(db, &buf, "SELECT * FROM test", "csv")
+		require.NoError(t, err)
+
+		err = db.Exec("CREATE TABLE copy(a INTEGER)")
+		require.NoError(t, err)
+		_, err = Import(db, strings.NewReader(buf.String()), "copy", ImportOptions{Format: "csv"})
+		require.NoError(t, err)
+
+		var copied bytes.Buffer
+		err = Export(db, &copied, "SELECT * FROM copy", "csv")
+		require.NoError(t, err)
+		require.Equal(t, buf.String(), copied.String())
+	})
+}
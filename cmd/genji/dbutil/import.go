@@ -0,0 +1,285 @@
+package dbutil
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+)
+
+// ImportOptions determines how Import reads documents.
+type ImportOptions struct {
+	// Format of the input: "csv", "ndjson" or "json".
+	// The json format accepts a stream of objects or an array of objects.
+	Format string
+	// Fields maps CSV column names to field names. Columns that are not in the map
+	// use their name as field name, and columns mapped to an empty name are ignored.
+	// Dots in field names create nested documents: the column "a.b" is stored
+	// in the field b of the document a.
+	Fields map[string]string
+	// Types declares the type of CSV fields, by field name. The values of the other
+	// fields are inferred: booleans, integers and doubles are recognized
+	// and anything else is stored as text. Empty values are omitted.
+	Types map[string]document.ValueType
+	// BatchSize is the number of documents inserted per transaction.
+	// Defaults to database.DefaultBulkLoadBatchSize.
+	BatchSize int
+}
+
+// Import reads documents from r and inserts them into an existing table,
+// in batches of documents. It returns the number of documents inserted.
+// If it fails, the documents of the batches committed before the failure are kept.
+func Import(db *genji.DB, r io.Reader, table string, opts ImportOptions) (int64, error) {
+	l, err := db.BulkLoad(table, &database.BulkLoadOptions{
+		BatchSize: opts.BatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	switch strings.ToLower(opts.Format) {
+	case "csv":
+		err = importCSV(l, r, opts)
+	case "ndjson", "json":
+		err = importJSON(l, r)
+	default:
+		err = fmt.Errorf("unsupported format %q", opts.Format)
+	}
+	if err != nil {
+		_ = l.Abort()
+		return l.Count(), err
+	}
+
+	err = l.Close()
+	return l.Count(), err
+}
+
+func importJSON(l *database.BulkLoader, r io.Reader) error {
+	rd := bufio.NewReader(r)
+
+	// look for the first non-space character to tell
+	// a stream of objects from an array of objects.
+	var c byte
+	var err error
+	for {
+		c, err = rd.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if c != '\n' && c != '\r' && c != ' ' && c != '\t' {
+			break
+		}
+	}
+	if err := rd.UnreadByte(); err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(rd)
+	switch c {
+	case '{':
+		for {
+			var fb document.FieldBuffer
+			err := dec.Decode(&fb)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := l.Write(&fb); err != nil {
+				return err
+			}
+		}
+	case '[':
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+
+		for dec.More() {
+			var fb document.FieldBuffer
+			if err := dec.Decode(&fb); err != nil {
+				return err
+			}
+			if err := l.Write(&fb); err != nil {
+				return err
+			}
+		}
+
+		_, err := dec.Token()
+		return err
+	}
+
+	return fmt.Errorf("found %q, but expected '{' or '['", c)
+}
+
+// a csvColumn determines where the values of a CSV column are stored.
+type csvColumn struct {
+	path []string
+	tp   document.ValueType
+}
+
+func importCSV(l *database.BulkLoader, r io.Reader, opts ImportOptions) error {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	columns, err := csvColumns(header, opts)
+	if err != nil {
+		return err
+	}
+
+	for n := 2; ; n++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		fb := document.NewFieldBuffer()
+		for i, s := range record {
+			col := columns[i]
+			if col.path == nil || s == "" {
+				continue
+			}
+
+			v, err := csvValue(s, col.tp)
+			if err != nil {
+				return fmt.Errorf("record %d, column %q: %w", n, header[i], err)
+			}
+
+			setField(fb, col.path, v)
+		}
+
+		if err := l.Write(fb); err != nil {
+			return err
+		}
+	}
+}
+
+// csvColumns returns where to store each column of the header.
+func csvColumns(header []string, opts ImportOptions) ([]csvColumn, error) {
+	columns := make([]csvColumn, len(header))
+	var fields []string
+
+	for i, name := range header {
+		field := name
+		if f, ok := opts.Fields[name]; ok {
+			field = f
+		}
+		if field == "" {
+			continue
+		}
+
+		for _, f := range strings.Split(field, ".") {
+			if f == "" {
+				return nil, fmt.Errorf("invalid field name %q", field)
+			}
+		}
+
+		// a field can't hold a value and be the parent of another one.
+		for _, other := range fields {
+			if field == other || strings.HasPrefix(field, other+".") || strings.HasPrefix(other, field+".") {
+				return nil, fmt.Errorf("field %q conflicts with field %q", field, other)
+			}
+		}
+		fields = append(fields, field)
+
+		columns[i] = csvColumn{path: strings.Split(field, "."), tp: opts.Types[field]}
+	}
+
+	return columns, nil
+}
+
+// csvValue converts s to the given type or, if tp is zero, to the inferred type.
+func csvValue(s string, tp document.ValueType) (document.Value, error) {
+	if tp != 0 {
+		return document.NewTextValue(s).CastAs(tp)
+	}
+
+	switch s {
+	case "true":
+		return document.NewBoolValue(true), nil
+	case "false":
+		return document.NewBoolValue(false), nil
+	}
+
+	if looksNumeric(s) {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return document.NewIntegerValue(i), nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return document.NewDoubleValue(f), nil
+		}
+	}
+
+	return document.NewTextValue(s), nil
+}
+
+// looksNumeric reports whether s only contains characters used to write numbers,
+// to avoid treating words like "inf" or "nan" as doubles.
+func looksNumeric(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && !strings.ContainsRune("+-.eE", c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// setField stores v at the given path, creating the intermediate documents.
+func setField(fb *document.FieldBuffer, path []string, v document.Value) {
+	for _, name := range path[:len(path)-1] {
+		sub, err := fb.GetByField(name)
+		if err == nil {
+			fb = sub.V.(*document.FieldBuffer)
+			continue
+		}
+
+		child := document.NewFieldBuffer()
+		fb.Add(name, document.NewDocumentValue(child))
+		fb = child
+	}
+
+	fb.Add(path[len(path)-1], v)
+}
+
+// ParseValueType returns the value type with the given name, as returned by
+// document.ValueType.String.
+func ParseValueType(name string) (document.ValueType, error) {
+	for _, tp := range []document.ValueType{
+		document.BoolValue,
+		document.IntegerValue,
+		document.DoubleValue,
+		document.TextValue,
+		document.BlobValue,
+		document.ArrayValue,
+		document.DocumentValue,
+	} {
+		if strings.EqualFold(tp.String(), name) {
+			return tp, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown type %q", name)
+}
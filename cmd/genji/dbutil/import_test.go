@@ -0,0 +1,101 @@
+package dbutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		opts  ImportOptions
+		want  []string
+		fails bool
+	}{
+		{"JSON stream", `{"a": 1} {"a": 2}`, ImportOptions{Format: "json"}, []string{`{"a": 1.0}`, `{"a": 2.0}`}, false},
+		{"JSON array", `[{"a": 1}, {"a": {"b": [1, 2]}}]`, ImportOptions{Format: "json"}, []string{`{"a": 1.0}`, `{"a": {"b": [1.0, 2.0]}}`}, false},
+		{"NDJSON", "{\"a\": \"x\"}\n{\"a\": \"y\"}\n", ImportOptions{Format: "ndjson"}, []string{`{"a": "x"}`, `{"a": "y"}`}, false},
+		{"Empty", "  ", ImportOptions{Format: "json"}, nil, false},
+		{"Invalid JSON", `"a"`, ImportOptions{Format: "json"}, nil, true},
+		{"CSV inference", "a,b,c,d,e\n1,1.5,true,foo,\n", ImportOptions{Format: "csv"},
+			[]string{`{"a": 1.0, "b": 1.5, "c": true, "d": "foo"}`}, false},
+		{"CSV words are text", "a,b\ninf,nan\n", ImportOptions{Format: "csv"}, []string{`{"a": "inf", "b": "nan"}`}, false},
+		{"CSV nested fields", "a.b,a.c,d\n1,2,3\n", ImportOptions{Format: "csv"}, []string{`{"a": {"b": 1.0, "c": 2.0}, "d": 3.0}`}, false},
+		{"CSV mapping", "First Name,id,zip\nfoo,1,01234\n", ImportOptions{
+			Format: "csv",
+			Fields: map[string]string{"First Name": "name.first", "id": ""},
+			Types:  map[string]document.ValueType{"zip": document.TextValue},
+		}, []string{`{"name": {"first": "foo"}, "zip": "01234"}`}, false},
+		{"CSV declared type", "a,b\n1,\"[1, 2]\"\n", ImportOptions{
+			Format: "csv",
+			Types:  map[string]document.ValueType{"a": document.BoolValue, "b": document.ArrayValue},
+		}, []string{`{"a": true, "b": [1.0, 2.0]}`}, false},
+		{"CSV invalid type", "a\nfoo\n", ImportOptions{Format: "csv", Types: map[string]document.ValueType{"a": document.IntegerValue}}, nil, true},
+		{"CSV conflicting fields", "a,a.b\n1,2\n", ImportOptions{Format: "csv"}, nil, true},
+		{"CSV wrong number of fields", "a,b\n1\n", ImportOptions{Format: "csv"}, nil, true},
+		{"Unknown format", "", ImportOptions{Format: "xml"}, nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec("CREATE TABLE test")
+			require.NoError(t, err)
+
+			n, err := Import(db, strings.NewReader(test.data), "test", test.opts)
+			if test.fails {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.EqualValues(t, len(test.want), n)
+
+			res, err := db.Query("SELECT * FROM test")
+			require.NoError(t, err)
+			defer res.Close()
+
+			var got []string
+			err = res.Iterate(func(d document.Document) error {
+				data, err := document.MarshalJSON(d)
+				if err != nil {
+					return err
+				}
+				got = append(got, string(data))
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, len(test.want), len(got))
+			for i := range test.want {
+				require.JSONEq(t, test.want[i], got[i])
+			}
+		})
+	}
+
+	t.Run("Batches", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec("CREATE TABLE test(a INTEGER); CREATE UNIQUE INDEX idx_a ON test(a)")
+		require.NoError(t, err)
+
+		// the batch containing the duplicate is rolled back.
+		n, err := Import(db, strings.NewReader("a\n1\n2\n3\n1\n"), "test", ImportOptions{Format: "csv", BatchSize: 2})
+		require.Error(t, err)
+		require.EqualValues(t, 2, n)
+
+		d, err := db.QueryDocument("SELECT COUNT(*) FROM test")
+		require.NoError(t, err)
+		v, err := d.GetByField("COUNT(*)")
+		require.NoError(t, err)
+		require.EqualValues(t, 2, v.V)
+	})
+}
@@ -0,0 +1,38 @@
+package dbutil
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/badgerengine"
+	"github.com/genjidb/genji/engine/boltengine"
+)
+
+// OpenDB opens the database stored at dbPath with the given engine.
+// Supported engines are 'bolt' and 'badger'.
+func OpenDB(ctx context.Context, dbPath, engineName string) (*genji.DB, error) {
+	var ng engine.Engine
+	var err error
+
+	switch engineName {
+	case "bolt":
+		ng, err = boltengine.NewEngine(dbPath, 0660, nil)
+	case "badger":
+		ng, err = badgerengine.NewEngine(badger.DefaultOptions(dbPath).WithLogger(nil))
+	default:
+		return nil, fmt.Errorf("unknown engine %s", engineName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := genji.New(ctx, ng)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.WithContext(ctx), nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/genjidb/genji/cmd/genji/dbutil"
+)
+
+func runDumpCommand(ctx context.Context, e, dbPath string, opts dbutil.DumpOptions) error {
+	if dbPath == "" {
+		return errors.New("db path required")
+	}
+
+	db, err := dbutil.OpenDB(ctx, dbPath, e)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return dbutil.Dump(db, os.Stdout, opts)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/genjidb/genji/cmd/genji/dbutil"
+)
+
+func runExportCommand(ctx context.Context, e, dbPath, format, q string) error {
+	if dbPath == "" {
+		return errors.New("db path required")
+	}
+	if q == "" {
+		return errors.New("query required")
+	}
+
+	db, err := dbutil.OpenDB(ctx, dbPath, e)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return dbutil.Export(db, os.Stdout, q, format)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/genjidb/genji/cmd/genji/dbutil"
+	"github.com/genjidb/genji/document"
+)
+
+func runImportCommand(ctx context.Context, e, dbPath, table, filePath string, mappings, types []string, opts dbutil.ImportOptions) error {
+	if dbPath == "" {
+		return errors.New("db path required")
+	}
+	if table == "" {
+		return errors.New("table required")
+	}
+
+	opts.Fields = make(map[string]string)
+	for _, m := range mappings {
+		column, field, ok := cutPair(m)
+		if !ok {
+			return fmt.Errorf("invalid mapping %q, expected column=field", m)
+		}
+		opts.Fields[column] = field
+	}
+
+	opts.Types = make(map[string]document.ValueType)
+	for _, t := range types {
+		field, name, ok := cutPair(t)
+		if !ok {
+			return fmt.Errorf("invalid type %q, expected field=type", t)
+		}
+		tp, err := dbutil.ParseValueType(name)
+		if err != nil {
+			return err
+		}
+		opts.Types[field] = tp
+	}
+
+	var r io.Reader = os.Stdin
+	if filePath != "" && filePath != "-" {
+		f, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	db, err := dbutil.OpenDB(ctx, dbPath, e)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	n, err := dbutil.Import(db, r, table, opts)
+	if err != nil {
+		return fmt.Errorf("%d documents imported: %w", n, err)
+	}
+
+	return nil
+}
+
+// cutPair splits s around the first equal sign.
+func cutPair(s string) (string, string, bool) {
+	i := strings.IndexByte(s, '=')
+	if i < 0 {
+		return "", "", false
+	}
+
+	return s[:i], s[i+1:], true
+}
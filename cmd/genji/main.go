@@ -5,7 +5,9 @@ import (
 	"os"
 	"runtime/debug"
 
+	"github.com/genjidb/genji/cmd/genji/dbutil"
 	"github.com/genjidb/genji/cmd/genji/shell"
+	"github.com/genjidb/genji/httpapi"
 	"github.com/urfave/cli/v2"
 )
 
@@ -23,6 +25,11 @@ func main() {
 			Name:  "badger",
 			Usage: "use badger engine",
 		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "output mode of the query results, options are 'json', 'ndjson', 'csv', 'table' or 'line'",
+			Value: "json",
+		},
 	}
 
 	app.Commands = []*cli.Command{
@@ -87,6 +94,241 @@ $ curl https://api.github.com/repos/genjidb/genji/issues | genji insert --db my.
 				return runInsertCommand(c.Context, engine, dbPath, table, c.Bool("auto"), args)
 			},
 		},
+		{
+			Name:      "dump",
+			Usage:     "Dump a database as SQL statements",
+			UsageText: "genji dump [options]",
+			Description: `
+The dump command writes the content of a database to the standard output
+as SQL statements that can be replayed with the restore command.
+
+$ genji dump --db my.db > dump.sql
+
+Only some tables can be dumped, and only their schema or their data:
+
+$ genji dump --db my.db -t foo -t bar --schema-only
+$ genji dump --db my.db -t foo --data-only --batch-size 1000`,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "engine",
+					Aliases: []string{"e"},
+					Usage:   "name of the engine to use, options are 'bolt' or 'badger'",
+					Value:   "bolt",
+				},
+				&cli.StringFlag{
+					Name:     "db",
+					Usage:    "path of the database file",
+					Required: true,
+				},
+				&cli.StringSliceFlag{
+					Name:    "table",
+					Aliases: []string{"t"},
+					Usage:   "name of a table to dump, can be repeated. Defaults to all the tables",
+				},
+				&cli.BoolFlag{
+					Name:  "schema-only",
+					Usage: "only dump the CREATE statements",
+				},
+				&cli.BoolFlag{
+					Name:  "data-only",
+					Usage: "only dump the INSERT statements",
+				},
+				&cli.IntFlag{
+					Name:  "batch-size",
+					Usage: "maximum number of documents per INSERT statement",
+					Value: 100,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return runDumpCommand(c.Context, c.String("engine"), c.String("db"), dbutil.DumpOptions{
+					SchemaOnly: c.Bool("schema-only"),
+					DataOnly:   c.Bool("data-only"),
+					Tables:     c.StringSlice("table"),
+					BatchSize:  c.Int("batch-size"),
+				})
+			},
+		},
+		{
+			Name:      "restore",
+			Usage:     "Replay a SQL dump",
+			UsageText: "genji restore [options] [file]",
+			Description: `
+The restore command executes the SQL statements of a dump created with
+the dump command. All the statements are executed in a single transaction:
+if one of them fails, the database is left untouched.
+
+$ genji restore --db my.db dump.sql
+$ genji dump --db my.db | genji restore --db other.db`,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "engine",
+					Aliases: []string{"e"},
+					Usage:   "name of the engine to use, options are 'bolt' or 'badger'",
+					Value:   "bolt",
+				},
+				&cli.StringFlag{
+					Name:     "db",
+					Usage:    "path of the database file",
+					Required: true,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return runRestoreCommand(c.Context, c.String("engine"), c.String("db"), c.Args().First())
+			},
+		},
+		{
+			Name:      "import",
+			Usage:     "Import documents from a CSV or JSON file",
+			UsageText: "genji import [options] [file]",
+			Description: `
+The import command inserts the documents of a file, or of the standard input,
+into an existing table. Documents are inserted in batches, one transaction per batch.
+
+$ genji import --db my.db -t foo --format csv data.csv
+$ cat data.ndjson | genji import --db my.db -t foo --format ndjson
+
+The first line of a CSV file contains the name of the columns, which are used
+as field names. Dots in field names create nested documents. Columns can be renamed,
+or ignored by mapping them to nothing:
+
+$ genji import --db my.db -t foo --format csv --map "First Name=name.first" --map id= data.csv
+
+The type of the values is inferred, unless it is declared:
+
+$ genji import --db my.db -t foo --format csv --type zip=text data.csv`,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "engine",
+					Aliases: []string{"e"},
+					Usage:   "name of the engine to use, options are 'bolt' or 'badger'",
+					Value:   "bolt",
+				},
+				&cli.StringFlag{
+					Name:     "db",
+					Usage:    "path of the database file",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:     "table",
+					Aliases:  []string{"t"},
+					Usage:    "name of the table, it must already exist",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:    "format",
+					Aliases: []string{"f"},
+					Usage:   "format of the input, options are 'csv', 'ndjson' or 'json'",
+					Value:   "json",
+				},
+				&cli.StringSliceFlag{
+					Name:  "map",
+					Usage: "maps a CSV column to a field, as column=field, can be repeated",
+				},
+				&cli.StringSliceFlag{
+					Name:  "type",
+					Usage: "declares the type of a CSV field, as field=type, can be repeated",
+				},
+				&cli.IntFlag{
+					Name:  "batch-size",
+					Usage: "number of documents inserted per transaction",
+					Value: 10000,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return runImportCommand(c.Context, c.String("engine"), c.String("db"), c.String("table"), c.Args().First(),
+					c.StringSlice("map"), c.StringSlice("type"), dbutil.ImportOptions{
+						Format:    c.String("format"),
+						BatchSize: c.Int("batch-size"),
+					})
+			},
+		},
+		{
+			Name:      "export",
+			Usage:     "Export the result of a query as CSV or NDJSON",
+			UsageText: "genji export [options] query",
+			Description: `
+The export command runs a query and writes the documents it returns
+to the standard output.
+
+$ genji export --db my.db --format csv "SELECT * FROM foo" > foo.csv
+$ genji export --db my.db --format ndjson "SELECT a, b FROM foo WHERE a > 10"
+
+With CSV, nested fields are written in columns named after their path,
+like "address.city", and arrays are written as JSON.`,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "engine",
+					Aliases: []string{"e"},
+					Usage:   "name of the engine to use, options are 'bolt' or 'badger'",
+					Value:   "bolt",
+				},
+				&cli.StringFlag{
+					Name:     "db",
+					Usage:    "path of the database file",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:    "format",
+					Aliases: []string{"f"},
+					Usage:   "format of the output, options are 'csv' or 'ndjson'",
+					Value:   "ndjson",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return runExportCommand(c.Context, c.String("engine"), c.String("db"), c.String("format"), c.Args().First())
+			},
+		},
+		{
+			Name:      "serve",
+			Usage:     "Serve a database over HTTP",
+			UsageText: "genji serve [options]",
+			Description: `
+The serve command starts an HTTP server running the queries it receives
+on a database. Queries are sent as JSON and their results are streamed
+as newline-delimited JSON, one document per line:
+
+$ genji serve --db my.db --addr :8080
+$ curl -d '{"query": "SELECT * FROM foo WHERE a > ?", "params": [10]}' localhost:8080/query
+
+Transactions spanning several requests are opened with /tx, used with
+/tx/{id}/query and closed with /tx/{id}/commit or /tx/{id}/rollback.
+They are rolled back if they are not used for longer than the timeout.
+
+$ curl -d '{"read_only": true}' localhost:8080/tx`,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "engine",
+					Aliases: []string{"e"},
+					Usage:   "name of the engine to use, options are 'bolt' or 'badger'",
+					Value:   "bolt",
+				},
+				&cli.StringFlag{
+					Name:     "db",
+					Usage:    "path of the database file",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:  "addr",
+					Usage: "address to listen on",
+					Value: ":8080",
+				},
+				&cli.BoolFlag{
+					Name:  "read-only",
+					Usage: "reject the queries that modify the database",
+				},
+				&cli.DurationFlag{
+					Name:  "tx-timeout",
+					Usage: "duration after which an unused transaction is rolled back",
+					Value: httpapi.DefaultTxTimeout,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return runServeCommand(c.Context, c.String("engine"), c.String("db"), c.String("addr"), httpapi.Options{
+					ReadOnly:  c.Bool("read-only"),
+					TxTimeout: c.Duration("tx-timeout"),
+				})
+			},
+		},
 		{
 			Name:  "version",
 			Usage: "Shows Genji and Genji CLI version",
@@ -140,6 +382,7 @@ $ curl https://api.github.com/repos/genjidb/genji/issues | genji insert --db my.
 		return shell.Run(c.Context, &shell.Options{
 			Engine: engine,
 			DBPath: dbpath,
+			Format: c.String("format"),
 		})
 	}
 
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/genjidb/genji/cmd/genji/dbutil"
+)
+
+func runRestoreCommand(ctx context.Context, e, dbPath, filePath string) error {
+	if dbPath == "" {
+		return errors.New("db path required")
+	}
+
+	var r io.Reader = os.Stdin
+	if filePath != "" && filePath != "-" {
+		f, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	db, err := dbutil.OpenDB(ctx, dbPath, e)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return dbutil.ExecSQL(db, r)
+}
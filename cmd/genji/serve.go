@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/genjidb/genji/cmd/genji/dbutil"
+	"github.com/genjidb/genji/httpapi"
+)
+
+func runServeCommand(ctx context.Context, e, dbPath, addr string, opts httpapi.Options) error {
+	if dbPath == "" {
+		return errors.New("db path required")
+	}
+
+	db, err := dbutil.OpenDB(ctx, dbPath, e)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	h := httpapi.NewHandler(db, &opts)
+	defer h.Close()
+
+	srv := http.Server{
+		Addr:    addr,
+		Handler: h,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+
+	fmt.Fprintf(os.Stderr, "Listening on %s\n", addr)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-sigc:
+	case <-ctx.Done():
+	}
+
+	// let the running requests finish.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return srv.Shutdown(shutdownCtx)
+}
@@ -1,17 +1,18 @@
 package shell
 
 import (
-	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"io"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/agnivade/levenshtein"
 	"github.com/dgraph-io/badger/v2"
 
 	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/cmd/genji/dbutil"
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/engine"
@@ -55,6 +56,53 @@ var commands = []struct {
 		DisplayName: ".dump",
 		Description: "Dump database content or table content as SQL statements.",
 	},
+	{
+		Name:        ".schema",
+		Options:     "[table_name]",
+		DisplayName: ".schema",
+		Description: "Display the CREATE statements of all the tables or of the given table.",
+	},
+	{
+		Name:        ".describe",
+		Options:     "table_name [n]",
+		DisplayName: ".describe",
+		Description: "Display the fields found in the first n documents of the table, 1000 by default.",
+	},
+	{
+		Name:        ".stats",
+		DisplayName: ".stats",
+		Description: "Display the number of documents and the size of the tables and indexes.",
+	},
+	{
+		Name:        ".read",
+		Options:     "filename",
+		DisplayName: ".read",
+		Description: "Execute the queries and commands of a file.",
+	},
+	{
+		Name:        ".param",
+		Options:     "set|unset|list|clear",
+		DisplayName: ".param",
+		Description: "Manage the named parameters of the queries, e.g. .param set $name 'value'.",
+	},
+	{
+		Name:        ".timer",
+		Options:     "on|off",
+		DisplayName: ".timer",
+		Description: "Display the execution time of each statement.",
+	},
+	{
+		Name:        ".mode",
+		Options:     "[mode]",
+		DisplayName: ".mode",
+		Description: "Display or set the output mode: json, ndjson, csv, table or line.",
+	},
+	{
+		Name:        ".output",
+		Options:     "[filename]",
+		DisplayName: ".output",
+		Description: "Write query results to a file, or to stdout if omitted.",
+	},
 	{
 		Name:        ".save",
 		Options:     "[badger?] [filename]",
@@ -203,177 +251,229 @@ func displaySuggestions(in string) error {
 	return nil
 }
 
-// dumpTable displays the content of the given table as SQL statements.
-func dumpTable(tx *genji.Tx, tableName string, w io.Writer) error {
-	var buf bytes.Buffer
+// runDumpCmd dumps the given tables if provided, otherwise it dumps the whole database.
+func runDumpCmd(db *genji.DB, tables []string, w io.Writer) error {
+	return dbutil.Dump(db, w, dbutil.DumpOptions{
+		Tables: tables,
+	})
+}
 
-	t, err := tx.GetTable(tableName)
-	if err != nil {
-		return err
+// runSchemaCmd displays the CREATE statements of the given tables if provided,
+// otherwise of all the tables.
+func runSchemaCmd(db *genji.DB, tables []string, w io.Writer) error {
+	if len(tables) > 1 {
+		return fmt.Errorf("usage: .schema [table_name]")
 	}
 
-	if _, err = fmt.Fprintf(w, "CREATE TABLE %s", t.Name()); err != nil {
-		return err
+	return dbutil.DumpSchema(db, w, tables...)
+}
+
+// defaultDescribeSampleSize is the number of documents read by .describe
+// if not specified.
+const defaultDescribeSampleSize = 1000
+
+// a fieldDescription holds the types of the values found at a path.
+type fieldDescription struct {
+	path     string
+	declared document.ValueType
+	count    int
+	types    map[document.ValueType]int
+}
+
+// runDescribeCmd displays the fields found in the first documents of a table,
+// with the distribution of their types and how often they are present.
+func runDescribeCmd(db *genji.DB, cmd []string, w io.Writer) error {
+	if len(cmd) < 2 || len(cmd) > 3 {
+		return fmt.Errorf("usage: .describe table_name [n]")
 	}
 
-	ti, err := t.Info()
-	if err != nil {
-		return err
+	n := defaultDescribeSampleSize
+	if len(cmd) == 3 {
+		var err error
+		n, err = strconv.Atoi(cmd[2])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid number of documents %q", cmd[2])
+		}
 	}
 
-	fcs := ti.FieldConstraints
-	// Fields constraints should be displayed between parenthesis.
-	if len(fcs) > 0 {
-		buf.WriteString(" (\n")
+	var fields []*fieldDescription
+	byPath := make(map[string]*fieldDescription)
+	get := func(path string) *fieldDescription {
+		f, ok := byPath[path]
+		if !ok {
+			f = &fieldDescription{path: path, types: make(map[document.ValueType]int)}
+			byPath[path] = f
+			fields = append(fields, f)
+		}
+		return f
 	}
 
-	for i, fc := range fcs {
-		// Don't display the last comma.
-		if i > 0 {
-			buf.WriteString(",\n")
+	var total int
+	err := db.View(func(tx *genji.Tx) error {
+		t, err := tx.GetTable(cmd[1])
+		if err != nil {
+			return err
 		}
 
-		buf.WriteString("  " + fcs[i].Path.String() + " ")
-		buf.WriteString(strings.ToUpper(fcs[i].Type.String()))
-		if fc.IsPrimaryKey {
-			buf.WriteString(" PRIMARY KEY")
+		info, err := t.Info()
+		if err != nil {
+			return err
 		}
 
-		if fc.IsNotNull {
-			buf.WriteString(" NOT NULL")
+		err = t.Iterate(func(d document.Document) error {
+			if total >= n {
+				return errStopSampling
+			}
+			total++
+
+			return dbutil.Flatten(d, func(column string, v document.Value) error {
+				f := get(column)
+				f.count++
+				f.types[v.Type]++
+				return nil
+			})
+		})
+		if err != nil && err != errStopSampling {
+			return err
 		}
-	}
 
-	// Fields constraints close parenthesis.
-	if len(fcs) > 0 {
-		buf.WriteString("\n);\n")
-	} else {
-		buf.WriteString(";\n")
-	}
+		// declared fields are displayed even if they were not found.
+		for _, fc := range info.FieldConstraints {
+			get(fc.Path.String()).declared = fc.Type
+		}
 
-	// Print CREATE TABLE statement.
-	if _, err = buf.WriteTo(w); err != nil {
+		return nil
+	})
+	if err != nil {
 		return err
 	}
 
-	// Indexes statements.
-	indexes, err := t.Indexes()
-	if err != nil {
-		return err
+	percent := func(n int) string {
+		if total == 0 {
+			return "0%"
+		}
+		return fmt.Sprintf("%.0f%%", 100*float64(n)/float64(total))
 	}
 
-	for _, index := range indexes {
-		u := ""
-		if index.Opts.Unique {
-			u = " UNIQUE"
+	rows := make([]document.Document, 0, len(fields))
+	for _, f := range fields {
+		types := make([]document.ValueType, 0, len(f.types))
+		for tp := range f.types {
+			types = append(types, tp)
 		}
+		sort.Slice(types, func(i, j int) bool {
+			if f.types[types[i]] != f.types[types[j]] {
+				return f.types[types[i]] > f.types[types[j]]
+			}
+			return types[i] < types[j]
+		})
 
-		_, err = fmt.Fprintf(w, "CREATE%s INDEX %s ON %s (%s);\n", u, index.Opts.IndexName, index.Opts.TableName,
-			index.Opts.Path)
-		if err != nil {
-			return err
+		var found []string
+		for _, tp := range types {
+			found = append(found, fmt.Sprintf("%s %.0f%%", tp, 100*float64(f.types[tp])/float64(f.count)))
 		}
+
+		rows = append(rows, document.NewFieldBuffer().
+			Add("path", document.NewTextValue(f.path)).
+			Add("declared", document.NewTextValue(f.declared.String())).
+			Add("types", document.NewTextValue(strings.Join(found, ", "))).
+			Add("present", document.NewTextValue(percent(f.count))))
 	}
 
-	q := fmt.Sprintf("SELECT * FROM %s", t.Name())
-	res, err := tx.Query(q)
+	_, err = fmt.Fprintf(w, "%d documents read\n", total)
 	if err != nil {
 		return err
 	}
-	defer res.Close()
 
-	// Inserts statements.
-	insert := fmt.Sprintf("INSERT INTO %s VALUES ", t.Name())
-	return res.Iterate(func(d document.Document) error {
-		buf.WriteString(insert)
-
-		data, err := document.MarshalJSON(d)
-		if err != nil {
-			return err
-		}
-		buf.Write(data)
-		buf.WriteString(";\n")
-
-		if _, err = buf.WriteTo(w); err != nil {
-			return err
-		}
-
-		return nil
-	})
+	return writeTable(w, rows)
 }
 
-// runDumpCmd dumps the given tables if provided, otherwise it dumps the whole database.
-func runDumpCmd(db *genji.DB, tables []string, w io.Writer) error {
-	tx, err := db.Begin(false)
-	if err != nil {
-		return err
+// runStatsCmd displays the number of documents and the size of each table,
+// and the number of entries and the size of their indexes.
+func runStatsCmd(db *genji.DB, cmd []string, w io.Writer) error {
+	if len(cmd) > 1 {
+		return fmt.Errorf("usage: .stats")
 	}
-	defer tx.Rollback()
 
-	if _, err = fmt.Fprintln(w, "BEGIN TRANSACTION;"); err != nil {
-		return err
+	var rows []document.Document
+	row := func(name, kind, table string, n, size int64) {
+		rows = append(rows, document.NewFieldBuffer().
+			Add("name", document.NewTextValue(name)).
+			Add("type", document.NewTextValue(kind)).
+			Add("table", document.NewTextValue(table)).
+			Add("entries", document.NewIntegerValue(n)).
+			Add("size", document.NewTextValue(formatSize(size))))
 	}
 
-	for i, table := range tables {
-		err = dumpTable(tx, table, w)
+	err := db.View(func(tx *genji.Tx) error {
+		res, err := tx.Query("SELECT table_name FROM __genji_tables")
 		if err != nil {
-			// If table doesn’t exist we skip it.
-			if errors.Is(err, database.ErrTableNotFound) {
-				continue
-			}
-			_, err = fmt.Fprintln(w, "COMMIT;")
 			return err
 		}
+		defer res.Close()
 
-		// Blank separation between tables.
-		if i > 0 {
-			if _, err := fmt.Fprintln(w, ""); err != nil {
+		return res.Iterate(func(d document.Document) error {
+			var tableName string
+			if err := document.Scan(d, &tableName); err != nil {
 				return err
 			}
-		}
-	}
 
-	// tables slice argument is not empty, all args tables has been displayed.
-	// If it is empty we should print the database content.
-	if len(tables) > 0 {
-		_, err = fmt.Fprintln(w, "COMMIT;")
-		return err
-	}
+			t, err := tx.GetTable(tableName)
+			if err != nil {
+				return err
+			}
 
-	// tables slice argument is empty.
-	// Dump database content.
-	res, err := tx.Query("SELECT table_name FROM __genji_tables")
-	if err != nil {
-		_, err = fmt.Fprintln(w, "ROLLBACK;")
-		return err
-	}
-	defer res.Close()
+			n, size, err := t.Size()
+			if err != nil {
+				return err
+			}
+			row(tableName, "table", tableName, n, size)
 
-	i := 0
-	err = res.Iterate(func(d document.Document) error {
-		// Blank separation between tables.
-		if i > 0 {
-			if _, err := fmt.Fprintln(w, ""); err != nil {
+			indexes, err := t.Indexes()
+			if err != nil {
 				return err
 			}
-		}
-		i++
 
-		// Get table name.
-		var tableName string
-		if err := document.Scan(d, &tableName); err != nil {
-			return err
-		}
+			list := make([]database.Index, 0, len(indexes))
+			for _, idx := range indexes {
+				list = append(list, idx)
+			}
+			sort.Slice(list, func(i, j int) bool {
+				return list[i].Opts.IndexName < list[j].Opts.IndexName
+			})
+
+			for _, idx := range list {
+				n, size, err := idx.Size()
+				if err != nil {
+					return err
+				}
+				row(idx.Opts.IndexName, "index", tableName, n, size)
+			}
 
-		return dumpTable(tx, tableName, w)
+			return nil
+		})
 	})
 	if err != nil {
-		_, err = fmt.Fprintln(w, "ROLLBACK;")
 		return err
 	}
 
-	_, err = fmt.Fprintln(w, "COMMIT;")
-	return err
+	return writeTable(w, rows)
+}
+
+// formatSize returns a human readable size.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
 }
 
 // runSaveCommand saves the currently opened database at the given path.
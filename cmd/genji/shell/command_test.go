@@ -267,3 +267,112 @@ func TestSaveCommand(t *testing.T) {
 		})
 	}
 }
+
+func newIntrospectionDB(t *testing.T) *genji.DB {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+
+	err = db.Exec(`
+		CREATE TABLE test(a INTEGER NOT NULL, b.c TEXT);
+		CREATE INDEX idx_d ON test (d);
+		INSERT INTO test (a, b, d) VALUES (1, {c: 'x'}, 1), (2, {c: 'y'}, 'foo'), (3, NULL, 2);
+		REINDEX;
+		CREATE TABLE foo;
+		CREATE TRIGGER on_insert AFTER INSERT ON test INSERT INTO foo (a) VALUES ($new.a);
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestRunSchemaCmd(t *testing.T) {
+	db := newIntrospectionDB(t)
+	defer db.Close()
+
+	var buf bytes.Buffer
+	require.NoError(t, runSchemaCmd(db, []string{"test"}, &buf))
+	require.Equal(t, `CREATE TABLE test (
+  a INTEGER NOT NULL,
+  b.c TEXT
+);
+CREATE INDEX idx_d ON test (d);
+CREATE TRIGGER on_insert AFTER INSERT ON test INSERT INTO foo (a) VALUES ($new.a);
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, runSchemaCmd(db, nil, &buf))
+	require.True(t, strings.HasPrefix(buf.String(), "CREATE TABLE foo;\nCREATE TABLE test ("))
+
+	require.Error(t, runSchemaCmd(db, []string{"unknown"}, &buf))
+	require.Error(t, runSchemaCmd(db, []string{"foo", "test"}, &buf))
+}
+
+func TestRunDescribeCmd(t *testing.T) {
+	db := newIntrospectionDB(t)
+	defer db.Close()
+
+	var buf bytes.Buffer
+	require.NoError(t, runDescribeCmd(db, strings.Fields(".describe test"), &buf))
+	require.Equal(t, `3 documents read
++------+----------+----------------------+---------+
+| path | declared | types                | present |
++------+----------+----------------------+---------+
+| a    | integer  | integer 100%         | 100%    |
+| b.c  | text     | text 100%            | 67%     |
+| d    |          | double 67%, text 33% | 100%    |
+| b    |          | null 100%            | 33%     |
++------+----------+----------------------+---------+
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, runDescribeCmd(db, strings.Fields(".describe test 1"), &buf))
+	require.Equal(t, `1 documents read
++------+----------+--------------+---------+
+| path | declared | types        | present |
++------+----------+--------------+---------+
+| a    | integer  | integer 100% | 100%    |
+| b.c  | text     | text 100%    | 100%    |
+| d    |          | double 100%  | 100%    |
++------+----------+--------------+---------+
+`, buf.String())
+
+	// names which are not valid identifiers don't need to be quoted.
+	err := db.Exec("CREATE TABLE `my-table`; INSERT INTO `my-table` (a) VALUES (1)")
+	require.NoError(t, err)
+	buf.Reset()
+	require.NoError(t, runDescribeCmd(db, strings.Fields(".describe my-table"), &buf))
+	require.Equal(t, `1 documents read
++------+----------+-------------+---------+
+| path | declared | types       | present |
++------+----------+-------------+---------+
+| a    |          | double 100% | 100%    |
++------+----------+-------------+---------+
+`, buf.String())
+
+	require.Error(t, runDescribeCmd(db, strings.Fields(".describe"), &buf))
+	require.Error(t, runDescribeCmd(db, strings.Fields(".describe test 0"), &buf))
+	require.Error(t, runDescribeCmd(db, strings.Fields(".describe unknown"), &buf))
+}
+
+func TestRunStatsCmd(t *testing.T) {
+	db := newIntrospectionDB(t)
+	defer db.Close()
+
+	var buf bytes.Buffer
+	require.NoError(t, runStatsCmd(db, strings.Fields(".stats"), &buf))
+
+	lines := strings.Split(buf.String(), "\n")
+	require.Len(t, lines, 8)
+	require.Equal(t, "| name  | type  | table | entries | size |", lines[1])
+	require.True(t, strings.HasPrefix(lines[3], "| foo   | table | foo   | 0       | 0 B  |"))
+	require.True(t, strings.HasPrefix(lines[4], "| test  | table | test  | 3       | "))
+	require.True(t, strings.HasPrefix(lines[5], "| idx_d | index | test  | 3       | "))
+
+	require.Error(t, runStatsCmd(db, strings.Fields(".stats foo"), &buf))
+}
+
+func TestFormatSize(t *testing.T) {
+	require.Equal(t, "0 B", formatSize(0))
+	require.Equal(t, "1023 B", formatSize(1023))
+	require.Equal(t, "1.0 KiB", formatSize(1024))
+	require.Equal(t, "1.5 MiB", formatSize(3*1024*1024/2))
+}
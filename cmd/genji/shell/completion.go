@@ -0,0 +1,333 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// number of documents read to find the fields of a table.
+const completionSampleSize = 100
+
+// characters separating the word being completed from the rest of the input.
+// Dots are not separators, to complete paths to nested fields.
+const completionSeparators = " \t,;()[]{}=<>!+-*/%|&^:"
+
+// completer returns the suggestions for the word before the cursor.
+// The input typed before the word, including the previous lines of a
+// multi-line query, is parsed to determine what can be typed next:
+// keywords, table names, index names, fields of the table of the statement
+// or functions.
+func (sh *Shell) completer(in prompt.Document) []prompt.Suggest {
+	if !sh.multiLine && strings.HasPrefix(in.Text, ".") {
+		return prompt.FilterHasPrefix(sh.cmdSuggestions, in.Text, true)
+	}
+
+	word := in.GetWordBeforeCursorUntilSeparator(completionSeparators)
+	before := sh.query + in.TextBeforeCursor()
+	before = before[:len(before)-len(word)]
+
+	expected, ok := expectedAt(before)
+	if !ok {
+		return []prompt.Suggest{}
+	}
+
+	var suggestions []prompt.Suggest
+	add := func(names ...string) {
+		for _, name := range names {
+			suggestions = append(suggestions, prompt.Suggest{Text: name})
+		}
+	}
+
+	add(nextKeywords(before)...)
+
+	ctx := context.Background()
+	for _, e := range expected {
+		switch e {
+		case "table_name":
+			tables, err := sh.getAllTables(ctx)
+			if err == nil {
+				add(tables...)
+			}
+		case "index_name":
+			indexes, err := sh.getAllIndexes(ctx)
+			if err == nil {
+				add(indexes...)
+			}
+		case "identifier", "path":
+			table := statementTable(before, in.TextAfterCursor())
+			if table == "" {
+				continue
+			}
+
+			fields, err := sh.tableFields(ctx, table)
+			if err == nil {
+				add(fields...)
+			}
+		case "string":
+			// a string is expected where any expression is.
+			add(functionNames()...)
+		}
+	}
+
+	return prompt.FilterHasPrefix(suggestions, word, true)
+}
+
+// expectedAt parses s followed by an illegal token and returns what the parser
+// expected instead of that token. It returns false if s can't be parsed.
+func expectedAt(s string) ([]string, bool) {
+	probe := s + "#"
+	_, err := parser.ParseQuery(probe)
+
+	var pErr *parser.ParseError
+	if !errors.As(err, &pErr) || pErr.Message != "" || pErr.Pos != lastTokenPos(probe) {
+		return nil, false
+	}
+
+	return pErr.Expected, true
+}
+
+// nextKeywords returns the keywords that the parser accepts after s.
+func nextKeywords(s string) []string {
+	var kws []string
+	for _, kw := range scanner.Keywords() {
+		if accepts(s + kw) {
+			kws = append(kws, kw)
+		}
+	}
+
+	return kws
+}
+
+// accepts returns true if the parser reads the last token of s without error,
+// even if s is not a complete query.
+func accepts(s string) bool {
+	_, err := parser.ParseQuery(s)
+	if err == nil {
+		return true
+	}
+
+	var pErr *parser.ParseError
+	if !errors.As(err, &pErr) {
+		// the query was parsed but is not valid.
+		return true
+	}
+	if pErr.Message != "" {
+		return false
+	}
+
+	last := lastTokenPos(s)
+	return pErr.Pos.Line > last.Line || (pErr.Pos.Line == last.Line && pErr.Pos.Char > last.Char)
+}
+
+// lastTokenPos returns the position of the last token of s
+// that is not a whitespace or a comment.
+func lastTokenPos(s string) scanner.Pos {
+	sc := scanner.NewScanner(strings.NewReader(s))
+
+	var pos scanner.Pos
+	for {
+		ti := sc.Scan()
+		switch ti.Tok {
+		case scanner.EOF:
+			return pos
+		case scanner.WS, scanner.COMMENT:
+		default:
+			pos = ti.Pos
+		}
+	}
+}
+
+// statementTable returns the name of the table the statement being typed
+// operates on, i.e. the name following FROM, INTO, UPDATE or ON.
+// The statement starts after the last semicolon of before and ends at the first
+// semicolon of after.
+func statementTable(before, after string) string {
+	var table string
+	var prev scanner.Token
+
+	scan := func(s string, last bool) {
+		sc := scanner.NewScanner(strings.NewReader(s))
+		for {
+			ti := sc.Scan()
+			switch ti.Tok {
+			case scanner.EOF:
+				return
+			case scanner.WS, scanner.COMMENT:
+				continue
+			case scanner.SEMICOLON:
+				if last {
+					return
+				}
+				table = ""
+			case scanner.IDENT:
+				if table == "" && (prev == scanner.FROM || prev == scanner.INTO || prev == scanner.UPDATE || prev == scanner.ON) {
+					table = ti.Lit
+				}
+			}
+			prev = ti.Tok
+		}
+	}
+
+	scan(before, false)
+	scan(after, true)
+
+	return table
+}
+
+// tableFields returns the paths of the fields of the table, found in its
+// field constraints and in its first documents. The fields are cached until
+// the next query is executed.
+func (sh *Shell) tableFields(ctx context.Context, table string) ([]string, error) {
+	if fields, ok := sh.fieldsCache[table]; ok {
+		return fields, nil
+	}
+
+	db, err := sh.getDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	seen := make(map[string]bool)
+	add := func(p document.Path) {
+		s := quotePath(p)
+		if !seen[s] {
+			seen[s] = true
+			fields = append(fields, s)
+		}
+	}
+
+	err = view(db, func(tx *database.Transaction) error {
+		t, err := tx.GetTable(table)
+		if err != nil {
+			return err
+		}
+
+		info, err := t.Info()
+		if err != nil {
+			return err
+		}
+
+		for _, fc := range info.FieldConstraints {
+			add(fc.Path)
+		}
+
+		var n int
+		err = t.Iterate(func(d document.Document) error {
+			if n >= completionSampleSize {
+				return errStopSampling
+			}
+			n++
+
+			return walkPaths(d, nil, add)
+		})
+		if err == errStopSampling {
+			err = nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(fields)
+
+	if sh.fieldsCache == nil {
+		sh.fieldsCache = make(map[string][]string)
+	}
+	sh.fieldsCache[table] = fields
+
+	return fields, nil
+}
+
+var errStopSampling = errors.New("stop sampling")
+
+// view runs fn in the transaction opened with BEGIN, if any,
+// or in a read-only transaction.
+func view(db *genji.DB, fn func(tx *database.Transaction) error) error {
+	if tx := db.DB.GetAttachedTx(); tx != nil {
+		return fn(tx)
+	}
+
+	return db.View(func(tx *genji.Tx) error {
+		return fn(tx.Transaction)
+	})
+}
+
+// walkPaths calls fn with the path of every field of d,
+// including the fields of nested documents.
+func walkPaths(d document.Document, prefix document.Path, fn func(p document.Path)) error {
+	return d.Iterate(func(field string, v document.Value) error {
+		p := append(prefix[:len(prefix):len(prefix)], document.PathFragment{FieldName: field})
+		fn(p)
+
+		if v.Type == document.DocumentValue {
+			return walkPaths(v.V.(document.Document), p, fn)
+		}
+
+		return nil
+	})
+}
+
+// quotePath returns the path as it must be written in a query:
+// field names that are not valid identifiers are quoted with backquotes.
+func quotePath(p document.Path) string {
+	var b strings.Builder
+
+	for i, f := range p {
+		if f.FieldName == "" {
+			continue
+		}
+		if i > 0 {
+			b.WriteByte('.')
+		}
+
+		if isIdent(f.FieldName) {
+			b.WriteString(f.FieldName)
+		} else {
+			b.WriteString("`" + identEscaper.Replace(f.FieldName) + "`")
+		}
+	}
+
+	return b.String()
+}
+
+var identEscaper = strings.NewReplacer("\\", "\\\\", "`", "\\`")
+
+// isIdent returns true if name can be written without quotes.
+func isIdent(name string) bool {
+	if name == "" || scanner.Lookup(name) != scanner.IDENT {
+		return false
+	}
+
+	for i, c := range name {
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+		return false
+	}
+
+	return true
+}
+
+// functionNames returns the names of the builtin functions,
+// followed by an opening parenthesis.
+func functionNames() []string {
+	var names []string
+	for name := range expr.BuiltinFunctions() {
+		names = append(names, name+"(")
+	}
+	sort.Strings(names)
+
+	return names
+}
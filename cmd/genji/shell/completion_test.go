@@ -0,0 +1,125 @@
+package shell
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompleter(t *testing.T) {
+	ctx := context.Background()
+	sh := newTestShell(t)
+	sh.loadCommandSuggestions()
+
+	require.NoError(t, sh.runQuery(ctx, `
+		CREATE TABLE foo (id INTEGER PRIMARY KEY, name TEXT);
+		CREATE INDEX idx_foo ON foo (name);
+		INSERT INTO foo VALUES {id: 1, name: 'a', address: {city: 'Lyon', zip: '69001'}, "first name": 'b'};
+		CREATE TABLE bar;
+	`))
+
+	// the cursor is placed at the end of the text or on the "|" character.
+	complete := func(text string) []string {
+		b := prompt.NewBuffer()
+		parts := strings.SplitN(text, "|", 2)
+		b.InsertText(parts[0], false, true)
+		if len(parts) > 1 {
+			b.InsertText(parts[1], false, false)
+		}
+
+		var texts []string
+		for _, s := range sh.completer(*b.Document()) {
+			texts = append(texts, s.Text)
+		}
+		return texts
+	}
+
+	tests := []struct {
+		text     string
+		contains []string
+		excludes []string
+	}{
+		{"", []string{"SELECT", "INSERT", "CREATE"}, []string{"FROM", "WHERE"}},
+		{"sel", []string{"SELECT"}, []string{"SAVEPOINT", "INSERT"}},
+		{"SELECT ", []string{"DISTINCT", "CAST", "count(", "pk("}, []string{"FROM", "id"}},
+		{"SELECT a ", []string{"FROM", "AS", "AND", "LIKE"}, []string{"WHERE", "SELECT"}},
+		{"SELECT a FROM ", []string{"foo", "bar"}, []string{"WHERE", "id"}},
+		{"SELECT a FROM foo ", []string{"WHERE", "GROUP", "ORDER", "LIMIT", "OFFSET"}, []string{"FROM", "SELECT"}},
+		{"SELECT a FROM foo WHERE ", []string{"id", "name", "address", "address.city", "`first name`", "count("}, []string{"WHERE"}},
+		{"SELECT a FROM foo WHERE addr", []string{"address", "address.zip"}, []string{"id", "WHERE"}},
+		{"SELECT a FROM foo WHERE address.c", []string{"address.city"}, []string{"address.zip"}},
+		{"SELECT a FROM foo ORDER BY ", []string{"id", "name"}, []string{"count("}},
+		{"SELECT a FROM foo ORDER BY id ", []string{"ASC", "DESC", "LIMIT"}, []string{"WHERE"}},
+		{"SELECT COUNT(na| FROM foo", []string{"name"}, []string{"id"}},
+		{"SELECT a| FROM foo; SELECT b FROM bar", []string{"address"}, nil},
+		{"UPDATE foo SET ", []string{"id", "name"}, nil},
+		{"DELETE FROM bar WHERE ", []string{"count("}, []string{"id"}},
+		{"DROP ", []string{"TABLE", "INDEX"}, []string{"SELECT"}},
+		{"DROP INDEX ", []string{"idx_foo"}, []string{"foo"}},
+		{"SELECT FROM ", nil, []string{"foo", "SELECT"}},
+		{".ta", []string{".tables"}, []string{"SELECT"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			got := complete(test.text)
+			for _, s := range test.contains {
+				require.Contains(t, got, s)
+			}
+			for _, s := range test.excludes {
+				require.NotContains(t, got, s)
+			}
+		})
+	}
+
+	t.Run("Multi-line", func(t *testing.T) {
+		require.NoError(t, sh.execute(ctx, "SELECT id"))
+		defer sh.resetQuery()
+
+		got := complete("FROM foo WHERE na")
+		require.Equal(t, []string{"name"}, got)
+	})
+
+	t.Run("Cache", func(t *testing.T) {
+		require.NotContains(t, complete("SELECT * FROM bar WHERE "), "c")
+		require.NoError(t, sh.runQuery(ctx, "INSERT INTO bar (c) VALUES (1);"))
+		require.Contains(t, complete("SELECT * FROM bar WHERE "), "c")
+	})
+
+	t.Run("Transaction", func(t *testing.T) {
+		require.NoError(t, sh.runQuery(ctx, "BEGIN; INSERT INTO bar (d) VALUES (1);"))
+		defer sh.runQuery(ctx, "ROLLBACK;")
+
+		require.Contains(t, complete("SELECT * FROM bar WHERE "), "d")
+	})
+}
+
+func TestQuotePath(t *testing.T) {
+	path := func(fields ...string) document.Path {
+		var p document.Path
+		for _, f := range fields {
+			p = append(p, document.PathFragment{FieldName: f})
+		}
+		return p
+	}
+
+	tests := []struct {
+		path     document.Path
+		expected string
+	}{
+		{path("a"), "a"},
+		{path("a", "b_1"), "a.b_1"},
+		{path("first name"), "`first name`"},
+		{path("select"), "`select`"},
+		{path("1a"), "`1a`"},
+		{path("a`b"), "`a\\`b`"},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.expected, quotePath(test.path))
+	}
+}
@@ -0,0 +1,282 @@
+package shell
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/genjidb/genji/cmd/genji/dbutil"
+	"github.com/genjidb/genji/document"
+)
+
+// output modes of the query results.
+const (
+	jsonMode   = "json"
+	ndjsonMode = "ndjson"
+	csvMode    = "csv"
+	tableMode  = "table"
+	lineMode   = "line"
+)
+
+var outputModes = []string{jsonMode, ndjsonMode, csvMode, tableMode, lineMode}
+
+func validateOutputMode(mode string) error {
+	for _, m := range outputModes {
+		if m == mode {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown output mode %q, expected one of %s", mode, strings.Join(outputModes, ", "))
+}
+
+// writeDocuments writes the documents returned by it to w, using the given output mode.
+// The csv and table modes read all the documents before writing anything,
+// to determine the columns.
+func writeDocuments(ctx context.Context, w io.Writer, mode string, it document.Iterator) error {
+	iterate := func(fn func(d document.Document) error) error {
+		return it.Iterate(func(d document.Document) error {
+			select {
+			case <-ctx.Done():
+				return errors.New("interrupted")
+			default:
+			}
+
+			return fn(d)
+		})
+	}
+
+	switch mode {
+	case ndjsonMode:
+		return writeNDJSON(w, iterate)
+	case csvMode, tableMode:
+		var rows []document.Document
+		err := iterate(func(d document.Document) error {
+			fb := document.NewFieldBuffer()
+			err := fb.Copy(d)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, fb)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if mode == csvMode {
+			return writeCSV(w, rows)
+		}
+		return writeTable(w, rows)
+	case lineMode:
+		return writeLines(w, iterate)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return iterate(func(d document.Document) error {
+		return enc.Encode(d)
+	})
+}
+
+func writeNDJSON(w io.Writer, iterate func(fn func(d document.Document) error) error) error {
+	bw := bufio.NewWriter(w)
+	err := iterate(func(d document.Document) error {
+		data, err := document.MarshalJSON(d)
+		if err != nil {
+			return err
+		}
+
+		if _, err := bw.Write(data); err != nil {
+			return err
+		}
+		return bw.WriteByte('\n')
+	})
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// flattenRows returns the columns of all the rows, in the order they appear,
+// and the cells of each row. Missing values are empty and null values
+// are replaced by null.
+func flattenRows(rows []document.Document, null string) ([]string, [][]string, error) {
+	var columns []string
+	index := make(map[string]int)
+	cells := make([][]string, len(rows))
+
+	for i, d := range rows {
+		err := dbutil.Flatten(d, func(column string, v document.Value) error {
+			j, ok := index[column]
+			if !ok {
+				j = len(columns)
+				index[column] = j
+				columns = append(columns, column)
+			}
+
+			for len(cells[i]) <= j {
+				cells[i] = append(cells[i], "")
+			}
+
+			s, err := cellText(v, null)
+			if err != nil {
+				return err
+			}
+			cells[i][j] = s
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for i := range cells {
+		for len(cells[i]) < len(columns) {
+			cells[i] = append(cells[i], "")
+		}
+	}
+
+	return columns, cells, nil
+}
+
+// cellText returns the text of the value, or null if the value is null.
+func cellText(v document.Value, null string) (string, error) {
+	if v.Type == document.NullValue {
+		return null, nil
+	}
+
+	tv, err := v.CastAsText()
+	if err != nil {
+		return "", err
+	}
+	return tv.V.(string), nil
+}
+
+func writeCSV(w io.Writer, rows []document.Document) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	columns, cells, err := flattenRows(rows, "")
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	err = cw.Write(columns)
+	if err != nil {
+		return err
+	}
+
+	err = cw.WriteAll(cells)
+	if err != nil {
+		return err
+	}
+
+	return cw.Error()
+}
+
+// writeTable writes the rows as a table with one column
+// per field found in the documents.
+func writeTable(w io.Writer, rows []document.Document) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	columns, cells, err := flattenRows(rows, "NULL")
+	if err != nil {
+		return err
+	}
+
+	widths := make([]int, len(columns))
+	for i, c := range columns {
+		widths[i] = utf8.RuneCountInString(c)
+	}
+	for _, row := range cells {
+		for i, c := range row {
+			if n := utf8.RuneCountInString(c); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+
+	separator := func() {
+		for _, width := range widths {
+			bw.WriteString("+" + strings.Repeat("-", width+2))
+		}
+		bw.WriteString("+\n")
+	}
+
+	line := func(row []string) {
+		for i, c := range row {
+			bw.WriteString("| " + c + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(c)) + " ")
+		}
+		bw.WriteString("|\n")
+	}
+
+	separator()
+	line(columns)
+	separator()
+	for _, row := range cells {
+		line(row)
+	}
+	separator()
+
+	return bw.Flush()
+}
+
+// writeLines writes each document as a list of "column = value" lines,
+// with a blank line between documents.
+func writeLines(w io.Writer, iterate func(fn func(d document.Document) error) error) error {
+	bw := bufio.NewWriter(w)
+
+	var columns, values []string
+	first := true
+	err := iterate(func(d document.Document) error {
+		columns, values = columns[:0], values[:0]
+		width := 0
+		err := dbutil.Flatten(d, func(column string, v document.Value) error {
+			s, err := cellText(v, "NULL")
+			if err != nil {
+				return err
+			}
+
+			columns = append(columns, column)
+			values = append(values, s)
+			if n := utf8.RuneCountInString(column); n > width {
+				width = n
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if !first {
+			bw.WriteByte('\n')
+		}
+		first = false
+
+		for i := range columns {
+			bw.WriteString(strings.Repeat(" ", width-utf8.RuneCountInString(columns[i])))
+			bw.WriteString(columns[i] + " = " + values[i] + "\n")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
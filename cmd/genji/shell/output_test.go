@@ -0,0 +1,111 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteDocuments(t *testing.T) {
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{jsonMode, `{
+  "a": 1,
+  "b": "foo"
+}
+{
+  "a": 2,
+  "c": {
+    "d": null,
+    "e": [
+      1,
+      "x"
+    ]
+  }
+}
+`},
+		{ndjsonMode, `{"a": 1, "b": "foo"}
+{"a": 2, "c": {"d": null, "e": [1, "x"]}}
+`},
+		{csvMode, `a,b,c.d,c.e
+1,foo,,
+2,,,"[1, ""x""]"
+`},
+		{tableMode, `+---+-----+------+----------+
+| a | b   | c.d  | c.e      |
++---+-----+------+----------+
+| 1 | foo |      |          |
+| 2 |     | NULL | [1, "x"] |
++---+-----+------+----------+
+`},
+		{lineMode, `a = 1
+b = foo
+
+  a = 2
+c.d = NULL
+c.e = [1, "x"]
+`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mode, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(`
+				CREATE TABLE test(a INTEGER);
+				INSERT INTO test (a, b) VALUES (1, 'foo');
+				INSERT INTO test (a, c) VALUES (2, {d: NULL, e: [1, "x"]});
+			`)
+			require.NoError(t, err)
+
+			res, err := db.Query("SELECT * FROM test")
+			require.NoError(t, err)
+			defer res.Close()
+
+			var buf bytes.Buffer
+			err = writeDocuments(context.Background(), &buf, test.mode, res)
+			require.NoError(t, err)
+			require.Equal(t, test.want, buf.String())
+		})
+	}
+}
+
+func TestOutputCommands(t *testing.T) {
+	ctx := context.Background()
+	opts := Options{Format: tableMode}
+	require.NoError(t, opts.validate())
+
+	sh := Shell{opts: &opts, mode: opts.Format}
+	defer func() {
+		sh.closeOutput()
+		if sh.db != nil {
+			sh.db.Close()
+		}
+	}()
+
+	require.Error(t, sh.runCommand(ctx, ".mode xml"))
+	require.Equal(t, tableMode, sh.mode)
+	require.NoError(t, sh.runCommand(ctx, ".mode csv"))
+	require.Equal(t, csvMode, sh.mode)
+
+	path := filepath.Join(t.TempDir(), "out.csv")
+	require.NoError(t, sh.runCommand(ctx, ".output "+path))
+	require.NoError(t, sh.runQuery(ctx, "SELECT 1 AS a, 'b' AS b;"))
+	require.NoError(t, sh.runCommand(ctx, ".output"))
+	require.Nil(t, sh.outFile)
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "a,b\n1,b\n", string(data))
+
+	require.Error(t, (&Options{Format: "xml"}).validate())
+}
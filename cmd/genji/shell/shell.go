@@ -3,16 +3,18 @@ package shell
 import (
 	"bufio"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/c-bata/go-prompt"
 	"github.com/dgraph-io/badger/v2"
@@ -23,6 +25,8 @@ import (
 	"github.com/genjidb/genji/engine/boltengine"
 	"github.com/genjidb/genji/engine/memoryengine"
 	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
 	"go.uber.org/multierr"
 	"golang.org/x/sync/errgroup"
 )
@@ -52,6 +56,22 @@ type Shell struct {
 	history []string
 
 	cmdSuggestions []prompt.Suggest
+	// fields of the tables suggested by the completer,
+	// cleared every time a query is executed.
+	fieldsCache map[string][]string
+
+	// named parameters of the queries, set with the .param command.
+	params map[string]document.Value
+	// if true, the execution time of each statement is displayed.
+	timer bool
+	// number of nested .read commands being executed.
+	readDepth int
+
+	// output mode of the query results.
+	mode string
+	// file the query results are written to,
+	// if redirected with the .output command.
+	outFile *os.File
 
 	// context used for execution cancelation,
 	// these must not be used manually.
@@ -70,6 +90,9 @@ type Options struct {
 	Engine string
 	// Path of the database file or directory that will be created.
 	DBPath string
+	// Output mode of the query results: "json", "ndjson", "csv", "table" or "line".
+	// If empty, "json" will be used.
+	Format string
 }
 
 func (o *Options) validate() error {
@@ -87,7 +110,11 @@ func (o *Options) validate() error {
 		return fmt.Errorf("unsupported engine %q", o.Engine)
 	}
 
-	return nil
+	if o.Format == "" {
+		o.Format = jsonMode
+	}
+
+	return validateOutputMode(o.Format)
 }
 
 func stdinFromTerminal() bool {
@@ -112,6 +139,8 @@ func Run(ctx context.Context, opts *Options) error {
 	var sh Shell
 
 	sh.opts = opts
+	sh.mode = opts.Format
+	defer sh.closeOutput()
 
 	if stdinFromTerminal() {
 		switch opts.Engine {
@@ -427,6 +456,11 @@ func (sh *Shell) dumpHistory() error {
 func (sh *Shell) executeInput(ctx context.Context, in string) error {
 	sh.history = append(sh.history, in)
 
+	return sh.execute(ctx, in)
+}
+
+// execute a line of input. Queries are executed once a line ends with a semicolon.
+func (sh *Shell) execute(ctx context.Context, in string) error {
 	switch {
 	// if it starts with a "." it's a command
 	// if the input is "help" or "exit", then it's a command.
@@ -460,6 +494,21 @@ func (sh *Shell) runCommand(ctx context.Context, in string) error {
 	in = strings.TrimSuffix(in, ";")
 	cmd := strings.Fields(in)
 	switch cmd[0] {
+	case ".read":
+		if len(cmd) != 2 {
+			return fmt.Errorf("usage: .read filename")
+		}
+
+		return sh.runReadCmd(ctx, cmd[1])
+	case ".param":
+		return sh.runParamCmd(in)
+	case ".timer":
+		if len(cmd) != 2 || (cmd[1] != "on" && cmd[1] != "off") {
+			return fmt.Errorf("usage: .timer on|off")
+		}
+
+		sh.timer = cmd[1] == "on"
+		return nil
 	case ".help", "help":
 		return runHelpCmd()
 	case ".tables":
@@ -488,6 +537,62 @@ func (sh *Shell) runCommand(ctx context.Context, in string) error {
 		}
 
 		return runDumpCmd(db, cmd[1:], os.Stdout)
+	case ".schema":
+		db, err := sh.getDB(ctx)
+		if err != nil {
+			return err
+		}
+
+		return runSchemaCmd(db, cmd[1:], os.Stdout)
+	case ".describe":
+		db, err := sh.getDB(ctx)
+		if err != nil {
+			return err
+		}
+
+		return runDescribeCmd(db, cmd, os.Stdout)
+	case ".stats":
+		db, err := sh.getDB(ctx)
+		if err != nil {
+			return err
+		}
+
+		return runStatsCmd(db, cmd, os.Stdout)
+	case ".mode":
+		switch len(cmd) {
+		case 1:
+			fmt.Println(sh.mode)
+			return nil
+		case 2:
+			err := validateOutputMode(cmd[1])
+			if err != nil {
+				return err
+			}
+			sh.mode = cmd[1]
+			return nil
+		}
+
+		return fmt.Errorf("usage: .mode [%s]", strings.Join(outputModes, "|"))
+	case ".output":
+		if len(cmd) > 2 {
+			return fmt.Errorf("usage: .output [filename]")
+		}
+
+		err := sh.closeOutput()
+		if err != nil {
+			return err
+		}
+
+		if len(cmd) == 1 || cmd[1] == "stdout" {
+			return nil
+		}
+
+		f, err := os.OpenFile(cmd[1], os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		sh.outFile = f
+		return nil
 	case ".save":
 		db, err := sh.getDB(ctx)
 		if err != nil {
@@ -512,31 +617,192 @@ func (sh *Shell) runCommand(ctx context.Context, in string) error {
 	}
 }
 
+// runQuery executes the statements of the query one by one,
+// using the named parameters of the shell, and writes their results.
 func (sh *Shell) runQuery(ctx context.Context, q string) error {
+	sh.fieldsCache = nil
+
 	db, err := sh.getDB(ctx)
 	if err != nil {
 		return err
 	}
 
-	res, err := db.Query(q)
+	pq, err := parser.ParseQuery(q)
 	if err != nil {
 		return err
 	}
 
-	defer res.Close()
+	params := sh.queryParams()
+	for _, stmt := range pq.Statements {
+		start := time.Now()
+		err := sh.runStatement(ctx, db, stmt, params)
+		if sh.timer {
+			fmt.Printf("Time: %s\n", time.Since(start))
+		}
+		if err != nil {
+			return err
+		}
+	}
 
-	enc := json.NewEncoder(os.Stdout)
-	enc.SetEscapeHTML(false)
-	enc.SetIndent("", "  ")
-	return res.Iterate(func(d document.Document) error {
-		select {
-		case <-ctx.Done():
-			return errors.New("interrupted")
-		default:
+	return nil
+}
+
+// runStatement executes a statement in its own transaction, unless a transaction
+// was opened with BEGIN, and writes its result.
+func (sh *Shell) runStatement(ctx context.Context, db *genji.DB, stmt query.Statement, params []expr.Param) error {
+	res, err := query.New(stmt).Run(ctx, db.DB, params)
+	if err != nil {
+		return err
+	}
+
+	err = writeDocuments(ctx, sh.output(), sh.mode, res)
+	closeErr := res.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// maxReadDepth is the maximum number of nested .read commands.
+const maxReadDepth = 16
+
+// runReadCmd executes the queries and commands of a file, line by line.
+// It stops at the first error.
+func (sh *Shell) runReadCmd(ctx context.Context, path string) error {
+	if sh.readDepth >= maxReadDepth {
+		return fmt.Errorf("too many nested .read commands")
+	}
+	sh.readDepth++
+	defer func() {
+		sh.readDepth--
+	}()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1024*1024)
+	for n := 1; s.Scan(); n++ {
+		err := sh.execute(ctx, strings.TrimSpace(s.Text()))
+		if err == errExitCommand {
+			return err
 		}
+		if err != nil {
+			sh.resetQuery()
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		sh.resetQuery()
+		return err
+	}
 
-		return enc.Encode(d)
-	})
+	// the last query doesn't need to end with a semicolon.
+	if sh.multiLine {
+		q := sh.query
+		sh.resetQuery()
+		return sh.runQuery(ctx, q)
+	}
+
+	return nil
+}
+
+// resetQuery discards the query being typed.
+func (sh *Shell) resetQuery() {
+	sh.query = ""
+	sh.multiLine = false
+}
+
+// runParamCmd manages the named parameters of the queries.
+func (sh *Shell) runParamCmd(in string) error {
+	sub, rest := cutWord(strings.TrimPrefix(in, ".param"))
+	name, value := cutWord(rest)
+	name = strings.TrimPrefix(name, "$")
+
+	switch {
+	case sub == "set" && name != "" && value != "":
+		// the value is everything after the name, including spaces.
+		e, err := parser.ParseExpr(value)
+		if err != nil {
+			return err
+		}
+
+		v, err := e.Eval(expr.EvalStack{})
+		if err != nil {
+			return err
+		}
+
+		if sh.params == nil {
+			sh.params = make(map[string]document.Value)
+		}
+		sh.params[name] = v
+		return nil
+	case sub == "unset" && name != "" && value == "":
+		delete(sh.params, name)
+		return nil
+	case (sub == "list" || sub == "") && name == "":
+		names := make([]string, 0, len(sh.params))
+		for name := range sh.params {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			fmt.Printf("$%s = %s\n", name, sh.params[name])
+		}
+		return nil
+	case sub == "clear" && name == "":
+		sh.params = nil
+		return nil
+	}
+
+	return fmt.Errorf("usage: .param set $name value | .param unset $name | .param list | .param clear")
+}
+
+// cutWord returns the first word of s and the rest of s, without surrounding spaces.
+func cutWord(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexAny(s, " \t")
+	if i < 0 {
+		return s, ""
+	}
+
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+// queryParams returns the named parameters of the shell.
+func (sh *Shell) queryParams() []expr.Param {
+	params := make([]expr.Param, 0, len(sh.params))
+	for name, v := range sh.params {
+		params = append(params, expr.Param{Name: name, Value: v.V})
+	}
+
+	return params
+}
+
+// output returns the writer the query results are written to.
+func (sh *Shell) output() io.Writer {
+	if sh.outFile != nil {
+		return sh.outFile
+	}
+
+	return os.Stdout
+}
+
+// closeOutput closes the file the query results are written to, if any,
+// and writes them to the standard output again.
+func (sh *Shell) closeOutput() error {
+	if sh.outFile == nil {
+		return nil
+	}
+
+	err := sh.outFile.Close()
+	sh.outFile = nil
+	return err
 }
 
 func (sh *Shell) getDB(ctx context.Context) (*genji.DB, error) {
@@ -587,8 +853,27 @@ func (sh *Shell) runPipedInput(ctx context.Context) (ran bool, err error) {
 	return true, nil
 }
 
+// changelivePrefix returns the prefix of the prompt. It indicates if a query
+// is being typed on multiple lines or if a transaction was opened with BEGIN.
 func (sh *Shell) changelivePrefix() (string, bool) {
-	return sh.livePrefix, sh.multiLine
+	if sh.multiLine {
+		return sh.livePrefix, true
+	}
+
+	if sh.db == nil {
+		return "", false
+	}
+
+	tx := sh.db.DB.GetAttachedTx()
+	if tx == nil {
+		return "", false
+	}
+
+	if tx.Writable() {
+		return "genji [tx]> ", true
+	}
+
+	return "genji [read-only tx]> ", true
 }
 
 func (sh *Shell) getAllIndexes(ctx context.Context) ([]string, error) {
@@ -645,42 +930,3 @@ func (sh *Shell) getAllTables(ctx context.Context) ([]string, error) {
 
 	return tables, nil
 }
-
-func (sh *Shell) completer(in prompt.Document) []prompt.Suggest {
-	suggestions := prompt.FilterHasPrefix(sh.cmdSuggestions, in.Text, true)
-
-	_, err := parser.ParseQuery(in.Text)
-	if err != nil {
-		e, ok := err.(*parser.ParseError)
-		if !ok || len(e.Expected) < 1 {
-			return suggestions
-		}
-		expected := e.Expected
-		switch expected[0] {
-		case "table_name":
-			expected, err = sh.getAllTables(context.Background())
-			if err != nil {
-				return suggestions
-			}
-		case "index_name":
-			expected, err = sh.getAllIndexes(context.Background())
-			if err != nil {
-				return suggestions
-			}
-		}
-		for _, e := range expected {
-			suggestions = append(suggestions, prompt.Suggest{
-				Text: e,
-			})
-		}
-
-		w := in.GetWordBeforeCursor()
-		if w == "" {
-			return suggestions
-		}
-
-		return prompt.FilterHasPrefix(suggestions, w, true)
-	}
-
-	return []prompt.Suggest{}
-}
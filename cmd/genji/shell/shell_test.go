@@ -0,0 +1,142 @@
+package shell
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestShell(t *testing.T) *Shell {
+	opts := Options{Format: ndjsonMode}
+	require.NoError(t, opts.validate())
+
+	sh := Shell{opts: &opts, mode: opts.Format}
+	t.Cleanup(func() {
+		sh.closeOutput()
+		if sh.db != nil {
+			sh.db.Close()
+		}
+	})
+
+	return &sh
+}
+
+func TestReadCommand(t *testing.T) {
+	ctx := context.Background()
+	sh := newTestShell(t)
+	dir := t.TempDir()
+
+	out := filepath.Join(dir, "out.json")
+	script := filepath.Join(dir, "script.sql")
+	err := ioutil.WriteFile(script, []byte(`
+.output `+out+`
+CREATE TABLE foo;
+INSERT INTO foo (a)
+  VALUES (1), (2);
+SELECT a FROM foo
+  WHERE a > 1
+`), 0644)
+	require.NoError(t, err)
+
+	require.NoError(t, sh.runCommand(ctx, ".read "+script))
+	require.False(t, sh.multiLine)
+	require.NoError(t, sh.runCommand(ctx, ".output"))
+
+	data, err := ioutil.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "{\"a\": 2}\n", string(data))
+
+	// errors are reported with the line of the script.
+	err = ioutil.WriteFile(script, []byte("SELECT 1;\nSELEC 2;\nSELECT 3;\n"), 0644)
+	require.NoError(t, err)
+	err = sh.runCommand(ctx, ".read "+script)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), script+":2:")
+
+	// scripts can't read themselves forever.
+	err = ioutil.WriteFile(script, []byte(".read "+script+"\n"), 0644)
+	require.NoError(t, err)
+	require.Error(t, sh.runCommand(ctx, ".read "+script))
+	require.Zero(t, sh.readDepth)
+
+	require.Error(t, sh.runCommand(ctx, ".read"))
+	require.Error(t, sh.runCommand(ctx, ".read "+filepath.Join(dir, "unknown.sql")))
+}
+
+func TestParamCommand(t *testing.T) {
+	ctx := context.Background()
+	sh := newTestShell(t)
+
+	require.NoError(t, sh.runCommand(ctx, ".param set $a 'hello  world'"))
+	require.NoError(t, sh.runCommand(ctx, ".param set b 1 + 2"))
+	require.NoError(t, sh.runCommand(ctx, ".param set $c [1, 2]"))
+	require.Len(t, sh.params, 3)
+	require.Equal(t, "hello  world", sh.params["a"].V)
+	require.EqualValues(t, 3, sh.params["b"].V)
+
+	out := filepath.Join(t.TempDir(), "out.json")
+	require.NoError(t, sh.runCommand(ctx, ".output "+out))
+	require.NoError(t, sh.runQuery(ctx, "SELECT $a AS a, $b AS b, $c AS c;"))
+	require.NoError(t, sh.runCommand(ctx, ".output"))
+
+	data, err := ioutil.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "{\"a\": \"hello  world\", \"b\": 3, \"c\": [1, 2]}\n", string(data))
+
+	require.NoError(t, sh.runCommand(ctx, ".param unset $a"))
+	require.Len(t, sh.params, 2)
+	require.Error(t, sh.runQuery(ctx, "SELECT $a;"))
+
+	require.NoError(t, sh.runCommand(ctx, ".param list"))
+	require.NoError(t, sh.runCommand(ctx, ".param clear"))
+	require.Empty(t, sh.params)
+
+	require.Error(t, sh.runCommand(ctx, ".param set $a"))
+	require.Error(t, sh.runCommand(ctx, ".param set $a 'foo"))
+	require.Error(t, sh.runCommand(ctx, ".param unset"))
+	require.Error(t, sh.runCommand(ctx, ".param foo"))
+}
+
+func TestTimerCommand(t *testing.T) {
+	ctx := context.Background()
+	sh := newTestShell(t)
+
+	require.NoError(t, sh.runCommand(ctx, ".timer on"))
+	require.True(t, sh.timer)
+	require.NoError(t, sh.runCommand(ctx, ".timer off"))
+	require.False(t, sh.timer)
+	require.Error(t, sh.runCommand(ctx, ".timer"))
+	require.Error(t, sh.runCommand(ctx, ".timer foo"))
+}
+
+func TestLivePrefix(t *testing.T) {
+	ctx := context.Background()
+	sh := newTestShell(t)
+
+	prefix, ok := sh.changelivePrefix()
+	require.False(t, ok)
+
+	require.NoError(t, sh.execute(ctx, "SELECT 1"))
+	prefix, ok = sh.changelivePrefix()
+	require.True(t, ok)
+	require.Equal(t, "... ", prefix)
+	sh.resetQuery()
+
+	require.NoError(t, sh.runQuery(ctx, "BEGIN;"))
+	prefix, ok = sh.changelivePrefix()
+	require.True(t, ok)
+	require.Equal(t, "genji [tx]> ", prefix)
+	require.NoError(t, sh.runQuery(ctx, "ROLLBACK;"))
+
+	require.NoError(t, sh.runQuery(ctx, "BEGIN READ ONLY;"))
+	prefix, ok = sh.changelivePrefix()
+	require.True(t, ok)
+	require.Equal(t, "genji [read-only tx]> ", prefix)
+	require.NoError(t, sh.runQuery(ctx, "ROLLBACK;"))
+
+	_, ok = sh.changelivePrefix()
+	require.False(t, ok)
+}
@@ -0,0 +1,442 @@
+package database
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+
+	"github.com/genjidb/genji/engine"
+)
+
+// Backups are a sequence of records, preceded by a header made of backupMagic
+// and the version of the format:
+//
+//	store record: 's' | uvarint(len(name)) | name
+//	pair record:  'p' | uvarint(len(key)) | key | uvarint(len(value)) | value
+//	end record:   'e' | crc32(header and every record, including 'e')
+//
+// Pair records belong to the last store record that precedes them.
+const (
+	backupMagic   = "GENJIBKP"
+	backupVersion = 1
+
+	backupStoreRecord = 's'
+	backupPairRecord  = 'p'
+	backupEndRecord   = 'e'
+)
+
+// ErrInvalidBackup is returned when restoring a backup that is corrupted
+// or that wasn't created by Backup.
+var ErrInvalidBackup = errors.New("invalid backup")
+
+// Backup writes a consistent snapshot of the database to w.
+// The content of every table is read from a single read-only transaction,
+// which, depending on the engine, doesn't prevent other transactions from writing
+// to the database during the backup.
+// Indexes are not part of the backup and are recreated by Restore.
+// The backup doesn't depend on the engine and can be restored on any engine,
+// as long as the database uses the same codec.
+func (db *Database) Backup(ctx context.Context, w io.Writer) error {
+	tx, err := db.BeginTx(ctx, &TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	bw := bufio.NewWriter(w)
+	bk := backupWriter{
+		w:   bw,
+		crc: crc32.NewIEEE(),
+	}
+
+	bk.write([]byte(backupMagic))
+	bk.write([]byte{backupVersion})
+
+	storeNames := [][]byte{
+		[]byte(tableInfoStoreName),
+		[]byte(indexStoreName),
+		[]byte(triggerStoreName),
+	}
+
+	tables, err := tx.listTableInfos()
+	if err != nil {
+		return err
+	}
+	for _, ti := range tables {
+		storeNames = append(storeNames, ti.storeName)
+	}
+
+	for _, name := range storeNames {
+		st, err := tx.tx.GetStore(name)
+		if err != nil {
+			return err
+		}
+
+		err = bk.writeStore(ctx, name, st)
+		if err != nil {
+			return err
+		}
+	}
+
+	bk.write([]byte{backupEndRecord})
+	if bk.err != nil {
+		return bk.err
+	}
+
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], bk.crc.Sum32())
+	_, err = bw.Write(sum[:])
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// listTableInfos returns the information of all the tables.
+func (tx *Transaction) listTableInfos() ([]*TableInfo, error) {
+	it := tx.tableInfoStore.st.Iterator(engine.IteratorOptions{})
+	defer it.Close()
+
+	var list []*TableInfo
+	var buf []byte
+	var err error
+	for it.Seek(nil); it.Valid(); it.Next() {
+		buf, err = it.Item().ValueCopy(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		var ti TableInfo
+		err = ti.ScanDocument(tx.db.Codec.NewDocument(buf))
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, &ti)
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+type backupWriter struct {
+	w   io.Writer
+	crc hash.Hash32
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (b *backupWriter) write(data []byte) {
+	if b.err != nil {
+		return
+	}
+
+	_, b.err = b.w.Write(data)
+	b.crc.Write(data)
+}
+
+func (b *backupWriter) writeBytes(data []byte) {
+	n := binary.PutUvarint(b.buf[:], uint64(len(data)))
+	b.write(b.buf[:n])
+	b.write(data)
+}
+
+func (b *backupWriter) writeStore(ctx context.Context, name []byte, st engine.Store) error {
+	b.write([]byte{backupStoreRecord})
+	b.writeBytes(name)
+
+	it := st.Iterator(engine.IteratorOptions{})
+	defer it.Close()
+
+	var v []byte
+	var err error
+	for it.Seek(nil); it.Valid(); it.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		item := it.Item()
+		v, err = item.ValueCopy(v[:0])
+		if err != nil {
+			return err
+		}
+
+		b.write([]byte{backupPairRecord})
+		b.writeBytes(item.Key())
+		b.writeBytes(v)
+		if b.err != nil {
+			return b.err
+		}
+	}
+
+	return it.Err()
+}
+
+// Restore reads a backup created by Backup and writes it to ng, then opens
+// the database. The engine must not contain any table.
+// The backup is restored within a single transaction: if it is corrupted,
+// nothing is written to the engine.
+func Restore(ctx context.Context, r io.Reader, ng engine.Engine, opts Options) (*Database, error) {
+	db, err := New(ctx, ng, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.restore(ctx, r)
+	if err != nil {
+		db.stopReaper()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func (db *Database) restore(ctx context.Context, r io.Reader) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tables, err := tx.listTableInfos()
+	if err != nil {
+		return err
+	}
+	if len(tables) > 0 {
+		return errors.New("cannot restore a backup in a database with tables")
+	}
+
+	bk := backupReader{
+		r:   bufio.NewReader(r),
+		crc: crc32.NewIEEE(),
+	}
+
+	header := make([]byte, len(backupMagic)+1)
+	err = bk.readFull(header)
+	if err != nil {
+		return err
+	}
+	if string(header[:len(backupMagic)]) != backupMagic {
+		return fmt.Errorf("%w: unknown format", ErrInvalidBackup)
+	}
+	if header[len(backupMagic)] != backupVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidBackup, header[len(backupMagic)])
+	}
+
+	var st engine.Store
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		kind, err := bk.readByte()
+		if err != nil {
+			return err
+		}
+
+		switch kind {
+		case backupStoreRecord:
+			name, err := bk.readBytes()
+			if err != nil {
+				return err
+			}
+
+			st, err = tx.tx.GetStore(name)
+			if err == engine.ErrStoreNotFound {
+				err = tx.tx.CreateStore(name)
+				if err == nil {
+					st, err = tx.tx.GetStore(name)
+				}
+			}
+			if err != nil {
+				return err
+			}
+		case backupPairRecord:
+			if st == nil {
+				return fmt.Errorf("%w: missing store record", ErrInvalidBackup)
+			}
+
+			k, err := bk.readBytes()
+			if err != nil {
+				return err
+			}
+			v, err := bk.readBytes()
+			if err != nil {
+				return err
+			}
+
+			err = st.Put(k, v)
+			if err != nil {
+				return err
+			}
+		case backupEndRecord:
+			sum := bk.crc.Sum32()
+
+			var got [4]byte
+			_, err = io.ReadFull(bk.r, got[:])
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+			}
+			if binary.BigEndian.Uint32(got[:]) != sum {
+				return fmt.Errorf("%w: checksum mismatch", ErrInvalidBackup)
+			}
+
+			err = tx.restoreSequences()
+			if err != nil {
+				return err
+			}
+
+			err = tx.ReIndexAll()
+			if err != nil {
+				return err
+			}
+
+			return tx.Commit()
+		default:
+			return fmt.Errorf("%w: unknown record %q", ErrInvalidBackup, kind)
+		}
+	}
+}
+
+// restoreSequences advances the sequences used to generate store names
+// and document ids, which are not part of the backup, past the restored values.
+func (tx *Transaction) restoreSequences() error {
+	tables, err := tx.listTableInfos()
+	if err != nil {
+		return err
+	}
+
+	var maxStoreSeq uint64
+	for _, ti := range tables {
+		seq, n := binary.Uvarint(ti.storeName[1:])
+		if n > 0 && seq > maxStoreSeq {
+			maxStoreSeq = seq
+		}
+
+		if ti.GetPrimaryKey() != nil {
+			continue
+		}
+
+		st, err := tx.tx.GetStore(ti.storeName)
+		if err != nil {
+			return err
+		}
+
+		maxDocID, err := lastDocID(st)
+		if err != nil {
+			return err
+		}
+
+		err = advanceSequence(st, maxDocID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return advanceSequence(tx.tableInfoStore.st, maxStoreSeq)
+}
+
+// lastDocID returns the greatest document id of a table without primary key.
+func lastDocID(st engine.Store) (uint64, error) {
+	it := st.Iterator(engine.IteratorOptions{})
+	defer it.Close()
+
+	// keys are uvarints, which are not sorted numerically,
+	// all of them must be read.
+	var max uint64
+	for it.Seek(nil); it.Valid(); it.Next() {
+		docid, n := binary.Uvarint(it.Item().Key())
+		if n > 0 && docid > max {
+			max = docid
+		}
+	}
+
+	return max, it.Err()
+}
+
+// advanceSequence sets the sequence of the store to seq, if it is lower.
+func advanceSequence(st engine.Store, seq uint64) error {
+	if seq == 0 {
+		return nil
+	}
+
+	if ss, ok := st.(engine.SequenceSetter); ok {
+		return ss.SetSequence(seq)
+	}
+
+	// engines which can't set the sequence directly
+	// can only advance it one value at a time.
+	for {
+		next, err := st.NextSequence()
+		if err != nil {
+			return err
+		}
+		if next >= seq {
+			return nil
+		}
+	}
+}
+
+type backupReader struct {
+	r   *bufio.Reader
+	crc hash.Hash32
+}
+
+// wrapErr reports unexpected ends of the backup as invalid backups.
+func (b *backupReader) wrapErr(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: unexpected end of backup", ErrInvalidBackup)
+	}
+
+	return err
+}
+
+func (b *backupReader) readByte() (byte, error) {
+	c, err := b.r.ReadByte()
+	if err != nil {
+		return 0, b.wrapErr(err)
+	}
+
+	b.crc.Write([]byte{c})
+	return c, nil
+}
+
+func (b *backupReader) readFull(buf []byte) error {
+	_, err := io.ReadFull(b.r, buf)
+	if err != nil {
+		return b.wrapErr(err)
+	}
+
+	b.crc.Write(buf)
+	return nil
+}
+
+func (b *backupReader) readBytes() ([]byte, error) {
+	l, err := binary.ReadUvarint(byteReaderFunc(b.readByte))
+	if err != nil {
+		return nil, err
+	}
+
+	// avoid allocating large buffers for corrupted backups.
+	if l > math.MaxInt32 {
+		return nil, fmt.Errorf("%w: invalid length", ErrInvalidBackup)
+	}
+
+	buf := make([]byte, l)
+	return buf, b.readFull(buf)
+}
+
+type byteReaderFunc func() (byte, error)
+
+func (f byteReaderFunc) ReadByte() (byte, error) {
+	return f()
+}
@@ -0,0 +1,199 @@
+package database_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine/boltengine"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+func backupTestDB(t *testing.T) []byte {
+	t.Helper()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(`
+		CREATE TABLE test; CREATE TABLE users(id INTEGER PRIMARY KEY); CREATE TABLE audit;
+		CREATE INDEX idx_a ON test (a);
+		CREATE UNIQUE INDEX idx_name ON users (name);
+		CREATE TRIGGER on_insert AFTER INSERT ON test INSERT INTO audit (a) VALUES ($new.a);
+		INSERT INTO test (a) VALUES (1), (2), (3);
+		INSERT INTO users (id, name) VALUES (1, 'foo'), (2, 'bar');
+	`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = db.Backup(context.Background(), &buf)
+	require.NoError(t, err)
+
+	return buf.Bytes()
+}
+
+func TestBackupRestore(t *testing.T) {
+	data := backupTestDB(t)
+
+	dir, err := ioutil.TempDir("", "genji")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	boltNg, err := boltengine.NewEngine(filepath.Join(dir, "test.db"), 0600, nil)
+	require.NoError(t, err)
+
+	engines := []struct {
+		name string
+		db   func() (*genji.DB, error)
+	}{
+		{"Memory", func() (*genji.DB, error) {
+			return genji.Restore(context.Background(), bytes.NewReader(data), memoryengine.NewEngine())
+		}},
+		{"Bolt", func() (*genji.DB, error) {
+			return genji.Restore(context.Background(), bytes.NewReader(data), boltNg)
+		}},
+	}
+
+	for _, e := range engines {
+		t.Run(e.name, func(t *testing.T) {
+			db, err := e.db()
+			require.NoError(t, err)
+			defer db.Close()
+
+			require.EqualValues(t, 3, countDocuments(t, db, "test"))
+			require.EqualValues(t, 2, countDocuments(t, db, "users"))
+			require.EqualValues(t, 3, countDocuments(t, db, "audit"))
+
+			// indexes are rebuilt.
+			d, err := db.QueryDocument("SELECT a FROM test WHERE a = 2")
+			require.NoError(t, err)
+			v, err := d.GetByField("a")
+			require.NoError(t, err)
+			require.Equal(t, document.NewDoubleValue(2), v)
+
+			err = db.Exec("INSERT INTO users (id, name) VALUES (3, 'foo')")
+			require.True(t, errors.Is(err, database.ErrDuplicateDocument))
+
+			// document ids continue after the restored ones
+			// and triggers are restored.
+			err = db.Exec("INSERT INTO test (a) VALUES (4)")
+			require.NoError(t, err)
+			require.EqualValues(t, 4, countDocuments(t, db, "test"))
+			require.EqualValues(t, 4, countDocuments(t, db, "audit"))
+
+			// new tables don't reuse the store of restored tables.
+			err = db.Exec("CREATE TABLE other; INSERT INTO other (a) VALUES (1)")
+			require.NoError(t, err)
+			require.EqualValues(t, 4, countDocuments(t, db, "test"))
+			require.EqualValues(t, 1, countDocuments(t, db, "other"))
+		})
+	}
+
+	t.Run("Concurrent writes", func(t *testing.T) {
+		db, err := genji.Open(filepath.Join(dir, "concurrent.db"))
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec("CREATE TABLE test; INSERT INTO test (a) VALUES (1)")
+		require.NoError(t, err)
+
+		// writes are not visible to the snapshot.
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		var buf bytes.Buffer
+		err = db.Backup(context.Background(), &buf)
+		require.NoError(t, err)
+
+		err = tx.Exec("INSERT INTO test (a) VALUES (2)")
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+
+		restored, err := genji.Restore(context.Background(), &buf, memoryengine.NewEngine())
+		require.NoError(t, err)
+		defer restored.Close()
+
+		require.EqualValues(t, 1, countDocuments(t, restored, "test"))
+	})
+
+	t.Run("Corrupted", func(t *testing.T) {
+		corrupted := append([]byte(nil), data...)
+		corrupted[len(corrupted)/2] ^= 0xFF
+
+		_, err := genji.Restore(context.Background(), bytes.NewReader(corrupted), memoryengine.NewEngine())
+		require.True(t, errors.Is(err, database.ErrInvalidBackup))
+
+		_, err = genji.Restore(context.Background(), bytes.NewReader(data[:len(data)-1]), memoryengine.NewEngine())
+		require.True(t, errors.Is(err, database.ErrInvalidBackup))
+
+		_, err = genji.Restore(context.Background(), bytes.NewReader([]byte("foo")), memoryengine.NewEngine())
+		require.True(t, errors.Is(err, database.ErrInvalidBackup))
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		// unlike the memory engine, Bolt iterators ignore the context.
+		db, err := genji.Open(filepath.Join(dir, "canceled.db"))
+		require.NoError(t, err)
+		defer db.Close()
+
+		var q strings.Builder
+		q.WriteString("CREATE TABLE test; INSERT INTO test (a, b) VALUES ")
+		for i := 0; i < 1000; i++ {
+			if i > 0 {
+				q.WriteString(", ")
+			}
+			fmt.Fprintf(&q, "(%d, 'some text to fill the buffers')", i)
+		}
+		err = db.Exec(q.String())
+		require.NoError(t, err)
+
+		// the context is canceled once the first bytes are written.
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		w := writerFunc(func(p []byte) (int, error) {
+			cancel()
+			return len(p), nil
+		})
+
+		err = db.Backup(ctx, w)
+		require.True(t, errors.Is(err, context.Canceled))
+
+		var buf bytes.Buffer
+		err = db.Backup(context.Background(), &buf)
+		require.NoError(t, err)
+
+		ctx, cancel = context.WithCancel(context.Background())
+		cancel()
+		_, err = genji.Restore(ctx, &buf, memoryengine.NewEngine())
+		require.True(t, errors.Is(err, context.Canceled))
+	})
+
+	t.Run("Non empty target", func(t *testing.T) {
+		ng := memoryengine.NewEngine()
+		db, err := genji.New(context.Background(), ng)
+		require.NoError(t, err)
+		err = db.Exec("CREATE TABLE foo")
+		require.NoError(t, err)
+
+		_, err = genji.Restore(context.Background(), bytes.NewReader(data), ng)
+		require.Error(t, err)
+	})
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,308 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/index"
+)
+
+// DefaultBulkLoadBatchSize is the number of documents inserted per transaction
+// by a BulkLoader, if not specified in the options.
+const DefaultBulkLoadBatchSize = 10000
+
+// ErrBulkLoaderClosed is returned when using a bulk loader that was closed or that failed.
+var ErrBulkLoaderClosed = errors.New("bulk loader closed")
+
+// BulkLoadOptions configures a BulkLoader.
+type BulkLoadOptions struct {
+	// Number of documents inserted per transaction.
+	// Defaults to DefaultBulkLoadBatchSize.
+	BatchSize int
+	// If set, the non-unique indexes of the table are not updated while
+	// documents are written but rebuilt once the loader is closed, by sorting
+	// all of their values in memory.
+	// Unique indexes are always updated while documents are written, to report
+	// duplicates as soon as possible.
+	// If the program stops before Close or Abort are called, the documents
+	// already committed are missing from the non-unique indexes until they are
+	// rebuilt with REINDEX.
+	DeferIndexes bool
+}
+
+// A BulkLoader inserts documents in a table in batches, one transaction per batch.
+// The table information, triggers and indexes are read once per batch, which is
+// faster than running an INSERT statement per document.
+// Each batch holds a writable transaction until it is committed.
+// A BulkLoader is not safe for concurrent use.
+type BulkLoader struct {
+	db        *Database
+	ctx       context.Context
+	tableName string
+	opts      BulkLoadOptions
+
+	// state of the current batch.
+	tx       *Transaction
+	table    *Table
+	info     *TableInfo
+	triggers []trigger
+	indexes  map[string]Index
+	size     int
+
+	count  int64
+	failed bool
+	closed bool
+}
+
+// BulkLoad returns a loader which inserts documents in the given table.
+// If opts is nil, it uses the default options.
+func (db *Database) BulkLoad(ctx context.Context, tableName string, opts *BulkLoadOptions) (*BulkLoader, error) {
+	var o BulkLoadOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultBulkLoadBatchSize
+	}
+
+	// ensure the table exists before loading anything.
+	tx, err := db.BeginTx(ctx, &TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.GetTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BulkLoader{
+		db:        db,
+		ctx:       ctx,
+		tableName: tableName,
+		opts:      o,
+	}, nil
+}
+
+// Write inserts a document in the table. The document is committed with the
+// rest of its batch, once the batch is full or when Flush or Close are called.
+// If Write returns an error, the documents written since the last commit are
+// rolled back and only Close or Abort can be called.
+func (l *BulkLoader) Write(d document.Document) error {
+	if l.closed || l.failed {
+		return ErrBulkLoaderClosed
+	}
+
+	if l.tx == nil {
+		err := l.begin()
+		if err != nil {
+			return l.fail(err)
+		}
+	}
+
+	_, err := l.table.insert(l.info, l.triggers, l.indexes, d)
+	if err != nil {
+		return l.fail(err)
+	}
+
+	l.size++
+	if l.size >= l.opts.BatchSize {
+		return l.Flush()
+	}
+
+	return nil
+}
+
+// begin a new batch and read the table information used to insert its documents.
+func (l *BulkLoader) begin() error {
+	tx, err := l.db.BeginTx(l.ctx, nil)
+	if err != nil {
+		return err
+	}
+	l.tx = tx
+
+	l.table, err = tx.GetTable(l.tableName)
+	if err != nil {
+		return err
+	}
+
+	l.info, err = l.table.Info()
+	if err != nil {
+		return err
+	}
+	if l.info.readOnly {
+		return errors.New("cannot write to read-only table")
+	}
+
+	l.triggers, err = l.table.triggers(InsertChange)
+	if err != nil {
+		return err
+	}
+
+	l.indexes, err = l.table.Indexes()
+	if err != nil {
+		return err
+	}
+
+	if l.opts.DeferIndexes {
+		for name, idx := range l.indexes {
+			if !idx.Opts.Unique {
+				delete(l.indexes, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// rollback the current batch, if any.
+func (l *BulkLoader) rollback() {
+	if l.tx != nil {
+		_ = l.tx.Rollback()
+		l.tx = nil
+	}
+	l.size = 0
+}
+
+// fail rolls back the current batch and prevents writing more documents.
+func (l *BulkLoader) fail(err error) error {
+	l.rollback()
+	l.failed = true
+	return err
+}
+
+// Flush commits the documents written since the last commit.
+func (l *BulkLoader) Flush() error {
+	if l.closed || l.failed {
+		return ErrBulkLoaderClosed
+	}
+	if l.tx == nil {
+		return nil
+	}
+
+	err := l.tx.Commit()
+	l.tx = nil
+	if err != nil {
+		return l.fail(err)
+	}
+
+	l.count += int64(l.size)
+	l.size = 0
+	return nil
+}
+
+// Count returns the number of documents committed by the loader.
+func (l *BulkLoader) Count() int64 {
+	return l.count
+}
+
+// Close commits the remaining documents and, if the indexes were deferred,
+// rebuilds the non-unique indexes of the table in a single transaction.
+// If a call to Write failed, the indexes are rebuilt but nothing is committed.
+func (l *BulkLoader) Close() error {
+	if l.closed {
+		return ErrBulkLoaderClosed
+	}
+
+	if !l.failed {
+		err := l.Flush()
+		if err != nil {
+			return err
+		}
+	}
+	l.closed = true
+
+	return l.buildIndexes()
+}
+
+// Abort rolls back the documents written since the last commit and closes the loader.
+// If the indexes were deferred, they are rebuilt to include the documents
+// already committed.
+func (l *BulkLoader) Abort() error {
+	if l.closed {
+		return ErrBulkLoaderClosed
+	}
+
+	l.rollback()
+	l.closed = true
+
+	return l.buildIndexes()
+}
+
+// buildIndexes rebuilds the non-unique indexes of the table in a single transaction,
+// if they were deferred and if documents were committed.
+func (l *BulkLoader) buildIndexes() error {
+	if !l.opts.DeferIndexes || l.count == 0 {
+		return nil
+	}
+
+	tx, err := l.db.BeginTx(l.ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tb, err := tx.GetTable(l.tableName)
+	if err != nil {
+		return err
+	}
+
+	indexes, err := tb.Indexes()
+	if err != nil {
+		return err
+	}
+
+	for _, idx := range indexes {
+		if idx.Opts.Unique {
+			continue
+		}
+
+		// full-text and spatial indexes are rebuilt by indexing the documents one by one.
+		if idx.FullText != nil || idx.Spatial != nil {
+			err = tx.ReIndex(idx.Opts.IndexName)
+		} else {
+			err = tb.buildIndex(idx)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// buildIndex truncates the index and fills it with the values of all the documents
+// of the table, sorted in memory. Documents without the indexed field are
+// indexed as null, like Insert does.
+func (t *Table) buildIndex(idx Index) error {
+	b := idx.NewBuilder()
+
+	// expired documents are indexed as well, until they are deleted.
+	err := t.iterateAll(func(d document.Document) error {
+		v, err := idx.Opts.Path.GetValue(d)
+		if err == document.ErrFieldNotFound {
+			v = document.NewNullValue()
+		} else if err != nil {
+			return err
+		}
+
+		if idx.Opts.Multikey {
+			return idx.iterateElements(v, func(e document.Value) error {
+				return b.Add(e, d.(document.Keyer).Key())
+			})
+		}
+
+		return b.Add(v, d.(document.Keyer).Key())
+	})
+	if err != nil {
+		return err
+	}
+
+	err = b.Build()
+	if err == index.ErrDuplicate {
+		return ErrDuplicateDocument
+	}
+	return err
+}
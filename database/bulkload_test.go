@@ -0,0 +1,220 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkLoad(t *testing.T) {
+	newDB := func(t *testing.T, indexes ...database.IndexConfig) *database.Database {
+		db, err := database.New(context.Background(), memoryengine.NewEngine(), database.Options{
+			Codec:           msgpack.NewCodec(),
+			TTLReapInterval: -1,
+		})
+		require.NoError(t, err)
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		require.NoError(t, tx.CreateTable("test", nil))
+		for _, cfg := range indexes {
+			cfg.TableName = "test"
+			require.NoError(t, tx.CreateIndex(cfg))
+		}
+		require.NoError(t, tx.Commit())
+
+		return db
+	}
+
+	count := func(t *testing.T, db *database.Database) int {
+		tx, err := db.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		var n int
+		err = tb.Iterate(func(d document.Document) error {
+			n++
+			return nil
+		})
+		require.NoError(t, err)
+		return n
+	}
+
+	// returns the keys of the documents in the order of the index.
+	indexKeys := func(t *testing.T, db *database.Database, name string) []string {
+		tx, err := db.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		idx, err := tx.GetIndex(name)
+		require.NoError(t, err)
+
+		var keys []string
+		err = idx.AscendGreaterOrEqual(document.Value{}, func(v, k []byte, isEqual bool) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+		require.NoError(t, err)
+		return keys
+	}
+
+	doc := func(pk string, a int64) document.Document {
+		return document.NewFieldBuffer().
+			Add("pk", document.NewTextValue(pk)).
+			Add("a", document.NewIntegerValue(a))
+	}
+
+	t.Run("Unknown table", func(t *testing.T) {
+		db := newDB(t)
+		defer db.Close()
+
+		_, err := db.BulkLoad(context.Background(), "unknown", nil)
+		require.True(t, errors.Is(err, database.ErrTableNotFound))
+	})
+
+	t.Run("Batches", func(t *testing.T) {
+		db := newDB(t)
+		defer db.Close()
+
+		before := db.Stats().WriteTransactions.Committed
+
+		l, err := db.BulkLoad(context.Background(), "test", &database.BulkLoadOptions{BatchSize: 3})
+		require.NoError(t, err)
+
+		for i := int64(0); i < 7; i++ {
+			require.NoError(t, l.Write(doc("", i)))
+		}
+		// the last document is not committed yet.
+		require.EqualValues(t, 6, l.Count())
+
+		require.NoError(t, l.Close())
+		require.EqualValues(t, 7, l.Count())
+		require.Equal(t, 7, count(t, db))
+		require.Equal(t, before+3, db.Stats().WriteTransactions.Committed)
+
+		require.Equal(t, database.ErrBulkLoaderClosed, l.Write(doc("", 8)))
+	})
+
+	for _, deferred := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Indexes/Deferred: %v", deferred), func(t *testing.T) {
+			db := newDB(t, database.IndexConfig{IndexName: "idx_a", Path: parsePath(t, "a")})
+			defer db.Close()
+
+			l, err := db.BulkLoad(context.Background(), "test", &database.BulkLoadOptions{
+				BatchSize:    2,
+				DeferIndexes: deferred,
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, l.Write(document.NewFieldBuffer().Add("pk", document.NewTextValue("e"))))
+			require.NoError(t, l.Write(doc("d", 1)))
+			require.NoError(t, l.Write(doc("c", 3)))
+			require.NoError(t, l.Write(doc("b", 1)))
+			require.NoError(t, l.Write(doc("a", 2)))
+			require.NoError(t, l.Close())
+
+			keys := indexKeys(t, db, "idx_a")
+			require.Len(t, keys, 5)
+
+			// documents are indexed by value, in the order they were written.
+			// the document without the field is indexed as null.
+			var pks []string
+			for _, k := range keys {
+				tx, err := db.Begin(false)
+				require.NoError(t, err)
+				tb, err := tx.GetTable("test")
+				require.NoError(t, err)
+				d, err := tb.GetDocument([]byte(k))
+				require.NoError(t, err)
+				v, err := d.GetByField("pk")
+				require.NoError(t, err)
+				pks = append(pks, v.V.(string))
+				require.NoError(t, tx.Rollback())
+			}
+			require.Equal(t, []string{"e", "d", "b", "a", "c"}, pks)
+		})
+	}
+
+	t.Run("Abort", func(t *testing.T) {
+		db := newDB(t, database.IndexConfig{IndexName: "idx_a", Path: parsePath(t, "a")})
+		defer db.Close()
+
+		l, err := db.BulkLoad(context.Background(), "test", &database.BulkLoadOptions{
+			BatchSize:    2,
+			DeferIndexes: true,
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, l.Write(doc("", 1)))
+		require.NoError(t, l.Write(doc("", 2)))
+		require.NoError(t, l.Write(doc("", 3)))
+		require.NoError(t, l.Abort())
+		require.Equal(t, database.ErrBulkLoaderClosed, l.Write(doc("", 4)))
+
+		// the committed documents are indexed.
+		require.EqualValues(t, 2, l.Count())
+		require.Equal(t, 2, count(t, db))
+		require.Len(t, indexKeys(t, db, "idx_a"), 2)
+	})
+
+	t.Run("Failed write", func(t *testing.T) {
+		db := newDB(t,
+			database.IndexConfig{IndexName: "idx_a", Path: parsePath(t, "a")},
+			database.IndexConfig{IndexName: "idx_pk", Path: parsePath(t, "pk"), Unique: true},
+		)
+		defer db.Close()
+
+		l, err := db.BulkLoad(context.Background(), "test", &database.BulkLoadOptions{
+			BatchSize:    2,
+			DeferIndexes: true,
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, l.Write(doc("a", 1)))
+		require.NoError(t, l.Write(doc("b", 2)))
+		require.NoError(t, l.Write(doc("c", 3)))
+		require.Equal(t, database.ErrDuplicateDocument, l.Write(doc("a", 4)))
+		require.NoError(t, l.Close())
+
+		// the deferred indexes contain the committed documents.
+		require.Equal(t, 2, count(t, db))
+		require.Len(t, indexKeys(t, db, "idx_a"), 2)
+		require.Len(t, indexKeys(t, db, "idx_pk"), 2)
+	})
+
+	t.Run("Unique index", func(t *testing.T) {
+		db := newDB(t, database.IndexConfig{IndexName: "idx_a", Path: parsePath(t, "a"), Unique: true})
+		defer db.Close()
+
+		l, err := db.BulkLoad(context.Background(), "test", &database.BulkLoadOptions{
+			BatchSize:    2,
+			DeferIndexes: true,
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, l.Write(doc("", 1)))
+		require.NoError(t, l.Write(doc("", 2)))
+		require.NoError(t, l.Write(doc("", 3)))
+		// duplicates are reported by Write, even when indexes are deferred.
+		require.Equal(t, database.ErrDuplicateDocument, l.Write(doc("", 1)))
+		require.Equal(t, database.ErrBulkLoaderClosed, l.Write(doc("", 4)))
+		require.NoError(t, l.Close())
+		require.Equal(t, database.ErrBulkLoaderClosed, l.Close())
+
+		// the failed batch is rolled back.
+		require.EqualValues(t, 2, l.Count())
+		require.Equal(t, 2, count(t, db))
+		require.Len(t, indexKeys(t, db, "idx_a"), 2)
+	})
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/engine"
 	"github.com/genjidb/genji/index"
+	"github.com/genjidb/genji/index/fulltext"
+	"github.com/genjidb/genji/index/spatial"
 )
 
 const storePrefix = 't'
@@ -173,6 +175,10 @@ type TableInfo struct {
 	readOnly  bool
 
 	FieldConstraints FieldConstraints
+
+	// If set, documents expire at the time stored at this path
+	// and are deleted automatically.
+	TTLPath document.Path
 }
 
 // GetPrimaryKey returns the field constraint of the primary key.
@@ -202,6 +208,10 @@ func (ti *TableInfo) ToDocument() document.Document {
 	buf.Add("field_constraints", document.NewArrayValue(vbuf))
 
 	buf.Add("read_only", document.NewBoolValue(ti.readOnly))
+
+	if len(ti.TTLPath) > 0 {
+		buf.Add("ttl_path", document.NewArrayValue(pathToArray(ti.TTLPath)))
+	}
 	return buf
 }
 
@@ -246,6 +256,18 @@ func (ti *TableInfo) ScanDocument(d document.Document) error {
 	}
 
 	ti.readOnly = v.V.(bool)
+
+	v, err = d.GetByField("ttl_path")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		ti.TTLPath, err = arrayToPath(v.V.(document.Array))
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -330,6 +352,22 @@ func (t *tableInfoStore) Get(tx *Transaction, tableName string) (*TableInfo, err
 			},
 		}, nil
 	}
+	if tableName == triggerStoreName {
+		return &TableInfo{
+			storeName: []byte(triggerStoreName),
+			readOnly:  true,
+			FieldConstraints: []FieldConstraint{
+				{
+					Path: document.Path{
+						document.PathFragment{
+							FieldName: "trigger_name",
+						},
+					},
+					IsPrimaryKey: true,
+				},
+			},
+		}, nil
+	}
 
 	v, err := t.st.Get([]byte(tableName))
 	if err != nil {
@@ -396,6 +434,18 @@ type IndexConfig struct {
 
 	// If set, the index is typed and only accepts that type
 	Type document.ValueType
+
+	// If set to true, the index is a full-text index: texts are split into terms
+	// and each term is associated with the keys of the documents containing it.
+	FullText bool
+
+	// If set to true, the index is a multi-key index: each element of the arrays
+	// found at Path is associated with the key of their document.
+	Multikey bool
+
+	// If set to true, the index is a spatial index: the points found at Path
+	// are associated with the key of their document, by location.
+	Spatial bool
 }
 
 // ToDocument creates a document from an IndexConfig.
@@ -409,6 +459,15 @@ func (i *IndexConfig) ToDocument() document.Document {
 	if i.Type != 0 {
 		buf.Add("type", document.NewIntegerValue(int64(i.Type)))
 	}
+	if i.FullText {
+		buf.Add("fulltext", document.NewBoolValue(true))
+	}
+	if i.Multikey {
+		buf.Add("multikey", document.NewBoolValue(true))
+	}
+	if i.Spatial {
+		buf.Add("spatial", document.NewBoolValue(true))
+	}
 	return buf
 }
 
@@ -449,14 +508,154 @@ func (i *IndexConfig) ScanDocument(d document.Document) error {
 		i.Type = document.ValueType(v.V.(int64))
 	}
 
+	v, err = d.GetByField("fulltext")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		i.FullText = v.V.(bool)
+	}
+
+	v, err = d.GetByField("multikey")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		i.Multikey = v.V.(bool)
+	}
+
+	v, err = d.GetByField("spatial")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		i.Spatial = v.V.(bool)
+	}
+
 	return nil
 }
 
 // Index of a table field. Contains information about
 // the index configuration and provides methods to manipulate the index.
+// Full-text and spatial indexes are manipulated using the FullText
+// and Spatial fields, and the embedded index is nil.
 type Index struct {
 	*index.Index
-	Opts IndexConfig
+	FullText *fulltext.Index
+	Spatial  *spatial.Index
+	Opts     IndexConfig
+}
+
+func newIndex(tx engine.Transaction, opts IndexConfig) Index {
+	if opts.FullText {
+		return Index{
+			FullText: fulltext.New(tx, opts.IndexName),
+			Opts:     opts,
+		}
+	}
+
+	if opts.Spatial {
+		return Index{
+			Spatial: spatial.New(tx, opts.IndexName),
+			Opts:    opts,
+		}
+	}
+
+	return Index{
+		Index: index.New(tx, opts.IndexName, index.Options{
+			Unique: opts.Unique,
+			Type:   opts.Type,
+		}),
+		Opts: opts,
+	}
+}
+
+// Set associates a value with a key, whatever the kind of the index.
+func (idx Index) Set(v document.Value, k []byte) error {
+	if idx.FullText != nil {
+		return idx.FullText.Set(v, k)
+	}
+
+	if idx.Spatial != nil {
+		return idx.Spatial.Set(v, k)
+	}
+
+	if idx.Opts.Multikey {
+		return idx.iterateElements(v, func(e document.Value) error {
+			return idx.Index.Set(e, k)
+		})
+	}
+
+	return idx.Index.Set(v, k)
+}
+
+// Delete all the references to the key from the index, whatever its kind.
+func (idx Index) Delete(v document.Value, k []byte) error {
+	if idx.FullText != nil {
+		return idx.FullText.Delete(v, k)
+	}
+
+	if idx.Spatial != nil {
+		return idx.Spatial.Delete(v, k)
+	}
+
+	if idx.Opts.Multikey {
+		return idx.iterateElements(v, func(e document.Value) error {
+			return idx.Index.Delete(e, k)
+		})
+	}
+
+	return idx.Index.Delete(v, k)
+}
+
+// iterateElements calls fn once for each distinct element of v, if v is an array.
+// Multi-key indexes associate the elements of arrays with the key of their document,
+// and ignore the other values.
+func (idx Index) iterateElements(v document.Value, fn func(e document.Value) error) error {
+	if v.Type != document.ArrayValue {
+		return nil
+	}
+
+	seen := make(map[string]struct{})
+	return v.V.(document.Array).Iterate(func(_ int, e document.Value) error {
+		enc, err := idx.EncodeValue(e)
+		if err != nil {
+			return err
+		}
+		if _, ok := seen[string(enc)]; ok {
+			return nil
+		}
+		seen[string(enc)] = struct{}{}
+
+		return fn(e)
+	})
+}
+
+// Truncate deletes all the index data.
+func (idx Index) Truncate() error {
+	if idx.FullText != nil {
+		return idx.FullText.Truncate()
+	}
+
+	if idx.Spatial != nil {
+		return idx.Spatial.Truncate()
+	}
+
+	return idx.Index.Truncate()
+}
+
+// Size returns the number of entries of the index
+// and the number of bytes used by their keys and values.
+func (idx Index) Size() (n int64, size int64, err error) {
+	if idx.FullText != nil {
+		return idx.FullText.Size()
+	}
+
+	if idx.Spatial != nil {
+		return idx.Spatial.Size()
+	}
+
+	return idx.Index.Size()
 }
 
 type indexStore struct {
@@ -5,6 +5,8 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/genjidb/genji/document/encoding"
 	"github.com/genjidb/genji/engine"
@@ -19,12 +21,47 @@ type Database struct {
 	// incremented atomically every time Begin is called.
 	lastTransactionID int64
 
-	// If this is non-nil, the user is running an explicit transaction
-	// using the BEGIN statement.
-	// Only one attached transaction can be run at a time and any calls to DB.Begin()
-	// will cause an error until that transaction is rolled back or commited.
-	attachedTransaction *Transaction
-	attachedTxMu        sync.Mutex
+	// Session used by BeginTx and GetAttachedTx.
+	session *Session
+
+	// Subscriptions to committed changes.
+	subscriptions   map[*Subscription]struct{}
+	subscriptionsMu sync.RWMutex
+	// Maximum number of events queued by a subscription.
+	subscriptionQueueSize int
+	// Ensures changes are published and shipped in commit order.
+	publishMu sync.Mutex
+
+	// Log shippers, protected by both publishMu and shipMu.
+	shippers map[*LogShipper]struct{}
+	// Held for reading by the writable transactions that don't record
+	// their operations, to let ShipLog wait for them.
+	shipMu sync.RWMutex
+
+	// If true, writable transactions can't be opened by the sessions.
+	readOnly bool
+
+	// Triggers registered using RegisterTrigger, by name.
+	triggers   map[string]registeredTrigger
+	triggersMu sync.RWMutex
+	// Function used to parse the statement of SQL triggers.
+	prepareTrigger func(stmt string) (TriggerFunc, error)
+
+	// Background deletion of expired documents.
+	ttlBatchSize int
+	reaperStop   chan struct{}
+	reaperDone   chan struct{}
+
+	// Version of the tables and indexes. Transactions that change them
+	// increment schemaPending before committing and schemaVersion after,
+	// to let the other transactions know if the version they see is reliable.
+	schemaPending uint64
+	schemaVersion uint64
+
+	// Statistics about the activity of the database.
+	stats *statsCollector
+	// Notified of the execution of the queries, if not nil.
+	tracer Tracer
 
 	// Codec used to encode documents. Defaults to MessagePack.
 	Codec encoding.Codec
@@ -32,6 +69,33 @@ type Database struct {
 
 type Options struct {
 	Codec encoding.Codec
+	// PrepareTrigger is called to parse the statement of SQL triggers. It returns
+	// the function that runs the statement every time the trigger is fired,
+	// which is cached with the triggers by each transaction.
+	// If nil, writing to a table with SQL triggers returns an error.
+	PrepareTrigger func(stmt string) (TriggerFunc, error)
+	// TTLReapInterval is the interval between two deletions of expired documents.
+	// Defaults to DefaultTTLReapInterval. If negative, expired documents are
+	// only deleted when calling ReapExpired.
+	TTLReapInterval time.Duration
+	// TTLBatchSize is the maximum number of expired documents deleted
+	// by a single transaction. Defaults to DefaultTTLBatchSize.
+	TTLBatchSize int
+	// SubscriptionQueueSize is the maximum number of events waiting to be read
+	// from a subscription. Subscriptions that fall further behind are closed.
+	// Defaults to DefaultSubscriptionQueueSize.
+	SubscriptionQueueSize int
+	// ReadOnly prevents writable transactions from being opened, except by ApplyLog.
+	// Expired documents are not deleted in the background.
+	ReadOnly bool
+	// StatsHook, if not nil, is notified of the transactions and queries.
+	StatsHook StatsHook
+	// NodeTimings enables the measure of the time spent in each node of the
+	// query plans. It reads the clock for every document passed from a node
+	// to the next one. Otherwise, only the duration of whole queries is measured.
+	NodeTimings bool
+	// Tracer, if not nil, is notified of the execution of the queries.
+	Tracer Tracer
 }
 
 // New initializes the DB using the given engine.
@@ -41,8 +105,23 @@ func New(ctx context.Context, ng engine.Engine, opts Options) (*Database, error)
 	}
 
 	db := Database{
-		ng:    ng,
-		Codec: opts.Codec,
+		ng:             ng,
+		Codec:          opts.Codec,
+		prepareTrigger: opts.PrepareTrigger,
+		ttlBatchSize:   opts.TTLBatchSize,
+		readOnly:       opts.ReadOnly,
+		stats:          newStatsCollector(opts),
+		tracer:         opts.Tracer,
+
+		subscriptionQueueSize: opts.SubscriptionQueueSize,
+	}
+	db.session = db.NewSession()
+
+	if db.ttlBatchSize <= 0 {
+		db.ttlBatchSize = DefaultTTLBatchSize
+	}
+	if db.subscriptionQueueSize <= 0 {
+		db.subscriptionQueueSize = DefaultSubscriptionQueueSize
 	}
 
 	ntx, err := db.ng.Begin(ctx, engine.TxOptions{
@@ -63,6 +142,16 @@ func New(ctx context.Context, ng engine.Engine, opts Options) (*Database, error)
 		return nil, err
 	}
 
+	interval := opts.TTLReapInterval
+	if interval == 0 {
+		interval = DefaultTTLReapInterval
+	}
+	if interval > 0 && !db.readOnly {
+		db.reaperStop = make(chan struct{})
+		db.reaperDone = make(chan struct{})
+		go db.runReaper(interval)
+	}
+
 	return &db, nil
 }
 
@@ -79,14 +168,34 @@ func (db *Database) initInternalStores(tx engine.Transaction) error {
 	if err == engine.ErrStoreNotFound {
 		err = tx.CreateStore([]byte(indexStoreName))
 	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.GetStore([]byte(triggerStoreName))
+	if err == engine.ErrStoreNotFound {
+		err = tx.CreateStore([]byte(triggerStoreName))
+	}
 	return err
 }
 
-// Close the underlying engine.
+// Close the subscriptions and the underlying engine.
 func (db *Database) Close() error {
+	db.stopReaper()
+	db.closeSubscriptions()
+
 	return db.ng.Close()
 }
 
+// stopReaper stops the deletion of expired documents, if it is running.
+func (db *Database) stopReaper() {
+	if db.reaperStop != nil {
+		close(db.reaperStop)
+		<-db.reaperDone
+		db.reaperStop = nil
+	}
+}
+
 // Begin starts a new transaction with default options.
 // The returned transaction must be closed either by calling Rollback or Commit.
 func (db *Database) Begin(writable bool) (*Transaction, error) {
@@ -98,20 +207,17 @@ func (db *Database) Begin(writable bool) (*Transaction, error) {
 // BeginTx starts a new transaction with the given options.
 // If opts is empty, it will use the default options.
 // The returned transaction must be closed either by calling Rollback or Commit.
-// If the Attached option is passed, it opens a database level transaction, which gets
-// attached to the database and prevents any other transaction to be opened afterwards
-// until it gets rolled back or commited.
+// If the Attached option is passed, it opens a transaction which gets
+// attached to the default session of the database and prevents any other transaction
+// to be opened afterwards using BeginTx until it gets rolled back or commited.
+// Transactions attached to other sessions don't prevent BeginTx from opening transactions.
 func (db *Database) BeginTx(ctx context.Context, opts *TxOptions) (*Transaction, error) {
-	if opts == nil {
-		opts = new(TxOptions)
-	}
-
-	db.attachedTxMu.Lock()
-	defer db.attachedTxMu.Unlock()
+	return db.session.BeginTx(ctx, opts)
+}
 
-	if db.attachedTransaction != nil {
-		return nil, errors.New("cannot open a transaction within a transaction")
-	}
+// beginTx starts a new transaction, regardless of the sessions.
+func (db *Database) beginTx(ctx context.Context, opts *TxOptions) (*Transaction, error) {
+	version := atomic.LoadUint64(&db.schemaVersion)
 
 	ntx, err := db.ng.Begin(ctx, engine.TxOptions{
 		Writable: !opts.ReadOnly,
@@ -121,10 +227,18 @@ func (db *Database) BeginTx(ctx context.Context, opts *TxOptions) (*Transaction,
 	}
 
 	tx := Transaction{
-		db:       db,
-		tx:       ntx,
-		writable: !opts.ReadOnly,
-		attached: opts.Attached,
+		db:            db,
+		tx:            ntx,
+		writable:      !opts.ReadOnly,
+		schemaVersion: version,
+		// if no change was committed nor started since the version was read,
+		// the transaction sees exactly that version.
+		schemaKnown: atomic.LoadUint64(&db.schemaPending) == version,
+	}
+
+	if tx.writable {
+		tx.journal = &journal{tx: ntx}
+		tx.tx = &journalTx{Transaction: ntx, j: tx.journal}
 	}
 
 	tx.tableInfoStore, err = tx.getTableInfoStore()
@@ -137,10 +251,23 @@ func (db *Database) BeginTx(ctx context.Context, opts *TxOptions) (*Transaction,
 		return nil, err
 	}
 
-	if opts.Attached {
-		db.attachedTransaction = &tx
+	tx.triggerStore, err = tx.getTriggerStore()
+	if err != nil {
+		return nil, err
 	}
 
+	if tx.writable {
+		db.shipMu.RLock()
+		if len(db.shippers) > 0 {
+			tx.journal.logging = true
+			db.shipMu.RUnlock()
+		} else {
+			tx.shipLocked = true
+		}
+	}
+
+	db.stats.txBegun(tx.writable)
+
 	return &tx, nil
 }
 
@@ -148,18 +275,15 @@ func (db *Database) BeginTx(ctx context.Context, opts *TxOptions) (*Transaction,
 type TxOptions struct {
 	// Open a read-only transaction.
 	ReadOnly bool
-	// Set the transaction as global at the database level.
-	// Any queries run by the database will use that transaction until it is
+	// Attach the transaction to the session.
+	// Any queries run by the session will use that transaction until it is
 	// rolled back or commited.
 	Attached bool
 }
 
-// GetAttachedTx returns the transaction attached to the database. It returns nil if there is no
-// such transaction.
+// GetAttachedTx returns the transaction attached to the default session of the database.
+// It returns nil if there is no such transaction.
 // The returned transaction is not thread safe.
 func (db *Database) GetAttachedTx() *Transaction {
-	db.attachedTxMu.Lock()
-	defer db.attachedTxMu.Unlock()
-
-	return db.attachedTransaction
+	return db.session.GetAttachedTx()
 }
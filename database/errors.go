@@ -19,10 +19,32 @@ var (
 	// same name as an existing one.
 	ErrIndexAlreadyExists = errors.New("index already exists")
 
+	// ErrTriggerNotFound is returned when the targeted trigger doesn't exist.
+	ErrTriggerNotFound = errors.New("trigger not found")
+
+	// ErrTriggerAlreadyExists is returned when attempting to create a trigger with the
+	// same name as an existing one.
+	ErrTriggerAlreadyExists = errors.New("trigger already exists")
+
+	// ErrSavepointNotFound is returned when the targeted savepoint doesn't exist.
+	ErrSavepointNotFound = errors.New("savepoint not found")
+
+	// ErrReadOnlyDatabase is returned when opening a writable transaction
+	// on a read-only database.
+	ErrReadOnlyDatabase = errors.New("database is read-only")
+
+	// ErrInvalidLog is returned by ApplyLog when reading a log that is corrupted
+	// or that wasn't created by ShipLog.
+	ErrInvalidLog = errors.New("invalid log")
+
 	// ErrDocumentNotFound is returned when no document is associated with the provided key.
 	ErrDocumentNotFound = errors.New("document not found")
 
 	// ErrDuplicateDocument is returned when another document is already associated with a given key, primary key,
 	// or if there is a unique index violation.
 	ErrDuplicateDocument = errors.New("duplicate document")
+
+	// ErrSubscriptionQueueFull is returned by Subscription.Err when the subscription
+	// was closed because too many events were waiting to be read.
+	ErrSubscriptionQueueFull = errors.New("subscription queue full")
 )
@@ -0,0 +1,696 @@
+package database
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/index"
+)
+
+// Logs start with logMagic and the version of the format,
+// followed by a sequence of frames:
+//
+//	frame: kind | uvarint(len(payload)) | payload | crc32(kind, length and payload)
+//
+// A log starts with a snapshot of the database: a 'b' frame, followed by 'o' frames
+// holding the content of the database and the operations of the transactions committed
+// while the snapshot was taken, and an 'e' frame. Each transaction committed afterwards
+// is sent as a 'c' frame.
+// The payload of 'o' and 'c' frames is a list of operations:
+//
+//	create store: 'C' | uvarint(len(store)) | store
+//	drop store:   'D' | uvarint(len(store)) | store
+//	truncate:     'T' | uvarint(len(store)) | store
+//	put:          'P' | uvarint(len(store)) | store | uvarint(len(key)) | key | uvarint(len(value)) | value
+//	delete:       'X' | uvarint(len(store)) | store | uvarint(len(key)) | key
+//	sequence:     'S' | uvarint(len(store)) | store | uvarint(sequence)
+const (
+	logMagic   = "GENJILOG"
+	logVersion = 1
+
+	logBeginSnapshotFrame = 'b'
+	logOpsFrame           = 'o'
+	logEndSnapshotFrame   = 'e'
+	logCommitFrame        = 'c'
+
+	logCreateStore = 'C'
+	logDropStore   = 'D'
+	logTruncate    = 'T'
+	logPut         = 'P'
+	logDelete      = 'X'
+	logSequence    = 'S'
+
+	// maximum size of the payload of the snapshot frames,
+	// before starting a new frame.
+	logSnapshotFrameSize = 1 << 20
+)
+
+// A logOp is a write to the engine, shipped to the replicas.
+type logOp struct {
+	op    byte
+	store []byte
+	key   []byte
+	value []byte
+	seq   uint64
+}
+
+func (o *logOp) appendTo(buf []byte) []byte {
+	buf = append(buf, o.op)
+	buf = appendBytes(buf, o.store)
+
+	switch o.op {
+	case logPut:
+		buf = appendBytes(buf, o.key)
+		buf = appendBytes(buf, o.value)
+	case logDelete:
+		buf = appendBytes(buf, o.key)
+	case logSequence:
+		buf = appendUvarint(buf, o.seq)
+	}
+
+	return buf
+}
+
+func appendUvarint(buf []byte, x uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], x)
+	return append(buf, tmp[:n]...)
+}
+
+func appendBytes(buf, data []byte) []byte {
+	buf = appendUvarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+// decodeLogOps decodes the operations of a frame.
+func decodeLogOps(payload []byte) ([]logOp, error) {
+	var ops []logOp
+
+	readBytes := func() ([]byte, error) {
+		l, n := binary.Uvarint(payload)
+		if n <= 0 || uint64(len(payload)-n) < l {
+			return nil, fmt.Errorf("%w: malformed operation", ErrInvalidLog)
+		}
+
+		data := payload[n : n+int(l)]
+		payload = payload[n+int(l):]
+		return data, nil
+	}
+
+	for len(payload) > 0 {
+		o := logOp{op: payload[0]}
+		payload = payload[1:]
+
+		var err error
+		o.store, err = readBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		switch o.op {
+		case logCreateStore, logDropStore, logTruncate:
+		case logPut:
+			o.key, err = readBytes()
+			if err == nil {
+				o.value, err = readBytes()
+			}
+		case logDelete:
+			o.key, err = readBytes()
+		case logSequence:
+			var n int
+			o.seq, n = binary.Uvarint(payload)
+			if n <= 0 {
+				err = fmt.Errorf("%w: malformed operation", ErrInvalidLog)
+				break
+			}
+			payload = payload[n:]
+		default:
+			err = fmt.Errorf("%w: unknown operation %q", ErrInvalidLog, o.op)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		ops = append(ops, o)
+	}
+
+	return ops, nil
+}
+
+// logWriter writes frames to a log.
+type logWriter struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+func (l *logWriter) writeFrame(kind byte, payload []byte) error {
+	l.buf = append(l.buf[:0], kind)
+	l.buf = appendUvarint(l.buf, uint64(len(payload)))
+
+	crc := crc32.NewIEEE()
+	crc.Write(l.buf)
+	crc.Write(payload)
+
+	_, err := l.w.Write(l.buf)
+	if err != nil {
+		return err
+	}
+	_, err = l.w.Write(payload)
+	if err != nil {
+		return err
+	}
+
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	_, err = l.w.Write(sum[:])
+	return err
+}
+
+func (l *logWriter) writeOps(kind byte, ops []logOp) error {
+	var payload []byte
+	for i := range ops {
+		payload = ops[i].appendTo(payload)
+	}
+
+	return l.writeFrame(kind, payload)
+}
+
+// A LogShipper writes the transactions committed to a database to a log,
+// which can be applied to another database by ApplyLog to replicate it.
+type LogShipper struct {
+	db *Database
+	lw logWriter
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	// operations of the transactions committed
+	// and not yet written, in commit order.
+	queue  [][]logOp
+	closed bool
+	err    error
+	done   chan struct{}
+}
+
+// ShipLog writes a snapshot of the database to w, then starts writing the operations
+// of every transaction committed to the database, in commit order, until the returned
+// LogShipper is closed. It returns once the snapshot is written.
+// Writable transactions opened before calling ShipLog must be closed for it to start:
+// it must not be called while holding one.
+// Indexes are not part of the snapshot and are recreated by ApplyLog.
+func (db *Database) ShipLog(ctx context.Context, w io.Writer) (*LogShipper, error) {
+	s := LogShipper{
+		db:   db,
+		lw:   logWriter{w: bufio.NewWriter(w)},
+		done: make(chan struct{}),
+	}
+	s.cond = sync.NewCond(&s.mu)
+
+	// wait for the transactions that don't record their operations,
+	// and register the shipper to make every new transaction record them.
+	db.shipMu.Lock()
+	db.publishMu.Lock()
+	if db.shippers == nil {
+		db.shippers = make(map[*LogShipper]struct{})
+	}
+	db.shippers[&s] = struct{}{}
+	db.publishMu.Unlock()
+	db.shipMu.Unlock()
+
+	err := s.writeSnapshot(ctx)
+	if err != nil {
+		db.removeShipper(&s)
+		return nil, err
+	}
+
+	go s.run()
+
+	return &s, nil
+}
+
+// writeSnapshot writes the content of the database, followed by the operations
+// of the transactions committed since the shipper was registered.
+// Since the snapshot may already contain some of them, they are applied
+// again within the snapshot transaction: as every operation overwrites
+// the state of a key or a store, the result is the same.
+func (s *LogShipper) writeSnapshot(ctx context.Context) error {
+	_, err := s.lw.w.WriteString(logMagic)
+	if err != nil {
+		return err
+	}
+	err = s.lw.w.WriteByte(logVersion)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.beginTx(ctx, &TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the transactions queued from now on are not part of the snapshot.
+	s.db.publishMu.Lock()
+	s.mu.Lock()
+	replay := s.queue
+	s.queue = nil
+	s.mu.Unlock()
+	s.db.publishMu.Unlock()
+
+	err = s.lw.writeFrame(logBeginSnapshotFrame, nil)
+	if err != nil {
+		return err
+	}
+
+	storeNames := [][]byte{
+		[]byte(tableInfoStoreName),
+		[]byte(indexStoreName),
+		[]byte(triggerStoreName),
+	}
+
+	tables, err := tx.listTableInfos()
+	if err != nil {
+		return err
+	}
+	for _, ti := range tables {
+		storeNames = append(storeNames, ti.storeName)
+	}
+
+	for _, name := range storeNames {
+		st, err := tx.tx.GetStore(name)
+		if err != nil {
+			return err
+		}
+
+		err = s.writeStore(name, st)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Rollback()
+	if err != nil {
+		return err
+	}
+
+	for _, ops := range replay {
+		err = s.lw.writeOps(logOpsFrame, ops)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.lw.writeFrame(logEndSnapshotFrame, nil)
+	if err != nil {
+		return err
+	}
+
+	return s.lw.w.Flush()
+}
+
+// writeStore writes the content of a store as put operations.
+func (s *LogShipper) writeStore(name []byte, st engine.Store) error {
+	payload := (&logOp{op: logCreateStore, store: name}).appendTo(nil)
+
+	it := st.Iterator(engine.IteratorOptions{})
+	defer it.Close()
+
+	var err error
+	op := logOp{op: logPut, store: name}
+	for it.Seek(nil); it.Valid(); it.Next() {
+		item := it.Item()
+		op.key = item.Key()
+		op.value, err = item.ValueCopy(op.value[:0])
+		if err != nil {
+			return err
+		}
+
+		payload = op.appendTo(payload)
+		if len(payload) >= logSnapshotFrameSize {
+			err = s.lw.writeFrame(logOpsFrame, payload)
+			if err != nil {
+				return err
+			}
+			payload = payload[:0]
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	return s.lw.writeFrame(logOpsFrame, payload)
+}
+
+// run writes the queued transactions until the shipper is closed.
+func (s *LogShipper) run() {
+	defer close(s.done)
+
+	for {
+		s.mu.Lock()
+		for len(s.queue) == 0 && !s.closed {
+			s.cond.Wait()
+		}
+		queue := s.queue
+		closed := s.closed
+		s.queue = nil
+		s.mu.Unlock()
+
+		if len(queue) == 0 && closed {
+			return
+		}
+
+		err := s.writeCommits(queue)
+		if err != nil {
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+			s.db.removeShipper(s)
+			return
+		}
+	}
+}
+
+func (s *LogShipper) writeCommits(queue [][]logOp) error {
+	for _, ops := range queue {
+		err := s.lw.writeOps(logCommitFrame, ops)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.lw.w.Flush()
+}
+
+// Err returns the error that stopped the shipper, if any.
+func (s *LogShipper) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// Close stops shipping the log, once the transactions committed
+// before calling Close are written. The underlying writer is not closed.
+// It returns the error that stopped the shipper, if any.
+func (s *LogShipper) Close() error {
+	s.db.removeShipper(s)
+
+	s.mu.Lock()
+	s.closed = true
+	s.cond.Signal()
+	s.mu.Unlock()
+
+	<-s.done
+	return s.Err()
+}
+
+func (db *Database) removeShipper(s *LogShipper) {
+	db.shipMu.Lock()
+	db.publishMu.Lock()
+	delete(db.shippers, s)
+	db.publishMu.Unlock()
+	db.shipMu.Unlock()
+}
+
+// ship queues the operations of a committed transaction.
+// It must be called with publishMu held.
+func (db *Database) ship(ops []logOp) {
+	for s := range db.shippers {
+		s.mu.Lock()
+		s.queue = append(s.queue, ops)
+		s.cond.Signal()
+		s.mu.Unlock()
+	}
+}
+
+// ApplyLog reads a log written by ShipLog from r and applies it to the database,
+// until r returns io.EOF or ctx is canceled. Each transaction of the log is applied
+// within its own transaction, and the snapshot replaces the content of the database.
+// The database is usually opened with the ReadOnly option, to prevent the replica
+// from diverging from the primary. Triggers and subscriptions are not run by ApplyLog.
+func (db *Database) ApplyLog(ctx context.Context, r io.Reader) error {
+	lr := logReader{r: bufio.NewReader(r)}
+
+	header := make([]byte, len(logMagic)+1)
+	_, err := io.ReadFull(lr.r, header)
+	if err != nil {
+		return lr.wrapErr(err)
+	}
+	if string(header[:len(logMagic)]) != logMagic {
+		return fmt.Errorf("%w: unknown format", ErrInvalidLog)
+	}
+	if header[len(logMagic)] != logVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidLog, header[len(logMagic)])
+	}
+
+	// transaction of the snapshot, if one is being applied.
+	var snapshot *Transaction
+	defer func() {
+		if snapshot != nil {
+			snapshot.Rollback()
+		}
+	}()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		kind, payload, err := lr.readFrame()
+		if err == io.EOF && snapshot == nil {
+			return nil
+		}
+		if err != nil {
+			return lr.wrapErr(err)
+		}
+
+		switch kind {
+		case logBeginSnapshotFrame:
+			if snapshot != nil {
+				return fmt.Errorf("%w: unexpected snapshot", ErrInvalidLog)
+			}
+
+			snapshot, err = db.beginTx(ctx, &TxOptions{})
+			if err != nil {
+				return err
+			}
+
+			snapshot.schemaChanged = true
+			err = snapshot.reset()
+		case logOpsFrame:
+			if snapshot == nil {
+				return fmt.Errorf("%w: missing snapshot", ErrInvalidLog)
+			}
+
+			err = snapshot.applyLogOps(payload)
+		case logEndSnapshotFrame:
+			if snapshot == nil {
+				return fmt.Errorf("%w: missing snapshot", ErrInvalidLog)
+			}
+
+			err = snapshot.restoreSequences()
+			if err == nil {
+				err = snapshot.ReIndexAll()
+			}
+			if err == nil {
+				err = snapshot.Commit()
+			}
+			snapshot = nil
+		case logCommitFrame:
+			if snapshot != nil {
+				return fmt.Errorf("%w: unexpected commit", ErrInvalidLog)
+			}
+
+			err = db.applyCommit(ctx, payload)
+		default:
+			return fmt.Errorf("%w: unknown frame %q", ErrInvalidLog, kind)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (db *Database) applyCommit(ctx context.Context, payload []byte) error {
+	tx, err := db.beginTx(ctx, &TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.applyLogOps(payload)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// reset removes all the tables, indexes and triggers of the database.
+func (tx *Transaction) reset() error {
+	tables, err := tx.listTableInfos()
+	if err != nil {
+		return err
+	}
+
+	for _, ti := range tables {
+		err = tx.tx.DropStore(ti.storeName)
+		if err != nil && err != engine.ErrStoreNotFound {
+			return err
+		}
+	}
+
+	indexes, err := tx.ListIndexes()
+	if err != nil {
+		return err
+	}
+
+	for _, cfg := range indexes {
+		err = index.New(tx.tx, cfg.IndexName, index.Options{}).Truncate()
+		if err != nil {
+			return err
+		}
+	}
+
+	tx.triggerStore.invalidate()
+	for _, st := range []engine.Store{tx.tableInfoStore.st, tx.indexStore.st, tx.triggerStore.st} {
+		err = st.Truncate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyLogOps applies the operations of a frame to the transaction.
+// Since operations of the snapshot may be applied again, operations on stores
+// that don't exist create them or are ignored.
+func (tx *Transaction) applyLogOps(payload []byte) error {
+	ops, err := decodeLogOps(payload)
+	if err != nil {
+		return err
+	}
+
+	for _, o := range ops {
+		err = tx.applyLogOp(&o)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (tx *Transaction) applyLogOp(o *logOp) error {
+	if name := string(o.store); name == tableInfoStoreName || name == indexStoreName {
+		tx.schemaChanged = true
+	}
+	if string(o.store) == triggerStoreName {
+		tx.triggerStore.invalidate()
+	}
+
+	switch o.op {
+	case logCreateStore:
+		err := tx.tx.CreateStore(o.store)
+		if err == engine.ErrStoreAlreadyExists {
+			return nil
+		}
+		return err
+	case logDropStore:
+		err := tx.tx.DropStore(o.store)
+		if err == engine.ErrStoreNotFound {
+			return nil
+		}
+		return err
+	}
+
+	st, err := tx.tx.GetStore(o.store)
+	if err == engine.ErrStoreNotFound {
+		if o.op != logPut {
+			return nil
+		}
+
+		err = tx.tx.CreateStore(o.store)
+		if err == nil {
+			st, err = tx.tx.GetStore(o.store)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	switch o.op {
+	case logTruncate:
+		return st.Truncate()
+	case logPut:
+		return st.Put(o.key, o.value)
+	case logDelete:
+		err = st.Delete(o.key)
+		if err == engine.ErrKeyNotFound {
+			return nil
+		}
+		return err
+	case logSequence:
+		return advanceSequence(st, o.seq)
+	}
+
+	return nil
+}
+
+// logReader reads the frames of a log.
+type logReader struct {
+	r *bufio.Reader
+}
+
+// wrapErr reports unexpected ends of the log as invalid logs.
+func (l *logReader) wrapErr(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: unexpected end of log", ErrInvalidLog)
+	}
+	return err
+}
+
+// readFrame returns the kind and the payload of the next frame.
+// It returns io.EOF if the log ends before the frame.
+func (l *logReader) readFrame() (byte, []byte, error) {
+	kind, err := l.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	size, err := binary.ReadUvarint(l.r)
+	if err != nil {
+		return 0, nil, l.wrapErr(err)
+	}
+	if size > 1<<31 {
+		return 0, nil, fmt.Errorf("%w: frame too large", ErrInvalidLog)
+	}
+
+	payload := make([]byte, size)
+	_, err = io.ReadFull(l.r, payload)
+	if err != nil {
+		return 0, nil, l.wrapErr(err)
+	}
+
+	var sum [4]byte
+	_, err = io.ReadFull(l.r, sum[:])
+	if err != nil {
+		return 0, nil, l.wrapErr(err)
+	}
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte{kind})
+	crc.Write(appendUvarint(nil, size))
+	crc.Write(payload)
+	if crc.Sum32() != binary.BigEndian.Uint32(sum[:]) {
+		return 0, nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidLog)
+	}
+
+	return kind, payload, nil
+}
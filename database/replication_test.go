@@ -0,0 +1,261 @@
+package database_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/boltengine"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+func readOnly(opts *database.Options) {
+	opts.ReadOnly = true
+}
+
+// tableContents returns the documents of every table of the database, encoded in JSON.
+func tableContents(t *testing.T, db *genji.DB) map[string][]string {
+	t.Helper()
+
+	res, err := db.Query("SELECT table_name FROM __genji_tables")
+	require.NoError(t, err)
+	var tables []string
+	err = res.Iterate(func(d document.Document) error {
+		var name string
+		err := document.Scan(d, &name)
+		tables = append(tables, name)
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, res.Close())
+
+	contents := make(map[string][]string)
+	for _, name := range tables {
+		res, err := db.Query("SELECT * FROM " + name)
+		require.NoError(t, err)
+
+		contents[name] = []string{}
+		err = res.Iterate(func(d document.Document) error {
+			data, err := document.MarshalJSON(d)
+			contents[name] = append(contents[name], string(data))
+			return err
+		})
+		require.NoError(t, err)
+		require.NoError(t, res.Close())
+	}
+
+	return contents
+}
+
+// replicate ships the log of primary to a replica while fn runs
+// and returns the replica once the log is applied.
+func replicate(t *testing.T, primary *genji.DB, ng engine.Engine, fn func()) *genji.DB {
+	t.Helper()
+
+	replica, err := genji.New(context.Background(), ng, readOnly)
+	require.NoError(t, err)
+
+	pr, pw := io.Pipe()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- replica.DB.ApplyLog(context.Background(), pr)
+	}()
+
+	s, err := primary.DB.ShipLog(context.Background(), pw)
+	require.NoError(t, err)
+
+	fn()
+
+	require.NoError(t, s.Close())
+	require.NoError(t, pw.Close())
+	require.NoError(t, <-errc)
+	return replica
+}
+
+func TestReplication(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genji")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	engines := map[string]func() engine.Engine{
+		"Memory": func() engine.Engine { return memoryengine.NewEngine() },
+		"Bolt": func() engine.Engine {
+			ng, err := boltengine.NewEngine(filepath.Join(dir, "replica.db"), 0600, nil)
+			require.NoError(t, err)
+			return ng
+		},
+	}
+
+	for name, newEngine := range engines {
+		t.Run(name, func(t *testing.T) {
+			primary, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer primary.Close()
+
+			err = primary.Exec(`
+				CREATE TABLE test; CREATE TABLE users(id INTEGER PRIMARY KEY); CREATE TABLE audit;
+				CREATE INDEX idx_a ON test (a);
+				CREATE UNIQUE INDEX idx_name ON users (name);
+				CREATE TRIGGER on_insert AFTER INSERT ON test INSERT INTO audit (a) VALUES ($new.a);
+				INSERT INTO test (a) VALUES (1), (2), (3);
+				INSERT INTO users (id, name) VALUES (1, 'foo'), (2, 'bar');
+			`)
+			require.NoError(t, err)
+
+			replica := replicate(t, primary, newEngine(), func() {
+				err := primary.Exec(`
+					INSERT INTO test (a) VALUES (4);
+					UPDATE users SET name = 'baz' WHERE id = 2;
+					DELETE FROM test WHERE a = 1;
+					CREATE TABLE foo; CREATE INDEX idx_b ON foo (b);
+					INSERT INTO foo (b) VALUES (1);
+					DROP TABLE audit;
+				`)
+				require.NoError(t, err)
+
+				// writes rolled back to a savepoint are not shipped.
+				err = primary.Update(func(tx *genji.Tx) error {
+					err := tx.Exec("INSERT INTO users (id, name) VALUES (3, 'a')")
+					require.NoError(t, err)
+					require.NoError(t, tx.Savepoint("sp"))
+					err = tx.Exec("INSERT INTO users (id, name) VALUES (4, 'b')")
+					require.NoError(t, err)
+					return tx.RollbackToSavepoint("sp")
+				})
+				require.NoError(t, err)
+
+				// rolled back transactions are not shipped.
+				tx, err := primary.Begin(true)
+				require.NoError(t, err)
+				require.NoError(t, tx.Exec("DROP TABLE users"))
+				require.NoError(t, tx.Rollback())
+			})
+			defer replica.Close()
+
+			require.Equal(t, tableContents(t, primary), tableContents(t, replica))
+
+			// indexes are up to date.
+			d, err := replica.QueryDocument("SELECT id FROM users WHERE name = 'baz'")
+			require.NoError(t, err)
+			v, err := d.GetByField("id")
+			require.NoError(t, err)
+			require.Equal(t, document.NewIntegerValue(2), v)
+
+			d, err = replica.QueryDocument("SELECT b FROM foo WHERE b = 1")
+			require.NoError(t, err)
+			v, err = d.GetByField("b")
+			require.NoError(t, err)
+			require.Equal(t, document.NewDoubleValue(1), v)
+
+			// the replica is read-only.
+			err = replica.Exec("INSERT INTO test (a) VALUES (10)")
+			require.True(t, errors.Is(err, database.ErrReadOnlyDatabase))
+		})
+	}
+
+	t.Run("Concurrent writes", func(t *testing.T) {
+		primary, err := genji.Open(filepath.Join(dir, "primary.db"))
+		require.NoError(t, err)
+		defer primary.Close()
+
+		err = primary.Exec("CREATE TABLE test(a INTEGER PRIMARY KEY); CREATE TABLE other")
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		stop := make(chan struct{})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				err := primary.Exec("INSERT INTO test (a) VALUES (?); INSERT INTO other (a) VALUES (?)", i, i)
+				require.NoError(t, err)
+			}
+		}()
+
+		replica := replicate(t, primary, memoryengine.NewEngine(), func() {
+			err := primary.Exec("INSERT INTO test (a) VALUES (-1)")
+			require.NoError(t, err)
+			close(stop)
+			wg.Wait()
+		})
+		defer replica.Close()
+
+		require.Equal(t, tableContents(t, primary), tableContents(t, replica))
+	})
+
+	t.Run("New snapshot", func(t *testing.T) {
+		primary, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer primary.Close()
+
+		err = primary.Exec("CREATE TABLE test; CREATE INDEX idx_a ON test (a); INSERT INTO test (a) VALUES (1)")
+		require.NoError(t, err)
+
+		ng := memoryengine.NewEngine()
+		replicate(t, primary, ng, func() {})
+
+		err = primary.Exec("DROP TABLE test; CREATE TABLE foo; INSERT INTO foo (a) VALUES (2)")
+		require.NoError(t, err)
+
+		// a new snapshot replaces the content of the replica.
+		replica := replicate(t, primary, ng, func() {})
+		defer replica.Close()
+
+		require.Equal(t, tableContents(t, primary), tableContents(t, replica))
+		tx, err := replica.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		indexes, err := tx.ListIndexes()
+		require.NoError(t, err)
+		require.Empty(t, indexes)
+	})
+
+	t.Run("Invalid log", func(t *testing.T) {
+		primary, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer primary.Close()
+
+		err = primary.Exec("CREATE TABLE test; INSERT INTO test (a) VALUES (1)")
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		s, err := primary.DB.ShipLog(context.Background(), &buf)
+		require.NoError(t, err)
+		require.NoError(t, primary.Exec("INSERT INTO test (a) VALUES (2)"))
+		require.NoError(t, s.Close())
+		data := buf.Bytes()
+
+		apply := func(data []byte) error {
+			replica, err := genji.New(context.Background(), memoryengine.NewEngine(), readOnly)
+			require.NoError(t, err)
+			defer replica.Close()
+
+			return replica.DB.ApplyLog(context.Background(), bytes.NewReader(data))
+		}
+
+		require.NoError(t, apply(data))
+
+		corrupted := append([]byte(nil), data...)
+		corrupted[len(corrupted)/2] ^= 0xFF
+		require.True(t, errors.Is(apply(corrupted), database.ErrInvalidLog))
+		require.True(t, errors.Is(apply(data[:len(data)-1]), database.ErrInvalidLog))
+		require.True(t, errors.Is(apply([]byte("foo")), database.ErrInvalidLog))
+	})
+}
@@ -0,0 +1,416 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/genjidb/genji/engine"
+)
+
+// A savepoint marks a state of a transaction it can be rolled back to.
+type savepoint struct {
+	name string
+	// length of the journal and of the list of changes
+	// when the savepoint was created.
+	journalLen int
+	changesLen int
+	logLen     int
+}
+
+// Savepoint creates a savepoint with the given name.
+// Rolling back to a savepoint cancels every write made after its creation
+// without cancelling the whole transaction.
+// Savepoints with the same name can be created, in that case the most
+// recent one is used.
+func (tx *Transaction) Savepoint(name string) error {
+	if !tx.writable {
+		return engine.ErrTransactionReadOnly
+	}
+	if name == "" {
+		return errors.New("missing savepoint name")
+	}
+
+	tx.savepoints = append(tx.savepoints, savepoint{
+		name:       name,
+		journalLen: len(tx.journal.entries),
+		changesLen: len(tx.changes),
+		logLen:     len(tx.journal.ops),
+	})
+	tx.journal.enabled = true
+
+	return nil
+}
+
+// RollbackToSavepoint cancels every write made since the creation of the savepoint
+// and removes the savepoints created after it. The savepoint itself is kept and
+// can be rolled back to again.
+// Sequences used to generate document ids are not rolled back.
+func (tx *Transaction) RollbackToSavepoint(name string) error {
+	i, err := tx.findSavepoint(name)
+	if err != nil {
+		return err
+	}
+
+	sp := tx.savepoints[i]
+	err = tx.journal.undo(sp.journalLen)
+	if err != nil {
+		return err
+	}
+
+	// the undone writes may have created or dropped triggers.
+	tx.triggerStore.invalidate()
+
+	tx.changes = tx.changes[:sp.changesLen]
+	tx.journal.ops = tx.journal.ops[:sp.logLen]
+	tx.savepoints = tx.savepoints[:i+1]
+	return nil
+}
+
+// ReleaseSavepoint removes the savepoint and all the savepoints created after it.
+// The writes made since its creation are kept.
+func (tx *Transaction) ReleaseSavepoint(name string) error {
+	i, err := tx.findSavepoint(name)
+	if err != nil {
+		return err
+	}
+
+	tx.savepoints = tx.savepoints[:i]
+
+	// the journal is only needed to roll back to a savepoint.
+	if len(tx.savepoints) == 0 {
+		tx.journal.enabled = false
+		tx.journal.entries = nil
+	}
+
+	return nil
+}
+
+// findSavepoint returns the position of the most recent savepoint with the given name.
+func (tx *Transaction) findSavepoint(name string) (int, error) {
+	for i := len(tx.savepoints) - 1; i >= 0; i-- {
+		if tx.savepoints[i].name == name {
+			return i, nil
+		}
+	}
+
+	return 0, ErrSavepointNotFound
+}
+
+type journalOp int
+
+const (
+	journalPut journalOp = iota + 1
+	journalDelete
+	journalCreateStore
+	journalDropStore
+	journalTruncate
+)
+
+// A journalEntry holds what is needed to cancel a write.
+type journalEntry struct {
+	op    journalOp
+	store []byte
+	key   []byte
+	// previous value of the key, if found.
+	value []byte
+	found bool
+	// content of dropped and truncated stores.
+	pairs []kvPair
+}
+
+type kvPair struct {
+	k, v []byte
+}
+
+// A journal records the writes of a transaction while it has savepoints
+// and, while logs are shipped, the operations sent to the replicas.
+type journal struct {
+	tx      engine.Transaction
+	enabled bool
+	entries []journalEntry
+
+	logging bool
+	ops     []logOp
+}
+
+// log records an operation to ship, if logs are shipped.
+func (j *journal) log(op logOp) {
+	if j.logging {
+		j.ops = append(j.ops, op)
+	}
+}
+
+// undo cancels the writes recorded after the n first entries, in reverse order,
+// and removes them from the journal.
+func (j *journal) undo(n int) error {
+	for i := len(j.entries) - 1; i >= n; i-- {
+		err := j.undoEntry(&j.entries[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	j.entries = j.entries[:n]
+	return nil
+}
+
+func (j *journal) undoEntry(e *journalEntry) error {
+	if e.op == journalCreateStore {
+		return j.tx.DropStore(e.store)
+	}
+
+	if e.op == journalDropStore {
+		err := j.tx.CreateStore(e.store)
+		if err != nil {
+			return err
+		}
+	}
+
+	st, err := j.tx.GetStore(e.store)
+	if err != nil {
+		return err
+	}
+
+	switch e.op {
+	case journalPut, journalDelete:
+		if e.found {
+			return st.Put(e.key, e.value)
+		}
+
+		err = st.Delete(e.key)
+		if err == engine.ErrKeyNotFound {
+			return nil
+		}
+		return err
+	}
+
+	for _, p := range e.pairs {
+		err = st.Put(p.k, p.v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readAll returns a copy of all the key value pairs of a store.
+func readAll(st engine.Store) ([]kvPair, error) {
+	it := st.Iterator(engine.IteratorOptions{})
+	defer it.Close()
+
+	var pairs []kvPair
+	for it.Seek(nil); it.Valid(); it.Next() {
+		item := it.Item()
+		v, err := item.ValueCopy(nil)
+		if err != nil {
+			return nil, err
+		}
+
+		pairs = append(pairs, kvPair{
+			k: append([]byte(nil), item.Key()...),
+			v: v,
+		})
+	}
+
+	return pairs, it.Err()
+}
+
+// journalTx is an engine transaction that records the writes
+// in the journal, when enabled.
+type journalTx struct {
+	engine.Transaction
+
+	j *journal
+}
+
+func (t *journalTx) GetStore(name []byte) (engine.Store, error) {
+	st, err := t.Transaction.GetStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &journalStore{
+		Store: st,
+		name:  append([]byte(nil), name...),
+		j:     t.j,
+	}, nil
+}
+
+func (t *journalTx) CreateStore(name []byte) error {
+	err := t.Transaction.CreateStore(name)
+	if err != nil {
+		return err
+	}
+
+	t.j.log(logOp{op: logCreateStore, store: append([]byte(nil), name...)})
+	if !t.j.enabled {
+		return nil
+	}
+
+	t.j.entries = append(t.j.entries, journalEntry{
+		op:    journalCreateStore,
+		store: append([]byte(nil), name...),
+	})
+	return nil
+}
+
+func (t *journalTx) DropStore(name []byte) error {
+	if !t.j.enabled {
+		err := t.Transaction.DropStore(name)
+		if err == nil {
+			t.j.log(logOp{op: logDropStore, store: append([]byte(nil), name...)})
+		}
+		return err
+	}
+
+	st, err := t.Transaction.GetStore(name)
+	if err != nil {
+		return err
+	}
+
+	pairs, err := readAll(st)
+	if err != nil {
+		return err
+	}
+
+	err = t.Transaction.DropStore(name)
+	if err != nil {
+		return err
+	}
+
+	t.j.log(logOp{op: logDropStore, store: append([]byte(nil), name...)})
+	t.j.entries = append(t.j.entries, journalEntry{
+		op:    journalDropStore,
+		store: append([]byte(nil), name...),
+		pairs: pairs,
+	})
+	return nil
+}
+
+// journalStore is a store that records the writes
+// in the journal, when enabled.
+type journalStore struct {
+	engine.Store
+
+	name []byte
+	j    *journal
+}
+
+// previous returns a journal entry holding the current value of the key.
+func (s *journalStore) previous(op journalOp, k []byte) (journalEntry, error) {
+	e := journalEntry{
+		op:    op,
+		store: s.name,
+		key:   append([]byte(nil), k...),
+	}
+
+	v, err := s.Store.Get(k)
+	if err == engine.ErrKeyNotFound {
+		return e, nil
+	}
+	if err != nil {
+		return e, err
+	}
+
+	e.found = true
+	e.value = append([]byte(nil), v...)
+	return e, nil
+}
+
+func (s *journalStore) Put(k, v []byte) error {
+	var e journalEntry
+	var err error
+	if s.j.enabled {
+		e, err = s.previous(journalPut, k)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.Store.Put(k, v)
+	if err != nil {
+		return err
+	}
+
+	s.j.log(logOp{
+		op:    logPut,
+		store: s.name,
+		key:   append([]byte(nil), k...),
+		value: append([]byte(nil), v...),
+	})
+	if s.j.enabled {
+		s.j.entries = append(s.j.entries, e)
+	}
+	return nil
+}
+
+func (s *journalStore) Delete(k []byte) error {
+	var e journalEntry
+	var err error
+	if s.j.enabled {
+		e, err = s.previous(journalDelete, k)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.Store.Delete(k)
+	if err != nil {
+		return err
+	}
+
+	s.j.log(logOp{op: logDelete, store: s.name, key: append([]byte(nil), k...)})
+	if s.j.enabled {
+		s.j.entries = append(s.j.entries, e)
+	}
+	return nil
+}
+
+func (s *journalStore) Truncate() error {
+	if !s.j.enabled {
+		err := s.Store.Truncate()
+		if err == nil {
+			s.j.log(logOp{op: logTruncate, store: s.name})
+		}
+		return err
+	}
+
+	pairs, err := readAll(s.Store)
+	if err != nil {
+		return err
+	}
+
+	err = s.Store.Truncate()
+	if err != nil {
+		return err
+	}
+
+	s.j.log(logOp{op: logTruncate, store: s.name})
+	s.j.entries = append(s.j.entries, journalEntry{
+		op:    journalTruncate,
+		store: s.name,
+		pairs: pairs,
+	})
+	return nil
+}
+
+func (s *journalStore) NextSequence() (uint64, error) {
+	seq, err := s.Store.NextSequence()
+	if err != nil {
+		return 0, err
+	}
+
+	s.j.log(logOp{op: logSequence, store: s.name, seq: seq})
+	return seq, nil
+}
+
+func (s *journalStore) SetSequence(seq uint64) error {
+	// the underlying store may not be able to set its sequence directly.
+	err := advanceSequence(s.Store, seq)
+	if err != nil {
+		return err
+	}
+
+	s.j.log(logOp{op: logSequence, store: s.name, seq: seq})
+	return nil
+}
@@ -0,0 +1,176 @@
+package database_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavepoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genji")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	paths := map[string]string{
+		"Memory": ":memory:",
+		"Bolt":   filepath.Join(dir, "test.db"),
+	}
+
+	for name, path := range paths {
+		open := func(t *testing.T) *genji.DB {
+			os.Remove(path)
+			db, err := genji.Open(path)
+			require.NoError(t, err)
+
+			err = db.Exec(`
+				CREATE TABLE test(a INTEGER PRIMARY KEY);
+				CREATE UNIQUE INDEX idx_b ON test (b);
+				INSERT INTO test (a, b) VALUES (1, 1), (2, 2);
+			`)
+			require.NoError(t, err)
+			return db
+		}
+
+		t.Run(name+"/Store and index writes", func(t *testing.T) {
+			db := open(t)
+			defer db.Close()
+
+			err := db.Update(func(tx *genji.Tx) error {
+				require.NoError(t, tx.Savepoint("sp"))
+
+				err := tx.Exec("INSERT INTO test (a, b) VALUES (3, 3); UPDATE test SET b = 10 WHERE a = 1; DELETE FROM test WHERE a = 2")
+				require.NoError(t, err)
+
+				require.NoError(t, tx.RollbackToSavepoint("sp"))
+
+				// the savepoint can be rolled back to again.
+				err = tx.Exec("INSERT INTO test (a, b) VALUES (4, 4)")
+				require.NoError(t, err)
+				require.NoError(t, tx.RollbackToSavepoint("sp"))
+				return tx.ReleaseSavepoint("sp")
+			})
+			require.NoError(t, err)
+
+			require.EqualValues(t, 2, countDocuments(t, db, "test"))
+			for _, b := range []int{1, 2} {
+				d, err := db.QueryDocument("SELECT a FROM test WHERE b = ?", b)
+				require.NoError(t, err)
+				v, err := d.GetByField("a")
+				require.NoError(t, err)
+				require.Equal(t, document.NewIntegerValue(int64(b)), v)
+			}
+			_, err = db.QueryDocument("SELECT a FROM test WHERE b = 10")
+			require.Equal(t, database.ErrDocumentNotFound, err)
+		})
+
+		t.Run(name+"/Nested", func(t *testing.T) {
+			db := open(t)
+			defer db.Close()
+
+			err := db.Update(func(tx *genji.Tx) error {
+				require.NoError(t, tx.Savepoint("a"))
+				require.NoError(t, tx.Exec("INSERT INTO test (a, b) VALUES (3, 3)"))
+				require.NoError(t, tx.Savepoint("b"))
+				require.NoError(t, tx.Exec("INSERT INTO test (a, b) VALUES (4, 4)"))
+				require.NoError(t, tx.Savepoint("c"))
+				require.NoError(t, tx.Exec("INSERT INTO test (a, b) VALUES (5, 5)"))
+
+				require.NoError(t, tx.RollbackToSavepoint("b"))
+				require.Equal(t, database.ErrSavepointNotFound, tx.RollbackToSavepoint("c"))
+				require.NoError(t, tx.ReleaseSavepoint("a"))
+				require.Equal(t, database.ErrSavepointNotFound, tx.ReleaseSavepoint("b"))
+				return nil
+			})
+			require.NoError(t, err)
+
+			require.EqualValues(t, 3, countDocuments(t, db, "test"))
+		})
+
+		t.Run(name+"/Schema changes", func(t *testing.T) {
+			db := open(t)
+			defer db.Close()
+
+			err := db.Update(func(tx *genji.Tx) error {
+				require.NoError(t, tx.Savepoint("sp"))
+				err := tx.Exec(`
+					CREATE TABLE foo; INSERT INTO foo (a) VALUES (1);
+					DROP TABLE test;
+					CREATE INDEX idx_c ON foo (c);
+				`)
+				require.NoError(t, err)
+				return tx.RollbackToSavepoint("sp")
+			})
+			require.NoError(t, err)
+
+			require.EqualValues(t, 2, countDocuments(t, db, "test"))
+			err = db.Exec("SELECT * FROM foo")
+			require.True(t, errors.Is(err, database.ErrTableNotFound))
+
+			// the unique index is still enforced.
+			err = db.Exec("INSERT INTO test (a, b) VALUES (3, 1)")
+			require.Equal(t, database.ErrDuplicateDocument, err)
+		})
+
+		t.Run(name+"/Reindex", func(t *testing.T) {
+			db := open(t)
+			defer db.Close()
+
+			err := db.Update(func(tx *genji.Tx) error {
+				require.NoError(t, tx.Savepoint("sp"))
+				err := tx.Exec("DELETE FROM test WHERE a = 1; REINDEX")
+				require.NoError(t, err)
+				return tx.RollbackToSavepoint("sp")
+			})
+			require.NoError(t, err)
+
+			d, err := db.QueryDocument("SELECT a FROM test WHERE b = 1")
+			require.NoError(t, err)
+			v, err := d.GetByField("a")
+			require.NoError(t, err)
+			require.Equal(t, document.NewIntegerValue(1), v)
+		})
+
+		t.Run(name+"/Changes", func(t *testing.T) {
+			db := open(t)
+			defer db.Close()
+
+			sub := db.Subscribe("test", nil)
+			defer sub.Close()
+
+			err := db.Update(func(tx *genji.Tx) error {
+				require.NoError(t, tx.Exec("INSERT INTO test (a, b) VALUES (3, 3)"))
+				require.NoError(t, tx.Savepoint("sp"))
+				require.NoError(t, tx.Exec("INSERT INTO test (a, b) VALUES (4, 4)"))
+				return tx.RollbackToSavepoint("sp")
+			})
+			require.NoError(t, err)
+
+			ev := nextEvent(t, sub)
+			require.Equal(t, database.InsertChange, ev.Type)
+			v, err := ev.New.GetByField("a")
+			require.NoError(t, err)
+			require.Equal(t, document.NewIntegerValue(3), v)
+			require.Len(t, sub.C, 0)
+		})
+	}
+
+	t.Run("Read-only", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.View(func(tx *genji.Tx) error {
+			require.Equal(t, engine.ErrTransactionReadOnly, tx.Savepoint("sp"))
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// A Session holds the state of a client of the database, like the transaction
+// opened with the BEGIN statement. Each session can have its own attached transaction,
+// independently of the other sessions of the same database.
+type Session struct {
+	db *Database
+
+	// If this is non-nil, the user is running an explicit transaction
+	// using the BEGIN statement.
+	// Only one attached transaction can be run at a time per session and any calls
+	// to BeginTx will cause an error until that transaction is rolled back or commited.
+	attachedTx *Transaction
+	mu         sync.Mutex
+}
+
+// NewSession creates a session with no attached transaction.
+func (db *Database) NewSession() *Session {
+	return &Session{db: db}
+}
+
+// DefaultSession returns the session used by the methods of the database itself,
+// like BeginTx and GetAttachedTx.
+func (db *Database) DefaultSession() *Session {
+	return db.session
+}
+
+// DB returns the database of the session.
+func (s *Session) DB() *Database {
+	return s.db
+}
+
+// BeginTx starts a new transaction with the given options.
+// If the Attached option is passed, the transaction gets attached to the session
+// and prevents any other transaction to be opened by the session afterwards,
+// until it gets rolled back or commited.
+func (s *Session) BeginTx(ctx context.Context, opts *TxOptions) (*Transaction, error) {
+	if opts == nil {
+		opts = new(TxOptions)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.attachedTx != nil {
+		return nil, errors.New("cannot open a transaction within a transaction")
+	}
+
+	if s.db.readOnly && !opts.ReadOnly {
+		return nil, ErrReadOnlyDatabase
+	}
+
+	tx, err := s.db.beginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Attached {
+		tx.session = s
+		s.attachedTx = tx
+	}
+
+	return tx, nil
+}
+
+// GetAttachedTx returns the transaction attached to the session. It returns nil if there is no
+// such transaction.
+// The returned transaction is not thread safe.
+func (s *Session) GetAttachedTx() *Transaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.attachedTx
+}
+
+// detach is called when the attached transaction is closed.
+func (s *Session) detach(tx *Transaction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.attachedTx == tx {
+		s.attachedTx = nil
+	}
+}
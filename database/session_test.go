@@ -0,0 +1,47 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession(t *testing.T) {
+	db, err := database.New(context.Background(), memoryengine.NewEngine(), database.Options{Codec: msgpack.NewCodec()})
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	s1 := db.NewSession()
+	s2 := db.NewSession()
+
+	tx1, err := s1.BeginTx(ctx, &database.TxOptions{ReadOnly: true, Attached: true})
+	require.NoError(t, err)
+	require.Equal(t, tx1, s1.GetAttachedTx())
+
+	// other sessions are not affected by the attached transaction.
+	tx2, err := s2.BeginTx(ctx, &database.TxOptions{ReadOnly: true, Attached: true})
+	require.NoError(t, err)
+	require.Nil(t, db.GetAttachedTx())
+
+	tx, err := db.BeginTx(ctx, &database.TxOptions{ReadOnly: true})
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+
+	_, err = s1.BeginTx(ctx, &database.TxOptions{ReadOnly: true})
+	require.Error(t, err)
+
+	require.NoError(t, tx1.Rollback())
+	require.Nil(t, s1.GetAttachedTx())
+	require.Equal(t, tx2, s2.GetAttachedTx())
+	require.NoError(t, tx2.Rollback())
+
+	tx1, err = s1.BeginTx(ctx, &database.TxOptions{ReadOnly: true})
+	require.NoError(t, err)
+	require.Nil(t, s1.GetAttachedTx())
+	require.NoError(t, tx1.Rollback())
+}
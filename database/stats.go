@@ -0,0 +1,230 @@
+package database
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultHistogramBounds are the upper bounds of the buckets
+// of the histograms of durations.
+var DefaultHistogramBounds = []time.Duration{
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+}
+
+// Stats holds statistics about the activity of the database since it was opened.
+type Stats struct {
+	ReadTransactions  TransactionStats
+	WriteTransactions TransactionStats
+
+	// Number of queries reported.
+	Queries int64
+	// Number of documents read from tables and indexes.
+	DocumentsScanned int64
+	// Number of documents returned by the queries.
+	DocumentsReturned int64
+	// Number of iterations over an index.
+	IndexLookups int64
+	// Duration of the queries.
+	QueryDuration Histogram
+	// Statistics of the planner nodes, by operation.
+	Nodes map[string]NodeStats
+}
+
+// TransactionStats counts transactions.
+type TransactionStats struct {
+	Begun      int64
+	Committed  int64
+	RolledBack int64
+}
+
+// QueryStats holds statistics about the execution of a query.
+type QueryStats struct {
+	// Description of the query plan.
+	Plan              string
+	DocumentsScanned  int64
+	DocumentsReturned int64
+	IndexLookups      int64
+	// Time spent executing the query. Unless the database was opened with
+	// NodeTimings, it includes the time spent by the caller between the
+	// documents returned.
+	Duration time.Duration
+	// Statistics of each node of the plan, from the input to the root.
+	Nodes []NodeStats
+}
+
+// NodeStats holds statistics about a node of a query plan.
+type NodeStats struct {
+	// Operation of the node.
+	Operation string
+	// Number of documents output by the node.
+	Documents int64
+	// Time spent in the node, excluding the other nodes.
+	// It is only measured if the database was opened with NodeTimings,
+	// and is zero otherwise.
+	Duration time.Duration
+}
+
+// A Histogram counts durations by buckets.
+type Histogram struct {
+	// Upper bounds of the buckets, in increasing order.
+	Bounds []time.Duration
+	// Counts[i] is the number of durations lower or equal to Bounds[i] and
+	// greater than Bounds[i-1]. The last element counts the durations
+	// greater than the last bound.
+	Counts []int64
+	Count  int64
+	Sum    time.Duration
+}
+
+// Observe adds a duration to the histogram.
+func (h *Histogram) Observe(d time.Duration) {
+	if h.Counts == nil {
+		h.Counts = make([]int64, len(h.Bounds)+1)
+	}
+
+	i := 0
+	for i < len(h.Bounds) && d > h.Bounds[i] {
+		i++
+	}
+
+	h.Counts[i]++
+	h.Count++
+	h.Sum += d
+}
+
+func (h Histogram) clone() Histogram {
+	h.Bounds = append([]time.Duration(nil), h.Bounds...)
+	h.Counts = append([]int64(nil), h.Counts...)
+	return h
+}
+
+// A StatsHook is notified of the activity of the database.
+// Methods are called synchronously, by the goroutine using the database:
+// they must be fast and safe for concurrent use.
+type StatsHook interface {
+	TransactionBegun(writable bool)
+	TransactionCommitted(writable bool)
+	TransactionRolledBack(writable bool)
+	QueryDone(qs QueryStats)
+}
+
+// statsCollector aggregates the statistics of the database.
+type statsCollector struct {
+	// transaction counters, updated atomically since they change
+	// on every transaction. They are kept first to be 64-bit aligned.
+	readTxs  TransactionStats
+	writeTxs TransactionStats
+
+	// protects the statistics of the queries.
+	mu    sync.Mutex
+	stats Stats
+
+	hook        StatsHook
+	nodeTimings bool
+}
+
+func newStatsCollector(opts Options) *statsCollector {
+	return &statsCollector{
+		stats: Stats{
+			QueryDuration: Histogram{Bounds: DefaultHistogramBounds},
+			Nodes:         make(map[string]NodeStats),
+		},
+		hook:        opts.StatsHook,
+		nodeTimings: opts.NodeTimings,
+	}
+}
+
+func (c *statsCollector) transactions(writable bool) *TransactionStats {
+	if writable {
+		return &c.writeTxs
+	}
+
+	return &c.readTxs
+}
+
+func (c *statsCollector) txBegun(writable bool) {
+	atomic.AddInt64(&c.transactions(writable).Begun, 1)
+
+	if c.hook != nil {
+		c.hook.TransactionBegun(writable)
+	}
+}
+
+func (c *statsCollector) txCommitted(writable bool) {
+	atomic.AddInt64(&c.transactions(writable).Committed, 1)
+
+	if c.hook != nil {
+		c.hook.TransactionCommitted(writable)
+	}
+}
+
+func (c *statsCollector) txRolledBack(writable bool) {
+	atomic.AddInt64(&c.transactions(writable).RolledBack, 1)
+
+	if c.hook != nil {
+		c.hook.TransactionRolledBack(writable)
+	}
+}
+
+func loadTransactionStats(ts *TransactionStats) TransactionStats {
+	return TransactionStats{
+		Begun:      atomic.LoadInt64(&ts.Begun),
+		Committed:  atomic.LoadInt64(&ts.Committed),
+		RolledBack: atomic.LoadInt64(&ts.RolledBack),
+	}
+}
+
+// Stats returns statistics about the activity of the database since it was opened.
+func (db *Database) Stats() Stats {
+	c := db.stats
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.stats
+	s.ReadTransactions = loadTransactionStats(&c.readTxs)
+	s.WriteTransactions = loadTransactionStats(&c.writeTxs)
+	s.QueryDuration = s.QueryDuration.clone()
+	s.Nodes = make(map[string]NodeStats, len(c.stats.Nodes))
+	for op, ns := range c.stats.Nodes {
+		s.Nodes[op] = ns
+	}
+
+	return s
+}
+
+// NodeTimings returns true if the time spent in each node
+// of the query plans must be measured.
+func (db *Database) NodeTimings() bool {
+	return db.stats.nodeTimings
+}
+
+// ReportQuery adds the statistics of a query to the statistics of the database
+// and passes them to the hook, if any.
+// It is called by the query planner after each execution of a query.
+func (db *Database) ReportQuery(qs QueryStats) {
+	c := db.stats
+	c.mu.Lock()
+	c.stats.Queries++
+	c.stats.DocumentsScanned += qs.DocumentsScanned
+	c.stats.DocumentsReturned += qs.DocumentsReturned
+	c.stats.IndexLookups += qs.IndexLookups
+	c.stats.QueryDuration.Observe(qs.Duration)
+	for _, ns := range qs.Nodes {
+		agg := c.stats.Nodes[ns.Operation]
+		agg.Operation = ns.Operation
+		agg.Documents += ns.Documents
+		agg.Duration += ns.Duration
+		c.stats.Nodes[ns.Operation] = agg
+	}
+	c.mu.Unlock()
+
+	if c.hook != nil {
+		c.hook.QueryDone(qs)
+	}
+}
@@ -0,0 +1,117 @@
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingHook struct {
+	mu      sync.Mutex
+	events  []string
+	queries []database.QueryStats
+}
+
+func (h *recordingHook) record(event string, writable bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, fmt.Sprintf("%s writable=%v", event, writable))
+}
+
+func (h *recordingHook) TransactionBegun(writable bool)      { h.record("begun", writable) }
+func (h *recordingHook) TransactionCommitted(writable bool)  { h.record("committed", writable) }
+func (h *recordingHook) TransactionRolledBack(writable bool) { h.record("rolledback", writable) }
+
+func (h *recordingHook) QueryDone(qs database.QueryStats) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.queries = append(h.queries, qs)
+}
+
+func TestStats(t *testing.T) {
+	var h recordingHook
+	db, err := database.New(context.Background(), memoryengine.NewEngine(), database.Options{
+		Codec:           msgpack.NewCodec(),
+		TTLReapInterval: -1,
+		StatsHook:       &h,
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+	// rolling back after a commit is not counted.
+	require.NoError(t, tx.Rollback())
+
+	tx, err = db.Begin(true)
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+
+	tx, err = db.Begin(false)
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+
+	stats := db.Stats()
+	require.Equal(t, database.TransactionStats{Begun: 2, Committed: 1, RolledBack: 1}, stats.WriteTransactions)
+	require.Equal(t, database.TransactionStats{Begun: 1, RolledBack: 1}, stats.ReadTransactions)
+	require.Equal(t, []string{
+		"begun writable=true", "committed writable=true",
+		"begun writable=true", "rolledback writable=true",
+		"begun writable=false", "rolledback writable=false",
+	}, h.events)
+
+	qs := database.QueryStats{
+		Plan:              "Table(test)",
+		DocumentsScanned:  10,
+		DocumentsReturned: 2,
+		IndexLookups:      1,
+		Duration:          5 * time.Millisecond,
+		Nodes: []database.NodeStats{
+			{Operation: "Input", Documents: 10, Duration: 3 * time.Millisecond},
+			{Operation: "Selection", Documents: 2, Duration: 2 * time.Millisecond},
+		},
+	}
+	db.ReportQuery(qs)
+	db.ReportQuery(qs)
+	require.Equal(t, []database.QueryStats{qs, qs}, h.queries)
+
+	stats = db.Stats()
+	require.EqualValues(t, 2, stats.Queries)
+	require.EqualValues(t, 20, stats.DocumentsScanned)
+	require.EqualValues(t, 4, stats.DocumentsReturned)
+	require.EqualValues(t, 2, stats.IndexLookups)
+	require.EqualValues(t, 2, stats.QueryDuration.Count)
+	require.Equal(t, 10*time.Millisecond, stats.QueryDuration.Sum)
+	require.Equal(t, []int64{0, 0, 2, 0, 0, 0, 0}, stats.QueryDuration.Counts)
+	require.Equal(t, map[string]database.NodeStats{
+		"Input":     {Operation: "Input", Documents: 20, Duration: 6 * time.Millisecond},
+		"Selection": {Operation: "Selection", Documents: 4, Duration: 4 * time.Millisecond},
+	}, stats.Nodes)
+
+	// the returned stats are a copy.
+	stats.Nodes["Input"] = database.NodeStats{}
+	stats.QueryDuration.Counts[0] = 10
+	require.EqualValues(t, 20, db.Stats().Nodes["Input"].Documents)
+	require.EqualValues(t, 0, db.Stats().QueryDuration.Counts[0])
+}
+
+func TestHistogram(t *testing.T) {
+	h := database.Histogram{Bounds: []time.Duration{time.Millisecond, time.Second}}
+
+	h.Observe(0)
+	h.Observe(time.Millisecond)
+	h.Observe(2 * time.Millisecond)
+	h.Observe(time.Minute)
+
+	require.Equal(t, []int64{2, 1, 1}, h.Counts)
+	require.EqualValues(t, 4, h.Count)
+	require.Equal(t, time.Minute+3*time.Millisecond, h.Sum)
+}
@@ -0,0 +1,301 @@
+package database
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/genjidb/genji/document"
+)
+
+// DefaultSubscriptionQueueSize is the default maximum number
+// of events waiting to be read from a subscription.
+const DefaultSubscriptionQueueSize = 10000
+
+// ChangeType describes the kind of write that produced a ChangeEvent.
+type ChangeType int
+
+const (
+	// InsertChange is used when a document is inserted in a table.
+	InsertChange ChangeType = iota + 1
+	// UpdateChange is used when a document of a table is replaced.
+	UpdateChange
+	// DeleteChange is used when a document is deleted from a table.
+	DeleteChange
+)
+
+func (c ChangeType) String() string {
+	switch c {
+	case InsertChange:
+		return "insert"
+	case UpdateChange:
+		return "update"
+	case DeleteChange:
+		return "delete"
+	}
+
+	return ""
+}
+
+// A ChangeEvent describes a write made on a document by a committed transaction.
+type ChangeEvent struct {
+	Type      ChangeType
+	TableName string
+	Key       []byte
+	// Old is the document before the change. It is nil for insertions.
+	Old document.Document
+	// New is the document after the change. It is nil for deletions.
+	New document.Document
+}
+
+// A Subscription receives the changes made to a table, once
+// the transaction that made them has been successfully committed.
+// Events are delivered in commit order on the C channel, which is
+// closed when the subscription or the database is closed, or when
+// the subscriber falls too far behind: see Err.
+type Subscription struct {
+	// C delivers the change events.
+	C <-chan ChangeEvent
+
+	db        *Database
+	tableName string
+	filter    func(ev ChangeEvent) bool
+
+	c      chan ChangeEvent
+	done   chan struct{}
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  []ChangeEvent
+	closed bool
+	err    error
+}
+
+// Subscribe returns a subscription that receives every change made to the given table.
+// If tableName is empty, changes made to any table are received.
+// If filter is not nil, only the events for which it returns true are delivered.
+// Events are queued until they are read from the subscription channel, so that slow
+// subscribers never block writers. If more than Options.SubscriptionQueueSize events
+// are queued, the subscription is closed and Err returns ErrSubscriptionQueueFull.
+// The subscription must be closed after usage.
+func (db *Database) Subscribe(tableName string, filter func(ev ChangeEvent) bool) *Subscription {
+	s := Subscription{
+		db:        db,
+		tableName: tableName,
+		filter:    filter,
+		c:         make(chan ChangeEvent),
+		done:      make(chan struct{}),
+	}
+	s.C = s.c
+	s.cond = sync.NewCond(&s.mu)
+
+	db.subscriptionsMu.Lock()
+	if db.subscriptions == nil {
+		db.subscriptions = make(map[*Subscription]struct{})
+	}
+	db.subscriptions[&s] = struct{}{}
+	db.subscriptionsMu.Unlock()
+
+	go s.run()
+
+	return &s
+}
+
+// Close the subscription. Any undelivered event is discarded.
+// Calling Close more than once is a no-op.
+func (s *Subscription) Close() error {
+	s.db.subscriptionsMu.Lock()
+	delete(s.db.subscriptions, s)
+	s.db.subscriptionsMu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closeLocked(nil)
+	return nil
+}
+
+// Err returns ErrSubscriptionQueueFull if the subscription was closed
+// because its events were not read fast enough, or nil otherwise.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// closeLocked closes the subscription with the given error.
+// s.mu must be held.
+func (s *Subscription) closeLocked(err error) {
+	if s.closed {
+		return
+	}
+
+	s.closed = true
+	s.err = err
+	s.queue = nil
+	close(s.done)
+	s.cond.Broadcast()
+}
+
+func (s *Subscription) matches(ev ChangeEvent) bool {
+	if s.tableName != "" && s.tableName != ev.TableName {
+		return false
+	}
+
+	return s.filter == nil || s.filter(ev)
+}
+
+// push queues the events. It returns false if the subscription is closed,
+// either before the call or because its queue is full.
+func (s *Subscription) push(evs []ChangeEvent) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	if len(s.queue)+len(evs) > s.db.subscriptionQueueSize {
+		s.closeLocked(ErrSubscriptionQueueFull)
+		return false
+	}
+
+	s.queue = append(s.queue, evs...)
+	s.cond.Signal()
+	return true
+}
+
+// run delivers queued events to the channel until
+// the subscription is closed.
+func (s *Subscription) run() {
+	defer close(s.c)
+
+	for {
+		s.mu.Lock()
+		for len(s.queue) == 0 && !s.closed {
+			s.cond.Wait()
+		}
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+		evs := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+
+		for _, ev := range evs {
+			select {
+			case s.c <- ev:
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+// hasSubscribers returns whether changes made to the given table
+// must be recorded.
+func (db *Database) hasSubscribers(tableName string) bool {
+	db.subscriptionsMu.RLock()
+	defer db.subscriptionsMu.RUnlock()
+
+	for s := range db.subscriptions {
+		if s.tableName == "" || s.tableName == tableName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// publish sends the events to every matching subscription.
+// Subscriptions closed because their queue is full stop receiving changes.
+func (db *Database) publish(evs []ChangeEvent) {
+	var closed []*Subscription
+
+	db.subscriptionsMu.RLock()
+	for s := range db.subscriptions {
+		var matching []ChangeEvent
+		for _, ev := range evs {
+			if s.matches(ev) {
+				matching = append(matching, ev)
+			}
+		}
+
+		if len(matching) > 0 && !s.push(matching) {
+			closed = append(closed, s)
+		}
+	}
+	db.subscriptionsMu.RUnlock()
+
+	if len(closed) == 0 {
+		return
+	}
+
+	db.subscriptionsMu.Lock()
+	for _, s := range closed {
+		delete(db.subscriptions, s)
+	}
+	db.subscriptionsMu.Unlock()
+}
+
+// closeSubscriptions closes all the subscriptions of the database.
+func (db *Database) closeSubscriptions() {
+	db.subscriptionsMu.RLock()
+	subs := make([]*Subscription, 0, len(db.subscriptions))
+	for s := range db.subscriptions {
+		subs = append(subs, s)
+	}
+	db.subscriptionsMu.RUnlock()
+
+	for _, s := range subs {
+		s.Close()
+	}
+}
+
+// recordChange stores a copy of the change in the transaction
+// if anyone subscribed to the table. Changes are published
+// once the transaction is committed.
+func (t *Table) recordChange(tp ChangeType, key []byte, old, new document.Document) error {
+	if !t.tx.db.hasSubscribers(t.name) {
+		return nil
+	}
+
+	ev := ChangeEvent{
+		Type:      tp,
+		TableName: t.name,
+		Key:       append([]byte(nil), key...),
+	}
+
+	var err error
+	if old != nil {
+		ev.Old, err = t.detachDocument(old)
+		if err != nil {
+			return err
+		}
+	}
+
+	if new != nil {
+		ev.New, err = t.detachDocument(new)
+		if err != nil {
+			return err
+		}
+	}
+
+	t.tx.changes = append(t.tx.changes, ev)
+	return nil
+}
+
+// detachDocument returns a copy of d that remains valid
+// after the transaction is closed.
+func (t *Table) detachDocument(d document.Document) (document.Document, error) {
+	var buf bytes.Buffer
+	enc := t.tx.db.Codec.NewEncoder(&buf)
+	defer enc.Close()
+
+	err := enc.EncodeDocument(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.tx.db.Codec.NewDocument(buf.Bytes()), nil
+}
@@ -0,0 +1,200 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+func nextEvent(t *testing.T, sub *database.Subscription) database.ChangeEvent {
+	t.Helper()
+
+	select {
+	case ev, ok := <-sub.C:
+		require.True(t, ok, "subscription closed")
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+
+	return database.ChangeEvent{}
+}
+
+func requireNoEvent(t *testing.T, sub *database.Subscription) {
+	t.Helper()
+
+	select {
+	case ev := <-sub.C:
+		t.Fatalf("unexpected event %v", ev)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	t.Run("Committed changes", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec("CREATE TABLE test(a INTEGER PRIMARY KEY); CREATE TABLE other")
+		require.NoError(t, err)
+
+		sub := db.Subscribe("test", nil)
+		defer sub.Close()
+
+		err = db.Exec("INSERT INTO test (a, b) VALUES (1, 'foo')")
+		require.NoError(t, err)
+		err = db.Exec("INSERT INTO other (a) VALUES (1)")
+		require.NoError(t, err)
+		err = db.Exec("UPDATE test SET b = 'bar'")
+		require.NoError(t, err)
+		err = db.Exec("DELETE FROM test")
+		require.NoError(t, err)
+
+		ev := nextEvent(t, sub)
+		require.Equal(t, database.InsertChange, ev.Type)
+		require.Equal(t, "test", ev.TableName)
+		require.Nil(t, ev.Old)
+		v, err := ev.New.GetByField("b")
+		require.NoError(t, err)
+		require.Equal(t, document.NewTextValue("foo"), v)
+
+		ev = nextEvent(t, sub)
+		require.Equal(t, database.UpdateChange, ev.Type)
+		v, err = ev.Old.GetByField("b")
+		require.NoError(t, err)
+		require.Equal(t, document.NewTextValue("foo"), v)
+		v, err = ev.New.GetByField("b")
+		require.NoError(t, err)
+		require.Equal(t, document.NewTextValue("bar"), v)
+
+		ev = nextEvent(t, sub)
+		require.Equal(t, database.DeleteChange, ev.Type)
+		require.Nil(t, ev.New)
+		v, err = ev.Old.GetByField("b")
+		require.NoError(t, err)
+		require.Equal(t, document.NewTextValue("bar"), v)
+
+		requireNoEvent(t, sub)
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec("CREATE TABLE test")
+		require.NoError(t, err)
+
+		sub := db.Subscribe("", nil)
+		defer sub.Close()
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		err = tx.Exec("INSERT INTO test (a) VALUES (1)")
+		require.NoError(t, err)
+		requireNoEvent(t, sub)
+		require.NoError(t, tx.Rollback())
+		requireNoEvent(t, sub)
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec("CREATE TABLE test")
+		require.NoError(t, err)
+
+		sub := db.Subscribe("test", func(ev database.ChangeEvent) bool {
+			return ev.Type == database.DeleteChange
+		})
+		defer sub.Close()
+
+		err = db.Exec("INSERT INTO test (a) VALUES (1); DELETE FROM test")
+		require.NoError(t, err)
+
+		ev := nextEvent(t, sub)
+		require.Equal(t, database.DeleteChange, ev.Type)
+		requireNoEvent(t, sub)
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec("CREATE TABLE test")
+		require.NoError(t, err)
+
+		sub := db.Subscribe("test", nil)
+		err = db.Exec("INSERT INTO test (a) VALUES (1)")
+		require.NoError(t, err)
+		require.NoError(t, sub.Close())
+		require.NoError(t, sub.Close())
+
+		// the channel must eventually be closed
+		for range sub.C {
+		}
+	})
+
+	t.Run("Database close", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+
+		sub := db.Subscribe("", nil)
+		require.NoError(t, db.Close())
+
+		requireClosed(t, sub)
+		require.NoError(t, sub.Err())
+	})
+
+	t.Run("Queue full", func(t *testing.T) {
+		db, err := genji.New(context.Background(), memoryengine.NewEngine(), func(opts *database.Options) {
+			opts.SubscriptionQueueSize = 2
+		})
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec("CREATE TABLE test")
+		require.NoError(t, err)
+
+		sub := db.Subscribe("test", nil)
+		defer sub.Close()
+
+		err = db.Exec("INSERT INTO test (a) VALUES (1), (2), (3)")
+		require.NoError(t, err)
+
+		// the subscriber didn't read the events: it is closed
+		// instead of letting the queue grow.
+		requireClosed(t, sub)
+		require.Equal(t, database.ErrSubscriptionQueueFull, sub.Err())
+
+		// writers are not affected.
+		err = db.Exec("INSERT INTO test (a) VALUES (4)")
+		require.NoError(t, err)
+	})
+}
+
+// requireClosed waits for the channel of the subscription to be closed.
+func requireClosed(t *testing.T, sub *database.Subscription) {
+	t.Helper()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-sub.C:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("timeout waiting for the subscription to be closed")
+		}
+	}
+}
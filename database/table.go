@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding"
@@ -18,6 +19,9 @@ type Table struct {
 	Store     engine.Store
 	name      string
 	infoStore *tableInfoStore
+	// path of the expiration time of documents,
+	// if the table has a TTL.
+	ttlPath document.Path
 }
 
 // Tx returns the current transaction.
@@ -54,7 +58,32 @@ func (t *Table) Insert(d document.Document) ([]byte, error) {
 		return nil, errors.New("cannot write to read-only table")
 	}
 
-	fb, err := info.FieldConstraints.ValidateDocument(d)
+	triggers, err := t.triggers(InsertChange)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes, err := t.Indexes()
+	if err != nil {
+		return nil, err
+	}
+
+	return t.insert(info, triggers, indexes, d)
+}
+
+// insert the document into the table, using the given table info, triggers and
+// indexes. Only the given indexes are updated.
+func (t *Table) insert(info *TableInfo, triggers []trigger, indexes map[string]Index, d document.Document) ([]byte, error) {
+	tc := TriggerContext{Type: InsertChange, New: d}
+	err := t.fireTriggers(triggers, BeforeTrigger, &tc)
+	if err != nil {
+		return nil, err
+	}
+	if tc.New == nil {
+		return nil, errors.New("trigger removed the document to insert")
+	}
+
+	fb, err := info.FieldConstraints.ValidateDocument(tc.New)
 	if err != nil {
 		return nil, err
 	}
@@ -64,9 +93,22 @@ func (t *Table) Insert(d document.Document) ([]byte, error) {
 		return nil, err
 	}
 
-	_, err = t.Store.Get(key)
+	old, err := t.getDocument(key)
 	if err == nil {
-		return nil, ErrDuplicateDocument
+		// an expired document that wasn't deleted yet
+		// can be replaced by the new one.
+		expired, err := t.isExpired(old, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if !expired {
+			return nil, ErrDuplicateDocument
+		}
+
+		err = t.Delete(key)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	var buf bytes.Buffer
@@ -82,18 +124,8 @@ func (t *Table) Insert(d document.Document) ([]byte, error) {
 		return nil, err
 	}
 
-	indexes, err := t.Indexes()
-	if err != nil {
-		return nil, err
-	}
-
 	for _, idx := range indexes {
-		v, err := idx.Opts.Path.GetValue(fb)
-		if err != nil {
-			v = document.NewNullValue()
-		}
-
-		err = idx.Set(v, key)
+		err = idx.Set(indexedValue(idx, fb), key)
 		if err != nil {
 			if err == index.ErrDuplicate {
 				return nil, ErrDuplicateDocument
@@ -103,6 +135,18 @@ func (t *Table) Insert(d document.Document) ([]byte, error) {
 		}
 	}
 
+	err = t.recordChange(InsertChange, key, nil, fb)
+	if err != nil {
+		return nil, err
+	}
+
+	tc.Key = key
+	tc.New = fb
+	err = t.fireTriggers(triggers, AfterTrigger, &tc)
+	if err != nil {
+		return nil, err
+	}
+
 	return key, nil
 }
 
@@ -118,29 +162,66 @@ func (t *Table) Delete(key []byte) error {
 		return errors.New("cannot write to read-only table")
 	}
 
-	d, err := t.GetDocument(key)
+	d, err := t.getDocument(key)
 	if err != nil {
 		return err
 	}
 
-	indexes, err := t.Indexes()
+	triggers, err := t.triggers(DeleteChange)
 	if err != nil {
 		return err
 	}
 
-	for _, idx := range indexes {
-		v, err := idx.Opts.Path.GetValue(d)
+	if len(triggers) > 0 {
+		// the document must remain valid for AFTER triggers,
+		// once it has been deleted from the store.
+		d, err = t.detachDocument(d)
 		if err != nil {
 			return err
 		}
+	}
+
+	tc := TriggerContext{Type: DeleteChange, Key: key, Old: d}
+	err = t.fireTriggers(triggers, BeforeTrigger, &tc)
+	if err != nil {
+		return err
+	}
+
+	indexes, err := t.Indexes()
+	if err != nil {
+		return err
+	}
 
-		err = idx.Delete(v, key)
+	for _, idx := range indexes {
+		err = idx.Delete(indexedValue(idx, d), key)
 		if err != nil {
 			return err
 		}
 	}
 
-	return t.Store.Delete(key)
+	err = t.recordChange(DeleteChange, key, d, nil)
+	if err != nil {
+		return err
+	}
+
+	err = t.Store.Delete(key)
+	if err != nil {
+		return err
+	}
+
+	return t.fireTriggers(triggers, AfterTrigger, &tc)
+}
+
+// indexedValue returns the value of d indexed by idx.
+// Documents without the indexed field are indexed as null,
+// so that they can be written to tables whose indexes are untyped.
+func indexedValue(idx Index, d document.Document) document.Value {
+	v, err := idx.Opts.Path.GetValue(d)
+	if err != nil {
+		return document.NewNullValue()
+	}
+
+	return v
 }
 
 // Replace a document by key.
@@ -156,7 +237,35 @@ func (t *Table) Replace(key []byte, d document.Document) error {
 		return errors.New("cannot write to read-only table")
 	}
 
-	d, err = info.FieldConstraints.ValidateDocument(d)
+	triggers, err := t.triggers(UpdateChange)
+	if err != nil {
+		return err
+	}
+
+	tc := TriggerContext{Type: UpdateChange, Key: key, New: d}
+	if len(triggers) > 0 {
+		old, err := t.getDocument(key)
+		if err != nil {
+			return err
+		}
+
+		// the old document must remain valid for AFTER triggers,
+		// once it has been replaced in the store.
+		tc.Old, err = t.detachDocument(old)
+		if err != nil {
+			return err
+		}
+
+		err = t.fireTriggers(triggers, BeforeTrigger, &tc)
+		if err != nil {
+			return err
+		}
+		if tc.New == nil {
+			return errors.New("trigger removed the document to update")
+		}
+	}
+
+	d, err = info.FieldConstraints.ValidateDocument(tc.New)
 	if err != nil {
 		return err
 	}
@@ -166,24 +275,30 @@ func (t *Table) Replace(key []byte, d document.Document) error {
 		return err
 	}
 
-	return t.replace(indexes, key, d)
+	err = t.replace(indexes, key, d)
+	if err != nil {
+		return err
+	}
+
+	tc.New = d
+	return t.fireTriggers(triggers, AfterTrigger, &tc)
 }
 
 func (t *Table) replace(indexes map[string]Index, key []byte, d document.Document) error {
 	// make sure key exists
-	old, err := t.GetDocument(key)
+	old, err := t.getDocument(key)
+	if err != nil {
+		return err
+	}
+
+	err = t.recordChange(UpdateChange, key, old, d)
 	if err != nil {
 		return err
 	}
 
 	// remove key from indexes
 	for _, idx := range indexes {
-		v, err := idx.Opts.Path.GetValue(old)
-		if err != nil {
-			return err
-		}
-
-		err = idx.Delete(v, key)
+		err = idx.Delete(indexedValue(idx, old), key)
 		if err != nil {
 			return err
 		}
@@ -206,12 +321,7 @@ func (t *Table) replace(indexes map[string]Index, key []byte, d document.Documen
 
 	// update indexes
 	for _, idx := range indexes {
-		v, err := idx.Opts.Path.GetValue(d)
-		if err != nil {
-			continue
-		}
-
-		err = idx.Set(v, key)
+		err = idx.Set(indexedValue(idx, d), key)
 		if err != nil {
 			return err
 		}
@@ -220,7 +330,17 @@ func (t *Table) replace(indexes map[string]Index, key []byte, d document.Documen
 	return err
 }
 
+// Prefixes of the path of full-text and spatial indexes in the map returned by Indexes,
+// since they can't be used like regular indexes.
+const (
+	fullTextIndexPrefix = "fulltext:"
+	spatialIndexPrefix  = "spatial:"
+)
+
 // Indexes returns a map of all the indexes of a table.
+// Regular indexes are keyed by path, full-text and spatial indexes by
+// path prefixed with "fulltext:" or "spatial:" and multi-key indexes by
+// path followed by "[*]".
 func (t *Table) Indexes() (map[string]Index, error) {
 	s, err := t.tx.tx.GetStore([]byte(indexStoreName))
 	if err != nil {
@@ -251,15 +371,7 @@ func (t *Table) Indexes() (map[string]Index, error) {
 				return err
 			}
 
-			idx := index.New(t.tx.tx, opts.IndexName, index.Options{
-				Unique: opts.Unique,
-				Type:   opts.Type,
-			})
-
-			indexes[opts.Path.String()] = Index{
-				Index: idx,
-				Opts:  opts,
-			}
+			indexes[indexKey(opts)] = newIndex(t.tx.tx, opts)
 
 			return nil
 		})
@@ -270,6 +382,22 @@ func (t *Table) Indexes() (map[string]Index, error) {
 	return indexes, nil
 }
 
+// indexKey returns the key of an index in the map returned by Indexes.
+// A table can't have two indexes with the same key.
+func indexKey(opts IndexConfig) string {
+	key := opts.Path.String()
+	switch {
+	case opts.FullText:
+		key = fullTextIndexPrefix + key
+	case opts.Spatial:
+		key = spatialIndexPrefix + key
+	case opts.Multikey:
+		key += "[*]"
+	}
+
+	return key
+}
+
 type encodedDocumentWithKey struct {
 	document.Document
 
@@ -332,7 +460,25 @@ func (d *lazilyDecodedDocument) copyFromItem() error {
 
 // Iterate goes through all the documents of the table and calls the given function by passing each one of them.
 // If the given function returns an error, the iteration stops.
+// If the table has a TTL, expired documents are skipped.
 func (t *Table) Iterate(fn func(d document.Document) error) error {
+	if len(t.ttlPath) == 0 {
+		return t.iterateAll(fn)
+	}
+
+	now := time.Now()
+	return t.iterateAll(func(d document.Document) error {
+		expired, err := t.isExpired(d, now)
+		if err != nil || expired {
+			return err
+		}
+
+		return fn(d)
+	})
+}
+
+// iterateAll goes through all the documents of the table, including expired ones.
+func (t *Table) iterateAll(fn func(d document.Document) error) error {
 	// To avoid unnecessary allocations, we create the struct once and reuse
 	// it during each iteration.
 	d := lazilyDecodedDocument{
@@ -362,7 +508,26 @@ func (t *Table) Iterate(fn func(d document.Document) error) error {
 }
 
 // GetDocument returns one document by key.
+// If the table has a TTL and the document has expired, it returns ErrDocumentNotFound.
 func (t *Table) GetDocument(key []byte) (document.Document, error) {
+	d, err := t.getDocument(key)
+	if err != nil {
+		return nil, err
+	}
+
+	expired, err := t.isExpired(d, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if expired {
+		return nil, ErrDocumentNotFound
+	}
+
+	return d, nil
+}
+
+// getDocument returns one document by key, even if it has expired.
+func (t *Table) getDocument(key []byte) (document.Document, error) {
 	v, err := t.Store.Get(key)
 	if err != nil {
 		if err == engine.ErrKeyNotFound {
@@ -420,6 +585,28 @@ func (t *Table) generateKey(info *TableInfo, fb *document.FieldBuffer) ([]byte,
 	return buf[:n], nil
 }
 
+// Size returns the number of documents of the table, including the expired
+// documents which are not deleted yet, and the number of bytes used by their keys
+// and encoded values.
+func (t *Table) Size() (n int64, size int64, err error) {
+	it := t.Store.Iterator(engine.IteratorOptions{})
+	defer it.Close()
+
+	var buf []byte
+	for it.Seek(nil); it.Valid(); it.Next() {
+		item := it.Item()
+		buf, err = item.ValueCopy(buf[:0])
+		if err != nil {
+			return 0, 0, err
+		}
+
+		n++
+		size += int64(len(item.Key()) + len(buf))
+	}
+
+	return n, size, it.Err()
+}
+
 // ReIndex all the indexes of the table.
 func (t *Table) ReIndex() error {
 	info, err := t.Info()
@@ -756,6 +756,26 @@ func TestTableReIndex(t *testing.T) {
 	})
 }
 
+func TestTableSize(t *testing.T) {
+	tb, cleanup := newTestTable(t)
+	defer cleanup()
+
+	n, size, err := tb.Size()
+	require.NoError(t, err)
+	require.Zero(t, n)
+	require.Zero(t, size)
+
+	for i := 0; i < 3; i++ {
+		_, err = tb.Insert(newDocument())
+		require.NoError(t, err)
+	}
+
+	n, size, err = tb.Size()
+	require.NoError(t, err)
+	require.EqualValues(t, 3, n)
+	require.True(t, size > 0)
+}
+
 func TestTableIndexes(t *testing.T) {
 	t.Run("Should succeed if table has no indexes", func(t *testing.T) {
 		tb, cleanup := newTestTable(t)
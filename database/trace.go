@@ -0,0 +1,156 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// A TraceStage is a stage of the execution of a query.
+type TraceStage int
+
+// Stages of the execution of a query.
+// Each statement of a query is executed in turn. The bind and optimize
+// stages of a statement occur during its execute stage.
+const (
+	// ParseStage is the parsing of the query text.
+	ParseStage TraceStage = iota
+	// BindStage is the binding of a statement to the tables and indexes.
+	BindStage
+	// OptimizeStage is the optimization of the plan of a statement.
+	OptimizeStage
+	// ExecuteStage is the execution of a statement. The documents returned by
+	// a SELECT statement are read while its result is iterated, after this stage.
+	ExecuteStage
+	// CommitStage is the commit of the transaction opened by the query.
+	CommitStage
+)
+
+func (s TraceStage) String() string {
+	switch s {
+	case ParseStage:
+		return "parse"
+	case BindStage:
+		return "bind"
+	case OptimizeStage:
+		return "optimize"
+	case ExecuteStage:
+		return "execute"
+	case CommitStage:
+		return "commit"
+	}
+
+	return fmt.Sprintf("TraceStage(%d)", int(s))
+}
+
+// A Tracer is notified of the execution of the queries.
+// It must be safe for concurrent use.
+type Tracer interface {
+	// StartQuery is called before a query is parsed, with the text of the query
+	// and its arguments. The returned trace follows the execution of the query.
+	StartQuery(ctx context.Context, q string, args []interface{}) QueryTrace
+}
+
+// A QueryTrace follows the execution of a query.
+// It is used by a single goroutine at a time.
+type QueryTrace interface {
+	// StartStage is called at the beginning of a stage. The returned function is called
+	// at the end of the stage, with the error returned by the stage, if any.
+	StartStage(stage TraceStage) func(err error)
+	// End is called once the result of the query is closed or if the query fails,
+	// with the error returned to the caller, if any.
+	End(err error)
+}
+
+// StartQueryTrace starts the trace of a query using the tracer of the database.
+// If the database has no tracer, it returns a trace which does nothing.
+func (db *Database) StartQueryTrace(ctx context.Context, q string, args []interface{}) QueryTrace {
+	if db.tracer == nil {
+		return noopTrace{}
+	}
+
+	return db.tracer.StartQuery(ctx, q, args)
+}
+
+type noopTrace struct{}
+
+func (noopTrace) StartStage(stage TraceStage) func(err error) { return func(err error) {} }
+func (noopTrace) End(err error)                               {}
+
+// Trace returns the trace of the query run by the transaction.
+// If there is none, it returns a trace which does nothing.
+func (tx *Transaction) Trace() QueryTrace {
+	if tx.trace == nil {
+		return noopTrace{}
+	}
+
+	return tx.trace
+}
+
+// SetTrace sets the trace of the query run by the transaction
+// and returns the previous one, which can be nil.
+func (tx *Transaction) SetTrace(t QueryTrace) QueryTrace {
+	prev := tx.trace
+	tx.trace = t
+	return prev
+}
+
+// SlowQueryLogger is a tracer which writes the queries that take
+// longer than a threshold to a writer, one per line.
+// The duration of a query goes from its parsing to the closing of its result.
+type SlowQueryLogger struct {
+	w         io.Writer
+	threshold time.Duration
+	mu        sync.Mutex
+}
+
+// NewSlowQueryLogger creates a tracer which writes the queries that take
+// longer than threshold to w.
+func NewSlowQueryLogger(w io.Writer, threshold time.Duration) *SlowQueryLogger {
+	return &SlowQueryLogger{
+		w:         w,
+		threshold: threshold,
+	}
+}
+
+// StartQuery implements the Tracer interface.
+func (l *SlowQueryLogger) StartQuery(ctx context.Context, q string, args []interface{}) QueryTrace {
+	return &slowQueryTrace{
+		l:     l,
+		q:     q,
+		args:  args,
+		start: time.Now(),
+	}
+}
+
+type slowQueryTrace struct {
+	l     *SlowQueryLogger
+	q     string
+	args  []interface{}
+	start time.Time
+}
+
+func (t *slowQueryTrace) StartStage(stage TraceStage) func(err error) {
+	return func(err error) {}
+}
+
+func (t *slowQueryTrace) End(err error) {
+	d := time.Since(t.start)
+	if d < t.l.threshold {
+		return
+	}
+
+	line := fmt.Sprintf("%s duration=%s query=%q", t.start.Format(time.RFC3339), d, t.q)
+	if len(t.args) > 0 {
+		line += fmt.Sprintf(" args=%v", t.args)
+	}
+	if err != nil {
+		line += fmt.Sprintf(" error=%q", err.Error())
+	}
+
+	t.l.mu.Lock()
+	defer t.l.mu.Unlock()
+	fmt.Fprintln(t.l.w, line)
+}
@@ -4,16 +4,17 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/engine"
-	"github.com/genjidb/genji/index"
 )
 
 var (
 	internalPrefix     = "__genji_"
 	tableInfoStoreName = internalPrefix + "tables"
 	indexStoreName     = internalPrefix + "indexes"
+	triggerStoreName   = internalPrefix + "triggers"
 )
 
 // Transaction represents a database transaction. It provides methods for managing the
@@ -24,11 +25,39 @@ type Transaction struct {
 	db       *Database
 	tx       engine.Transaction
 	writable bool
-	// if set to true, this transaction is attached to the database
-	attached bool
+	// if not nil, this transaction is attached to the session
+	session *Session
 
 	tableInfoStore *tableInfoStore
 	indexStore     *indexStore
+	triggerStore   *triggerStore
+
+	// changes recorded for subscribers,
+	// published after a successful commit.
+	changes []ChangeEvent
+
+	// number of nested trigger calls.
+	triggerDepth int
+
+	// writes recorded to roll back to savepoints.
+	journal    *journal
+	savepoints []savepoint
+
+	// true if the transaction prevents ShipLog from starting.
+	shipLocked bool
+
+	// true once the transaction is committed or rolled back.
+	done bool
+
+	// trace of the query run by the transaction, if any.
+	trace QueryTrace
+
+	// version of the schema seen by the transaction,
+	// valid if schemaKnown is true.
+	schemaVersion uint64
+	schemaKnown   bool
+	// true if the transaction changed tables or indexes.
+	schemaChanged bool
 }
 
 // DB returns the underlying database that created the transaction.
@@ -38,18 +67,21 @@ func (tx *Transaction) DB() *Database {
 
 // Rollback the transaction. Can be used safely after commit.
 func (tx *Transaction) Rollback() error {
+	tx.changes = nil
+	tx.releaseShipLock()
+
+	if !tx.done {
+		tx.done = true
+		tx.db.stats.txRolledBack(tx.writable)
+	}
+
 	err := tx.tx.Rollback()
 	if err != nil {
 		return err
 	}
 
-	if tx.attached {
-		tx.db.attachedTxMu.Lock()
-		defer tx.db.attachedTxMu.Unlock()
-
-		if tx.db.attachedTransaction != nil {
-			tx.db.attachedTransaction = nil
-		}
+	if tx.session != nil {
+		tx.session.detach(tx)
 	}
 
 	return nil
@@ -57,24 +89,62 @@ func (tx *Transaction) Rollback() error {
 
 // Commit the transaction.
 func (tx *Transaction) Commit() error {
+	defer tx.releaseShipLock()
+
+	ship := tx.journal != nil && len(tx.journal.ops) > 0
+	if len(tx.changes) > 0 || ship {
+		tx.db.publishMu.Lock()
+		defer tx.db.publishMu.Unlock()
+	}
+
+	if tx.schemaChanged {
+		atomic.AddUint64(&tx.db.schemaPending, 1)
+		defer atomic.AddUint64(&tx.db.schemaVersion, 1)
+	}
+
 	err := tx.tx.Commit()
 	if err != nil {
 		return err
 	}
 
-	if tx.attached {
-		tx.db.attachedTxMu.Lock()
-		defer tx.db.attachedTxMu.Unlock()
+	tx.done = true
+	tx.db.stats.txCommitted(tx.writable)
 
-		if tx.db.attachedTransaction != nil {
-			tx.db.attachedTransaction = nil
-		}
+	if len(tx.changes) > 0 {
+		tx.db.publish(tx.changes)
+		tx.changes = nil
+	}
+
+	if ship {
+		tx.db.ship(tx.journal.ops)
+		tx.journal.ops = nil
+	}
+
+	if tx.session != nil {
+		tx.session.detach(tx)
 	}
 
 	return nil
 
 }
 
+// releaseShipLock lets ShipLog start, if the transaction prevented it.
+func (tx *Transaction) releaseShipLock() {
+	if tx.shipLocked {
+		tx.shipLocked = false
+		tx.db.shipMu.RUnlock()
+	}
+}
+
+// SchemaVersion returns the version of the tables and indexes of the database
+// seen by the transaction. The version changes every time a transaction that
+// creates, alters or drops tables or indexes is committed.
+// It returns false if the version is unknown, because the transaction changed
+// the schema itself or began while another transaction was committing a change.
+func (tx *Transaction) SchemaVersion() (uint64, bool) {
+	return tx.schemaVersion, tx.schemaKnown && !tx.schemaChanged
+}
+
 // Writable indicates if the transaction is writable or not.
 func (tx *Transaction) Writable() bool {
 	return tx.writable
@@ -83,6 +153,8 @@ func (tx *Transaction) Writable() bool {
 // CreateTable creates a table with the given name.
 // If it already exists, returns ErrTableAlreadyExists.
 func (tx *Transaction) CreateTable(name string, info *TableInfo) error {
+	tx.schemaChanged = true
+
 	if strings.HasPrefix(name, internalPrefix) {
 		return fmt.Errorf("table name must not start with %s", internalPrefix)
 	}
@@ -91,6 +163,13 @@ func (tx *Transaction) CreateTable(name string, info *TableInfo) error {
 		info = new(TableInfo)
 	}
 
+	if len(info.TTLPath) > 0 {
+		err := validateTTLPath(info)
+		if err != nil {
+			return err
+		}
+	}
+
 	info.tableName = name
 	err := tx.tableInfoStore.Insert(tx, name, info)
 	if err != nil {
@@ -102,6 +181,19 @@ func (tx *Transaction) CreateTable(name string, info *TableInfo) error {
 		return fmt.Errorf("failed to create table %q: %w", name, err)
 	}
 
+	// index the expiration time to find expired documents quickly.
+	// the index is untyped, to accept documents without expiration time.
+	if len(info.TTLPath) > 0 {
+		err = tx.indexStore.Insert(IndexConfig{
+			IndexName: internalPrefix + "ttl_" + name,
+			TableName: name,
+			Path:      info.TTLPath,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -122,11 +214,14 @@ func (tx *Transaction) GetTable(name string) (*Table, error) {
 		Store:     s,
 		name:      name,
 		infoStore: tx.tableInfoStore,
+		ttlPath:   ti.TTLPath,
 	}, nil
 }
 
 // AddField adds a field constraint to a table.
 func (tx *Transaction) AddField(tableName string, fc FieldConstraint) error {
+	tx.schemaChanged = true
+
 	info, err := tx.tableInfoStore.Get(tx, tableName)
 	if err != nil {
 		return err
@@ -152,6 +247,8 @@ func (tx *Transaction) AddField(tableName string, fc FieldConstraint) error {
 // RenameTable renames a table.
 // If it doesn't exist, it returns ErrTableNotFound.
 func (tx *Transaction) RenameTable(oldName, newName string) error {
+	tx.schemaChanged = true
+
 	ti, err := tx.tableInfoStore.Get(tx, oldName)
 	if err != nil {
 		return err
@@ -183,12 +280,29 @@ func (tx *Transaction) RenameTable(oldName, newName string) error {
 		}
 	}
 
+	// Update the triggers.
+	triggers, err := tx.ListTriggers()
+	if err != nil {
+		return err
+	}
+	for _, tr := range triggers {
+		if tr.TableName == oldName {
+			tr.TableName = newName
+			err = tx.triggerStore.put(*tr)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	// Delete the old reference from the tableInfoStore.
 	return tx.tableInfoStore.Delete(tx, oldName)
 }
 
 // DropTable deletes a table from the database.
 func (tx *Transaction) DropTable(name string) error {
+	tx.schemaChanged = true
+
 	ti, err := tx.tableInfoStore.Get(tx, name)
 	if err != nil {
 		return err
@@ -229,6 +343,22 @@ func (tx *Transaction) DropTable(name string) error {
 		return err
 	}
 
+	// Remove the triggers associated with the table.
+	triggers, err := tx.ListTriggers()
+	if err != nil {
+		return err
+	}
+	for _, tr := range triggers {
+		if tr.TableName != name {
+			continue
+		}
+
+		err = tx.DropTrigger(tr.TriggerName)
+		if err != nil {
+			return err
+		}
+	}
+
 	err = tx.tableInfoStore.Delete(tx, name)
 	if err != nil {
 		return err
@@ -240,6 +370,8 @@ func (tx *Transaction) DropTable(name string) error {
 // CreateIndex creates an index with the given name.
 // If it already exists, returns ErrIndexAlreadyExists.
 func (tx *Transaction) CreateIndex(opts IndexConfig) error {
+	tx.schemaChanged = true
+
 	t, err := tx.GetTable(opts.TableName)
 	if err != nil {
 		return err
@@ -250,14 +382,57 @@ func (tx *Transaction) CreateIndex(opts IndexConfig) error {
 		return err
 	}
 
+	if opts.FullText && opts.Unique {
+		return errors.New("full-text indexes cannot be unique")
+	}
+	if opts.FullText && opts.Multikey {
+		return errors.New("full-text indexes cannot be multi-key indexes")
+	}
+	if opts.Spatial && opts.Unique {
+		return errors.New("spatial indexes cannot be unique")
+	}
+	if opts.Spatial && opts.Multikey {
+		return errors.New("spatial indexes cannot be multi-key indexes")
+	}
+
+	// the planner and the TTL reaper look up indexes by path: a second index
+	// on the same path, like the TTL index, would never be used nor maintained.
+	indexes, err := t.Indexes()
+	if err != nil {
+		return err
+	}
+	if other, ok := indexes[indexKey(opts)]; ok && other.Opts.IndexName != opts.IndexName {
+		return fmt.Errorf("cannot create index %s: path %s is already indexed by %s", opts.IndexName, opts.Path, other.Opts.IndexName)
+	}
+
 	// if the index is created on a field on which we know the type,
 	// create a typed index.
 	for _, fc := range info.FieldConstraints {
 		if fc.Path.IsEqual(opts.Path) {
-			if fc.Type != 0 {
-				opts.Type = fc.Type
+			if fc.Type == 0 {
+				break
+			}
+
+			// the elements of the arrays indexed by multi-key indexes can be of any type.
+			if opts.Multikey {
+				break
+			}
+
+			if opts.FullText {
+				if fc.Type != document.TextValue {
+					return fmt.Errorf("cannot create a full-text index on field %s of type %s", opts.Path, fc.Type)
+				}
+				break
+			}
+
+			if opts.Spatial {
+				if fc.Type != document.DocumentValue {
+					return fmt.Errorf("cannot create a spatial index on field %s of type %s", opts.Path, fc.Type)
+				}
+				break
 			}
 
+			opts.Type = fc.Type
 			break
 		}
 	}
@@ -272,19 +447,14 @@ func (tx *Transaction) GetIndex(name string) (*Index, error) {
 		return nil, err
 	}
 
-	idx := index.New(tx.tx, opts.IndexName, index.Options{
-		Unique: opts.Unique,
-		Type:   opts.Type,
-	})
-
-	return &Index{
-		Index: idx,
-		Opts:  *opts,
-	}, nil
+	idx := newIndex(tx.tx, *opts)
+	return &idx, nil
 }
 
 // DropIndex deletes an index from the database.
 func (tx *Transaction) DropIndex(name string) error {
+	tx.schemaChanged = true
+
 	opts, err := tx.indexStore.Get(name)
 	if err != nil {
 		return err
@@ -294,12 +464,7 @@ func (tx *Transaction) DropIndex(name string) error {
 		return err
 	}
 
-	idx := index.New(tx.tx, opts.IndexName, index.Options{
-		Unique: opts.Unique,
-		Type:   opts.Type,
-	})
-
-	return idx.Truncate()
+	return newIndex(tx.tx, *opts).Truncate()
 }
 
 // ListIndexes lists all indexes.
@@ -324,7 +489,8 @@ func (tx *Transaction) ReIndex(indexName string) error {
 		return err
 	}
 
-	return tb.Iterate(func(d document.Document) error {
+	// expired documents are indexed as well, until they are deleted.
+	return tb.iterateAll(func(d document.Document) error {
 		v, err := idx.Opts.Path.GetValue(d)
 		if err == document.ErrFieldNotFound {
 			return nil
@@ -391,3 +557,14 @@ func (tx *Transaction) getIndexStore() (*indexStore, error) {
 		db: tx.db,
 	}, nil
 }
+
+func (tx *Transaction) getTriggerStore() (*triggerStore, error) {
+	st, err := tx.tx.GetStore([]byte(triggerStoreName))
+	if err != nil {
+		return nil, err
+	}
+	return &triggerStore{
+		st: st,
+		db: tx.db,
+	}, nil
+}
@@ -246,6 +246,27 @@ func TestTxCreateIndex(t *testing.T) {
 		require.Equal(t, database.ErrIndexAlreadyExists, err)
 	})
 
+	t.Run("Should fail if the path is already indexed", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", nil)
+		require.NoError(t, err)
+
+		err = tx.CreateIndex(database.IndexConfig{
+			IndexName: "idxFoo", TableName: "test", Path: parsePath(t, "foo"),
+		})
+		require.NoError(t, err)
+
+		err = tx.CreateIndex(database.IndexConfig{
+			IndexName: "idxBar", TableName: "test", Path: parsePath(t, "foo"), Unique: true,
+		})
+		require.Error(t, err)
+
+		_, err = tx.GetIndex("idxBar")
+		require.Equal(t, database.ErrIndexNotFound, err)
+	})
+
 	t.Run("Should fail if table doesn't exists", func(t *testing.T) {
 		tx, cleanup := newTestDB(t)
 		defer cleanup()
@@ -473,3 +494,58 @@ func TestReIndexAll(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestTxSchemaVersion(t *testing.T) {
+	db, err := database.New(context.Background(), memoryengine.NewEngine(), database.Options{
+		Codec: msgpack.NewCodec(),
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	version := func() (uint64, bool) {
+		tx, err := db.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		return tx.SchemaVersion()
+	}
+
+	v1, ok := version()
+	require.True(t, ok)
+
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	err = tx.CreateTable("test", nil)
+	require.NoError(t, err)
+	// the version is unknown within a transaction changing the schema.
+	_, ok = tx.SchemaVersion()
+	require.False(t, ok)
+	require.NoError(t, tx.Commit())
+
+	v2, ok := version()
+	require.True(t, ok)
+	require.NotEqual(t, v1, v2)
+
+	// writing documents doesn't change the version.
+	tx, err = db.Begin(true)
+	require.NoError(t, err)
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+	_, err = tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(1)))
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	v3, ok := version()
+	require.True(t, ok)
+	require.Equal(t, v2, v3)
+
+	// rolled back changes don't change the version.
+	tx, err = db.Begin(true)
+	require.NoError(t, err)
+	err = tx.CreateIndex(database.IndexConfig{IndexName: "idx_a", TableName: "test", Path: document.Path{document.PathFragment{FieldName: "a"}}})
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+
+	v4, ok := version()
+	require.True(t, ok)
+	require.Equal(t, v2, v4)
+}
@@ -0,0 +1,434 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+)
+
+// maxTriggerDepth is the maximum number of nested trigger calls,
+// used to detect triggers that fire each other endlessly.
+const maxTriggerDepth = 32
+
+// TriggerTiming determines if a trigger is fired before or after the write.
+type TriggerTiming int
+
+const (
+	// BeforeTrigger triggers are fired before the document is stored.
+	// They can veto the write by returning an error or rewrite the document.
+	BeforeTrigger TriggerTiming = iota + 1
+	// AfterTrigger triggers are fired after the document is stored.
+	AfterTrigger
+)
+
+func (t TriggerTiming) String() string {
+	switch t {
+	case BeforeTrigger:
+		return "BEFORE"
+	case AfterTrigger:
+		return "AFTER"
+	}
+
+	return ""
+}
+
+// A TriggerContext describes the write that fired a trigger.
+type TriggerContext struct {
+	// Tx is the transaction in which the write happens.
+	Tx        *Transaction
+	TableName string
+	Timing    TriggerTiming
+	Type      ChangeType
+	// Key of the document. It is nil when inserting a document before it's stored.
+	Key []byte
+	// Old is the document before the change. It is nil for insertions.
+	Old document.Document
+	// New is the document after the change. It is nil for deletions.
+	// BEFORE INSERT and BEFORE UPDATE triggers can replace it to rewrite
+	// the document that will be stored.
+	New document.Document
+}
+
+// A TriggerFunc is called every time a trigger is fired.
+// Returning an error aborts the write.
+type TriggerFunc func(tc *TriggerContext) error
+
+// TriggerConfig holds the configuration of a trigger created with
+// the CREATE TRIGGER statement.
+type TriggerConfig struct {
+	TriggerName string
+	TableName   string
+	Timing      TriggerTiming
+	Event       ChangeType
+	// SQL statement run every time the trigger is fired.
+	Statement string
+}
+
+// ToDocument creates a document from a TriggerConfig.
+func (t *TriggerConfig) ToDocument() document.Document {
+	buf := document.NewFieldBuffer()
+
+	buf.Add("trigger_name", document.NewTextValue(t.TriggerName))
+	buf.Add("table_name", document.NewTextValue(t.TableName))
+	buf.Add("timing", document.NewTextValue(t.Timing.String()))
+	buf.Add("event", document.NewTextValue(strings.ToUpper(t.Event.String())))
+	buf.Add("statement", document.NewTextValue(t.Statement))
+	return buf
+}
+
+// ScanDocument implements the document.Scanner interface.
+func (t *TriggerConfig) ScanDocument(d document.Document) error {
+	v, err := d.GetByField("trigger_name")
+	if err != nil {
+		return err
+	}
+	t.TriggerName = v.V.(string)
+
+	v, err = d.GetByField("table_name")
+	if err != nil {
+		return err
+	}
+	t.TableName = v.V.(string)
+
+	v, err = d.GetByField("timing")
+	if err != nil {
+		return err
+	}
+	switch v.V.(string) {
+	case "BEFORE":
+		t.Timing = BeforeTrigger
+	case "AFTER":
+		t.Timing = AfterTrigger
+	default:
+		return fmt.Errorf("unknown trigger timing %q", v.V)
+	}
+
+	v, err = d.GetByField("event")
+	if err != nil {
+		return err
+	}
+	switch v.V.(string) {
+	case "INSERT":
+		t.Event = InsertChange
+	case "UPDATE":
+		t.Event = UpdateChange
+	case "DELETE":
+		t.Event = DeleteChange
+	default:
+		return fmt.Errorf("unknown trigger event %q", v.V)
+	}
+
+	v, err = d.GetByField("statement")
+	if err != nil {
+		return err
+	}
+	t.Statement = v.V.(string)
+
+	return nil
+}
+
+type triggerStore struct {
+	db *Database
+	st engine.Store
+
+	// triggers returned by ListAll, cached until the store is modified
+	// by the transaction, since they are needed on every write.
+	cache  []*storedTrigger
+	cached bool
+}
+
+// a storedTrigger is a SQL trigger cached by the store.
+type storedTrigger struct {
+	cfg *TriggerConfig
+	// function running the parsed statement, set when the trigger is first fired.
+	fn TriggerFunc
+}
+
+// prepare returns the function running the statement of the trigger,
+// parsing the statement the first time.
+func (st *storedTrigger) prepare(db *Database) (TriggerFunc, error) {
+	if st.fn != nil {
+		return st.fn, nil
+	}
+
+	if db.prepareTrigger == nil {
+		return nil, errors.New("SQL triggers are not supported by this database")
+	}
+
+	fn, err := db.prepareTrigger(st.cfg.Statement)
+	if err != nil {
+		return nil, fmt.Errorf("trigger %q: %w", st.cfg.TriggerName, err)
+	}
+
+	st.fn = fn
+	return fn, nil
+}
+
+// invalidate clears the cache of the triggers.
+func (t *triggerStore) invalidate() {
+	t.cache = nil
+	t.cached = false
+}
+
+func (t *triggerStore) Insert(cfg TriggerConfig) error {
+	key := []byte(cfg.TriggerName)
+	_, err := t.st.Get(key)
+	if err == nil {
+		return ErrTriggerAlreadyExists
+	}
+	if err != engine.ErrKeyNotFound {
+		return err
+	}
+
+	return t.put(cfg)
+}
+
+func (t *triggerStore) Get(triggerName string) (*TriggerConfig, error) {
+	v, err := t.st.Get([]byte(triggerName))
+	if err == engine.ErrKeyNotFound {
+		return nil, ErrTriggerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg TriggerConfig
+	err = cfg.ScanDocument(t.db.Codec.NewDocument(v))
+	if err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+func (t *triggerStore) put(cfg TriggerConfig) error {
+	var buf bytes.Buffer
+	enc := t.db.Codec.NewEncoder(&buf)
+	defer enc.Close()
+	err := enc.EncodeDocument(cfg.ToDocument())
+	if err != nil {
+		return err
+	}
+
+	t.invalidate()
+	return t.st.Put([]byte(cfg.TriggerName), buf.Bytes())
+}
+
+func (t *triggerStore) Delete(triggerName string) error {
+	t.invalidate()
+	err := t.st.Delete([]byte(triggerName))
+	if err == engine.ErrKeyNotFound {
+		return ErrTriggerNotFound
+	}
+	return err
+}
+
+// ListAll returns all the triggers of the store.
+// The returned configs must not be modified.
+func (t *triggerStore) ListAll() ([]*storedTrigger, error) {
+	if t.cached {
+		return t.cache, nil
+	}
+
+	it := t.st.Iterator(engine.IteratorOptions{})
+	defer it.Close()
+
+	var list []*storedTrigger
+	var buf []byte
+	var err error
+	for it.Seek(nil); it.Valid(); it.Next() {
+		buf, err = it.Item().ValueCopy(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		var cfg TriggerConfig
+		err = cfg.ScanDocument(t.db.Codec.NewDocument(buf))
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, &storedTrigger{cfg: &cfg})
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	t.cache, t.cached = list, true
+	return list, nil
+}
+
+// CreateTrigger creates a SQL trigger on a table.
+// If a trigger with the same name already exists, returns ErrTriggerAlreadyExists.
+func (tx *Transaction) CreateTrigger(cfg TriggerConfig) error {
+	if cfg.Statement == "" {
+		return errors.New("missing trigger statement")
+	}
+
+	_, err := tx.tableInfoStore.Get(tx, cfg.TableName)
+	if err != nil {
+		return err
+	}
+
+	return tx.triggerStore.Insert(cfg)
+}
+
+// DropTrigger deletes a SQL trigger from the database.
+func (tx *Transaction) DropTrigger(name string) error {
+	return tx.triggerStore.Delete(name)
+}
+
+// ListTriggers lists all the SQL triggers.
+func (tx *Transaction) ListTriggers() ([]*TriggerConfig, error) {
+	stored, err := tx.triggerStore.ListAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// the configs are cached by the store: return copies.
+	list := make([]*TriggerConfig, len(stored))
+	for i, st := range stored {
+		c := *st.cfg
+		list[i] = &c
+	}
+
+	return list, nil
+}
+
+type registeredTrigger struct {
+	tableName string
+	timing    TriggerTiming
+	event     ChangeType
+	fn        TriggerFunc
+}
+
+// RegisterTrigger registers a Go function that is called every time a document of the
+// given table is written by the given event. Unlike SQL triggers, Go triggers are not
+// stored and must be registered every time the database is opened.
+// BEFORE triggers can veto the write by returning an error, or rewrite
+// the document by replacing the New field of the trigger context.
+func (db *Database) RegisterTrigger(name, tableName string, timing TriggerTiming, event ChangeType, fn TriggerFunc) error {
+	if name == "" {
+		return errors.New("missing trigger name")
+	}
+	if fn == nil {
+		return errors.New("missing trigger function")
+	}
+
+	db.triggersMu.Lock()
+	defer db.triggersMu.Unlock()
+
+	if _, ok := db.triggers[name]; ok {
+		return ErrTriggerAlreadyExists
+	}
+
+	if db.triggers == nil {
+		db.triggers = make(map[string]registeredTrigger)
+	}
+
+	db.triggers[name] = registeredTrigger{
+		tableName: tableName,
+		timing:    timing,
+		event:     event,
+		fn:        fn,
+	}
+
+	return nil
+}
+
+// UnregisterTrigger removes a trigger registered with RegisterTrigger.
+func (db *Database) UnregisterTrigger(name string) error {
+	db.triggersMu.Lock()
+	defer db.triggersMu.Unlock()
+
+	if _, ok := db.triggers[name]; !ok {
+		return ErrTriggerNotFound
+	}
+
+	delete(db.triggers, name)
+	return nil
+}
+
+type trigger struct {
+	name   string
+	timing TriggerTiming
+	fn     TriggerFunc
+}
+
+// triggers returns the list of Go and SQL triggers fired by the given event
+// on the table, sorted by name.
+func (t *Table) triggers(event ChangeType) ([]trigger, error) {
+	var triggers []trigger
+
+	t.tx.db.triggersMu.RLock()
+	for name, rt := range t.tx.db.triggers {
+		if rt.tableName == t.name && rt.event == event {
+			triggers = append(triggers, trigger{name: name, timing: rt.timing, fn: rt.fn})
+		}
+	}
+	t.tx.db.triggersMu.RUnlock()
+
+	stored, err := t.tx.triggerStore.ListAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, st := range stored {
+		if st.cfg.TableName != t.name || st.cfg.Event != event {
+			continue
+		}
+
+		fn, err := st.prepare(t.tx.db)
+		if err != nil {
+			return nil, err
+		}
+
+		triggers = append(triggers, trigger{
+			name:   st.cfg.TriggerName,
+			timing: st.cfg.Timing,
+			fn:     fn,
+		})
+	}
+
+	sort.Slice(triggers, func(i, j int) bool {
+		return triggers[i].name < triggers[j].name
+	})
+
+	return triggers, nil
+}
+
+// fireTriggers calls every trigger with the given timing.
+func (t *Table) fireTriggers(triggers []trigger, timing TriggerTiming, tc *TriggerContext) error {
+	if len(triggers) == 0 {
+		return nil
+	}
+
+	if t.tx.triggerDepth >= maxTriggerDepth {
+		return errors.New("too many nested trigger calls")
+	}
+
+	t.tx.triggerDepth++
+	defer func() { t.tx.triggerDepth-- }()
+
+	tc.Tx = t.tx
+	tc.TableName = t.name
+	tc.Timing = timing
+
+	for _, tr := range triggers {
+		if tr.timing != timing {
+			continue
+		}
+
+		err := tr.fn(tc)
+		if err != nil {
+			return fmt.Errorf("trigger %q: %w", tr.name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,370 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+func countDocuments(t *testing.T, db *genji.DB, table string) int64 {
+	t.Helper()
+
+	d, err := db.QueryDocument("SELECT COUNT(*) AS n FROM " + table)
+	require.NoError(t, err)
+	v, err := d.GetByField("n")
+	require.NoError(t, err)
+	return v.V.(int64)
+}
+
+func TestSQLTriggers(t *testing.T) {
+	t.Run("Audit", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(`
+			CREATE TABLE test(a INTEGER PRIMARY KEY); CREATE TABLE audit;
+			CREATE TRIGGER on_insert AFTER INSERT ON test INSERT INTO audit (op, a) VALUES ('insert', $new.a);
+			CREATE TRIGGER on_update AFTER UPDATE ON test FOR EACH ROW INSERT INTO audit (op, old, new) VALUES ('update', $old.b, $new.b);
+			CREATE TRIGGER on_delete AFTER DELETE ON test INSERT INTO audit (op, a) VALUES ('delete', $old.a);
+		`)
+		require.NoError(t, err)
+
+		err = db.Exec("INSERT INTO test (a, b) VALUES (1, 'foo'), (2, 'foo')")
+		require.NoError(t, err)
+		err = db.Exec("UPDATE test SET b = 'bar' WHERE a = 1")
+		require.NoError(t, err)
+		err = db.Exec("DELETE FROM test WHERE a = 2")
+		require.NoError(t, err)
+
+		res, err := db.Query("SELECT op, a, old, new FROM audit")
+		require.NoError(t, err)
+		defer res.Close()
+
+		var rows []string
+		err = res.Iterate(func(d document.Document) error {
+			data, err := document.MarshalJSON(d)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, string(data))
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			`{"op": "insert", "a": 1, "old": null, "new": null}`,
+			`{"op": "insert", "a": 2, "old": null, "new": null}`,
+			`{"op": "update", "a": null, "old": "foo", "new": "bar"}`,
+			`{"op": "delete", "a": 2, "old": null, "new": null}`,
+		}, rows)
+	})
+
+	t.Run("Counter", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(`
+			CREATE TABLE test; CREATE TABLE counters;
+			INSERT INTO counters (name, n) VALUES ('test', 0);
+			CREATE TRIGGER incr AFTER INSERT ON test UPDATE counters SET n = n + 1 WHERE name = 'test';
+			CREATE TRIGGER decr AFTER DELETE ON test UPDATE counters SET n = n - 1 WHERE name = 'test';
+		`)
+		require.NoError(t, err)
+
+		err = db.Exec("INSERT INTO test (a) VALUES (1), (2), (3)")
+		require.NoError(t, err)
+		err = db.Exec("DELETE FROM test WHERE a = 2")
+		require.NoError(t, err)
+
+		d, err := db.QueryDocument("SELECT n FROM counters")
+		require.NoError(t, err)
+		v, err := d.GetByField("n")
+		require.NoError(t, err)
+		require.Equal(t, document.NewDoubleValue(2), v)
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(`
+			CREATE TABLE test; CREATE TABLE audit;
+			CREATE TRIGGER on_insert AFTER INSERT ON test INSERT INTO audit (a) VALUES ($new.a);
+		`)
+		require.NoError(t, err)
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		err = tx.Exec("INSERT INTO test (a) VALUES (1)")
+		require.NoError(t, err)
+		require.NoError(t, tx.Rollback())
+
+		require.EqualValues(t, 0, countDocuments(t, db, "audit"))
+	})
+
+	t.Run("Failing trigger aborts the write", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(`
+			CREATE TABLE test; CREATE TABLE audit(a INTEGER NOT NULL);
+			CREATE TRIGGER on_insert BEFORE INSERT ON test INSERT INTO audit (a) VALUES ($new.a);
+		`)
+		require.NoError(t, err)
+
+		err = db.Exec("INSERT INTO test (b) VALUES (1)")
+		require.Error(t, err)
+		require.EqualValues(t, 0, countDocuments(t, db, "test"))
+	})
+
+	t.Run("Recursion", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(`
+			CREATE TABLE test;
+			CREATE TRIGGER loop AFTER INSERT ON test INSERT INTO test (a) VALUES ($new.a + 1);
+		`)
+		require.NoError(t, err)
+
+		err = db.Exec("INSERT INTO test (a) VALUES (1)")
+		require.Error(t, err)
+		require.EqualValues(t, 0, countDocuments(t, db, "test"))
+	})
+
+	t.Run("Drop and rename table", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(`
+			CREATE TABLE test; CREATE TABLE other; CREATE TABLE audit;
+			CREATE TRIGGER tr1 AFTER INSERT ON test INSERT INTO audit (a) VALUES ($new.a);
+			CREATE TRIGGER tr2 AFTER INSERT ON other INSERT INTO audit (a) VALUES ($new.a);
+			ALTER TABLE test RENAME TO test2;
+			DROP TABLE other;
+		`)
+		require.NoError(t, err)
+
+		err = db.View(func(tx *genji.Tx) error {
+			triggers, err := tx.ListTriggers()
+			require.NoError(t, err)
+			require.Len(t, triggers, 1)
+			require.Equal(t, "tr1", triggers[0].TriggerName)
+			require.Equal(t, "test2", triggers[0].TableName)
+			return nil
+		})
+		require.NoError(t, err)
+
+		err = db.Exec("INSERT INTO test2 (a) VALUES (1)")
+		require.NoError(t, err)
+		require.EqualValues(t, 1, countDocuments(t, db, "audit"))
+	})
+
+	t.Run("Changes within a transaction", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec("CREATE TABLE test; CREATE TABLE audit")
+		require.NoError(t, err)
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		count := func() int64 {
+			d, err := tx.QueryDocument("SELECT COUNT(*) AS n FROM audit")
+			require.NoError(t, err)
+			v, err := d.GetByField("n")
+			require.NoError(t, err)
+			return v.V.(int64)
+		}
+
+		// the triggers of the transaction are cached by the first write.
+		err = tx.Exec("INSERT INTO test (a) VALUES (1)")
+		require.NoError(t, err)
+		require.EqualValues(t, 0, count())
+
+		err = tx.Exec("CREATE TRIGGER tr AFTER INSERT ON test INSERT INTO audit (a) VALUES ($new.a)")
+		require.NoError(t, err)
+		err = tx.Exec("INSERT INTO test (a) VALUES (2)")
+		require.NoError(t, err)
+		require.EqualValues(t, 1, count())
+
+		err = tx.Savepoint("sp")
+		require.NoError(t, err)
+		err = tx.Exec("DROP TRIGGER tr")
+		require.NoError(t, err)
+		err = tx.Exec("INSERT INTO test (a) VALUES (3)")
+		require.NoError(t, err)
+		require.EqualValues(t, 1, count())
+
+		// rolling back to the savepoint restores the trigger.
+		err = tx.RollbackToSavepoint("sp")
+		require.NoError(t, err)
+		err = tx.Exec("INSERT INTO test (a) VALUES (4)")
+		require.NoError(t, err)
+		require.EqualValues(t, 2, count())
+	})
+
+	t.Run("Statements are parsed once per transaction", func(t *testing.T) {
+		var prepared int
+		db, err := genji.New(context.Background(), memoryengine.NewEngine(), func(opts *database.Options) {
+			prepare := opts.PrepareTrigger
+			opts.PrepareTrigger = func(stmt string) (database.TriggerFunc, error) {
+				prepared++
+				return prepare(stmt)
+			}
+		})
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(`
+			CREATE TABLE test; CREATE TABLE audit;
+			CREATE TRIGGER tr AFTER INSERT ON test INSERT INTO audit (a) VALUES ($new.a);
+		`)
+		require.NoError(t, err)
+
+		err = db.Exec("INSERT INTO test (a) VALUES (1), (2), (3)")
+		require.NoError(t, err)
+		require.Equal(t, 1, prepared)
+		require.EqualValues(t, 3, countDocuments(t, db, "audit"))
+
+		// the cache is cleared when the triggers change.
+		err = db.Exec(`
+			INSERT INTO test (a) VALUES (4);
+			CREATE TRIGGER tr2 AFTER INSERT ON test INSERT INTO audit (b) VALUES ($new.a);
+			INSERT INTO test (a) VALUES (5), (6);
+		`)
+		require.NoError(t, err)
+		require.Equal(t, 4, prepared)
+		require.EqualValues(t, 8, countDocuments(t, db, "audit"))
+	})
+}
+
+func TestRegisterTrigger(t *testing.T) {
+	t.Run("Veto", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec("CREATE TABLE test")
+		require.NoError(t, err)
+
+		errVeto := errors.New("negative values are not allowed")
+		err = db.RegisterTrigger("veto", "test", database.BeforeTrigger, database.InsertChange, func(tc *database.TriggerContext) error {
+			v, err := tc.New.GetByField("a")
+			if err != nil {
+				return err
+			}
+			if v.V.(float64) < 0 {
+				return errVeto
+			}
+			return nil
+		})
+		require.NoError(t, err)
+
+		err = db.Exec("INSERT INTO test (a) VALUES (1.0)")
+		require.NoError(t, err)
+		err = db.Exec("INSERT INTO test (a) VALUES (-1.0)")
+		require.True(t, errors.Is(err, errVeto))
+		require.EqualValues(t, 1, countDocuments(t, db, "test"))
+
+		require.NoError(t, db.UnregisterTrigger("veto"))
+		require.Equal(t, database.ErrTriggerNotFound, db.UnregisterTrigger("veto"))
+
+		err = db.Exec("INSERT INTO test (a) VALUES (-1.0)")
+		require.NoError(t, err)
+	})
+
+	t.Run("Rewrite", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec("CREATE TABLE test(a INTEGER PRIMARY KEY)")
+		require.NoError(t, err)
+
+		rewrite := func(tc *database.TriggerContext) error {
+			var fb document.FieldBuffer
+			err := fb.Copy(tc.New)
+			if err != nil {
+				return err
+			}
+			err = fb.Set(document.Path{document.PathFragment{FieldName: "rev"}}, document.NewIntegerValue(int64(tc.Type)))
+			if err != nil {
+				return err
+			}
+			tc.New = &fb
+			return nil
+		}
+
+		err = db.RegisterTrigger("on_insert", "test", database.BeforeTrigger, database.InsertChange, rewrite)
+		require.NoError(t, err)
+		err = db.RegisterTrigger("on_update", "test", database.BeforeTrigger, database.UpdateChange, rewrite)
+		require.NoError(t, err)
+		require.Equal(t, database.ErrTriggerAlreadyExists, db.RegisterTrigger("on_update", "test", database.BeforeTrigger, database.UpdateChange, rewrite))
+
+		err = db.Exec("INSERT INTO test (a) VALUES (1)")
+		require.NoError(t, err)
+
+		d, err := db.QueryDocument("SELECT rev FROM test")
+		require.NoError(t, err)
+		v, err := d.GetByField("rev")
+		require.NoError(t, err)
+		require.Equal(t, document.NewDoubleValue(float64(database.InsertChange)), v)
+
+		err = db.Exec("UPDATE test SET b = 1")
+		require.NoError(t, err)
+
+		d, err = db.QueryDocument("SELECT rev FROM test")
+		require.NoError(t, err)
+		v, err = d.GetByField("rev")
+		require.NoError(t, err)
+		require.Equal(t, document.NewDoubleValue(float64(database.UpdateChange)), v)
+	})
+
+	t.Run("After", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec("CREATE TABLE test; INSERT INTO test (a) VALUES (1)")
+		require.NoError(t, err)
+
+		var fired []database.TriggerContext
+		record := func(tc *database.TriggerContext) error {
+			fired = append(fired, *tc)
+			return nil
+		}
+
+		err = db.RegisterTrigger("b", "test", database.AfterTrigger, database.DeleteChange, record)
+		require.NoError(t, err)
+		err = db.RegisterTrigger("a", "test", database.BeforeTrigger, database.DeleteChange, record)
+		require.NoError(t, err)
+
+		err = db.Exec("DELETE FROM test")
+		require.NoError(t, err)
+
+		require.Len(t, fired, 2)
+		require.Equal(t, database.BeforeTrigger, fired[0].Timing)
+		require.Equal(t, database.AfterTrigger, fired[1].Timing)
+		require.Equal(t, "test", fired[1].TableName)
+		require.NotNil(t, fired[1].Key)
+		require.Nil(t, fired[1].New)
+		v, err := fired[1].Old.GetByField("a")
+		require.NoError(t, err)
+		require.Equal(t, document.NewDoubleValue(1), v)
+	})
+}
@@ -0,0 +1,326 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+)
+
+const (
+	// DefaultTTLReapInterval is the default interval between two
+	// deletions of expired documents.
+	DefaultTTLReapInterval = time.Minute
+	// DefaultTTLBatchSize is the default maximum number of expired documents
+	// deleted by a single transaction.
+	DefaultTTLBatchSize = 100
+)
+
+var errStopReaping = errors.New("stop")
+
+// ttlExpiry returns the expiration time stored in v.
+// Integers and doubles are read as unix timestamps in seconds and
+// texts are parsed as RFC 3339 dates. Any other value never expires.
+func ttlExpiry(v document.Value) (time.Time, bool) {
+	switch v.Type {
+	case document.IntegerValue:
+		return time.Unix(v.V.(int64), 0), true
+	case document.DoubleValue:
+		f := v.V.(float64)
+		sec := math.Floor(f)
+		return time.Unix(int64(sec), int64((f-sec)*1e9)), true
+	case document.TextValue:
+		t, err := time.Parse(time.RFC3339Nano, v.V.(string))
+		if err != nil {
+			return time.Time{}, false
+		}
+		return t, true
+	}
+
+	return time.Time{}, false
+}
+
+// validateTTLPath makes sure the field constraint associated with
+// the TTL path, if any, has a type that can hold an expiration time.
+func validateTTLPath(info *TableInfo) error {
+	for _, fc := range info.FieldConstraints {
+		if !fc.Path.IsEqual(info.TTLPath) {
+			continue
+		}
+
+		switch fc.Type {
+		case 0, document.IntegerValue, document.DoubleValue, document.TextValue:
+			return nil
+		}
+
+		return fmt.Errorf("TTL field %q must be an integer, a double or a text, got %s", info.TTLPath, fc.Type)
+	}
+
+	return nil
+}
+
+// isExpired returns whether d has expired at the given time.
+// Documents of tables without TTL never expire.
+func (t *Table) isExpired(d document.Document, now time.Time) (bool, error) {
+	if len(t.ttlPath) == 0 {
+		return false, nil
+	}
+
+	v, err := t.ttlPath.GetValue(d)
+	if err == document.ErrFieldNotFound || err == document.ErrValueNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	exp, ok := ttlExpiry(v)
+	if !ok {
+		return false, nil
+	}
+
+	return !exp.After(now), nil
+}
+
+// VisitIndexedDocument calls fn with the document associated with a key read from
+// an index of the table. Indexes reference the expired documents until the reaper
+// deletes them: these documents are skipped, as if they were already deleted.
+func (t *Table) VisitIndexedDocument(key []byte, fn func(d document.Document) error) error {
+	d, err := t.getDocument(key)
+	if err != nil {
+		return err
+	}
+
+	expired, err := t.isExpired(d, time.Now())
+	if err != nil || expired {
+		return err
+	}
+
+	return fn(d)
+}
+
+// expiredKeys returns the keys of at most limit documents that have expired at the given time.
+// It uses the index on the TTL path, if any, and otherwise scans the whole table.
+func (t *Table) expiredKeys(now time.Time, limit int) ([][]byte, error) {
+	var keys [][]byte
+
+	indexes, err := t.Indexes()
+	if err != nil {
+		return nil, err
+	}
+
+	idx, ok := indexes[t.ttlPath.String()]
+	if !ok {
+		err = t.iterateAll(func(d document.Document) error {
+			expired, err := t.isExpired(d, now)
+			if err != nil || !expired {
+				return err
+			}
+
+			keys = append(keys, append([]byte(nil), d.(document.Keyer).Key()...))
+			if len(keys) >= limit {
+				return errStopReaping
+			}
+			return nil
+		})
+		if err != nil && err != errStopReaping {
+			return nil, err
+		}
+
+		return keys, nil
+	}
+
+	// numbers are sorted, the scan can stop at the first value
+	// that is greater than the current time.
+	thresholds := []document.Value{
+		document.NewIntegerValue(now.Unix()),
+		document.NewDoubleValue(float64(now.UnixNano()) / 1e9),
+	}
+	for _, threshold := range thresholds {
+		if idx.Type != 0 && idx.Type != threshold.Type {
+			continue
+		}
+
+		enc, err := idx.EncodeValue(threshold)
+		if err != nil {
+			return nil, err
+		}
+
+		pivot := document.Value{Type: threshold.Type}
+		if idx.Type != 0 {
+			pivot = document.Value{}
+		}
+
+		err = idx.AscendGreaterOrEqual(pivot, func(val, key []byte, isEqual bool) error {
+			if len(keys) >= limit || bytes.Compare(val, enc) > 0 {
+				return errStopReaping
+			}
+
+			keys = append(keys, append([]byte(nil), key...))
+			return nil
+		})
+		if err != nil && err != errStopReaping {
+			return nil, err
+		}
+	}
+
+	if idx.Type != 0 && idx.Type != document.TextValue {
+		return keys, nil
+	}
+
+	// dates stored as text are not sorted by time
+	// and must all be checked.
+	pivot := document.Value{Type: document.TextValue}
+	if idx.Type != 0 {
+		pivot = document.Value{}
+	}
+
+	err = idx.AscendGreaterOrEqual(pivot, func(val, key []byte, isEqual bool) error {
+		if len(keys) >= limit {
+			return errStopReaping
+		}
+
+		d, err := t.getDocument(key)
+		if err != nil {
+			return err
+		}
+
+		expired, err := t.isExpired(d, now)
+		if err != nil || !expired {
+			return err
+		}
+
+		keys = append(keys, append([]byte(nil), key...))
+		return nil
+	})
+	if err != nil && err != errStopReaping {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// ttlTables returns the names of the tables with a TTL.
+func (tx *Transaction) ttlTables() ([]string, error) {
+	it := tx.tableInfoStore.st.Iterator(engine.IteratorOptions{})
+	defer it.Close()
+
+	var tables []string
+	var buf []byte
+	var err error
+	for it.Seek(nil); it.Valid(); it.Next() {
+		buf, err = it.Item().ValueCopy(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		var ti TableInfo
+		err = ti.ScanDocument(tx.db.Codec.NewDocument(buf))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(ti.TTLPath) > 0 {
+			tables = append(tables, ti.tableName)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	return tables, nil
+}
+
+// ReapExpired deletes all the expired documents of the tables with a TTL and returns
+// the number of deleted documents.
+// Documents are deleted in batches, each batch in its own transaction.
+// This is called periodically by the database, but can be called
+// manually to force the deletion of expired documents.
+func (db *Database) ReapExpired(ctx context.Context) (int, error) {
+	tx, err := db.BeginTx(ctx, &TxOptions{ReadOnly: true})
+	if err != nil {
+		return 0, err
+	}
+	tables, err := tx.ttlTables()
+	tx.Rollback()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int
+	for _, tableName := range tables {
+		for {
+			n, err := db.reapBatch(ctx, tableName)
+			total += n
+			if err != nil {
+				return total, err
+			}
+
+			if n < db.ttlBatchSize {
+				break
+			}
+		}
+	}
+
+	return total, nil
+}
+
+// reapBatch deletes a batch of expired documents from the table.
+func (db *Database) reapBatch(ctx context.Context, tableName string) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	t, err := tx.GetTable(tableName)
+	if errors.Is(err, ErrTableNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	keys, err := t.expiredKeys(time.Now(), db.ttlBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	for _, key := range keys {
+		err = t.Delete(key)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(keys), tx.Commit()
+}
+
+// runReaper periodically deletes expired documents until
+// the database is closed.
+func (db *Database) runReaper(interval time.Duration) {
+	defer close(db.reaperDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-db.reaperStop:
+			return
+		case <-ticker.C:
+			// errors are ignored, expired documents
+			// will be deleted during the next run.
+			_, _ = db.ReapExpired(context.Background())
+		}
+	}
+}
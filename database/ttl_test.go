@@ -0,0 +1,202 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTL(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		create  string
+		expired interface{}
+		alive   interface{}
+	}{
+		{"Untyped number", "CREATE TABLE test WITH TTL ON exp", past.Unix(), future.Unix()},
+		{"Integer", "CREATE TABLE test(exp INTEGER) WITH TTL ON exp", past.Unix(), future.Unix()},
+		{"Double", "CREATE TABLE test(exp DOUBLE) WITH TTL ON exp", float64(past.UnixNano()) / 1e9, float64(future.UnixNano()) / 1e9},
+		{"Text", "CREATE TABLE test(exp TEXT) WITH TTL ON exp", past.Format(time.RFC3339), future.Format(time.RFC3339)},
+		{"Untyped text", "CREATE TABLE test WITH TTL ON exp", past.Format(time.RFC3339), future.Format(time.RFC3339)},
+		{"Nested path", "CREATE TABLE test WITH TTL ON a.exp", past.Unix(), future.Unix()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(test.create)
+			require.NoError(t, err)
+
+			insert := "INSERT INTO test (id, exp, a) VALUES (?, ?, {exp: ?})"
+			for i := 0; i < 5; i++ {
+				err = db.Exec(insert, i, test.expired, test.expired)
+				require.NoError(t, err)
+			}
+			err = db.Exec(insert, 10, test.alive, test.alive)
+			require.NoError(t, err)
+			err = db.Exec("INSERT INTO test (id) VALUES (11)")
+			require.NoError(t, err)
+
+			// expired documents are hidden from reads.
+			require.EqualValues(t, 2, countDocuments(t, db, "test"))
+			_, err = db.QueryDocument("SELECT * FROM test WHERE exp = ?", test.expired)
+			require.Equal(t, database.ErrDocumentNotFound, err)
+
+			n, err := db.DB.ReapExpired(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 5, n)
+
+			// expired documents are deleted from the store.
+			err = db.View(func(tx *genji.Tx) error {
+				tb, err := tx.GetTable("test")
+				require.NoError(t, err)
+
+				var count int
+				it := tb.Store.Iterator(engine.IteratorOptions{})
+				defer it.Close()
+				for it.Seek(nil); it.Valid(); it.Next() {
+					count++
+				}
+				require.Equal(t, 2, count)
+				return nil
+			})
+			require.NoError(t, err)
+
+			n, err = db.DB.ReapExpired(context.Background())
+			require.NoError(t, err)
+			require.Zero(t, n)
+		})
+	}
+
+	t.Run("Insert over expired document", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec("CREATE TABLE test(id INTEGER PRIMARY KEY) WITH TTL ON exp")
+		require.NoError(t, err)
+
+		err = db.Exec("INSERT INTO test (id, exp) VALUES (1, ?)", past.Unix())
+		require.NoError(t, err)
+		err = db.Exec("INSERT INTO test (id, exp) VALUES (1, ?)", future.Unix())
+		require.NoError(t, err)
+		err = db.Exec("INSERT INTO test (id, exp) VALUES (1, ?)", future.Unix())
+		require.Equal(t, database.ErrDuplicateDocument, err)
+	})
+
+	t.Run("Document without the TTL field", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec("CREATE TABLE test WITH TTL ON exp; INSERT INTO test (a) VALUES (1)")
+		require.NoError(t, err)
+
+		err = db.Exec("UPDATE test SET a = 2")
+		require.NoError(t, err)
+		d, err := db.QueryDocument("SELECT a FROM test")
+		require.NoError(t, err)
+		var a int
+		require.NoError(t, document.Scan(d, &a))
+		require.Equal(t, 2, a)
+
+		err = db.Exec("DELETE FROM test")
+		require.NoError(t, err)
+		require.EqualValues(t, 0, countDocuments(t, db, "test"))
+	})
+
+	t.Run("Invalid type", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec("CREATE TABLE test(exp BOOL) WITH TTL ON exp")
+		require.Error(t, err)
+	})
+
+	t.Run("Index on the TTL path", func(t *testing.T) {
+		for _, create := range []string{
+			"CREATE UNIQUE INDEX idx ON test (exp)",
+			"CREATE INDEX idx ON test (exp)",
+		} {
+			t.Run(create, func(t *testing.T) {
+				db, err := genji.Open(":memory:")
+				require.NoError(t, err)
+				defer db.Close()
+
+				err = db.Exec("CREATE TABLE test WITH TTL ON exp")
+				require.NoError(t, err)
+
+				// the path is already indexed by the TTL index, which would
+				// hide the new index from the planner and from writes.
+				err = db.Exec(create)
+				require.Error(t, err)
+
+				err = db.Exec("INSERT INTO test (exp) VALUES (?), (?)", future.Unix(), future.Unix())
+				require.NoError(t, err)
+
+				d, err := db.QueryDocument("EXPLAIN SELECT * FROM test WHERE exp = ?", future.Unix())
+				require.NoError(t, err)
+				v, err := d.GetByField("plan")
+				require.NoError(t, err)
+				require.Equal(t, "Index(__genji_ttl_test) -> ∏(*)", v.V.(string))
+				require.EqualValues(t, 2, countDocuments(t, db, "test"))
+			})
+		}
+
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		// other paths can be indexed.
+		err = db.Exec("CREATE TABLE test WITH TTL ON exp; CREATE UNIQUE INDEX idx ON test (a)")
+		require.NoError(t, err)
+		err = db.Exec("INSERT INTO test (a, exp) VALUES (1, ?)", future.Unix())
+		require.NoError(t, err)
+		err = db.Exec("INSERT INTO test (a, exp) VALUES (1, ?)", future.Unix())
+		require.Equal(t, database.ErrDuplicateDocument, err)
+	})
+
+	t.Run("Background reaper", func(t *testing.T) {
+		ddb, err := database.New(context.Background(), memoryengine.NewEngine(), database.Options{
+			Codec:           msgpack.NewCodec(),
+			TTLReapInterval: 10 * time.Millisecond,
+			TTLBatchSize:    2,
+		})
+		require.NoError(t, err)
+		db := (&genji.DB{DB: ddb}).WithContext(context.Background())
+		defer db.Close()
+
+		err = db.Exec("CREATE TABLE test WITH TTL ON exp")
+		require.NoError(t, err)
+
+		sub := db.Subscribe("test", func(ev database.ChangeEvent) bool {
+			return ev.Type == database.DeleteChange
+		})
+		defer sub.Close()
+
+		for i := 0; i < 5; i++ {
+			err = db.Exec("INSERT INTO test (exp) VALUES (?)", past.Unix())
+			require.NoError(t, err)
+		}
+
+		for i := 0; i < 5; i++ {
+			ev := nextEvent(t, sub)
+			require.Equal(t, database.DeleteChange, ev.Type)
+		}
+	})
+}
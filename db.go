@@ -71,6 +71,27 @@ func (db *DB) Update(fn func(tx *Tx) error) error {
 	return tx.Commit()
 }
 
+// Stats returns statistics about the transactions and the queries
+// run since the database was opened.
+func (db *DB) Stats() database.Stats {
+	return db.DB.Stats()
+}
+
+// BulkLoad returns a loader which inserts documents in the given table in batches,
+// without parsing any query. The loader must be closed to commit the last batch.
+// If opts is nil, it uses the default options.
+func (db *DB) BulkLoad(table string, opts *database.BulkLoadOptions) (*database.BulkLoader, error) {
+	return db.DB.BulkLoad(db.ctx, table, opts)
+}
+
+// Subscribe returns a subscription that receives the changes made to the given table
+// once they have been committed. If table is empty, changes made to any table are received.
+// If filter is not nil, only the events for which it returns true are delivered.
+// The subscription must be closed after usage.
+func (db *DB) Subscribe(table string, filter func(ev database.ChangeEvent) bool) *database.Subscription {
+	return db.DB.Subscribe(table, filter)
+}
+
 // Exec a query against the database without returning the result.
 func (db *DB) Exec(q string, args ...interface{}) error {
 	res, err := db.Query(q, args...)
@@ -84,7 +105,7 @@ func (db *DB) Exec(q string, args ...interface{}) error {
 // Query the database and return the result.
 // The returned result must always be closed after usage.
 func (db *DB) Query(q string, args ...interface{}) (*query.Result, error) {
-	pq, err := parser.ParseQuery(q)
+	pq, err := parseQuery(db.DB.StartQueryTrace(db.ctx, q, args), q)
 	if err != nil {
 		return nil, err
 	}
@@ -92,6 +113,21 @@ func (db *DB) Query(q string, args ...interface{}) (*query.Result, error) {
 	return pq.Run(db.ctx, db.DB, argsToParams(args))
 }
 
+// parseQuery parses q during the parse stage of its trace.
+// If the parsing fails, the trace is ended.
+func parseQuery(trace database.QueryTrace, q string) (query.Query, error) {
+	end := trace.StartStage(database.ParseStage)
+	pq, err := parser.ParseQuery(q)
+	end(err)
+	if err != nil {
+		trace.End(err)
+		return pq, err
+	}
+
+	pq.Trace = trace
+	return pq, nil
+}
+
 // QueryDocument runs the query and returns the first document.
 // If the query returns no error, QueryDocument returns database.ErrDocumentNotFound.
 func (db *DB) QueryDocument(q string, args ...interface{}) (document.Document, error) {
@@ -101,6 +137,11 @@ func (db *DB) QueryDocument(q string, args ...interface{}) (document.Document, e
 	}
 	defer res.Close()
 
+	return firstDocument(res)
+}
+
+// firstDocument returns a copy of the first document of the result.
+func firstDocument(res *query.Result) (document.Document, error) {
 	r, err := res.First()
 	if err != nil {
 		return nil, err
@@ -130,7 +171,7 @@ type Tx struct {
 // Query the database withing the transaction and returns the result.
 // Closing the returned result after usage is not mandatory.
 func (tx *Tx) Query(q string, args ...interface{}) (*query.Result, error) {
-	pq, err := parser.ParseQuery(q)
+	pq, err := parseQuery(tx.DB().StartQueryTrace(context.Background(), q, args), q)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,173 @@
+// Package compressed implements a codec that compresses the documents
+// encoded by another codec, using Snappy.
+//
+// Documents are prefixed by a byte indicating whether they are compressed or not.
+// Small documents, or those that don't benefit from compression, are stored as is
+// and keep the random-access decoding of the underlying codec. Compressed documents
+// are decompressed once, the first time one of their fields is accessed.
+// Data encoded by the underlying codec alone cannot be read by this codec, and vice versa.
+package compressed
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding"
+	"github.com/golang/snappy"
+)
+
+// DefaultMinSize is the default size, in bytes, under which encoded documents
+// are not compressed.
+const DefaultMinSize = 128
+
+const (
+	flagRaw byte = iota
+	flagSnappy
+)
+
+// A Codec wraps another codec and compresses the documents it encodes.
+type Codec struct {
+	codec encoding.Codec
+
+	// MinSize is the size, in bytes, under which encoded documents
+	// are not compressed.
+	MinSize int
+}
+
+// NewCodec creates a codec that compresses the documents encoded by c.
+func NewCodec(c encoding.Codec) *Codec {
+	return &Codec{
+		codec:   c,
+		MinSize: DefaultMinSize,
+	}
+}
+
+// NewEncoder implements the encoding.Codec interface.
+func (c *Codec) NewEncoder(w io.Writer) encoding.Encoder {
+	e := Encoder{
+		w:       w,
+		minSize: c.MinSize,
+	}
+	e.enc = c.codec.NewEncoder(&e.buf)
+	return &e
+}
+
+// NewDocument implements the encoding.Codec interface.
+// Documents that are not compressed are returned by the underlying codec directly.
+func (c *Codec) NewDocument(data []byte) document.Document {
+	if len(data) > 0 && data[0] == flagRaw {
+		return c.codec.NewDocument(data[1:])
+	}
+
+	return &EncodedDocument{
+		codec: c.codec,
+		data:  data,
+	}
+}
+
+// Encoder encodes documents with the underlying codec
+// and compresses them.
+type Encoder struct {
+	w       io.Writer
+	enc     encoding.Encoder
+	buf     bytes.Buffer
+	cbuf    []byte
+	minSize int
+}
+
+// EncodeDocument encodes d and compresses it if it is large enough
+// and if compression reduces its size.
+func (e *Encoder) EncodeDocument(d document.Document) error {
+	e.buf.Reset()
+	e.buf.WriteByte(flagRaw)
+
+	err := e.enc.EncodeDocument(d)
+	if err != nil {
+		return err
+	}
+
+	raw := e.buf.Bytes()
+	if len(raw)-1 < e.minSize {
+		_, err = e.w.Write(raw)
+		return err
+	}
+
+	n := snappy.MaxEncodedLen(len(raw) - 1)
+	if cap(e.cbuf) < n+1 {
+		e.cbuf = make([]byte, n+1)
+	}
+	e.cbuf = e.cbuf[:n+1]
+	e.cbuf[0] = flagSnappy
+	compressed := snappy.Encode(e.cbuf[1:], raw[1:])
+
+	if len(compressed) >= len(raw)-1 {
+		_, err = e.w.Write(raw)
+		return err
+	}
+
+	_, err = e.w.Write(e.cbuf[:len(compressed)+1])
+	return err
+}
+
+// Close the underlying encoder.
+func (e *Encoder) Close() {
+	e.enc.Close()
+}
+
+// An EncodedDocument is a compressed document.
+// It is decompressed and decoded by the underlying codec
+// the first time it is accessed.
+type EncodedDocument struct {
+	codec encoding.Codec
+	data  []byte
+
+	d   document.Document
+	err error
+}
+
+func (e *EncodedDocument) decode() (document.Document, error) {
+	if e.d != nil || e.err != nil {
+		return e.d, e.err
+	}
+
+	if len(e.data) == 0 || e.data[0] != flagSnappy {
+		e.err = errors.New("cannot decode data: unknown compression")
+		return nil, e.err
+	}
+
+	data, err := snappy.Decode(nil, e.data[1:])
+	if err != nil {
+		e.err = err
+		return nil, err
+	}
+
+	e.d = e.codec.NewDocument(data)
+	return e.d, nil
+}
+
+// GetByField decompresses the document, if needed, and returns the selected field.
+func (e *EncodedDocument) GetByField(field string) (document.Value, error) {
+	d, err := e.decode()
+	if err != nil {
+		return document.Value{}, err
+	}
+
+	return d.GetByField(field)
+}
+
+// Iterate decompresses the document, if needed, and iterates over its fields.
+func (e *EncodedDocument) Iterate(fn func(field string, value document.Value) error) error {
+	d, err := e.decode()
+	if err != nil {
+		return err
+	}
+
+	return d.Iterate(fn)
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (e *EncodedDocument) MarshalJSON() ([]byte, error) {
+	return document.MarshalJSON(e)
+}
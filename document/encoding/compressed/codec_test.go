@@ -0,0 +1,138 @@
+package compressed_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding"
+	"github.com/genjidb/genji/document/encoding/compressed"
+	"github.com/genjidb/genji/document/encoding/custom"
+	"github.com/genjidb/genji/document/encoding/encodingtest"
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodec(t *testing.T) {
+	inner := map[string]func() encoding.Codec{
+		"MessagePack": func() encoding.Codec { return msgpack.NewCodec() },
+		"Custom":      func() encoding.Codec { return custom.NewCodec() },
+	}
+
+	for name, codec := range inner {
+		t.Run(name, func(t *testing.T) {
+			encodingtest.TestCodec(t, func() encoding.Codec {
+				return compressed.NewCodec(codec())
+			})
+		})
+	}
+}
+
+func largeDocument() *document.FieldBuffer {
+	return document.NewFieldBuffer().
+		Add("a", document.NewIntegerValue(10)).
+		Add("b", document.NewTextValue(strings.Repeat("hello world ", 100))).
+		Add("c", document.NewDocumentValue(document.NewFieldBuffer().
+			Add("d", document.NewTextValue(strings.Repeat("foo bar ", 100)))))
+}
+
+func encode(t *testing.T, codec encoding.Codec, d document.Document) []byte {
+	var buf bytes.Buffer
+	enc := codec.NewEncoder(&buf)
+	defer enc.Close()
+
+	require.NoError(t, enc.EncodeDocument(d))
+	return buf.Bytes()
+}
+
+func TestCompression(t *testing.T) {
+	codec := compressed.NewCodec(msgpack.NewCodec())
+
+	t.Run("Large documents are compressed", func(t *testing.T) {
+		d := largeDocument()
+		raw := encode(t, msgpack.NewCodec(), d)
+		data := encode(t, codec, d)
+		require.Less(t, len(data), len(raw)/5)
+
+		doc := codec.NewDocument(data)
+		require.IsType(t, &compressed.EncodedDocument{}, doc)
+
+		v, err := doc.GetByField("b")
+		require.NoError(t, err)
+		require.Equal(t, document.NewTextValue(strings.Repeat("hello world ", 100)), v)
+
+		_, err = doc.GetByField("z")
+		require.Equal(t, document.ErrFieldNotFound, err)
+
+		expected, err := document.MarshalJSON(d)
+		require.NoError(t, err)
+		actual, err := document.MarshalJSON(doc)
+		require.NoError(t, err)
+		require.JSONEq(t, string(expected), string(actual))
+
+		// compressed documents can be encoded again.
+		require.Equal(t, len(data), len(encode(t, codec, doc)))
+	})
+
+	t.Run("Small documents are not compressed", func(t *testing.T) {
+		d := document.NewFieldBuffer().Add("a", document.NewIntegerValue(10))
+		data := encode(t, codec, d)
+
+		doc := codec.NewDocument(data)
+		require.IsType(t, msgpack.EncodedDocument{}, doc)
+
+		v, err := doc.GetByField("a")
+		require.NoError(t, err)
+		require.Equal(t, document.NewIntegerValue(10), v)
+	})
+
+	t.Run("MinSize", func(t *testing.T) {
+		codec := compressed.NewCodec(msgpack.NewCodec())
+		codec.MinSize = 1 << 20
+
+		doc := codec.NewDocument(encode(t, codec, largeDocument()))
+		require.IsType(t, msgpack.EncodedDocument{}, doc)
+	})
+
+	t.Run("Corrupted data", func(t *testing.T) {
+		data := encode(t, codec, largeDocument())
+
+		doc := codec.NewDocument(data[:len(data)/2])
+		_, err := doc.GetByField("a")
+		require.Error(t, err)
+		require.Error(t, doc.Iterate(func(string, document.Value) error { return nil }))
+	})
+}
+
+func TestDatabase(t *testing.T) {
+	db, err := genji.New(context.Background(), memoryengine.NewEngine(), genji.WithCodec(compressed.NewCodec(msgpack.NewCodec())))
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec("CREATE TABLE test; CREATE INDEX idx_a ON test (a)")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		err = db.Exec("INSERT INTO test (a, b) VALUES (?, ?)", i, strings.Repeat("hello world ", 100))
+		require.NoError(t, err)
+	}
+
+	err = db.Exec("UPDATE test SET c = 'foo' WHERE a < 5")
+	require.NoError(t, err)
+
+	d, err := db.QueryDocument("SELECT COUNT(*) FROM test WHERE c = 'foo'")
+	require.NoError(t, err)
+	v, err := d.GetByField("COUNT(*)")
+	require.NoError(t, err)
+	require.Equal(t, document.NewIntegerValue(5), v)
+
+	d, err = db.QueryDocument("SELECT b FROM test WHERE a = 7")
+	require.NoError(t, err)
+	v, err = d.GetByField("b")
+	require.NoError(t, err)
+	require.Equal(t, document.NewTextValue(strings.Repeat("hello world ", 100)), v)
+}
@@ -1,6 +1,8 @@
 package badgerengine_test
 
 import (
+	"bytes"
+	"context"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -9,6 +11,7 @@ import (
 	"github.com/dgraph-io/badger/v2"
 	"github.com/genjidb/genji/engine"
 	"github.com/genjidb/genji/engine/badgerengine"
+	"github.com/genjidb/genji/engine/encryptedengine"
 	"github.com/genjidb/genji/engine/enginetest"
 	"github.com/stretchr/testify/require"
 )
@@ -29,6 +32,21 @@ func TestBadgerEngine(t *testing.T) {
 	enginetest.TestSuite(t, builder(t))
 }
 
+func TestEncryptedBadgerEngine(t *testing.T) {
+	enginetest.TestSuite(t, func() (engine.Engine, func()) {
+		ng, cleanup := builder(t)()
+		eng, err := encryptedengine.NewEngine(context.Background(), ng, encryptedengine.Options{
+			Keys:  map[uint32][]byte{1: bytes.Repeat([]byte{1}, 32)},
+			KeyID: 1,
+		})
+		require.NoError(t, err)
+		return eng, func() {
+			eng.Close()
+			cleanup()
+		}
+	})
+}
+
 func BenchmarkBadgerEngineStorePut(b *testing.B) {
 	enginetest.BenchmarkStorePut(b, builder(b))
 }
@@ -3,6 +3,7 @@ package badgerengine
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
 
 	"github.com/dgraph-io/badger/v2"
@@ -159,6 +160,48 @@ func (s *Store) NextSequence() (uint64, error) {
 	return nb + 1, nil
 }
 
+// SetSequence sets the sequence of the store if seq is greater than the current one.
+func (s *Store) SetSequence(seq uint64) error {
+	select {
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	default:
+	}
+
+	if !s.writable {
+		return engine.ErrTransactionReadOnly
+	}
+
+	// like NextSequence, the sequence is updated outside of the transaction.
+	// Badger stores the next number of the sequence, which is
+	// the last one returned by NextSequence since they are shifted by one.
+	return s.ng.DB.Update(func(txn *badger.Txn) error {
+		var cur uint64
+		item, err := txn.Get(s.name)
+		switch err {
+		case nil:
+			err = item.Value(func(v []byte) error {
+				cur = binary.BigEndian.Uint64(v)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		case badger.ErrKeyNotFound:
+		default:
+			return err
+		}
+
+		if seq <= cur {
+			return nil
+		}
+
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], seq)
+		return txn.Set(s.name, buf[:])
+	})
+}
+
 // Iterator uses a Badger iterator with default options.
 // Only one iterator is allowed per read-write transaction.
 func (s *Store) Iterator(opts engine.IteratorOptions) engine.Iterator {
@@ -103,6 +103,25 @@ func (s *Store) NextSequence() (uint64, error) {
 	return s.bucket.NextSequence()
 }
 
+// SetSequence sets the sequence of the store if seq is greater than the current one.
+func (s *Store) SetSequence(seq uint64) error {
+	select {
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	default:
+	}
+
+	if !s.bucket.Writable() {
+		return engine.ErrTransactionReadOnly
+	}
+
+	if seq <= s.bucket.Sequence() {
+		return nil
+	}
+
+	return s.bucket.SetSequence(seq)
+}
+
 // Iterator uses the Bolt bucket cursor.
 func (s *Store) Iterator(opts engine.IteratorOptions) engine.Iterator {
 	return &iterator{
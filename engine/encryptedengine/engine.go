@@ -0,0 +1,476 @@
+// Package encryptedengine implements an engine that encrypts the values
+// stored by another engine, using AES-GCM.
+//
+// Values are encrypted with the active key and tagged with its id, which allows
+// to rotate keys: values encrypted with a previous key are still readable,
+// as long as the key is provided, until Rotate encrypts them with the active key.
+// Keys of the values and names of the stores are not encrypted,
+// as the database relies on their order.
+package encryptedengine
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/genjidb/genji/engine"
+)
+
+// headerStoreName is the name of the store holding the id of the key used to encrypt
+// the whole database, a value used to verify the key and the list of stores.
+const headerStoreName = "__encryptedengine_header"
+
+var (
+	headerKeyID     = []byte("k")
+	headerCheck     = []byte("c")
+	headerStoreList = []byte("s")
+	checkValue      = []byte("genji")
+)
+
+var (
+	// ErrInvalidKey is returned when a value cannot be decrypted with the provided keys.
+	ErrInvalidKey = errors.New("invalid encryption key")
+
+	// ErrKeyNotFound is returned when a value is encrypted with a key that wasn't provided.
+	ErrKeyNotFound = errors.New("encryption key not found")
+)
+
+// Options of the engine.
+type Options struct {
+	// Keys used to encrypt and decrypt values, by id.
+	// Keys must be 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+	Keys map[uint32][]byte
+	// KeyID is the id of the key used to encrypt new values.
+	KeyID uint32
+}
+
+// Engine wraps an engine and encrypts the values it stores.
+type Engine struct {
+	ng    engine.Engine
+	keyID uint32
+	aeads map[uint32]cipher.AEAD
+}
+
+// NewEngine creates an engine that encrypts the values stored in ng.
+// If ng already contains encrypted data, the keys are verified and ErrInvalidKey
+// is returned if they don't match.
+func NewEngine(ctx context.Context, ng engine.Engine, opts Options) (*Engine, error) {
+	if _, ok := opts.Keys[opts.KeyID]; !ok {
+		return nil, fmt.Errorf("%w: %d", ErrKeyNotFound, opts.KeyID)
+	}
+
+	e := Engine{
+		ng:    ng,
+		keyID: opts.KeyID,
+		aeads: make(map[uint32]cipher.AEAD, len(opts.Keys)),
+	}
+
+	for id, key := range opts.Keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+		e.aeads[id], err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := e.init(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// init creates the header store if it doesn't exist, otherwise it verifies the keys.
+func (ng *Engine) init(ctx context.Context) error {
+	tx, err := ng.ng.Begin(ctx, engine.TxOptions{Writable: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	st, err := tx.GetStore([]byte(headerStoreName))
+	if err == engine.ErrStoreNotFound {
+		err = tx.CreateStore([]byte(headerStoreName))
+		if err != nil {
+			return err
+		}
+
+		st, err = tx.GetStore([]byte(headerStoreName))
+		if err != nil {
+			return err
+		}
+
+		err = ng.writeHeader(st)
+		if err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	}
+	if err != nil {
+		return err
+	}
+
+	check, err := st.Get(headerCheck)
+	if err != nil {
+		return err
+	}
+
+	_, err = ng.decrypt(nil, []byte(headerStoreName), headerCheck, check)
+	return err
+}
+
+// writeHeader stores the id of the active key and the check value,
+// encrypted with the active key.
+func (ng *Engine) writeHeader(st engine.Store) error {
+	var id [4]byte
+	binary.BigEndian.PutUint32(id[:], ng.keyID)
+	err := st.Put(headerKeyID, id[:])
+	if err != nil {
+		return err
+	}
+
+	check, err := ng.encrypt([]byte(headerStoreName), headerCheck, checkValue)
+	if err != nil {
+		return err
+	}
+
+	return st.Put(headerCheck, check)
+}
+
+// KeyID returns the id of the key used to encrypt all the values of the database.
+// It differs from the active key until Rotate is called.
+func (ng *Engine) KeyID(ctx context.Context) (uint32, error) {
+	tx, err := ng.ng.Begin(ctx, engine.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	st, err := tx.GetStore([]byte(headerStoreName))
+	if err != nil {
+		return 0, err
+	}
+
+	v, err := st.Get(headerKeyID)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint32(v), nil
+}
+
+// Rotate encrypts all the values with the active key, within a single transaction.
+// Once done, the previous keys are not needed anymore.
+func (ng *Engine) Rotate(ctx context.Context) error {
+	tx, err := ng.ng.Begin(ctx, engine.TxOptions{Writable: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	header, err := tx.GetStore([]byte(headerStoreName))
+	if err != nil {
+		return err
+	}
+
+	var names [][]byte
+	it := header.Iterator(engine.IteratorOptions{})
+	for it.Seek(headerStoreList); it.Valid() && bytes.HasPrefix(it.Item().Key(), headerStoreList); it.Next() {
+		names = append(names, append([]byte(nil), it.Item().Key()[len(headerStoreList):]...))
+	}
+	err = it.Err()
+	it.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		st, err := tx.GetStore(name)
+		if err != nil {
+			return err
+		}
+
+		err = ng.rotateStore(name, st)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = ng.writeHeader(header)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// rotateStore encrypts the values of the store that are not encrypted with the active key.
+func (ng *Engine) rotateStore(name []byte, st engine.Store) error {
+	type kv struct {
+		k, v []byte
+	}
+	var pairs []kv
+
+	it := st.Iterator(engine.IteratorOptions{})
+	for it.Seek(nil); it.Valid(); it.Next() {
+		item := it.Item()
+		v, err := item.ValueCopy(nil)
+		if err != nil {
+			it.Close()
+			return err
+		}
+
+		if len(v) >= 4 && binary.BigEndian.Uint32(v) == ng.keyID {
+			continue
+		}
+
+		pairs = append(pairs, kv{append([]byte(nil), item.Key()...), v})
+	}
+	err := it.Err()
+	it.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pairs {
+		v, err := ng.decrypt(nil, name, p.k, p.v)
+		if err != nil {
+			return err
+		}
+
+		v, err = ng.encrypt(name, p.k, v)
+		if err != nil {
+			return err
+		}
+
+		err = st.Put(p.k, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// additionalData binds the encrypted value to its store and key,
+// to prevent values from being moved.
+func additionalData(storeName, k []byte) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(len(storeName)))
+
+	ad := make([]byte, 0, n+len(storeName)+len(k))
+	ad = append(ad, buf[:n]...)
+	ad = append(ad, storeName...)
+	return append(ad, k...)
+}
+
+// encrypt v with the active key.
+// The encrypted value is made of the id of the key, the nonce and the sealed value.
+func (ng *Engine) encrypt(storeName, k, v []byte) ([]byte, error) {
+	aead := ng.aeads[ng.keyID]
+
+	buf := make([]byte, 4+aead.NonceSize(), 4+aead.NonceSize()+len(v)+aead.Overhead())
+	binary.BigEndian.PutUint32(buf, ng.keyID)
+
+	nonce := buf[4:]
+	_, err := io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(buf, nonce, v, additionalData(storeName, k)), nil
+}
+
+// decrypt v and append the result to dst.
+func (ng *Engine) decrypt(dst, storeName, k, v []byte) ([]byte, error) {
+	if len(v) < 4 {
+		return nil, ErrInvalidKey
+	}
+
+	id := binary.BigEndian.Uint32(v)
+	aead, ok := ng.aeads[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrKeyNotFound, id)
+	}
+
+	if len(v) < 4+aead.NonceSize() {
+		return nil, ErrInvalidKey
+	}
+
+	nonce := v[4 : 4+aead.NonceSize()]
+	out, err := aead.Open(dst, nonce, v[4+aead.NonceSize():], additionalData(storeName, k))
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	return out, nil
+}
+
+// Begin creates a transaction.
+func (ng *Engine) Begin(ctx context.Context, opts engine.TxOptions) (engine.Transaction, error) {
+	tx, err := ng.ng.Begin(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transaction{
+		Transaction: tx,
+		ng:          ng,
+	}, nil
+}
+
+// Close the underlying engine.
+func (ng *Engine) Close() error {
+	return ng.ng.Close()
+}
+
+// transaction encrypts the values of the stores it returns.
+type transaction struct {
+	engine.Transaction
+
+	ng *Engine
+}
+
+// GetStore returns a store by name.
+func (tx *transaction) GetStore(name []byte) (engine.Store, error) {
+	if string(name) == headerStoreName {
+		return nil, engine.ErrStoreNotFound
+	}
+
+	st, err := tx.Transaction.GetStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &store{
+		Store: st,
+		ng:    tx.ng,
+		name:  append([]byte(nil), name...),
+	}, nil
+}
+
+// CreateStore creates a store and records its name in the header,
+// to be able to rotate keys.
+func (tx *transaction) CreateStore(name []byte) error {
+	if string(name) == headerStoreName {
+		return fmt.Errorf("reserved store name %q", name)
+	}
+
+	err := tx.Transaction.CreateStore(name)
+	if err != nil {
+		return err
+	}
+
+	header, err := tx.Transaction.GetStore([]byte(headerStoreName))
+	if err != nil {
+		return err
+	}
+
+	return header.Put(append(headerStoreList, name...), []byte{1})
+}
+
+// DropStore deletes a store and removes its name from the header.
+func (tx *transaction) DropStore(name []byte) error {
+	if string(name) == headerStoreName {
+		return engine.ErrStoreNotFound
+	}
+
+	err := tx.Transaction.DropStore(name)
+	if err != nil {
+		return err
+	}
+
+	header, err := tx.Transaction.GetStore([]byte(headerStoreName))
+	if err != nil {
+		return err
+	}
+
+	err = header.Delete(append(headerStoreList, name...))
+	if err == engine.ErrKeyNotFound {
+		return nil
+	}
+	return err
+}
+
+// store encrypts values on write and decrypts them on read.
+type store struct {
+	engine.Store
+
+	ng   *Engine
+	name []byte
+}
+
+// Get returns the decrypted value associated with the given key.
+func (s *store) Get(k []byte) ([]byte, error) {
+	v, err := s.Store.Get(k)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.ng.decrypt(nil, s.name, k, v)
+}
+
+// Put encrypts v and stores it.
+func (s *store) Put(k, v []byte) error {
+	if len(k) == 0 {
+		return errors.New("empty keys are not supported")
+	}
+
+	enc, err := s.ng.encrypt(s.name, k, v)
+	if err != nil {
+		return err
+	}
+
+	return s.Store.Put(k, enc)
+}
+
+// Iterator returns an iterator that decrypts values.
+func (s *store) Iterator(opts engine.IteratorOptions) engine.Iterator {
+	return &iterator{
+		Iterator: s.Store.Iterator(opts),
+		s:        s,
+	}
+}
+
+type iterator struct {
+	engine.Iterator
+
+	s    *store
+	item item
+}
+
+func (it *iterator) Item() engine.Item {
+	it.item.Item = it.Iterator.Item()
+	it.item.s = it.s
+	return &it.item
+}
+
+type item struct {
+	engine.Item
+
+	s   *store
+	buf []byte
+}
+
+// ValueCopy decrypts the value and copies it to buf.
+func (i *item) ValueCopy(buf []byte) ([]byte, error) {
+	var err error
+	i.buf, err = i.Item.ValueCopy(i.buf[:0])
+	if err != nil {
+		return nil, err
+	}
+
+	return i.s.ng.decrypt(buf[:0], i.s.name, i.Key(), i.buf)
+}
@@ -0,0 +1,257 @@
+package encryptedengine_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/boltengine"
+	"github.com/genjidb/genji/engine/encryptedengine"
+	"github.com/genjidb/genji/engine/enginetest"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	key1 = bytes.Repeat([]byte{1}, 32)
+	key2 = bytes.Repeat([]byte{2}, 16)
+)
+
+func options() encryptedengine.Options {
+	return encryptedengine.Options{
+		Keys:  map[uint32][]byte{1: key1},
+		KeyID: 1,
+	}
+}
+
+func TestMemoryEngine(t *testing.T) {
+	enginetest.TestSuite(t, func() (engine.Engine, func()) {
+		ng, err := encryptedengine.NewEngine(context.Background(), memoryengine.NewEngine(), options())
+		require.NoError(t, err)
+		return ng, func() { ng.Close() }
+	})
+}
+
+func TestBoltEngine(t *testing.T) {
+	enginetest.TestSuite(t, func() (engine.Engine, func()) {
+		dir, cleanup := tempDir(t)
+		bng, err := boltengine.NewEngine(filepath.Join(dir, "test.db"), 0o600, nil)
+		require.NoError(t, err)
+
+		ng, err := encryptedengine.NewEngine(context.Background(), bng, options())
+		require.NoError(t, err)
+		return ng, func() {
+			ng.Close()
+			cleanup()
+		}
+	})
+}
+
+// readRaw returns the value stored in the underlying engine.
+func readRaw(t *testing.T, ng engine.Engine, store, k []byte) []byte {
+	tx, err := ng.Begin(context.Background(), engine.TxOptions{})
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	st, err := tx.GetStore(store)
+	require.NoError(t, err)
+	v, err := st.Get(k)
+	require.NoError(t, err)
+	return append([]byte(nil), v...)
+}
+
+func put(t *testing.T, ng engine.Engine, store, k, v []byte) {
+	tx, err := ng.Begin(context.Background(), engine.TxOptions{Writable: true})
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	st, err := tx.GetStore(store)
+	if err == engine.ErrStoreNotFound {
+		require.NoError(t, tx.CreateStore(store))
+		st, err = tx.GetStore(store)
+	}
+	require.NoError(t, err)
+	require.NoError(t, st.Put(k, v))
+	require.NoError(t, tx.Commit())
+}
+
+func get(ng engine.Engine, store, k []byte) ([]byte, error) {
+	tx, err := ng.Begin(context.Background(), engine.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	st, err := tx.GetStore(store)
+	if err != nil {
+		return nil, err
+	}
+	return st.Get(k)
+}
+
+func TestEngine(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Values are encrypted", func(t *testing.T) {
+		inner := memoryengine.NewEngine()
+		ng, err := encryptedengine.NewEngine(ctx, inner, options())
+		require.NoError(t, err)
+		defer ng.Close()
+
+		put(t, ng, []byte("foo"), []byte("k"), []byte("secret value"))
+
+		raw := readRaw(t, inner, []byte("foo"), []byte("k"))
+		require.False(t, bytes.Contains(raw, []byte("secret value")))
+
+		v, err := get(ng, []byte("foo"), []byte("k"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("secret value"), v)
+	})
+
+	t.Run("Header store is hidden", func(t *testing.T) {
+		ng, err := encryptedengine.NewEngine(ctx, memoryengine.NewEngine(), options())
+		require.NoError(t, err)
+		defer ng.Close()
+
+		_, err = get(ng, []byte("__encryptedengine_header"), []byte("k"))
+		require.Equal(t, engine.ErrStoreNotFound, err)
+	})
+
+	t.Run("Values can't be moved", func(t *testing.T) {
+		inner := memoryengine.NewEngine()
+		ng, err := encryptedengine.NewEngine(ctx, inner, options())
+		require.NoError(t, err)
+		defer ng.Close()
+
+		put(t, ng, []byte("foo"), []byte("a"), []byte("A"))
+		put(t, ng, []byte("foo"), []byte("b"), []byte("B"))
+
+		// copy the encrypted value of a to b in the underlying engine.
+		put(t, inner, []byte("foo"), []byte("b"), readRaw(t, inner, []byte("foo"), []byte("a")))
+
+		_, err = get(ng, []byte("foo"), []byte("b"))
+		require.Equal(t, encryptedengine.ErrInvalidKey, err)
+	})
+
+	t.Run("Wrong key", func(t *testing.T) {
+		inner := memoryengine.NewEngine()
+		_, err := encryptedengine.NewEngine(ctx, inner, options())
+		require.NoError(t, err)
+
+		_, err = encryptedengine.NewEngine(ctx, inner, encryptedengine.Options{
+			Keys:  map[uint32][]byte{1: key2},
+			KeyID: 1,
+		})
+		require.Equal(t, encryptedengine.ErrInvalidKey, err)
+
+		_, err = encryptedengine.NewEngine(ctx, inner, encryptedengine.Options{
+			Keys:  map[uint32][]byte{2: key2},
+			KeyID: 2,
+		})
+		require.True(t, errors.Is(err, encryptedengine.ErrKeyNotFound))
+	})
+
+	t.Run("Invalid options", func(t *testing.T) {
+		_, err := encryptedengine.NewEngine(ctx, memoryengine.NewEngine(), encryptedengine.Options{
+			Keys:  map[uint32][]byte{1: key1},
+			KeyID: 2,
+		})
+		require.True(t, errors.Is(err, encryptedengine.ErrKeyNotFound))
+
+		_, err = encryptedengine.NewEngine(ctx, memoryengine.NewEngine(), encryptedengine.Options{
+			Keys:  map[uint32][]byte{1: []byte("short")},
+			KeyID: 1,
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("Rotate", func(t *testing.T) {
+		inner := memoryengine.NewEngine()
+		ng, err := encryptedengine.NewEngine(ctx, inner, options())
+		require.NoError(t, err)
+
+		put(t, ng, []byte("foo"), []byte("a"), []byte("A"))
+		put(t, ng, []byte("bar"), []byte("b"), []byte("B"))
+
+		// values are written with the active key, and previous
+		// values are still readable.
+		ng, err = encryptedengine.NewEngine(ctx, inner, encryptedengine.Options{
+			Keys:  map[uint32][]byte{1: key1, 2: key2},
+			KeyID: 2,
+		})
+		require.NoError(t, err)
+		put(t, ng, []byte("foo"), []byte("c"), []byte("C"))
+
+		id, err := ng.KeyID(ctx)
+		require.NoError(t, err)
+		require.EqualValues(t, 1, id)
+
+		require.NoError(t, ng.Rotate(ctx))
+		id, err = ng.KeyID(ctx)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, id)
+
+		// the previous key is not needed anymore.
+		ng, err = encryptedengine.NewEngine(ctx, inner, encryptedengine.Options{
+			Keys:  map[uint32][]byte{2: key2},
+			KeyID: 2,
+		})
+		require.NoError(t, err)
+		defer ng.Close()
+
+		for _, kv := range [][3]string{{"foo", "a", "A"}, {"bar", "b", "B"}, {"foo", "c", "C"}} {
+			v, err := get(ng, []byte(kv[0]), []byte(kv[1]))
+			require.NoError(t, err)
+			require.Equal(t, []byte(kv[2]), v)
+		}
+	})
+
+	t.Run("Database", func(t *testing.T) {
+		dir, cleanup := tempDir(t)
+		defer cleanup()
+
+		open := func() *genji.DB {
+			bng, err := boltengine.NewEngine(filepath.Join(dir, "test.db"), 0o600, nil)
+			require.NoError(t, err)
+			ng, err := encryptedengine.NewEngine(ctx, bng, options())
+			require.NoError(t, err)
+			db, err := genji.New(ctx, ng)
+			require.NoError(t, err)
+			return db
+		}
+
+		db := open()
+		err := db.Exec(`
+			CREATE TABLE test(a INTEGER PRIMARY KEY);
+			CREATE INDEX idx_b ON test (b);
+			INSERT INTO test (a, b) VALUES (1, 'hello'), (2, 'world');
+		`)
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		db = open()
+		defer db.Close()
+
+		d, err := db.QueryDocument("SELECT a FROM test WHERE b = 'world'")
+		require.NoError(t, err)
+		v, err := d.GetByField("a")
+		require.NoError(t, err)
+		require.Equal(t, document.NewIntegerValue(2), v)
+	})
+}
+
+func tempDir(t require.TestingT) (string, func()) {
+	dir, err := ioutil.TempDir("", "genji")
+	require.NoError(t, err)
+
+	return dir, func() {
+		os.RemoveAll(dir)
+	}
+}
@@ -78,6 +78,15 @@ type Store interface {
 	NextSequence() (uint64, error)
 }
 
+// A SequenceSetter is a Store whose sequence can be set directly.
+// Implementing it is optional: the sequence of the other stores can only
+// be advanced by calling NextSequence.
+type SequenceSetter interface {
+	// SetSequence sets the sequence of the store, which is the last value
+	// returned by NextSequence, if seq is greater than the current one.
+	SetSequence(seq uint64) error
+}
+
 // IteratorOptions is used to configure an iterator upon creation.
 type IteratorOptions struct {
 	Reverse bool
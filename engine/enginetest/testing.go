@@ -40,6 +40,7 @@ func TestSuite(t *testing.T, builder Builder) {
 		{"Store/Delete", TestStoreDelete},
 		{"Store/Truncate", TestStoreTruncate},
 		{"Store/NextSequence", TestStoreNextSequence},
+		{"Store/SetSequence", TestStoreSetSequence},
 		{"TestQueries", TestQueries},
 		{"TestQueriesSameTransaction", TestQueriesSameTransaction},
 	}
@@ -1005,6 +1006,47 @@ func TestStoreDelete(t *testing.T, builder Builder) {
 		require.Equal(t, []byte("BAR"), v)
 	})
 
+	t.Run("Should keep a key put again after being deleted", func(t *testing.T) {
+		ng, cleanup := builder()
+		defer cleanup()
+
+		tx, err := ng.Begin(context.Background(), engine.TxOptions{Writable: true})
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		err = tx.CreateStore([]byte("test"))
+		require.NoError(t, err)
+		st, err := tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		err = st.Put([]byte("foo"), []byte("FOO"))
+		require.NoError(t, err)
+		err = tx.Commit()
+		require.NoError(t, err)
+
+		tx, err = ng.Begin(context.Background(), engine.TxOptions{Writable: true})
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		st, err = tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		err = st.Delete([]byte("foo"))
+		require.NoError(t, err)
+		err = st.Put([]byte("foo"), []byte("BAR"))
+		require.NoError(t, err)
+		err = tx.Commit()
+		require.NoError(t, err)
+
+		tx, err = ng.Begin(context.Background(), engine.TxOptions{Writable: false})
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		st, err = tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		v, err := st.Get([]byte("foo"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("BAR"), v)
+	})
+
 	t.Run("Should fail if context canceled", func(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -1050,6 +1092,33 @@ func TestStoreTruncate(t *testing.T, builder Builder) {
 		require.False(t, it.Valid())
 	})
 
+	t.Run("Should be visible from the transaction", func(t *testing.T) {
+		ng, cleanup := builder()
+		defer cleanup()
+		defer func() {
+			require.NoError(t, ng.Close())
+		}()
+
+		tx, err := ng.Begin(context.Background(), engine.TxOptions{Writable: true})
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		err = tx.CreateStore([]byte("test"))
+		require.NoError(t, err)
+		st, err := tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		err = st.Put([]byte("foo"), []byte("FOO"))
+		require.NoError(t, err)
+
+		err = st.Truncate()
+		require.NoError(t, err)
+
+		st, err = tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		_, err = st.Get([]byte("foo"))
+		require.Equal(t, engine.ErrKeyNotFound, err)
+	})
+
 	t.Run("Should fail if context canceled", func(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -1158,6 +1227,50 @@ func TestStoreNextSequence(t *testing.T, builder Builder) {
 	})
 }
 
+// TestStoreSetSequence verifies SetSequence behaviour,
+// if the store implements engine.SequenceSetter.
+func TestStoreSetSequence(t *testing.T, builder Builder) {
+	t.Run("Should set the sequence", func(t *testing.T) {
+		st, cleanup := storeBuilder(t, builder)
+		defer cleanup()
+
+		ss, ok := st.(engine.SequenceSetter)
+		if !ok {
+			t.Skip("the store doesn't implement engine.SequenceSetter")
+		}
+
+		err := ss.SetSequence(1 << 40)
+		require.NoError(t, err)
+		s, err := st.NextSequence()
+		require.NoError(t, err)
+		require.Equal(t, uint64(1<<40+1), s)
+
+		// the sequence never moves backwards.
+		err = ss.SetSequence(10)
+		require.NoError(t, err)
+		s, err = st.NextSequence()
+		require.NoError(t, err)
+		require.Equal(t, uint64(1<<40+2), s)
+	})
+
+	t.Run("Should fail if context canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		st, cleanup := storeBuilderWithContext(ctx, t, builder)
+		defer cleanup()
+
+		ss, ok := st.(engine.SequenceSetter)
+		if !ok {
+			t.Skip("the store doesn't implement engine.SequenceSetter")
+		}
+
+		cancel()
+		err := ss.SetSequence(10)
+		require.Equal(t, context.Canceled, err)
+	})
+}
+
 // TestQueries test simple queries against the engine.
 func TestQueries(t *testing.T, builder Builder) {
 	t.Run("SELECT", func(t *testing.T) {
@@ -152,9 +152,12 @@ func (s *storeTx) Delete(k []byte) error {
 		i.deleted = false
 	})
 
-	// on commit, remove the item from the tree.
+	// on commit, remove the item from the tree,
+	// unless it was put again after being deleted.
 	s.tx.onCommit = append(s.tx.onCommit, func() {
-		s.tr.Delete(i)
+		if i.deleted {
+			s.tr.Delete(i)
+		}
 	})
 	return nil
 }
@@ -175,10 +178,12 @@ func (s *storeTx) Truncate() error {
 
 	old := s.tr
 	s.tr = btree.New(btreeDegree)
+	s.tx.ng.stores[s.name] = s.tr
 
 	// on rollback replace the new tree by the old one.
 	s.tx.onRollback = append(s.tx.onRollback, func() {
 		s.tr = old
+		s.tx.ng.stores[s.name] = old
 	})
 
 	return nil
@@ -201,6 +206,25 @@ func (s *storeTx) NextSequence() (uint64, error) {
 	return s.tx.ng.sequences[s.name], nil
 }
 
+// SetSequence sets the sequence of the store if seq is greater than the current one.
+func (s *storeTx) SetSequence(seq uint64) error {
+	select {
+	case <-s.tx.ctx.Done():
+		return s.tx.ctx.Err()
+	default:
+	}
+
+	if !s.tx.writable {
+		return engine.ErrTransactionReadOnly
+	}
+
+	if seq > s.tx.ng.sequences[s.name] {
+		s.tx.ng.sequences[s.name] = seq
+	}
+
+	return nil
+}
+
 // Iterator creates an iterator with the given options.
 func (s *storeTx) Iterator(opts engine.IteratorOptions) engine.Iterator {
 	return &iterator{
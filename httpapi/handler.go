@@ -0,0 +1,477 @@
+// Package httpapi serves a Genji database over HTTP.
+//
+// Queries are sent as JSON objects, with optional positional or named parameters,
+// and the documents they return are streamed as newline-delimited JSON:
+//
+//	POST /query                   {"query": "SELECT * FROM foo WHERE a > ?", "params": [10]}
+//	POST /query                   {"query": "SELECT * FROM foo WHERE a > $a", "params": {"a": 10}}
+//
+// Each query runs in its own session: statements are executed in their own
+// transaction, unless the query opens one with BEGIN. Transactions that span
+// several requests are opened explicitly, and are rolled back if they are not
+// used for longer than the transaction timeout:
+//
+//	POST /tx                      {"read_only": true}, returns {"id": "..."}
+//	POST /tx/{id}/query           {"query": "..."}
+//	POST /tx/{id}/commit
+//	POST /tx/{id}/rollback
+//
+// Errors are returned as a JSON object with an "error" field. If an error occurs
+// after documents were sent, it is returned in the Genji-Error trailer.
+package httpapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// DefaultTxTimeout is the duration after which an unused transaction
+// is rolled back, if not specified in the options.
+const DefaultTxTimeout = time.Minute
+
+// ErrorTrailer is the trailer containing the error that occurred
+// while streaming the documents of a query.
+const ErrorTrailer = "Genji-Error"
+
+// Options configures a Handler.
+type Options struct {
+	// ReadOnly rejects the queries that modify the database
+	// and only allows read-only transactions.
+	ReadOnly bool
+	// TxTimeout is the duration after which a transaction opened with /tx
+	// is rolled back if no request uses it. Defaults to DefaultTxTimeout.
+	TxTimeout time.Duration
+}
+
+// A Handler is an http.Handler serving the queries of a database.
+type Handler struct {
+	db   *genji.DB
+	opts Options
+	mux  *http.ServeMux
+
+	mu     sync.Mutex
+	txs    map[string]*txSession
+	closed bool
+}
+
+// a txSession is a transaction opened with /tx, attached to its own session.
+type txSession struct {
+	id      string
+	session *database.Session
+
+	// mu serializes the requests using the transaction,
+	// which is not safe for concurrent use.
+	mu       sync.Mutex
+	lastUsed time.Time
+	timer    *time.Timer
+	done     bool
+}
+
+// NewHandler returns a handler serving the queries of db.
+// If opts is nil, it uses the default options.
+func NewHandler(db *genji.DB, opts *Options) *Handler {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.TxTimeout <= 0 {
+		o.TxTimeout = DefaultTxTimeout
+	}
+
+	h := Handler{
+		db:   db,
+		opts: o,
+		mux:  http.NewServeMux(),
+		txs:  make(map[string]*txSession),
+	}
+
+	h.mux.HandleFunc("/query", h.handleQuery)
+	h.mux.HandleFunc("/tx", h.handleBegin)
+	h.mux.HandleFunc("/tx/", h.handleTx)
+
+	return &h
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	h.mux.ServeHTTP(w, r)
+}
+
+// Close rolls back the open transactions. The handler must not be used afterwards.
+func (h *Handler) Close() error {
+	h.mu.Lock()
+	h.closed = true
+	txs := h.txs
+	h.txs = make(map[string]*txSession)
+	h.mu.Unlock()
+
+	var err error
+	for _, t := range txs {
+		t.mu.Lock()
+		if rerr := t.end(false); rerr != nil && err == nil {
+			err = rerr
+		}
+		t.mu.Unlock()
+	}
+
+	return err
+}
+
+// queryRequest is the body of the requests running a query.
+type queryRequest struct {
+	Query  string          `json:"query"`
+	Params json.RawMessage `json:"params"`
+}
+
+func (h *Handler) handleQuery(w http.ResponseWriter, r *http.Request) {
+	s := h.db.DB.NewSession()
+
+	h.runQuery(w, r, s)
+
+	// transactions opened with BEGIN don't outlive the request.
+	if tx := s.GetAttachedTx(); tx != nil {
+		_ = tx.Rollback()
+	}
+}
+
+// runQuery runs the query of the request in the session and streams the documents
+// returned by its last statement.
+func (h *Handler) runQuery(w http.ResponseWriter, r *http.Request, s *database.Session) {
+	var req queryRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	pq, err := parser.ParseQuery(req.Query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if h.opts.ReadOnly {
+		for _, stmt := range pq.Statements {
+			if !stmt.IsReadOnly() && !isTransactionControl(stmt) {
+				writeError(w, http.StatusForbidden, errors.New("cannot run a statement that modifies the database in read-only mode"))
+				return
+			}
+		}
+	}
+
+	params, err := decodeParams(req.Params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	res, err := pq.RunSession(r.Context(), s, params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	bw := bufio.NewWriter(w)
+	var n int
+	err = res.Iterate(func(d document.Document) error {
+		if n == 0 {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Header().Set("Trailer", ErrorTrailer)
+			w.WriteHeader(http.StatusOK)
+		}
+		n++
+
+		data, err := document.MarshalJSON(d)
+		if err != nil {
+			return err
+		}
+
+		if _, err := bw.Write(data); err != nil {
+			return err
+		}
+		return bw.WriteByte('\n')
+	})
+
+	// closing the result commits the transaction of the last statement.
+	if cerr := res.Close(); err == nil {
+		err = cerr
+	}
+
+	if n == 0 {
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if ferr := bw.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		w.Header().Set(ErrorTrailer, err.Error())
+	}
+}
+
+// decodeParams returns the parameters of a query, sent as an array
+// of positional parameters or as an object of named parameters.
+func decodeParams(data json.RawMessage) ([]expr.Param, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+
+	var params []expr.Param
+	switch data[0] {
+	case '[':
+		var vb document.ValueBuffer
+		err := json.Unmarshal(data, &vb)
+		if err != nil {
+			return nil, err
+		}
+
+		err = vb.Iterate(func(i int, v document.Value) error {
+			params = append(params, expr.Param{Value: v.V})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	case '{':
+		var fb document.FieldBuffer
+		err := json.Unmarshal(data, &fb)
+		if err != nil {
+			return nil, err
+		}
+
+		err = fb.Iterate(func(f string, v document.Value) error {
+			params = append(params, expr.Param{Name: f, Value: v.V})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("params must be an array or an object")
+	}
+
+	return params, nil
+}
+
+// isTransactionControl returns true if the statement ends a transaction.
+// These statements are allowed in read-only mode, where only
+// read-only transactions can be opened.
+func isTransactionControl(stmt query.Statement) bool {
+	switch stmt.(type) {
+	case query.CommitStmt, query.RollbackStmt:
+		return true
+	}
+
+	return false
+}
+
+// beginRequest is the body of the requests opening a transaction.
+type beginRequest struct {
+	ReadOnly bool `json:"read_only"`
+}
+
+func (h *Handler) handleBegin(w http.ResponseWriter, r *http.Request) {
+	var req beginRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if h.opts.ReadOnly && !req.ReadOnly {
+		writeError(w, http.StatusForbidden, errors.New("cannot open a read-write transaction in read-only mode"))
+		return
+	}
+
+	id, err := newTxID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	t := txSession{
+		id:       id,
+		session:  h.db.DB.NewSession(),
+		lastUsed: time.Now(),
+	}
+
+	// the transaction outlives the request.
+	_, err = t.session.BeginTx(context.Background(), &database.TxOptions{
+		ReadOnly: req.ReadOnly,
+		Attached: true,
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		_ = t.end(false)
+		writeError(w, http.StatusServiceUnavailable, errors.New("server closed"))
+		return
+	}
+	h.txs[id] = &t
+	t.timer = time.AfterFunc(h.opts.TxTimeout, func() {
+		h.expire(&t)
+	})
+	h.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]string{"id": id})
+}
+
+func (h *Handler) handleTx(w http.ResponseWriter, r *http.Request) {
+	// the path is /tx/{id}/{action}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/tx/"), "/")
+	if len(parts) != 2 {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	id, action := parts[0], parts[1]
+
+	h.mu.Lock()
+	t, ok := h.txs[id]
+	h.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("transaction %q not found", id))
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// the transaction may have been rolled back while waiting for the lock.
+	if t.done {
+		writeError(w, http.StatusNotFound, fmt.Errorf("transaction %q not found", id))
+		return
+	}
+
+	switch action {
+	case "query":
+		h.runQuery(w, r, t.session)
+		t.lastUsed = time.Now()
+		t.timer.Reset(h.opts.TxTimeout)
+
+		// the transaction was closed by a COMMIT or ROLLBACK statement.
+		if t.session.GetAttachedTx() == nil {
+			h.remove(t)
+		}
+	case "commit", "rollback":
+		err := t.end(action == "commit")
+		h.remove(t)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+// expire rolls back the transaction if it was not used during the timeout.
+func (h *Handler) expire(t *txSession) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.done {
+		return
+	}
+
+	if idle := time.Since(t.lastUsed); idle < h.opts.TxTimeout {
+		t.timer.Reset(h.opts.TxTimeout - idle)
+		return
+	}
+
+	_ = t.end(false)
+	h.remove(t)
+}
+
+// remove the transaction from the list of open transactions.
+func (h *Handler) remove(t *txSession) {
+	t.done = true
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+
+	h.mu.Lock()
+	delete(h.txs, t.id)
+	h.mu.Unlock()
+}
+
+// end commits or rolls back the transaction attached to the session, if any.
+// t.mu must be held.
+func (t *txSession) end(commit bool) error {
+	t.done = true
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+
+	tx := t.session.GetAttachedTx()
+	if tx == nil {
+		return nil
+	}
+
+	if !commit || !tx.Writable() {
+		return tx.Rollback()
+	}
+
+	err := tx.Commit()
+	if err != nil {
+		// make sure the transaction is released.
+		_ = tx.Rollback()
+	}
+	return err
+}
+
+func newTxID() (string, error) {
+	var b [16]byte
+	_, err := rand.Read(b[:])
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b[:]), nil
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,264 @@
+package httpapi_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/httpapi"
+	"github.com/stretchr/testify/require"
+)
+
+func newServer(t *testing.T, opts *httpapi.Options) (*genji.DB, *httptest.Server) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+
+	err = db.Exec(`
+		CREATE TABLE foo (a INTEGER);
+		INSERT INTO foo (a, b) VALUES (1, 'x'), (2, 'y'), (3, 'z');
+	`)
+	require.NoError(t, err)
+
+	h := httpapi.NewHandler(db, opts)
+	srv := httptest.NewServer(h)
+	t.Cleanup(func() {
+		srv.Close()
+		require.NoError(t, h.Close())
+		require.NoError(t, db.Close())
+	})
+
+	return db, srv
+}
+
+type response struct {
+	status  int
+	body    string
+	trailer http.Header
+}
+
+func post(t *testing.T, srv *httptest.Server, path string, body interface{}) response {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		require.NoError(t, err)
+	}
+
+	resp, err := http.Post(srv.URL+path, "application/json", bytes.NewReader(data))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return response{status: resp.StatusCode, body: string(b), trailer: resp.Trailer}
+}
+
+type query struct {
+	Query  string      `json:"query"`
+	Params interface{} `json:"params,omitempty"`
+}
+
+func TestQuery(t *testing.T) {
+	_, srv := newServer(t, nil)
+
+	tests := []struct {
+		name   string
+		q      query
+		status int
+		body   string
+	}{
+		{"Select", query{Query: "SELECT a, b FROM foo"}, 200, "{\"a\": 1, \"b\": \"x\"}\n{\"a\": 2, \"b\": \"y\"}\n{\"a\": 3, \"b\": \"z\"}\n"},
+		{"Positional params", query{Query: "SELECT b FROM foo WHERE a > ? AND b != ?", Params: []interface{}{1, "z"}}, 200, "{\"b\": \"y\"}\n"},
+		{"Named params", query{Query: "SELECT $d.c AS c FROM foo WHERE a = $a", Params: map[string]interface{}{"a": 2, "d": map[string]interface{}{"c": []int{1}}}}, 200, "{\"c\": [1]}\n"},
+		{"No documents", query{Query: "SELECT * FROM foo WHERE a > 10"}, 200, ""},
+		{"Multiple statements", query{Query: "INSERT INTO foo (a) VALUES (4); SELECT COUNT(*) FROM foo"}, 200, "{\"COUNT(*)\": 4}\n"},
+		{"Parse error", query{Query: "SELEC 1"}, 400, ""},
+		{"Unknown table", query{Query: "SELECT * FROM bar"}, 400, ""},
+		{"Invalid params", query{Query: "SELECT ?", Params: 1}, 400, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := post(t, srv, "/query", test.q)
+			require.Equal(t, test.status, resp.status, resp.body)
+			if test.status == 200 {
+				require.Equal(t, test.body, resp.body)
+			} else {
+				require.Contains(t, resp.body, `"error"`)
+			}
+		})
+	}
+
+	t.Run("Error while streaming", func(t *testing.T) {
+		resp := post(t, srv, "/query", query{Query: "SELECT CAST(b AS INTEGER) AS b FROM foo WHERE a > 0"})
+		require.Equal(t, 200, resp.status)
+		require.NotEmpty(t, resp.trailer.Get(httpapi.ErrorTrailer))
+	})
+
+	t.Run("BEGIN doesn't outlive the request", func(t *testing.T) {
+		resp := post(t, srv, "/query", query{Query: "BEGIN; INSERT INTO foo (a) VALUES (10)"})
+		require.Equal(t, 200, resp.status)
+
+		resp = post(t, srv, "/query", query{Query: "SELECT a FROM foo WHERE a = 10"})
+		require.Equal(t, 200, resp.status)
+		require.Empty(t, resp.body)
+	})
+
+	t.Run("Method not allowed", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/query")
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+}
+
+func TestTransactions(t *testing.T) {
+	begin := func(t *testing.T, srv *httptest.Server, readOnly bool) string {
+		resp := post(t, srv, "/tx", map[string]bool{"read_only": readOnly})
+		require.Equal(t, http.StatusCreated, resp.status, resp.body)
+
+		var body struct{ ID string }
+		require.NoError(t, json.Unmarshal([]byte(resp.body), &body))
+		require.NotEmpty(t, body.ID)
+		return body.ID
+	}
+
+	t.Run("Commit", func(t *testing.T) {
+		_, srv := newServer(t, nil)
+
+		id := begin(t, srv, false)
+		resp := post(t, srv, "/tx/"+id+"/query", query{Query: "INSERT INTO foo (a) VALUES (4)"})
+		require.Equal(t, 200, resp.status, resp.body)
+		resp = post(t, srv, "/tx/"+id+"/query", query{Query: "SELECT COUNT(*) FROM foo"})
+		require.Equal(t, "{\"COUNT(*)\": 4}\n", resp.body)
+
+		resp = post(t, srv, "/tx/"+id+"/commit", nil)
+		require.Equal(t, http.StatusNoContent, resp.status)
+
+		resp = post(t, srv, "/query", query{Query: "SELECT COUNT(*) FROM foo"})
+		require.Equal(t, "{\"COUNT(*)\": 4}\n", resp.body)
+
+		// the transaction is closed.
+		resp = post(t, srv, "/tx/"+id+"/query", query{Query: "SELECT 1"})
+		require.Equal(t, http.StatusNotFound, resp.status)
+		resp = post(t, srv, "/tx/"+id+"/commit", nil)
+		require.Equal(t, http.StatusNotFound, resp.status)
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		_, srv := newServer(t, nil)
+
+		id := begin(t, srv, false)
+		resp := post(t, srv, "/tx/"+id+"/query", query{Query: "DELETE FROM foo"})
+		require.Equal(t, 200, resp.status, resp.body)
+		resp = post(t, srv, "/tx/"+id+"/rollback", nil)
+		require.Equal(t, http.StatusNoContent, resp.status)
+
+		resp = post(t, srv, "/query", query{Query: "SELECT COUNT(*) FROM foo"})
+		require.Equal(t, "{\"COUNT(*)\": 3}\n", resp.body)
+	})
+
+	t.Run("COMMIT statement", func(t *testing.T) {
+		_, srv := newServer(t, nil)
+
+		id := begin(t, srv, false)
+		resp := post(t, srv, "/tx/"+id+"/query", query{Query: "INSERT INTO foo (a) VALUES (4); COMMIT"})
+		require.Equal(t, 200, resp.status, resp.body)
+
+		resp = post(t, srv, "/tx/"+id+"/query", query{Query: "SELECT 1"})
+		require.Equal(t, http.StatusNotFound, resp.status)
+
+		resp = post(t, srv, "/query", query{Query: "SELECT COUNT(*) FROM foo"})
+		require.Equal(t, "{\"COUNT(*)\": 4}\n", resp.body)
+	})
+
+	t.Run("Read-only", func(t *testing.T) {
+		_, srv := newServer(t, nil)
+
+		id := begin(t, srv, true)
+		resp := post(t, srv, "/tx/"+id+"/query", query{Query: "INSERT INTO foo (a) VALUES (4)"})
+		require.Equal(t, http.StatusBadRequest, resp.status)
+		resp = post(t, srv, "/tx/"+id+"/query", query{Query: "SELECT COUNT(*) FROM foo"})
+		require.Equal(t, "{\"COUNT(*)\": 3}\n", resp.body)
+		resp = post(t, srv, "/tx/"+id+"/rollback", nil)
+		require.Equal(t, http.StatusNoContent, resp.status)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		_, srv := newServer(t, &httpapi.Options{TxTimeout: 50 * time.Millisecond})
+
+		id := begin(t, srv, false)
+		resp := post(t, srv, "/tx/"+id+"/query", query{Query: "DELETE FROM foo"})
+		require.Equal(t, 200, resp.status, resp.body)
+
+		// the memory engine blocks readers until the transaction is rolled back.
+		start := time.Now()
+		resp = post(t, srv, "/query", query{Query: "SELECT COUNT(*) FROM foo"})
+		require.Equal(t, "{\"COUNT(*)\": 3}\n", resp.body)
+		require.True(t, time.Since(start) >= 40*time.Millisecond)
+
+		resp = post(t, srv, "/tx/"+id+"/query", query{Query: "SELECT 1"})
+		require.Equal(t, http.StatusNotFound, resp.status)
+	})
+
+	t.Run("Unknown transaction", func(t *testing.T) {
+		_, srv := newServer(t, nil)
+
+		resp := post(t, srv, "/tx/foo/query", query{Query: "SELECT 1"})
+		require.Equal(t, http.StatusNotFound, resp.status)
+
+		id := begin(t, srv, true)
+		resp = post(t, srv, "/tx/"+id+"/foo", nil)
+		require.Equal(t, http.StatusNotFound, resp.status)
+	})
+}
+
+func TestReadOnly(t *testing.T) {
+	_, srv := newServer(t, &httpapi.Options{ReadOnly: true})
+
+	resp := post(t, srv, "/query", query{Query: "SELECT COUNT(*) FROM foo"})
+	require.Equal(t, "{\"COUNT(*)\": 3}\n", resp.body)
+
+	resp = post(t, srv, "/query", query{Query: "SELECT 1; DELETE FROM foo"})
+	require.Equal(t, http.StatusForbidden, resp.status)
+
+	resp = post(t, srv, "/tx", map[string]bool{"read_only": false})
+	require.Equal(t, http.StatusForbidden, resp.status)
+
+	resp = post(t, srv, "/tx", map[string]bool{"read_only": true})
+	require.Equal(t, http.StatusCreated, resp.status)
+
+	resp = post(t, srv, "/query", query{Query: "SELECT COUNT(*) FROM foo"})
+	require.Equal(t, "{\"COUNT(*)\": 3}\n", resp.body)
+
+	// transaction control statements are allowed.
+	resp = post(t, srv, "/query", query{Query: "BEGIN READ ONLY; SELECT COUNT(*) FROM foo; COMMIT"})
+	require.Equal(t, http.StatusOK, resp.status, resp.body)
+
+	resp = post(t, srv, "/query", query{Query: "BEGIN; SELECT COUNT(*) FROM foo; COMMIT"})
+	require.Equal(t, http.StatusForbidden, resp.status)
+
+	resp = post(t, srv, "/tx", map[string]bool{"read_only": true})
+	require.Equal(t, http.StatusCreated, resp.status)
+	var body struct{ ID string }
+	require.NoError(t, json.Unmarshal([]byte(resp.body), &body))
+
+	resp = post(t, srv, "/tx/"+body.ID+"/query", query{Query: "SELECT COUNT(*) FROM foo; ROLLBACK"})
+	require.Equal(t, http.StatusOK, resp.status, resp.body)
+
+	// the transaction is closed.
+	resp = post(t, srv, "/tx/"+body.ID+"/query", query{Query: "SELECT 1"})
+	require.Equal(t, http.StatusNotFound, resp.status)
+
+	resp = post(t, srv, "/tx", map[string]bool{"read_only": true})
+	require.Equal(t, http.StatusCreated, resp.status)
+	require.NoError(t, json.Unmarshal([]byte(resp.body), &body))
+	resp = post(t, srv, "/tx/"+body.ID+"/commit", nil)
+	require.Equal(t, http.StatusNoContent, resp.status, resp.body)
+}
@@ -0,0 +1,91 @@
+// Package fulltext implements full-text indexes: texts are split into terms
+// by an analyzer and stored in an inverted index, which associates each term
+// with the keys of the documents containing it. The documents matching a query
+// are ranked using the BM25 relevance function.
+package fulltext
+
+import (
+	"strings"
+	"unicode"
+)
+
+// A Tokenizer splits a text into tokens.
+type Tokenizer func(text string) []string
+
+// A Filter transforms a list of tokens. It can modify, remove or add tokens.
+type Filter func(tokens []string) []string
+
+// An Analyzer turns a text into a list of terms, by splitting it into tokens
+// and passing the tokens through a list of filters.
+type Analyzer struct {
+	Tokenizer Tokenizer
+	Filters   []Filter
+}
+
+// DefaultAnalyzer splits texts on non-alphanumeric characters, lowercases the tokens,
+// removes English stop words and reduces the remaining words to their stem.
+var DefaultAnalyzer = &Analyzer{
+	Tokenizer: Tokenize,
+	Filters: []Filter{
+		LowercaseFilter,
+		StopWordFilter,
+		StemFilter,
+	},
+}
+
+// Analyze returns the terms of the text, in order of appearance.
+func (a *Analyzer) Analyze(text string) []string {
+	tokens := a.Tokenizer(text)
+	for _, f := range a.Filters {
+		tokens = f(tokens)
+	}
+
+	return tokens
+}
+
+// Tokenize splits text into sequences of letters and digits.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// LowercaseFilter converts the tokens to lower case.
+func LowercaseFilter(tokens []string) []string {
+	for i := range tokens {
+		tokens[i] = strings.ToLower(tokens[i])
+	}
+
+	return tokens
+}
+
+// stopWords are common English words that are not worth indexing.
+var stopWords = map[string]struct{}{
+	"a": {}, "an": {}, "and": {}, "are": {}, "as": {}, "at": {}, "be": {}, "but": {},
+	"by": {}, "for": {}, "if": {}, "in": {}, "into": {}, "is": {}, "it": {}, "no": {},
+	"not": {}, "of": {}, "on": {}, "or": {}, "such": {}, "that": {}, "the": {}, "their": {},
+	"then": {}, "there": {}, "these": {}, "they": {}, "this": {}, "to": {}, "was": {}, "will": {},
+	"with": {},
+}
+
+// StopWordFilter removes English stop words. It expects lowercase tokens.
+func StopWordFilter(tokens []string) []string {
+	filtered := tokens[:0]
+	for _, t := range tokens {
+		if _, ok := stopWords[t]; !ok {
+			filtered = append(filtered, t)
+		}
+	}
+
+	return filtered
+}
+
+// StemFilter reduces English words to their stem, using the Porter stemming algorithm.
+// It expects lowercase tokens.
+func StemFilter(tokens []string) []string {
+	for i := range tokens {
+		tokens[i] = Stem(tokens[i])
+	}
+
+	return tokens
+}
@@ -0,0 +1,86 @@
+package fulltext_test
+
+import (
+	"testing"
+
+	"github.com/genjidb/genji/index/fulltext"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzer(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"", nil},
+		{"The quick brown fox, jumping over the lazy dogs!", []string{"quick", "brown", "fox", "jump", "over", "lazi", "dog"}},
+		{"Connection CONNECTED connecting; connections", []string{"connect", "connect", "connect", "connect"}},
+		{"it is what it is", []string{"what"}},
+		{"Genji 0.10 -- naïve café", []string{"genji", "0", "10", "naïve", "café"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			terms := fulltext.DefaultAnalyzer.Analyze(test.text)
+			if len(test.expected) == 0 {
+				require.Empty(t, terms)
+				return
+			}
+			require.Equal(t, test.expected, terms)
+		})
+	}
+}
+
+func TestStem(t *testing.T) {
+	tests := map[string]string{
+		"a":              "a",
+		"is":             "is",
+		"caresses":       "caress",
+		"ponies":         "poni",
+		"ties":           "ti",
+		"caress":         "caress",
+		"cats":           "cat",
+		"feed":           "feed",
+		"agreed":         "agre",
+		"plastered":      "plaster",
+		"bled":           "bled",
+		"motoring":       "motor",
+		"sing":           "sing",
+		"conflated":      "conflat",
+		"troubled":       "troubl",
+		"sized":          "size",
+		"hopping":        "hop",
+		"tanned":         "tan",
+		"falling":        "fall",
+		"hissing":        "hiss",
+		"fizzed":         "fizz",
+		"failing":        "fail",
+		"filing":         "file",
+		"happy":          "happi",
+		"sky":            "sky",
+		"relational":     "relat",
+		"conditional":    "condit",
+		"rational":       "ration",
+		"valenci":        "valenc",
+		"digitizer":      "digit",
+		"generalization": "gener",
+		"hopefulness":    "hope",
+		"triplicate":     "triplic",
+		"formative":      "form",
+		"electrical":     "electr",
+		"goodness":       "good",
+		"revival":        "reviv",
+		"allowance":      "allow",
+		"adoption":       "adopt",
+		"homologou":      "homolog",
+		"probate":        "probat",
+		"rate":           "rate",
+		"controll":       "control",
+		"roll":           "roll",
+		"naïve":          "naïve",
+	}
+
+	for word, stem := range tests {
+		require.Equal(t, stem, fulltext.Stem(word), word)
+	}
+}
@@ -0,0 +1,443 @@
+package fulltext
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+)
+
+const (
+	// storePrefix is the prefix used to name the index stores.
+	// It is the same as the one of regular indexes, since both kinds
+	// share the same namespace.
+	storePrefix = "i"
+)
+
+// Prefixes of the keys of the index store.
+const (
+	// postings associate a term and a key with the frequency
+	// of the term in the indexed text: 'p' + term + 0x00 + key -> tf
+	postingPrefix = 'p'
+	// terms are associated with the number of keys whose text
+	// contains them: 't' + term -> df
+	termPrefix = 't'
+	// keys are associated with the number of terms of their text: 'k' + key -> length
+	keyPrefix = 'k'
+)
+
+// statsKey is associated with the number of indexed keys and the sum of their lengths.
+var statsKey = []byte{'s'}
+
+// BM25 parameters.
+const (
+	// k1 controls how fast the score saturates as the frequency of a term increases.
+	k1 = 1.2
+	// b controls how much the length of a text penalizes its score.
+	b = 0.75
+)
+
+// An Index is an inverted index which associates the terms of texts with keys.
+type Index struct {
+	Analyzer *Analyzer
+
+	tx        engine.Transaction
+	storeName []byte
+}
+
+// New creates a full-text index which uses the default analyzer.
+func New(tx engine.Transaction, idxName string) *Index {
+	return &Index{
+		Analyzer:  DefaultAnalyzer,
+		tx:        tx,
+		storeName: append([]byte(storePrefix), idxName...),
+	}
+}
+
+// Set indexes the terms of the text v and associates them with k.
+// Values other than texts are not indexed and are ignored.
+// A key must be deleted before being indexed again.
+func (idx *Index) Set(v document.Value, k []byte) error {
+	if len(k) == 0 {
+		return errors.New("cannot index value without a key")
+	}
+
+	terms := idx.terms(v)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	st, err := getOrCreateStore(idx.tx, idx.storeName)
+	if err != nil {
+		return err
+	}
+
+	length := 0
+	for term, tf := range terms {
+		err = st.Put(postingKey(term, k), encodeUints(uint64(tf)))
+		if err != nil {
+			return err
+		}
+
+		err = addUint(st, termKey(term), 1)
+		if err != nil {
+			return err
+		}
+
+		length += tf
+	}
+
+	err = st.Put(lengthKey(k), encodeUints(uint64(length)))
+	if err != nil {
+		return err
+	}
+
+	docCount, totalLength, err := idx.stats(st)
+	if err != nil {
+		return err
+	}
+
+	return st.Put(statsKey, encodeUints(docCount+1, totalLength+uint64(length)))
+}
+
+// Delete removes the association between the terms of the text v and k.
+// The value must be the one that was indexed.
+// If k is not associated with the terms of the text, it returns engine.ErrKeyNotFound.
+func (idx *Index) Delete(v document.Value, k []byte) error {
+	terms := idx.terms(v)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	st, err := idx.tx.GetStore(idx.storeName)
+	if err == engine.ErrStoreNotFound {
+		return engine.ErrKeyNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	length, err := getUint(st, lengthKey(k))
+	if err != nil {
+		return err
+	}
+
+	for term := range terms {
+		err = st.Delete(postingKey(term, k))
+		if err != nil {
+			return err
+		}
+
+		err = addUint(st, termKey(term), -1)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = st.Delete(lengthKey(k))
+	if err != nil {
+		return err
+	}
+
+	docCount, totalLength, err := idx.stats(st)
+	if err != nil {
+		return err
+	}
+
+	return st.Put(statsKey, encodeUints(docCount-1, totalLength-length))
+}
+
+// Search returns the keys whose text contains all the terms of the query,
+// ranked by relevance. It calls fn for each key, by descending score.
+// The score of a key is computed with the BM25 ranking function.
+func (idx *Index) Search(query string, fn func(k []byte, score float64) error) error {
+	terms := idx.Analyzer.Analyze(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	st, err := idx.tx.GetStore(idx.storeName)
+	if err == engine.ErrStoreNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	docCount, totalLength, err := idx.stats(st)
+	if err != nil || docCount == 0 {
+		return err
+	}
+
+	// compute the inverse document frequency of each term.
+	idfs := make(map[string]float64, len(terms))
+	rarest := ""
+	var minDF uint64
+	for _, term := range terms {
+		if _, ok := idfs[term]; ok {
+			continue
+		}
+
+		df, err := getUint(st, termKey(term))
+		if err == engine.ErrKeyNotFound {
+			// no text contains all the terms.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		idfs[term] = math.Log(1 + (float64(docCount)-float64(df)+0.5)/(float64(df)+0.5))
+		if rarest == "" || df < minDF {
+			rarest, minDF = term, df
+		}
+	}
+
+	results, err := idx.candidates(st, rarest, idfs, float64(totalLength)/float64(docCount))
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+
+		return bytes.Compare(results[i].key, results[j].key) < 0
+	})
+
+	for _, r := range results {
+		err = fn(r.key, r.score)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type result struct {
+	key   []byte
+	score float64
+}
+
+// candidates returns the keys associated with the rarest term which are
+// associated with the other terms as well, and computes their score.
+// The store is not modified while being iterated, which allows
+// the caller to modify it once the candidates are returned.
+func (idx *Index) candidates(st engine.Store, rarest string, idfs map[string]float64, avgLength float64) ([]result, error) {
+	var results []result
+
+	prefix := postingKey(rarest, nil)
+	it := st.Iterator(engine.IteratorOptions{})
+	defer it.Close()
+
+	var buf []byte
+	for it.Seek(prefix); it.Valid(); it.Next() {
+		item := it.Item()
+		if !bytes.HasPrefix(item.Key(), prefix) {
+			break
+		}
+		k := append([]byte(nil), item.Key()[len(prefix):]...)
+
+		length, err := getUint(st, lengthKey(k))
+		if err != nil {
+			return nil, err
+		}
+		norm := k1 * (1 - b + b*float64(length)/avgLength)
+
+		var score float64
+		matches := true
+		for term, idf := range idfs {
+			var tf uint64
+			if term == rarest {
+				buf, err = item.ValueCopy(buf[:0])
+				if err == nil {
+					tf, _ = binary.Uvarint(buf)
+				}
+			} else {
+				tf, err = getUint(st, postingKey(term, k))
+			}
+			if err == engine.ErrKeyNotFound {
+				matches = false
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			score += idf * float64(tf) * (k1 + 1) / (float64(tf) + norm)
+		}
+
+		if matches {
+			results = append(results, result{key: k, score: score})
+		}
+	}
+
+	return results, it.Err()
+}
+
+// Match returns true if text contains all the terms of the query,
+// once analyzed by the default analyzer. It doesn't require an index.
+func Match(text, query string) bool {
+	terms := DefaultAnalyzer.Analyze(query)
+	if len(terms) == 0 {
+		return false
+	}
+
+	set := make(map[string]struct{})
+	for _, t := range DefaultAnalyzer.Analyze(text) {
+		set[t] = struct{}{}
+	}
+
+	for _, t := range terms {
+		if _, ok := set[t]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Truncate deletes all the index data.
+func (idx *Index) Truncate() error {
+	err := idx.tx.DropStore(idx.storeName)
+	if err != nil && err != engine.ErrStoreNotFound {
+		return err
+	}
+
+	return nil
+}
+
+// Size returns the number of entries of the index
+// and the number of bytes used by their keys and values.
+func (idx *Index) Size() (n int64, size int64, err error) {
+	st, err := idx.tx.GetStore(idx.storeName)
+	if err == engine.ErrStoreNotFound {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	it := st.Iterator(engine.IteratorOptions{})
+	defer it.Close()
+
+	var buf []byte
+	for it.Seek(nil); it.Valid(); it.Next() {
+		item := it.Item()
+		buf, err = item.ValueCopy(buf[:0])
+		if err != nil {
+			return 0, 0, err
+		}
+
+		n++
+		size += int64(len(item.Key()) + len(buf))
+	}
+
+	return n, size, it.Err()
+}
+
+// terms returns the frequency of each term of v, if v is a text.
+func (idx *Index) terms(v document.Value) map[string]int {
+	if v.Type != document.TextValue {
+		return nil
+	}
+
+	terms := make(map[string]int)
+	for _, t := range idx.Analyzer.Analyze(v.V.(string)) {
+		terms[t]++
+	}
+
+	return terms
+}
+
+// stats returns the number of indexed keys and the sum of the lengths of their texts.
+func (idx *Index) stats(st engine.Store) (docCount, totalLength uint64, err error) {
+	v, err := st.Get(statsKey)
+	if err == engine.ErrKeyNotFound {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	docCount, n := binary.Uvarint(v)
+	totalLength, _ = binary.Uvarint(v[n:])
+	return docCount, totalLength, nil
+}
+
+func postingKey(term string, k []byte) []byte {
+	buf := make([]byte, 0, len(term)+len(k)+2)
+	buf = append(buf, postingPrefix)
+	buf = append(buf, term...)
+	buf = append(buf, 0)
+	return append(buf, k...)
+}
+
+func termKey(term string) []byte {
+	return append([]byte{termPrefix}, term...)
+}
+
+func lengthKey(k []byte) []byte {
+	return append([]byte{keyPrefix}, k...)
+}
+
+func encodeUints(values ...uint64) []byte {
+	buf := make([]byte, 0, len(values)*binary.MaxVarintLen64)
+	for _, v := range values {
+		var tmp [binary.MaxVarintLen64]byte
+		n := binary.PutUvarint(tmp[:], v)
+		buf = append(buf, tmp[:n]...)
+	}
+
+	return buf
+}
+
+func getUint(st engine.Store, k []byte) (uint64, error) {
+	v, err := st.Get(k)
+	if err != nil {
+		return 0, err
+	}
+
+	n, _ := binary.Uvarint(v)
+	return n, nil
+}
+
+// addUint adds delta to the integer associated with k.
+// The key is deleted when the integer reaches zero.
+func addUint(st engine.Store, k []byte, delta int64) error {
+	n, err := getUint(st, k)
+	if err != nil && err != engine.ErrKeyNotFound {
+		return err
+	}
+
+	n = uint64(int64(n) + delta)
+	if n == 0 {
+		return st.Delete(k)
+	}
+
+	return st.Put(k, encodeUints(n))
+}
+
+func getOrCreateStore(tx engine.Transaction, name []byte) (engine.Store, error) {
+	st, err := tx.GetStore(name)
+	if err == nil {
+		return st, nil
+	}
+
+	if err != engine.ErrStoreNotFound {
+		return nil, err
+	}
+
+	err = tx.CreateStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.GetStore(name)
+}
@@ -0,0 +1,102 @@
+package fulltext_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/genjidb/genji/index/fulltext"
+	"github.com/stretchr/testify/require"
+)
+
+func getIndex(t testing.TB) (*fulltext.Index, func()) {
+	ng := memoryengine.NewEngine()
+	tx, err := ng.Begin(context.Background(), engine.TxOptions{
+		Writable: true,
+	})
+	require.NoError(t, err)
+
+	idx := fulltext.New(tx, "foo")
+
+	return idx, func() {
+		tx.Rollback()
+	}
+}
+
+func search(t *testing.T, idx *fulltext.Index, query string) []string {
+	t.Helper()
+
+	var keys []string
+	var last float64
+	err := idx.Search(query, func(k []byte, score float64) error {
+		require.Greater(t, score, 0.0)
+		if len(keys) > 0 {
+			require.LessOrEqual(t, score, last)
+		}
+		last = score
+		keys = append(keys, string(k))
+		return nil
+	})
+	require.NoError(t, err)
+	return keys
+}
+
+func TestIndexSet(t *testing.T) {
+	t.Run("Set nil key fails", func(t *testing.T) {
+		idx, cleanup := getIndex(t)
+		defer cleanup()
+		require.Error(t, idx.Set(document.NewTextValue("hello"), nil))
+	})
+
+	t.Run("Values other than texts are ignored", func(t *testing.T) {
+		idx, cleanup := getIndex(t)
+		defer cleanup()
+		require.NoError(t, idx.Set(document.NewIntegerValue(10), []byte("a")))
+		require.NoError(t, idx.Set(document.NewNullValue(), []byte("b")))
+		require.NoError(t, idx.Delete(document.NewIntegerValue(10), []byte("a")))
+
+		n, _, err := idx.Size()
+		require.NoError(t, err)
+		require.Zero(t, n)
+	})
+}
+
+func TestIndexSearch(t *testing.T) {
+	idx, cleanup := getIndex(t)
+	defer cleanup()
+
+	texts := map[string]string{
+		"a": "Genji is a document-oriented, embedded SQL database written in Go.",
+		"b": "Go is an open source programming language.",
+		"c": "Documents are stored in tables, and tables can be indexed. Indexing documents makes queries faster.",
+		"d": "The database stores documents.",
+	}
+	for k, text := range texts {
+		require.NoError(t, idx.Set(document.NewTextValue(text), []byte(k)))
+	}
+
+	// shorter texts come first.
+	require.Equal(t, []string{"b", "a"}, search(t, idx, "go"))
+	// c contains the term twice, but d is much shorter.
+	require.Equal(t, []string{"d", "c", "a"}, search(t, idx, "DOCUMENT"))
+	// all the terms must match.
+	require.Equal(t, []string{"d", "a"}, search(t, idx, "databases documents"))
+	require.Empty(t, search(t, idx, "database language"))
+	require.Empty(t, search(t, idx, "unknown"))
+	require.Empty(t, search(t, idx, "the"))
+
+	require.NoError(t, idx.Delete(document.NewTextValue(texts["d"]), []byte("d")))
+	require.Equal(t, []string{"a"}, search(t, idx, "databases documents"))
+	require.Equal(t, engine.ErrKeyNotFound, idx.Delete(document.NewTextValue(texts["d"]), []byte("d")))
+
+	require.NoError(t, idx.Truncate())
+	require.Empty(t, search(t, idx, "go"))
+}
+
+func TestMatch(t *testing.T) {
+	require.True(t, fulltext.Match("Indexing documents", "document index"))
+	require.False(t, fulltext.Match("Indexing documents", "document table"))
+	require.False(t, fulltext.Match("Indexing documents", "the"))
+}
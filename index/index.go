@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/engine"
@@ -226,6 +227,126 @@ func (idx *Index) Truncate() error {
 	return nil
 }
 
+// A Builder fills an index from scratch by sorting its values in memory
+// before writing them in order, which is faster than calling Set for each value.
+type Builder struct {
+	idx     *Index
+	entries []builderEntry
+}
+
+type builderEntry struct {
+	value, key []byte
+}
+
+// NewBuilder returns a builder which replaces the content of the index.
+func (idx *Index) NewBuilder() *Builder {
+	return &Builder{idx: idx}
+}
+
+// Add a value associated with a key to the builder.
+// Nothing is written to the index until Build is called.
+func (b *Builder) Add(v document.Value, k []byte) error {
+	if len(k) == 0 {
+		return errors.New("cannot index value without a key")
+	}
+
+	if b.idx.Type != 0 && b.idx.Type != v.Type {
+		return fmt.Errorf("cannot index value of type %s in %s index", v.Type, b.idx.Type)
+	}
+
+	buf, err := b.idx.EncodeValue(v)
+	if err != nil {
+		return err
+	}
+
+	b.entries = append(b.entries, builderEntry{
+		value: buf,
+		key:   append([]byte(nil), k...),
+	})
+	return nil
+}
+
+// Build truncates the index and writes all the values added to the builder.
+// If the index is unique and a value was added more than once, it returns ErrDuplicate.
+func (b *Builder) Build() error {
+	// a stable sort keeps duplicated values in the order they were added,
+	// like successive calls to Set would.
+	sort.SliceStable(b.entries, func(i, j int) bool {
+		return bytes.Compare(b.entries[i].value, b.entries[j].value) < 0
+	})
+
+	err := b.idx.Truncate()
+	if err != nil {
+		return err
+	}
+
+	st, err := getOrCreateStore(b.idx.tx, b.idx.storeName)
+	if err != nil {
+		return err
+	}
+
+	vbuf := make([]byte, binary.MaxVarintLen64)
+	for i, e := range b.entries {
+		dup := i > 0 && bytes.Equal(e.value, b.entries[i-1].value)
+
+		buf := e.value
+		switch {
+		case b.idx.Unique && dup:
+			return ErrDuplicate
+		case b.idx.Unique:
+		case !dup:
+			// every value of a non-unique index ends with a byte that starts at zero.
+			buf = append(buf, 0)
+		default:
+			// duplicated values are suffixed the same way as in Set.
+			seq, err := st.NextSequence()
+			if err != nil {
+				return err
+			}
+			n := binary.PutUvarint(vbuf, seq)
+			buf = append(buf, vbuf[:n]...)
+			buf = append(buf, byte(n))
+		}
+
+		err = st.Put(buf, e.key)
+		if err != nil {
+			return err
+		}
+	}
+
+	b.entries = nil
+	return nil
+}
+
+// Size returns the number of entries of the index
+// and the number of bytes used by their keys and values.
+func (idx *Index) Size() (n int64, size int64, err error) {
+	st, err := idx.tx.GetStore(idx.storeName)
+	if err == engine.ErrStoreNotFound {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	it := st.Iterator(engine.IteratorOptions{})
+	defer it.Close()
+
+	var buf []byte
+	for it.Seek(nil); it.Valid(); it.Next() {
+		item := it.Item()
+		buf, err = item.ValueCopy(buf[:0])
+		if err != nil {
+			return 0, 0, err
+		}
+
+		n++
+		size += int64(len(item.Key()) + len(buf))
+	}
+
+	return n, size, it.Err()
+}
+
 // EncodeValue encodes the value we are going to use as a key,
 // If the index is typed, encode the value without expecting
 // the presence of other types.
@@ -152,6 +152,87 @@ func requireEqualEncoded(t *testing.T, expected document.Value, actual []byte) {
 	require.Equal(t, buf.Bytes(), actual)
 }
 
+func TestIndexBuilder(t *testing.T) {
+	keys := func(idx *index.Index) []string {
+		var keys []string
+		err := idx.AscendGreaterOrEqual(document.Value{Type: document.IntegerValue}, func(v, k []byte, isEqual bool) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+		require.NoError(t, err)
+		return keys
+	}
+
+	t.Run("Unique: false", func(t *testing.T) {
+		idx, cleanup := getIndex(t, false)
+		defer cleanup()
+
+		// existing values are replaced.
+		require.NoError(t, idx.Set(document.NewIntegerValue(1), []byte("old")))
+
+		b := idx.NewBuilder()
+		require.NoError(t, b.Add(document.NewIntegerValue(3), []byte("a")))
+		require.NoError(t, b.Add(document.NewIntegerValue(1), []byte("b")))
+		require.NoError(t, b.Add(document.NewIntegerValue(2), []byte("c")))
+		require.NoError(t, b.Add(document.NewIntegerValue(1), []byte("d")))
+		require.Error(t, b.Add(document.NewIntegerValue(1), nil))
+		require.NoError(t, b.Build())
+
+		require.Equal(t, []string{"b", "d", "c", "a"}, keys(idx))
+
+		// the index can be modified as if it was filled with Set.
+		require.NoError(t, idx.Set(document.NewIntegerValue(1), []byte("e")))
+		require.NoError(t, idx.Delete(document.NewIntegerValue(1), []byte("d")))
+		require.Equal(t, []string{"b", "e", "c", "a"}, keys(idx))
+	})
+
+	t.Run("Unique: true", func(t *testing.T) {
+		idx, cleanup := getIndex(t, true)
+		defer cleanup()
+
+		b := idx.NewBuilder()
+		require.NoError(t, b.Add(document.NewIntegerValue(2), []byte("a")))
+		require.NoError(t, b.Add(document.NewIntegerValue(1), []byte("b")))
+		require.NoError(t, b.Build())
+		require.Equal(t, []string{"b", "a"}, keys(idx))
+		require.Equal(t, index.ErrDuplicate, idx.Set(document.NewIntegerValue(1), []byte("c")))
+
+		b = idx.NewBuilder()
+		require.NoError(t, b.Add(document.NewIntegerValue(1), []byte("a")))
+		require.NoError(t, b.Add(document.NewIntegerValue(1), []byte("b")))
+		require.Equal(t, index.ErrDuplicate, b.Build())
+	})
+
+	t.Run("Typed", func(t *testing.T) {
+		ng := memoryengine.NewEngine()
+		tx, err := ng.Begin(context.Background(), engine.TxOptions{Writable: true})
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		idx := index.New(tx, "foo", index.Options{Type: document.IntegerValue})
+		b := idx.NewBuilder()
+		require.Error(t, b.Add(document.NewTextValue("a"), []byte("a")))
+	})
+}
+
+func TestIndexSize(t *testing.T) {
+	idx, cleanup := getIndex(t, false)
+	defer cleanup()
+
+	n, size, err := idx.Size()
+	require.NoError(t, err)
+	require.Zero(t, n)
+	require.Zero(t, size)
+
+	require.NoError(t, idx.Set(document.NewIntegerValue(10), []byte("a")))
+	require.NoError(t, idx.Set(document.NewIntegerValue(10), []byte("b")))
+
+	n, size, err = idx.Size()
+	require.NoError(t, err)
+	require.EqualValues(t, 2, n)
+	require.True(t, size > 2)
+}
+
 func TestIndexAscendGreaterThan(t *testing.T) {
 	for _, unique := range []bool{true, false} {
 		text := fmt.Sprintf("Unique: %v, ", unique)
@@ -0,0 +1,364 @@
+package spatial
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+)
+
+const (
+	// storePrefix is the prefix used to name the index stores.
+	// It is the same as the one of regular indexes, since all kinds
+	// of indexes share the same namespace.
+	storePrefix = "i"
+
+	// cellBits is the number of bits used to encode each coordinate of a cell.
+	// The cells of the finest level are about 1cm wide.
+	cellBits = 32
+
+	// maxCells is the maximum number of cells read to cover an area.
+	// Larger cells are used for larger areas.
+	maxCells = 16
+)
+
+// An Index associates points with keys. Each entry of the index store
+// associates the cell of a point and a key with the coordinates of the point:
+// cell (8 bytes, big endian) + key -> lat (8 bytes) + lon (8 bytes)
+type Index struct {
+	tx        engine.Transaction
+	storeName []byte
+}
+
+// New creates a spatial index.
+func New(tx engine.Transaction, idxName string) *Index {
+	return &Index{
+		tx:        tx,
+		storeName: append([]byte(storePrefix), idxName...),
+	}
+}
+
+// Set associates the point v with k.
+// Values that are not points are not indexed and are ignored.
+func (idx *Index) Set(v document.Value, k []byte) error {
+	if len(k) == 0 {
+		return errors.New("cannot index value without a key")
+	}
+
+	p, ok := PointFromValue(v)
+	if !ok {
+		return nil
+	}
+
+	st, err := getOrCreateStore(idx.tx, idx.storeName)
+	if err != nil {
+		return err
+	}
+
+	return st.Put(entryKey(cellOf(p), k), encodePoint(p))
+}
+
+// Delete removes the association between the point v and k.
+// The value must be the one that was indexed.
+// If k is not associated with the point, it returns engine.ErrKeyNotFound.
+func (idx *Index) Delete(v document.Value, k []byte) error {
+	p, ok := PointFromValue(v)
+	if !ok {
+		return nil
+	}
+
+	st, err := idx.tx.GetStore(idx.storeName)
+	if err == engine.ErrStoreNotFound {
+		return engine.ErrKeyNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return st.Delete(entryKey(cellOf(p), k))
+}
+
+// SearchRadius calls fn for each key whose point is at most radius meters
+// away from center, by ascending distance.
+func (idx *Index) SearchRadius(center Point, radius float64, fn func(k []byte, distance float64) error) error {
+	results, err := idx.search(BoundingBox(center, radius), func(p Point) (float64, bool) {
+		d := Distance(center, p)
+		return d, d <= radius
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].distance != results[j].distance {
+			return results[i].distance < results[j].distance
+		}
+
+		return bytes.Compare(results[i].key, results[j].key) < 0
+	})
+
+	for _, r := range results {
+		err = fn(r.key, r.distance)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SearchBox calls fn for each key whose point is inside the box, in key order.
+func (idx *Index) SearchBox(b Box, fn func(k []byte) error) error {
+	results, err := idx.search(b, func(p Point) (float64, bool) {
+		return 0, b.Contains(p)
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return bytes.Compare(results[i].key, results[j].key) < 0
+	})
+
+	for _, r := range results {
+		err = fn(r.key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type result struct {
+	key      []byte
+	distance float64
+}
+
+// search returns the keys whose point is inside the cells covering the box
+// and matches the filter. The store is not modified while being iterated,
+// which allows the caller to modify it once the results are returned.
+func (idx *Index) search(b Box, filter func(p Point) (float64, bool)) ([]result, error) {
+	st, err := idx.tx.GetStore(idx.storeName)
+	if err == engine.ErrStoreNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	it := st.Iterator(engine.IteratorOptions{})
+	defer it.Close()
+
+	var results []result
+	var buf []byte
+	var seek [8]byte
+	for _, r := range cover(b) {
+		binary.BigEndian.PutUint64(seek[:], r.lo)
+		for it.Seek(seek[:]); it.Valid(); it.Next() {
+			item := it.Item()
+			if binary.BigEndian.Uint64(item.Key()) > r.hi {
+				break
+			}
+
+			buf, err = item.ValueCopy(buf[:0])
+			if err != nil {
+				return nil, err
+			}
+
+			if d, ok := filter(decodePoint(buf)); ok {
+				k := append([]byte(nil), item.Key()[8:]...)
+				results = append(results, result{key: k, distance: d})
+			}
+		}
+		if err := it.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// Truncate deletes all the index data.
+func (idx *Index) Truncate() error {
+	err := idx.tx.DropStore(idx.storeName)
+	if err != nil && err != engine.ErrStoreNotFound {
+		return err
+	}
+
+	return nil
+}
+
+// Size returns the number of entries of the index
+// and the number of bytes used by their keys and values.
+func (idx *Index) Size() (n int64, size int64, err error) {
+	st, err := idx.tx.GetStore(idx.storeName)
+	if err == engine.ErrStoreNotFound {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	it := st.Iterator(engine.IteratorOptions{})
+	defer it.Close()
+
+	var buf []byte
+	for it.Seek(nil); it.Valid(); it.Next() {
+		item := it.Item()
+		buf, err = item.ValueCopy(buf[:0])
+		if err != nil {
+			return 0, 0, err
+		}
+
+		n++
+		size += int64(len(item.Key()) + len(buf))
+	}
+
+	return n, size, it.Err()
+}
+
+// cellRange is an inclusive range of cells.
+type cellRange struct {
+	lo, hi uint64
+}
+
+// cover returns the ranges of cells of the finest level which cover the box,
+// sorted in ascending order. It uses the finest level at which the box
+// is covered by at most maxCells cells.
+func cover(b Box) []cellRange {
+	var ranges []cellRange
+
+	for _, b := range b.split() {
+		if b.SW.Lat > b.NE.Lat {
+			continue
+		}
+
+		x0, x1 := scale(b.SW.Lon, -180, 360), scale(b.NE.Lon, -180, 360)
+		y0, y1 := scale(b.SW.Lat, -90, 180), scale(b.NE.Lat, -90, 180)
+
+		level := uint(cellBits)
+		var nx, ny uint64
+		for ; level > 0; level-- {
+			shift := cellBits - level
+			nx, ny = uint64(x1>>shift-x0>>shift)+1, uint64(y1>>shift-y0>>shift)+1
+			if nx <= maxCells && ny <= maxCells && nx*ny <= maxCells {
+				break
+			}
+		}
+		if level == 0 {
+			nx, ny = 1, 1
+		}
+
+		// a cell of the given level contains all the cells of the finest level
+		// whose code starts with its own code.
+		shift := cellBits - level
+		for i := uint64(0); i < nx; i++ {
+			for j := uint64(0); j < ny; j++ {
+				lo := interleave(x0>>shift+uint32(i), y0>>shift+uint32(j)) << (2 * shift)
+				ranges = append(ranges, cellRange{lo: lo, hi: lo | (1<<(2*shift) - 1)})
+			}
+		}
+	}
+
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].lo < ranges[j].lo
+	})
+
+	// merge contiguous ranges
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if last.hi == math.MaxUint64 || r.lo <= last.hi+1 {
+			if r.hi > last.hi {
+				last.hi = r.hi
+			}
+			continue
+		}
+
+		merged = append(merged, r)
+	}
+
+	return merged
+}
+
+// cellOf returns the cell of the finest level which contains p.
+func cellOf(p Point) uint64 {
+	return interleave(scale(p.Lon, -180, 360), scale(p.Lat, -90, 180))
+}
+
+// scale maps a coordinate within [min, min + width] to [0, 2^cellBits - 1].
+func scale(v, min, width float64) uint32 {
+	f := (v - min) / width * (1 << cellBits)
+	if f >= 1<<cellBits {
+		return math.MaxUint32
+	}
+	if f < 0 {
+		return 0
+	}
+
+	return uint32(f)
+}
+
+// interleave interleaves the bits of x and y, starting with x,
+// so that cells close to each other share a common prefix.
+func interleave(x, y uint32) uint64 {
+	return spread(x)<<1 | spread(y)
+}
+
+// spread inserts a zero bit before each bit of v.
+func spread(v uint32) uint64 {
+	u := uint64(v)
+	u = (u | u<<16) & 0x0000FFFF0000FFFF
+	u = (u | u<<8) & 0x00FF00FF00FF00FF
+	u = (u | u<<4) & 0x0F0F0F0F0F0F0F0F
+	u = (u | u<<2) & 0x3333333333333333
+	u = (u | u<<1) & 0x5555555555555555
+	return u
+}
+
+func entryKey(cell uint64, k []byte) []byte {
+	buf := make([]byte, 8, 8+len(k))
+	binary.BigEndian.PutUint64(buf, cell)
+	return append(buf, k...)
+}
+
+func encodePoint(p Point) []byte {
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[:8], math.Float64bits(p.Lat))
+	binary.BigEndian.PutUint64(buf[8:], math.Float64bits(p.Lon))
+	return buf[:]
+}
+
+func decodePoint(buf []byte) Point {
+	return Point{
+		Lat: math.Float64frombits(binary.BigEndian.Uint64(buf[:8])),
+		Lon: math.Float64frombits(binary.BigEndian.Uint64(buf[8:])),
+	}
+}
+
+func getOrCreateStore(tx engine.Transaction, name []byte) (engine.Store, error) {
+	st, err := tx.GetStore(name)
+	if err == nil {
+		return st, nil
+	}
+
+	if err != engine.ErrStoreNotFound {
+		return nil, err
+	}
+
+	err = tx.CreateStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.GetStore(name)
+}
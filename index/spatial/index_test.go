@@ -0,0 +1,188 @@
+package spatial_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/genjidb/genji/index/spatial"
+	"github.com/stretchr/testify/require"
+)
+
+func getIndex(t testing.TB) (*spatial.Index, func()) {
+	ng := memoryengine.NewEngine()
+	tx, err := ng.Begin(context.Background(), engine.TxOptions{
+		Writable: true,
+	})
+	require.NoError(t, err)
+
+	idx := spatial.New(tx, "foo")
+
+	return idx, func() {
+		tx.Rollback()
+	}
+}
+
+func searchRadius(t *testing.T, idx *spatial.Index, center spatial.Point, radius float64) []string {
+	t.Helper()
+
+	var keys []string
+	var last float64
+	err := idx.SearchRadius(center, radius, func(k []byte, distance float64) error {
+		require.LessOrEqual(t, distance, radius)
+		require.GreaterOrEqual(t, distance, last)
+		last = distance
+		keys = append(keys, string(k))
+		return nil
+	})
+	require.NoError(t, err)
+	return keys
+}
+
+func searchBox(t *testing.T, idx *spatial.Index, b spatial.Box) []string {
+	t.Helper()
+
+	var keys []string
+	err := idx.SearchBox(b, func(k []byte) error {
+		keys = append(keys, string(k))
+		return nil
+	})
+	require.NoError(t, err)
+	return keys
+}
+
+func cities(t *testing.T, idx *spatial.Index) {
+	for k, p := range map[string]spatial.Point{
+		"berlin":   berlin,
+		"fiji":     fiji,
+		"london":   london,
+		"paris":    paris,
+		"samoa":    samoa,
+		"svalbard": svalbard,
+	} {
+		require.NoError(t, idx.Set(p.Value(), []byte(k)))
+	}
+}
+
+func TestIndexSet(t *testing.T) {
+	t.Run("Set nil key fails", func(t *testing.T) {
+		idx, cleanup := getIndex(t)
+		defer cleanup()
+		require.Error(t, idx.Set(paris.Value(), nil))
+	})
+
+	t.Run("Values that are not points are ignored", func(t *testing.T) {
+		idx, cleanup := getIndex(t)
+		defer cleanup()
+
+		require.NoError(t, idx.Set(document.NewTextValue("paris"), []byte("a")))
+		require.NoError(t, idx.Set(document.NewNullValue(), []byte("b")))
+		require.NoError(t, idx.Delete(document.NewNullValue(), []byte("b")))
+
+		n, _, err := idx.Size()
+		require.NoError(t, err)
+		require.Zero(t, n)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		idx, cleanup := getIndex(t)
+		defer cleanup()
+		cities(t, idx)
+
+		require.NoError(t, idx.Delete(london.Value(), []byte("london")))
+		require.Equal(t, engine.ErrKeyNotFound, idx.Delete(london.Value(), []byte("london")))
+		require.Equal(t, engine.ErrKeyNotFound, idx.Delete(berlin.Value(), []byte("paris")))
+		require.Equal(t, []string{"paris"}, searchRadius(t, idx, paris, 500e3))
+
+		n, _, err := idx.Size()
+		require.NoError(t, err)
+		require.EqualValues(t, 5, n)
+
+		require.NoError(t, idx.Truncate())
+		require.Empty(t, searchRadius(t, idx, paris, 1000e3))
+	})
+}
+
+func TestIndexSearch(t *testing.T) {
+	idx, cleanup := getIndex(t)
+	defer cleanup()
+
+	require.Empty(t, searchRadius(t, idx, paris, 1000e3))
+	cities(t, idx)
+
+	t.Run("Radius", func(t *testing.T) {
+		require.Equal(t, []string{"paris"}, searchRadius(t, idx, paris, 0))
+		require.Equal(t, []string{"paris", "london"}, searchRadius(t, idx, paris, 400e3))
+		require.Equal(t, []string{"london", "paris", "berlin"}, searchRadius(t, idx, london, 1000e3))
+		require.Equal(t, []string{"fiji", "samoa"}, searchRadius(t, idx, fiji, 1200e3))
+		require.Equal(t, []string{"svalbard", "berlin", "london", "paris"}, searchRadius(t, idx, svalbard, 4000e3))
+		require.Len(t, searchRadius(t, idx, paris, 30000e3), 6)
+	})
+
+	t.Run("Box", func(t *testing.T) {
+		europe := spatial.Box{SW: spatial.Point{Lat: 35, Lon: -10}, NE: spatial.Point{Lat: 70, Lon: 40}}
+		require.Equal(t, []string{"berlin", "london", "paris"}, searchBox(t, idx, europe))
+
+		pacific := spatial.Box{SW: spatial.Point{Lat: -30, Lon: 170}, NE: spatial.Point{Lat: 0, Lon: -170}}
+		require.Equal(t, []string{"fiji", "samoa"}, searchBox(t, idx, pacific))
+
+		world := spatial.Box{SW: spatial.Point{Lat: -90, Lon: -180}, NE: spatial.Point{Lat: 90, Lon: 180}}
+		require.Len(t, searchBox(t, idx, world), 6)
+
+		empty := spatial.Box{SW: spatial.Point{Lat: 10, Lon: 0}, NE: spatial.Point{Lat: -10, Lon: 10}}
+		require.Empty(t, searchBox(t, idx, empty))
+	})
+}
+
+// TestIndexSearchRandom compares the results of the index with the ones
+// of a full scan, for random points and areas of various sizes.
+func TestIndexSearchRandom(t *testing.T) {
+	idx, cleanup := getIndex(t)
+	defer cleanup()
+
+	r := rand.New(rand.NewSource(42))
+	randomPoint := func() spatial.Point {
+		return spatial.Point{Lat: r.Float64()*180 - 90, Lon: r.Float64()*360 - 180}
+	}
+
+	points := make(map[string]spatial.Point)
+	for i := 0; i < 1000; i++ {
+		k := fmt.Sprintf("%04d", i)
+		points[k] = randomPoint()
+		require.NoError(t, idx.Set(points[k].Value(), []byte(k)))
+	}
+
+	scan := func(match func(p spatial.Point) bool) []string {
+		var keys []string
+		for k, p := range points {
+			if match(p) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		return keys
+	}
+
+	for i := 0; i < 100; i++ {
+		center := randomPoint()
+		// radiuses from 10km to 10000km
+		radius := 10e3 * float64(int(1)<<uint(i%11))
+
+		keys := searchRadius(t, idx, center, radius)
+		sort.Strings(keys)
+		require.Equal(t, scan(func(p spatial.Point) bool {
+			return spatial.Distance(center, p) <= radius
+		}), keys)
+
+		b := spatial.Box{SW: randomPoint(), NE: randomPoint()}
+		if b.SW.Lat > b.NE.Lat {
+			b.SW.Lat, b.NE.Lat = b.NE.Lat, b.SW.Lat
+		}
+		require.Equal(t, scan(b.Contains), searchBox(t, idx, b))
+	}
+}
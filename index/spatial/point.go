@@ -0,0 +1,155 @@
+// Package spatial implements spatial indexes, which associate geographic points
+// with keys. Points are stored under the cell of a grid that covers the earth,
+// identified by interleaving the bits of their latitude and longitude,
+// like geohashes. Points close to each other share the prefix of their cell,
+// which allows to read the points of an area by scanning a few ranges of keys.
+package spatial
+
+import (
+	"math"
+
+	"github.com/genjidb/genji/document"
+)
+
+// EarthRadius is the mean radius of the earth, in meters.
+const EarthRadius = 6371008.8
+
+// A Point is a geographic location, in degrees.
+// Documents with numeric lat and lon fields are points.
+type Point struct {
+	Lat float64 `genji:"lat"`
+	Lon float64 `genji:"lon"`
+}
+
+// PointFromValue returns the point represented by v, if v is a document
+// whose lat and lon fields are numbers within the valid ranges:
+// [-90, 90] for the latitude and [-180, 180] for the longitude.
+func PointFromValue(v document.Value) (Point, bool) {
+	if v.Type != document.DocumentValue {
+		return Point{}, false
+	}
+	d := v.V.(document.Document)
+
+	lat, ok := numberField(d, "lat")
+	if !ok || lat < -90 || lat > 90 {
+		return Point{}, false
+	}
+
+	lon, ok := numberField(d, "lon")
+	if !ok || lon < -180 || lon > 180 {
+		return Point{}, false
+	}
+
+	return Point{Lat: lat, Lon: lon}, true
+}
+
+func numberField(d document.Document, field string) (float64, bool) {
+	v, err := d.GetByField(field)
+	if err != nil {
+		return 0, false
+	}
+
+	switch v.Type {
+	case document.IntegerValue:
+		return float64(v.V.(int64)), true
+	case document.DoubleValue:
+		return v.V.(float64), true
+	}
+
+	return 0, false
+}
+
+// Value returns the point as a document value.
+func (p Point) Value() document.Value {
+	fb := document.NewFieldBuffer().
+		Add("lat", document.NewDoubleValue(p.Lat)).
+		Add("lon", document.NewDoubleValue(p.Lon))
+
+	return document.NewDocumentValue(fb)
+}
+
+// Distance returns the great-circle distance between a and b, in meters,
+// computed with the haversine formula.
+func Distance(a, b Point) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat := lat2 - lat1
+	dLon := radians(b.Lon - a.Lon)
+
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+// A Box is an area delimited by its south-west and north-east corners.
+// If the longitude of the south-west corner is greater than the longitude
+// of the north-east corner, the box crosses the antimeridian.
+type Box struct {
+	SW, NE Point
+}
+
+// Contains returns true if p is inside the box or on its edges.
+func (b Box) Contains(p Point) bool {
+	if p.Lat < b.SW.Lat || p.Lat > b.NE.Lat {
+		return false
+	}
+
+	if b.SW.Lon <= b.NE.Lon {
+		return p.Lon >= b.SW.Lon && p.Lon <= b.NE.Lon
+	}
+
+	return p.Lon >= b.SW.Lon || p.Lon <= b.NE.Lon
+}
+
+// split returns the box as one or two boxes that don't cross the antimeridian.
+func (b Box) split() []Box {
+	if b.SW.Lon <= b.NE.Lon {
+		return []Box{b}
+	}
+
+	return []Box{
+		{SW: b.SW, NE: Point{Lat: b.NE.Lat, Lon: 180}},
+		{SW: Point{Lat: b.SW.Lat, Lon: -180}, NE: b.NE},
+	}
+}
+
+// BoundingBox returns the smallest box containing the circle
+// of the given radius, in meters, around center.
+func BoundingBox(center Point, radius float64) Box {
+	// angular radius of the circle
+	r := radius / EarthRadius
+	dLat := degrees(r)
+
+	minLat, maxLat := center.Lat-dLat, center.Lat+dLat
+	// the circle contains a pole: all the longitudes are inside the box.
+	if minLat <= -90 || maxLat >= 90 {
+		return Box{
+			SW: Point{Lat: math.Max(minLat, -90), Lon: -180},
+			NE: Point{Lat: math.Min(maxLat, 90), Lon: 180},
+		}
+	}
+
+	dLon := 180.0
+	if s := math.Sin(r) / math.Cos(radians(center.Lat)); s < 1 {
+		dLon = degrees(math.Asin(s))
+	}
+	if dLon >= 180 {
+		return Box{SW: Point{Lat: minLat, Lon: -180}, NE: Point{Lat: maxLat, Lon: 180}}
+	}
+
+	minLon, maxLon := center.Lon-dLon, center.Lon+dLon
+	if minLon < -180 {
+		minLon += 360
+	}
+	if maxLon > 180 {
+		maxLon -= 360
+	}
+
+	return Box{SW: Point{Lat: minLat, Lon: minLon}, NE: Point{Lat: maxLat, Lon: maxLon}}
+}
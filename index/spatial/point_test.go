@@ -0,0 +1,98 @@
+package spatial_test
+
+import (
+	"testing"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/index/spatial"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	paris    = spatial.Point{Lat: 48.8566, Lon: 2.3522}
+	london   = spatial.Point{Lat: 51.5074, Lon: -0.1278}
+	berlin   = spatial.Point{Lat: 52.52, Lon: 13.405}
+	fiji     = spatial.Point{Lat: -17.7134, Lon: 178.065}
+	samoa    = spatial.Point{Lat: -13.759, Lon: -172.1046}
+	svalbard = spatial.Point{Lat: 78.2232, Lon: 15.6267}
+)
+
+func TestPointFromValue(t *testing.T) {
+	doc := func(fields ...interface{}) document.Value {
+		fb := document.NewFieldBuffer()
+		for i := 0; i < len(fields); i += 2 {
+			v, err := document.NewValue(fields[i+1])
+			require.NoError(t, err)
+			fb.Add(fields[i].(string), v)
+		}
+		return document.NewDocumentValue(fb)
+	}
+
+	tests := []struct {
+		name     string
+		v        document.Value
+		expected *spatial.Point
+	}{
+		{"doubles", doc("lat", 48.8566, "lon", 2.3522), &paris},
+		{"integers", doc("lon", -180, "lat", 90, "name", "north pole"), &spatial.Point{Lat: 90, Lon: -180}},
+		{"struct", spatial.Point{Lat: 1, Lon: 2}.Value(), &spatial.Point{Lat: 1, Lon: 2}},
+		{"latitude out of range", doc("lat", 90.5, "lon", 0), nil},
+		{"longitude out of range", doc("lat", 0, "lon", -181), nil},
+		{"missing field", doc("lat", 0), nil},
+		{"text field", doc("lat", 0, "lon", "0"), nil},
+		{"not a document", document.NewArrayValue(document.NewValueBuffer(document.NewDoubleValue(0), document.NewDoubleValue(0))), nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, ok := spatial.PointFromValue(test.v)
+			require.Equal(t, test.expected != nil, ok)
+			if test.expected != nil {
+				require.Equal(t, *test.expected, p)
+			}
+		})
+	}
+}
+
+func TestDistance(t *testing.T) {
+	require.Zero(t, spatial.Distance(paris, paris))
+	require.InDelta(t, 343.5e3, spatial.Distance(paris, london), 1e3)
+	require.Equal(t, spatial.Distance(paris, london), spatial.Distance(london, paris))
+	// the shortest path crosses the antimeridian.
+	require.InDelta(t, 1140e3, spatial.Distance(fiji, samoa), 10e3)
+	// half of the circumference of the earth.
+	require.InDelta(t, 20015e3, spatial.Distance(spatial.Point{Lat: 0, Lon: 0}, spatial.Point{Lat: 0, Lon: 180}), 1e3)
+}
+
+func TestBox(t *testing.T) {
+	t.Run("Contains", func(t *testing.T) {
+		europe := spatial.Box{SW: spatial.Point{Lat: 35, Lon: -10}, NE: spatial.Point{Lat: 70, Lon: 40}}
+		require.True(t, europe.Contains(paris))
+		require.True(t, europe.Contains(spatial.Point{Lat: 35, Lon: 40}))
+		require.False(t, europe.Contains(fiji))
+		require.False(t, europe.Contains(svalbard))
+
+		pacific := spatial.Box{SW: spatial.Point{Lat: -30, Lon: 170}, NE: spatial.Point{Lat: 0, Lon: -170}}
+		require.True(t, pacific.Contains(fiji))
+		require.True(t, pacific.Contains(samoa))
+		require.False(t, pacific.Contains(paris))
+	})
+
+	t.Run("BoundingBox", func(t *testing.T) {
+		b := spatial.BoundingBox(paris, 400e3)
+		require.True(t, b.Contains(london))
+		require.False(t, b.Contains(berlin))
+		require.Less(t, b.SW.Lon, b.NE.Lon)
+
+		// the box crosses the antimeridian.
+		b = spatial.BoundingBox(fiji, 1200e3)
+		require.Greater(t, b.SW.Lon, b.NE.Lon)
+		require.True(t, b.Contains(samoa))
+
+		// the box contains the north pole.
+		b = spatial.BoundingBox(svalbard, 2000e3)
+		require.Equal(t, 90.0, b.NE.Lat)
+		require.Equal(t, -180.0, b.SW.Lon)
+		require.Equal(t, 180.0, b.NE.Lon)
+	})
+}
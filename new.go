@@ -1,3 +1,4 @@
+//go:build !wasm
 // +build !wasm
 
 package genji
@@ -11,8 +12,8 @@ import (
 )
 
 // New initializes the DB using the given engine.
-func New(ctx context.Context, ng engine.Engine) (*DB, error) {
-	db, err := database.New(ctx, ng, database.Options{Codec: msgpack.NewCodec()})
+func New(ctx context.Context, ng engine.Engine, opts ...Option) (*DB, error) {
+	db, err := database.New(ctx, ng, options(opts...))
 	if err != nil {
 		return nil, err
 	}
@@ -22,3 +23,17 @@ func New(ctx context.Context, ng engine.Engine) (*DB, error) {
 		ctx: context.Background(),
 	}, nil
 }
+
+// options returns the options used to create the database.
+func options(opts ...Option) database.Options {
+	o := database.Options{
+		Codec:          msgpack.NewCodec(),
+		PrepareTrigger: prepareTrigger,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
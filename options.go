@@ -0,0 +1,40 @@
+package genji
+
+import (
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document/encoding"
+)
+
+// An Option configures the database created by New or Restore.
+type Option func(opts *database.Options)
+
+// WithCodec sets the codec used to encode documents.
+// A database must always be opened with the codec it was created with.
+func WithCodec(c encoding.Codec) Option {
+	return func(opts *database.Options) {
+		opts.Codec = c
+	}
+}
+
+// WithStatsHook sets a hook notified of the transactions and of the statistics of the queries.
+func WithStatsHook(h database.StatsHook) Option {
+	return func(opts *database.Options) {
+		opts.StatsHook = h
+	}
+}
+
+// WithNodeTimings enables the measure of the time spent in each node
+// of the query plans, reported in the statistics of the queries.
+// It slows down the queries reading many documents.
+func WithNodeTimings() Option {
+	return func(opts *database.Options) {
+		opts.NodeTimings = true
+	}
+}
+
+// WithTracer sets a tracer notified of the execution of the queries.
+func WithTracer(t database.Tracer) Option {
+	return func(opts *database.Options) {
+		opts.Tracer = t
+	}
+}
@@ -0,0 +1,785 @@
+package pgwire
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// a conn is a connection to a client.
+type conn struct {
+	id      int32
+	nc      net.Conn
+	r       *bufio.Reader
+	w       msgWriter
+	ctx     context.Context
+	cancel  func()
+	session *database.Session
+
+	stmts   map[string]*prepared
+	portals map[string]*portal
+
+	// portal described by the last message, whose row description
+	// is sent by its execution if it is the next message.
+	pending *portal
+
+	// set when an error occurs in the extended query protocol,
+	// the messages are ignored until the next Sync.
+	failed bool
+}
+
+// a prepared statement, created by a Parse message.
+type prepared struct {
+	// nil if the query is empty.
+	st *statement
+	// types of the parameters, zero if unspecified by the client.
+	paramOIDs []uint32
+}
+
+// a portal is a prepared statement bound to its parameters by a Bind message.
+type portal struct {
+	st      *statement
+	params  []expr.Param
+	formats []int16
+
+	// result of the statement, once executed.
+	res *result
+}
+
+// maxBufferedRows is the maximum number of documents of a statement held in memory.
+// The columns of a result are the fields of its first documents, up to this limit,
+// which are buffered until the row description is sent; the following documents
+// are sent while the statement runs.
+// The result of a portal that is described before its execution, or that is
+// executed with a maximum number of rows, is buffered entirely: the statement
+// fails if it returns more documents.
+const maxBufferedRows = 10000
+
+// result of a portal. The documents are copied to be sent in batches,
+// after the transaction of the statement is closed.
+type result struct {
+	columns      []column
+	docs         []document.Document
+	sent         int
+	rowsAffected int64
+}
+
+// a pgError is an error sent to the client with its SQLSTATE code.
+type pgError struct {
+	code string
+	msg  string
+}
+
+func (e *pgError) Error() string {
+	return e.msg
+}
+
+func protocolError(format string, a ...interface{}) error {
+	return &pgError{code: "08P01", msg: fmt.Sprintf(format, a...)}
+}
+
+// serve runs the startup phase and processes the messages
+// of the client until it terminates the connection.
+func (c *conn) serve() error {
+	ok, err := c.startup()
+	if err != nil || !ok {
+		return err
+	}
+
+	for {
+		typ, body, err := readMessage(c.r)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		if c.pending != nil && typ != msgExecute && typ != msgTerminate {
+			err = c.describePending()
+			if err != nil {
+				c.sendError(err)
+				c.failed = true
+			}
+		}
+
+		if c.failed && typ != msgSync && typ != msgTerminate {
+			continue
+		}
+
+		r := msgReader{buf: body}
+		switch typ {
+		case msgQuery:
+			q := r.string()
+			if r.err == nil {
+				err = c.simpleQuery(q)
+			} else {
+				err = r.err
+			}
+			if err != nil {
+				c.sendError(err)
+			}
+			err = c.readyForQuery()
+		case msgParse, msgBind, msgDescribe, msgExecute, msgClose:
+			err = c.extendedQuery(typ, &r)
+			if err != nil {
+				c.sendError(err)
+				c.failed = true
+			}
+			err = nil
+		case msgSync:
+			c.failed = false
+			err = c.readyForQuery()
+		case msgFlush:
+			err = c.w.flush()
+		case msgTerminate:
+			return nil
+		default:
+			c.sendError(protocolError("unknown message type %q", typ))
+			return c.w.flush()
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// startup reads the startup message of the client, possibly preceded by
+// requests for encryption, and accepts the connection.
+// It returns false if the client must be disconnected.
+func (c *conn) startup() (bool, error) {
+	for {
+		body, err := readBody(c.r)
+		if err != nil {
+			return false, err
+		}
+
+		r := msgReader{buf: body}
+		switch code := r.int32(); code {
+		case sslRequest, gssEncRequest:
+			// encryption is not supported.
+			if _, err := c.nc.Write([]byte{'N'}); err != nil {
+				return false, err
+			}
+			continue
+		case cancelRequest:
+			return false, nil
+		case protocolVersion:
+		default:
+			c.sendError(&pgError{code: "0A000", msg: fmt.Sprintf("unsupported protocol version %d.%d", code>>16, code&0xFFFF)})
+			return false, c.w.flush()
+		}
+
+		var appName string
+		for {
+			name := r.string()
+			if name == "" || r.err != nil {
+				break
+			}
+			if v := r.string(); name == "application_name" {
+				appName = v
+			}
+		}
+		if r.err != nil {
+			c.sendError(protocolError("invalid startup message"))
+			return false, c.w.flush()
+		}
+
+		c.w.start(msgAuthentication)
+		c.w.int32(0)
+		c.w.send()
+
+		for _, p := range [][2]string{
+			{"server_version", "13.0"},
+			{"server_encoding", "UTF8"},
+			{"client_encoding", "UTF8"},
+			{"application_name", appName},
+			{"DateStyle", "ISO, MDY"},
+			{"IntervalStyle", "postgres"},
+			{"TimeZone", "UTC"},
+			{"integer_datetimes", "on"},
+			{"standard_conforming_strings", "on"},
+		} {
+			c.w.start(msgParameterStatus)
+			c.w.string(p[0])
+			c.w.string(p[1])
+			c.w.send()
+		}
+
+		// cancel requests are ignored, the secret key is not used.
+		c.w.start(msgBackendKeyData)
+		c.w.int32(c.id)
+		c.w.int32(0)
+		c.w.send()
+
+		return true, c.readyForQuery()
+	}
+}
+
+// close rolls back the transaction left open by the client
+// and closes the connection.
+func (c *conn) close() {
+	c.cancel()
+	if tx := c.session.GetAttachedTx(); tx != nil {
+		_ = tx.Rollback()
+	}
+	c.nc.Close()
+}
+
+func (c *conn) readyForQuery() error {
+	status := byte('I')
+	if c.session.GetAttachedTx() != nil {
+		status = 'T'
+	}
+
+	c.w.start(msgReadyForQuery)
+	c.w.byte(status)
+	c.w.send()
+	return c.w.flush()
+}
+
+// sendError sends an error response, with the SQLSTATE code matching the error.
+func (c *conn) sendError(err error) {
+	var pErr *pgError
+	if !errors.As(err, &pErr) {
+		pErr = &pgError{code: errorCode(err), msg: err.Error()}
+	}
+
+	c.w.start(msgErrorResponse)
+	for _, f := range []struct {
+		typ byte
+		val string
+	}{
+		{'S', "ERROR"},
+		{'V', "ERROR"},
+		{'C', pErr.code},
+		{'M', pErr.msg},
+	} {
+		c.w.byte(f.typ)
+		c.w.string(f.val)
+	}
+	c.w.byte(0)
+	c.w.send()
+}
+
+func errorCode(err error) string {
+	var parseErr *parser.ParseError
+
+	switch {
+	case errors.As(err, &parseErr):
+		return "42601"
+	case errors.Is(err, database.ErrTableNotFound):
+		return "42P01"
+	case errors.Is(err, database.ErrIndexNotFound):
+		return "42704"
+	case errors.Is(err, database.ErrTableAlreadyExists), errors.Is(err, database.ErrIndexAlreadyExists):
+		return "42P07"
+	case errors.Is(err, database.ErrDuplicateDocument):
+		return "23505"
+	case errors.Is(err, database.ErrReadOnlyDatabase):
+		return "25006"
+	case errors.Is(err, errMalformedMessage):
+		return "08P01"
+	}
+
+	return "XX000"
+}
+
+// simpleQuery runs the statements of the query and sends their results.
+// It stops at the first error.
+func (c *conn) simpleQuery(q string) error {
+	stmts, err := parseStatements(q)
+	if err != nil {
+		return err
+	}
+
+	if len(stmts) == 0 {
+		c.w.start(msgEmptyQueryResponse)
+		c.w.send()
+		return nil
+	}
+
+	for _, st := range stmts {
+		_, err := c.stream(st, nil, nil, st.returnsRows())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// run runs the statement and calls fn with each document it returns,
+// inside the transaction of the statement.
+func (c *conn) run(st *statement, params []expr.Param, fn func(d document.Document) error) (int64, error) {
+	res, err := query.New(st.stmt).RunSession(c.ctx, c.session, params)
+	if err != nil {
+		return 0, err
+	}
+
+	err = res.Iterate(fn)
+	if cerr := res.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected, nil
+}
+
+// stream runs the statement and sends the documents it returns while it runs.
+// The first documents are buffered until the columns are known,
+// which are described first if describe is true.
+func (c *conn) stream(st *statement, params []expr.Param, formats []int16, describe bool) (*result, error) {
+	var (
+		r       result
+		started bool
+	)
+
+	start := func() error {
+		var err error
+		r.columns, err = resultColumns(r.docs, formats)
+		if err != nil {
+			return err
+		}
+		started = true
+
+		if describe {
+			c.sendRowDescription(r.columns)
+		}
+		for _, d := range r.docs {
+			if err := c.sendRow(d, r.columns); err != nil {
+				return err
+			}
+		}
+		r.sent = len(r.docs)
+		r.docs = nil
+		return nil
+	}
+
+	rowsAffected, err := c.run(st, params, func(d document.Document) error {
+		if started {
+			r.sent++
+			return c.sendRow(d, r.columns)
+		}
+
+		var fb document.FieldBuffer
+		err := fb.Copy(d)
+		if err != nil {
+			return err
+		}
+
+		r.docs = append(r.docs, &fb)
+		if len(r.docs) == maxBufferedRows {
+			return start()
+		}
+		return nil
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.rowsAffected = rowsAffected
+	c.sendCommandComplete(st, r.sent, r.rowsAffected)
+
+	// the documents are sent: executing the portal again completes it without rows.
+	r.sent = 0
+	return &r, nil
+}
+
+// execute runs the statement and copies the documents it returns,
+// up to maxBufferedRows.
+func (c *conn) execute(st *statement, params []expr.Param, formats []int16) (*result, error) {
+	var r result
+	rowsAffected, err := c.run(st, params, func(d document.Document) error {
+		if len(r.docs) == maxBufferedRows {
+			return &pgError{code: "54000", msg: fmt.Sprintf("result of portal exceeds %d rows, execute it without a row limit right after describing it", maxBufferedRows)}
+		}
+
+		var fb document.FieldBuffer
+		err := fb.Copy(d)
+		if err != nil {
+			return err
+		}
+
+		r.docs = append(r.docs, &fb)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.rowsAffected = rowsAffected
+	r.columns, err = resultColumns(r.docs, formats)
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// resultColumns returns the columns of the documents, in the given formats.
+func resultColumns(docs []document.Document, formats []int16) ([]column, error) {
+	columns, err := documentColumns(docs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range columns {
+		switch {
+		case len(formats) == 1:
+			columns[i].format = formats[0]
+		case i < len(formats):
+			columns[i].format = formats[i]
+		}
+	}
+
+	return columns, nil
+}
+
+func (c *conn) sendRowDescription(columns []column) {
+	c.w.start(msgRowDescription)
+	c.w.int16(int16(len(columns)))
+	for _, col := range columns {
+		c.w.string(col.name)
+		c.w.int32(0) // table
+		c.w.int16(0) // attribute number
+		c.w.int32(int32(col.oid))
+		c.w.int16(typeSize(col.oid))
+		c.w.int32(-1) // type modifier
+		c.w.int16(col.format)
+	}
+	c.w.send()
+}
+
+// sendRows sends at most max documents of the result, or all of them
+// if max is zero. It completes the command once all the documents are sent,
+// or suspends the portal otherwise.
+func (c *conn) sendRows(st *statement, res *result, max int) error {
+	n := len(res.docs) - res.sent
+	if max > 0 && max < n {
+		n = max
+	}
+
+	for _, d := range res.docs[res.sent : res.sent+n] {
+		if err := c.sendRow(d, res.columns); err != nil {
+			return err
+		}
+	}
+	res.sent += n
+
+	if res.sent < len(res.docs) {
+		c.w.start(msgPortalSuspended)
+		c.w.send()
+		return nil
+	}
+
+	c.sendCommandComplete(st, res.sent, res.rowsAffected)
+
+	// the portal is completed: executing it again returns no rows.
+	res.docs = nil
+	res.sent = 0
+	return nil
+}
+
+// sendRow sends the values of the columns of d.
+// The fields of d that aren't columns are not sent.
+func (c *conn) sendRow(d document.Document, columns []column) error {
+	c.w.start(msgDataRow)
+	c.w.int16(int16(len(columns)))
+	for i := range columns {
+		v, err := d.GetByField(columns[i].name)
+		if err != nil && err != document.ErrFieldNotFound {
+			return err
+		}
+		if err == document.ErrFieldNotFound {
+			v = document.NewNullValue()
+		}
+
+		data, err := encodeValue(v, &columns[i])
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			c.w.int32(-1)
+			continue
+		}
+		c.w.int32(int32(len(data)))
+		c.w.bytes(data)
+	}
+	c.w.send()
+	return nil
+}
+
+func (c *conn) sendCommandComplete(st *statement, rows int, rowsAffected int64) {
+	c.w.start(msgCommandComplete)
+	c.w.string(st.commandTag(rows, rowsAffected))
+	c.w.send()
+}
+
+// extendedQuery processes a message of the extended query protocol.
+func (c *conn) extendedQuery(typ byte, r *msgReader) error {
+	switch typ {
+	case msgParse:
+		return c.parse(r)
+	case msgBind:
+		return c.bind(r)
+	case msgDescribe:
+		return c.describe(r)
+	case msgExecute:
+		return c.executePortal(r)
+	}
+
+	return c.closeObject(r)
+}
+
+func (c *conn) parse(r *msgReader) error {
+	name := r.string()
+	q := r.string()
+	n := int(r.int16())
+	var oids []uint32
+	for i := 0; i < n && r.err == nil; i++ {
+		oids = append(oids, uint32(r.int32()))
+	}
+	if r.err != nil {
+		return r.err
+	}
+
+	if _, ok := c.stmts[name]; ok && name != "" {
+		return &pgError{code: "42P05", msg: fmt.Sprintf("prepared statement %q already exists", name)}
+	}
+
+	stmts, err := parseStatements(q)
+	if err != nil {
+		return err
+	}
+	if len(stmts) > 1 {
+		return &pgError{code: "42601", msg: "cannot insert multiple commands into a prepared statement"}
+	}
+
+	var p prepared
+	if len(stmts) == 1 {
+		p.st = stmts[0]
+		for len(oids) < p.st.params {
+			oids = append(oids, 0)
+		}
+	}
+	p.paramOIDs = oids
+	c.stmts[name] = &p
+
+	c.w.start(msgParseComplete)
+	c.w.send()
+	return nil
+}
+
+func (c *conn) bind(r *msgReader) error {
+	name := r.string()
+	stmtName := r.string()
+	paramFormats := r.int16s()
+	n := int(r.int16())
+	values := make([][]byte, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		l := r.int32()
+		if l < 0 {
+			values = append(values, nil)
+			continue
+		}
+		values = append(values, r.next(int(l)))
+	}
+	formats := r.int16s()
+	if r.err != nil {
+		return r.err
+	}
+
+	p, ok := c.stmts[stmtName]
+	if !ok {
+		return &pgError{code: "26000", msg: fmt.Sprintf("prepared statement %q does not exist", stmtName)}
+	}
+	if _, ok := c.portals[name]; ok && name != "" {
+		return &pgError{code: "42P03", msg: fmt.Sprintf("portal %q already exists", name)}
+	}
+	if len(values) != len(p.paramOIDs) {
+		return protocolError("bind message supplies %d parameters, but prepared statement %q requires %d", len(values), stmtName, len(p.paramOIDs))
+	}
+	if len(paramFormats) > 1 && len(paramFormats) != len(values) {
+		return protocolError("bind message has %d parameter formats but %d parameters", len(paramFormats), len(values))
+	}
+
+	params := make([]expr.Param, len(values))
+	for i, data := range values {
+		format := int16(textFormat)
+		switch {
+		case len(paramFormats) == 1:
+			format = paramFormats[0]
+		case len(paramFormats) > 1:
+			format = paramFormats[i]
+		}
+
+		v, err := decodeParam(data, p.paramOIDs[i], format)
+		if err != nil {
+			return &pgError{code: "22P02", msg: fmt.Sprintf("parameter $%d: %v", i+1, err)}
+		}
+
+		// $1 is parsed as a named parameter and ? as a positional one,
+		// the parameter is found in both cases.
+		params[i] = expr.Param{Name: strconv.Itoa(i + 1), Value: v.V}
+	}
+
+	c.portals[name] = &portal{st: p.st, params: params, formats: formats}
+
+	c.w.start(msgBindComplete)
+	c.w.send()
+	return nil
+}
+
+// describe describes a prepared statement or a portal.
+// The columns of a statement are only known once it is executed:
+// statements only describe their parameters, and portals of statements
+// returning documents are described when they are executed.
+// If the portal isn't executed by the next message, it is executed
+// and its result buffered to be described, see describePending.
+func (c *conn) describe(r *msgReader) error {
+	typ := r.byte()
+	name := r.string()
+	if r.err != nil {
+		return r.err
+	}
+
+	switch typ {
+	case 'S':
+		p, ok := c.stmts[name]
+		if !ok {
+			return &pgError{code: "26000", msg: fmt.Sprintf("prepared statement %q does not exist", name)}
+		}
+
+		c.w.start(msgParameterDescription)
+		c.w.int16(int16(len(p.paramOIDs)))
+		for _, oid := range p.paramOIDs {
+			if oid == 0 {
+				oid = oidJSON
+			}
+			c.w.int32(int32(oid))
+		}
+		c.w.send()
+	case 'P':
+		p, ok := c.portals[name]
+		if !ok {
+			return &pgError{code: "34000", msg: fmt.Sprintf("portal %q does not exist", name)}
+		}
+
+		if p.st != nil && p.st.returnsRows() {
+			if p.res == nil {
+				c.pending = p
+				return nil
+			}
+
+			c.sendRowDescription(p.res.columns)
+			return nil
+		}
+	default:
+		return protocolError("invalid describe message subtype %q", typ)
+	}
+
+	c.w.start(msgNoData)
+	c.w.send()
+	return nil
+}
+
+func (c *conn) executePortal(r *msgReader) error {
+	name := r.string()
+	max := int(r.int32())
+	if r.err != nil {
+		return r.err
+	}
+
+	p, ok := c.portals[name]
+	if !ok {
+		return &pgError{code: "34000", msg: fmt.Sprintf("portal %q does not exist", name)}
+	}
+
+	if c.pending != nil && (c.pending != p || max > 0) {
+		if err := c.describePending(); err != nil {
+			return err
+		}
+	}
+
+	if p.st == nil {
+		c.w.start(msgEmptyQueryResponse)
+		c.w.send()
+		return nil
+	}
+
+	if p.res == nil && max <= 0 {
+		describe := c.pending == p
+		c.pending = nil
+
+		res, err := c.stream(p.st, p.params, p.formats, describe)
+		if err != nil {
+			return err
+		}
+		p.res = res
+		return nil
+	}
+
+	if p.res == nil {
+		res, err := c.execute(p.st, p.params, p.formats)
+		if err != nil {
+			return err
+		}
+		p.res = res
+	}
+
+	return c.sendRows(p.st, p.res, max)
+}
+
+// describePending executes the portal described by the previous message,
+// which isn't followed by its execution, and sends its row description.
+func (c *conn) describePending() error {
+	p := c.pending
+	c.pending = nil
+
+	res, err := c.execute(p.st, p.params, p.formats)
+	if err != nil {
+		return err
+	}
+	p.res = res
+
+	c.sendRowDescription(p.res.columns)
+	return nil
+}
+
+func (c *conn) closeObject(r *msgReader) error {
+	typ := r.byte()
+	name := r.string()
+	if r.err != nil {
+		return r.err
+	}
+
+	switch typ {
+	case 'S':
+		delete(c.stmts, name)
+	case 'P':
+		delete(c.portals, name)
+	default:
+		return protocolError("invalid close message subtype %q", typ)
+	}
+
+	c.w.start(msgCloseComplete)
+	c.w.send()
+	return nil
+}
@@ -0,0 +1,13 @@
+module github.com/genjidb/genji/pgwire
+
+go 1.15
+
+require (
+	github.com/genjidb/genji v0.10.0
+	github.com/jackc/pgconn v1.14.3
+	github.com/jackc/pgproto3/v2 v2.3.3
+	github.com/jackc/pgx/v4 v4.18.3
+	github.com/stretchr/testify v1.8.1
+)
+
+replace github.com/genjidb/genji v0.10.0 => ../
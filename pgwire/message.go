@@ -0,0 +1,195 @@
+package pgwire
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// maxMessageSize is the maximum size of a message sent by a client.
+const maxMessageSize = 1 << 30
+
+// Types of the messages sent by clients.
+const (
+	msgBind      = 'B'
+	msgClose     = 'C'
+	msgDescribe  = 'D'
+	msgExecute   = 'E'
+	msgFlush     = 'H'
+	msgParse     = 'P'
+	msgQuery     = 'Q'
+	msgSync      = 'S'
+	msgTerminate = 'X'
+)
+
+// Types of the messages sent by the server.
+const (
+	msgAuthentication       = 'R'
+	msgBackendKeyData       = 'K'
+	msgBindComplete         = '2'
+	msgCloseComplete        = '3'
+	msgCommandComplete      = 'C'
+	msgDataRow              = 'D'
+	msgEmptyQueryResponse   = 'I'
+	msgErrorResponse        = 'E'
+	msgNoData               = 'n'
+	msgParameterDescription = 't'
+	msgParameterStatus      = 'S'
+	msgParseComplete        = '1'
+	msgPortalSuspended      = 's'
+	msgReadyForQuery        = 'Z'
+	msgRowDescription       = 'T'
+)
+
+// Codes of the messages sent by clients before the startup message.
+const (
+	protocolVersion = 3 << 16
+	sslRequest      = 80877103
+	gssEncRequest   = 80877104
+	cancelRequest   = 80877102
+)
+
+var errMalformedMessage = errors.New("malformed message")
+
+// readBody reads the length of a message followed by its body.
+func readBody(r io.Reader) ([]byte, error) {
+	var hdr [4]byte
+	_, err := io.ReadFull(r, hdr[:])
+	if err != nil {
+		return nil, err
+	}
+
+	n := int64(binary.BigEndian.Uint32(hdr[:])) - 4
+	if n < 0 || n > maxMessageSize {
+		return nil, errMalformedMessage
+	}
+
+	body := make([]byte, n)
+	_, err = io.ReadFull(r, body)
+	return body, err
+}
+
+// readMessage reads the type and the body of a message sent after the startup.
+func readMessage(r *bufio.Reader) (byte, []byte, error) {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	body, err := readBody(r)
+	return typ, body, err
+}
+
+// a msgReader reads the fields of the body of a message.
+// If the body is too short, the reader returns zero values and its err field
+// is set to errMalformedMessage.
+type msgReader struct {
+	buf []byte
+	err error
+}
+
+func (r *msgReader) next(n int) []byte {
+	if r.err != nil || n < 0 || len(r.buf) < n {
+		r.err = errMalformedMessage
+		return nil
+	}
+
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *msgReader) byte() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *msgReader) int16() int16 {
+	if b := r.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (r *msgReader) int32() int32 {
+	if b := r.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+// string reads a null-terminated string.
+func (r *msgReader) string() string {
+	for i, c := range r.buf {
+		if c == 0 {
+			s := string(r.buf[:i])
+			r.buf = r.buf[i+1:]
+			return s
+		}
+	}
+
+	r.err = errMalformedMessage
+	return ""
+}
+
+// int16s reads a list of int16 preceded by its length.
+func (r *msgReader) int16s() []int16 {
+	n := int(r.int16())
+	if n < 0 {
+		r.err = errMalformedMessage
+	}
+
+	var l []int16
+	for i := 0; i < n && r.err == nil; i++ {
+		l = append(l, r.int16())
+	}
+	return l
+}
+
+// a msgWriter buffers the messages sent to the client.
+// Write errors are returned by flush.
+type msgWriter struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+// start starts a message of the given type.
+func (w *msgWriter) start(typ byte) {
+	w.buf = append(w.buf[:0], typ, 0, 0, 0, 0)
+}
+
+func (w *msgWriter) byte(b byte) {
+	w.buf = append(w.buf, b)
+}
+
+func (w *msgWriter) int16(n int16) {
+	w.buf = append(w.buf, byte(n>>8), byte(n))
+}
+
+func (w *msgWriter) int32(n int32) {
+	w.buf = append(w.buf, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+// string writes a null-terminated string.
+func (w *msgWriter) string(s string) {
+	w.buf = append(w.buf, s...)
+	w.buf = append(w.buf, 0)
+}
+
+func (w *msgWriter) bytes(b []byte) {
+	w.buf = append(w.buf, b...)
+}
+
+// send completes the message and writes it to the buffer.
+func (w *msgWriter) send() {
+	binary.BigEndian.PutUint32(w.buf[1:5], uint32(len(w.buf)-1))
+	// the error is returned by the next call to flush.
+	_, _ = w.w.Write(w.buf)
+}
+
+func (w *msgWriter) flush() error {
+	return w.w.Flush()
+}
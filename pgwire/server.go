@@ -0,0 +1,176 @@
+// Package pgwire serves a Genji database over the PostgreSQL wire protocol,
+// allowing existing PostgreSQL clients and tools to query it.
+//
+// Both the simple and the extended query protocols are supported.
+// Statements are written in Genji SQL, and the parameters of prepared statements
+// are referenced with $1, $2, etc. Documents are returned as rows whose columns
+// are their top-level fields; arrays and nested documents are returned as JSON.
+// Results are sent while the statement runs: their columns are the fields of their
+// first 10000 documents, which are buffered. The following documents are sent with
+// these columns only, and their values converted to the type of the columns.
+//
+// Since tables have no fixed schema, the parameters whose type isn't specified
+// by the client are described as JSON: their value is parsed as JSON, or used as
+// text if it isn't valid JSON. Text parameters that look like JSON values, like
+// '1' or 'true', must therefore be sent with the text type.
+//
+// Each connection has its own session: statements run in their own transaction
+// unless a transaction was opened with BEGIN.
+// Clients are not authenticated and SSL is not supported.
+package pgwire
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/genjidb/genji"
+)
+
+// ErrServerClosed is returned by Serve after a call to Close.
+var ErrServerClosed = errors.New("pgwire: server closed")
+
+// A Server serves a database to PostgreSQL clients.
+type Server struct {
+	db *genji.DB
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[*conn]struct{}
+	nextID    int32
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// NewServer returns a server for db.
+func NewServer(db *genji.DB) *Server {
+	return &Server{
+		db:        db,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[*conn]struct{}),
+	}
+}
+
+// Serve accepts connections on l and serves each of them in its own goroutine.
+// It returns ErrServerClosed once the server is closed.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+	}()
+
+	var delay time.Duration
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+
+			// retry on temporary errors, like net/http.
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				if delay == 0 {
+					delay = 5 * time.Millisecond
+				} else if delay *= 2; delay > time.Second {
+					delay = time.Second
+				}
+				time.Sleep(delay)
+				continue
+			}
+
+			return err
+		}
+		delay = 0
+
+		c := s.newConn(nc)
+		if c == nil {
+			nc.Close()
+			return ErrServerClosed
+		}
+
+		go func() {
+			defer s.wg.Done()
+			defer s.removeConn(c)
+
+			_ = c.serve()
+		}()
+	}
+}
+
+// Close closes the listeners and the connections of the server and waits
+// for the connections to be closed. Transactions left open by clients
+// are rolled back.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+
+	var err error
+	for l := range s.listeners {
+		if lerr := l.Close(); lerr != nil && err == nil {
+			err = lerr
+		}
+	}
+	for c := range s.conns {
+		c.cancel()
+		c.nc.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed
+}
+
+// newConn registers a connection. It returns nil if the server is closed.
+func (s *Server) newConn(nc net.Conn) *conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+
+	s.nextID++
+	ctx, cancel := context.WithCancel(context.Background())
+	c := conn{
+		id:      s.nextID,
+		nc:      nc,
+		r:       bufio.NewReader(nc),
+		w:       msgWriter{w: bufio.NewWriter(nc)},
+		ctx:     ctx,
+		cancel:  cancel,
+		session: s.db.DB.NewSession(),
+		stmts:   make(map[string]*prepared),
+		portals: make(map[string]*portal),
+	}
+
+	s.conns[&c] = struct{}{}
+	s.wg.Add(1)
+	return &c
+}
+
+func (s *Server) removeConn(c *conn) {
+	c.close()
+
+	s.mu.Lock()
+	delete(s.conns, c)
+	s.mu.Unlock()
+}
@@ -0,0 +1,436 @@
+package pgwire_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/pgwire"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func newConn(t *testing.T) (*genji.DB, *pgx.Conn) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+
+	err = db.Exec(`
+		CREATE TABLE foo (a INTEGER);
+		INSERT INTO foo (a, b) VALUES (1, 'x'), (2, 'y'), (3, 'z');
+	`)
+	require.NoError(t, err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := pgwire.NewServer(db)
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(l)
+	}()
+
+	conn, err := pgx.Connect(context.Background(), "postgres://genji@"+l.Addr().String()+"/genji")
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close(context.Background())
+		require.NoError(t, srv.Close())
+		require.Equal(t, pgwire.ErrServerClosed, <-done)
+		require.NoError(t, db.Close())
+	})
+
+	return db, conn
+}
+
+func TestSimpleQuery(t *testing.T) {
+	ctx := context.Background()
+	_, conn := newConn(t)
+
+	results, err := conn.PgConn().Exec(ctx, `
+		CREATE TABLE bar;
+		INSERT INTO bar (a) VALUES (1), (2);
+		SELECT a, b FROM foo WHERE a > 1;
+		SELECT * FROM bar WHERE a > 10;
+		DELETE FROM bar;
+	`).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+
+	var tags []string
+	for _, r := range results {
+		require.NoError(t, r.Err)
+		tags = append(tags, string(r.CommandTag))
+	}
+	require.Equal(t, []string{"CREATE TABLE", "INSERT 0 2", "SELECT 2", "SELECT 0", "DELETE 0"}, tags)
+
+	require.Len(t, results[2].FieldDescriptions, 2)
+	require.Equal(t, [][][]byte{{[]byte("2"), []byte("y")}, {[]byte("3"), []byte("z")}}, results[2].Rows)
+
+	// the statements following an error are not executed.
+	_, err = conn.PgConn().Exec(ctx, "INSERT INTO foo (a) VALUES (4); SELECT * FROM unknown; INSERT INTO foo (a) VALUES (5)").ReadAll()
+	require.Error(t, err)
+	var n int
+	require.NoError(t, conn.QueryRow(ctx, "SELECT COUNT(*) FROM foo").Scan(&n))
+	require.Equal(t, 4, n)
+
+	// no statement is executed if the query can't be parsed.
+	_, err = conn.PgConn().Exec(ctx, "INSERT INTO foo (a) VALUES (5); SELEC 1").ReadAll()
+	require.Error(t, err)
+	require.NoError(t, conn.QueryRow(ctx, "SELECT COUNT(*) FROM foo").Scan(&n))
+	require.Equal(t, 4, n)
+
+	results, err = conn.PgConn().Exec(ctx, " ; ").ReadAll()
+	require.NoError(t, err)
+	require.Len(t, results, 0)
+}
+
+func TestExtendedQuery(t *testing.T) {
+	ctx := context.Background()
+	_, conn := newConn(t)
+
+	t.Run("Params", func(t *testing.T) {
+		rows, err := conn.Query(ctx, "SELECT a, b FROM foo WHERE a >= $1 AND b != $2", 2, "z")
+		require.NoError(t, err)
+		defer rows.Close()
+
+		require.True(t, rows.Next())
+		var a int64
+		var b string
+		require.NoError(t, rows.Scan(&a, &b))
+		require.Equal(t, int64(2), a)
+		require.Equal(t, "y", b)
+		require.False(t, rows.Next())
+		require.NoError(t, rows.Err())
+		require.Equal(t, "SELECT 1", string(rows.CommandTag()))
+	})
+
+	t.Run("Types", func(t *testing.T) {
+		var (
+			i   int64
+			f   float64
+			s   string
+			bl  bool
+			bb  []byte
+			doc map[string]interface{}
+			arr []interface{}
+			n   *string
+		)
+
+		err := conn.QueryRow(ctx, "SELECT 1 AS i, 1.5 AS f, 'a' AS s, true AS bl, CAST('qv8=' AS BLOB) AS bb, $1 AS doc, [1, 'a'] AS arr, NULL AS n",
+			map[string]interface{}{"a": map[string]interface{}{"b": 1}},
+		).Scan(&i, &f, &s, &bl, &bb, &doc, &arr, &n)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), i)
+		require.Equal(t, 1.5, f)
+		require.Equal(t, "a", s)
+		require.True(t, bl)
+		require.Equal(t, []byte{0xAA, 0xFF}, bb)
+		require.Equal(t, map[string]interface{}{"a": map[string]interface{}{"b": float64(1)}}, doc)
+		require.Equal(t, []interface{}{float64(1), "a"}, arr)
+		require.Nil(t, n)
+	})
+
+	t.Run("Columns", func(t *testing.T) {
+		_, err := conn.Exec(ctx, "CREATE TABLE baz; INSERT INTO baz (a) VALUES (1); INSERT INTO baz (b, a) VALUES ('x', 1.5); INSERT INTO baz (b) VALUES (true)")
+		require.NoError(t, err)
+
+		rows, err := conn.Query(ctx, "SELECT * FROM baz")
+		require.NoError(t, err)
+		defer rows.Close()
+
+		var names []string
+		for _, fd := range rows.FieldDescriptions() {
+			names = append(names, string(fd.Name))
+		}
+		// columns are the union of the fields of the documents.
+		require.Equal(t, []string{"a", "b"}, names)
+
+		var values [][]interface{}
+		for rows.Next() {
+			var a *float64
+			var b interface{}
+			require.NoError(t, rows.Scan(&a, &b))
+			var av interface{}
+			if a != nil {
+				av = *a
+			}
+			values = append(values, []interface{}{av, b})
+		}
+		require.NoError(t, rows.Err())
+		// integers and doubles are returned as doubles,
+		// values of different types as JSON.
+		require.Equal(t, [][]interface{}{{1.0, nil}, {1.5, "x"}, {nil, true}}, values)
+	})
+
+	t.Run("Exec", func(t *testing.T) {
+		tag, err := conn.Exec(ctx, "INSERT INTO foo (a) VALUES ($1), ($2)", 10, 11)
+		require.NoError(t, err)
+		require.Equal(t, "INSERT 0 2", string(tag))
+
+		tag, err = conn.Exec(ctx, "DELETE FROM foo WHERE a >= $1", 10)
+		require.NoError(t, err)
+		require.Equal(t, "DELETE 0", string(tag))
+
+		var n int
+		require.NoError(t, conn.QueryRow(ctx, "SELECT COUNT(*) FROM foo").Scan(&n))
+		require.Equal(t, 3, n)
+	})
+
+	t.Run("Text params", func(t *testing.T) {
+		// text that isn't valid JSON is passed as text.
+		var a int
+		require.NoError(t, conn.QueryRow(ctx, "SELECT a FROM foo WHERE b = $1", "y").Scan(&a))
+		require.Equal(t, 2, a)
+	})
+
+	t.Run("Prepared", func(t *testing.T) {
+		sd, err := conn.Prepare(ctx, "byA", "SELECT b FROM foo WHERE a = ?")
+		require.NoError(t, err)
+		require.Len(t, sd.ParamOIDs, 1)
+
+		for a, b := range map[int]string{1: "x", 3: "z"} {
+			var s string
+			require.NoError(t, conn.QueryRow(ctx, "byA", a).Scan(&s))
+			require.Equal(t, b, s)
+		}
+	})
+}
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	_, conn := newConn(t)
+
+	tests := []struct {
+		query    string
+		code     string
+		extended bool
+	}{
+		{"SELEC 1", "42601", false},
+		{"SELECT * FROM unknown", "42P01", false},
+		{"CREATE TABLE foo", "42P07", false},
+		{"SELECT 1; SELECT 2", "42601", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			check := func(err error) {
+				var pgErr *pgconn.PgError
+				require.True(t, errors.As(err, &pgErr), err)
+				require.Equal(t, test.code, pgErr.Code)
+
+				// the connection can still be used.
+				var n int
+				require.NoError(t, conn.QueryRow(ctx, "SELECT 1").Scan(&n))
+			}
+
+			if !test.extended {
+				_, err := conn.PgConn().Exec(ctx, test.query).ReadAll()
+				check(err)
+			}
+
+			rows, err := conn.Query(ctx, test.query)
+			if err == nil {
+				rows.Close()
+				err = rows.Err()
+			}
+			check(err)
+		})
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	ctx := context.Background()
+	db, conn := newConn(t)
+
+	tx, err := conn.Begin(ctx)
+	require.NoError(t, err)
+	require.Equal(t, byte('T'), conn.PgConn().TxStatus())
+
+	_, err = tx.Exec(ctx, "DELETE FROM foo")
+	require.NoError(t, err)
+	var n int
+	require.NoError(t, tx.QueryRow(ctx, "SELECT COUNT(*) FROM foo").Scan(&n))
+	require.Zero(t, n)
+
+	require.NoError(t, tx.Rollback(ctx))
+	require.Equal(t, byte('I'), conn.PgConn().TxStatus())
+	require.NoError(t, conn.QueryRow(ctx, "SELECT COUNT(*) FROM foo").Scan(&n))
+	require.Equal(t, 3, n)
+
+	tx, err = conn.Begin(ctx)
+	require.NoError(t, err)
+	_, err = tx.Exec(ctx, "INSERT INTO foo (a) VALUES (4)")
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit(ctx))
+
+	res, err := db.QueryDocument("SELECT COUNT(*) FROM foo")
+	require.NoError(t, err)
+	v, err := res.GetByField("COUNT(*)")
+	require.NoError(t, err)
+	require.EqualValues(t, 4, v.V)
+
+	// transactions left open are rolled back when the client disconnects.
+	_, err = conn.Exec(ctx, "BEGIN; DELETE FROM foo")
+	require.NoError(t, err)
+	require.NoError(t, conn.Close(ctx))
+
+	res, err = db.QueryDocument("SELECT COUNT(*) FROM foo")
+	require.NoError(t, err)
+	v, err = res.GetByField("COUNT(*)")
+	require.NoError(t, err)
+	require.EqualValues(t, 4, v.V)
+}
+
+func TestLargeResult(t *testing.T) {
+	ctx := context.Background()
+	db, conn := newConn(t)
+
+	// the documents following the first maxBufferedRows (10000) ones are streamed,
+	// with the columns and types of the first ones.
+	const total = 10050
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	err = tx.Exec("CREATE TABLE big (id INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+	for i := 0; i < total; i++ {
+		if i < 10000 {
+			err = tx.Exec("INSERT INTO big (id, a) VALUES (?, ?)", i, i)
+		} else {
+			err = tx.Exec("INSERT INTO big (id, a, b) VALUES (?, ?, 'ignored')", i, strconv.Itoa(i))
+		}
+		require.NoError(t, err)
+	}
+	require.NoError(t, tx.Commit())
+
+	check := func(rows pgx.Rows) {
+		defer rows.Close()
+
+		require.Len(t, rows.FieldDescriptions(), 2)
+		var n int64
+		for rows.Next() {
+			var id, a int64
+			require.NoError(t, rows.Scan(&id, &a))
+			require.Equal(t, n, id)
+			require.Equal(t, n, a)
+			n++
+		}
+		require.NoError(t, rows.Err())
+		require.EqualValues(t, total, n)
+		require.Equal(t, "SELECT 10050", string(rows.CommandTag()))
+	}
+
+	rows, err := conn.Query(ctx, "SELECT * FROM big", pgx.QuerySimpleProtocol(true))
+	require.NoError(t, err)
+	check(rows)
+
+	rows, err = conn.Query(ctx, "SELECT * FROM big")
+	require.NoError(t, err)
+	check(rows)
+
+	// the result of a portal executed in batches is bounded.
+	msgs := roundTrip(t, conn.PgConn(),
+		&pgproto3.Parse{Query: "SELECT * FROM big"},
+		&pgproto3.Bind{DestinationPortal: "p"},
+		&pgproto3.Execute{Portal: "p", MaxRows: 10},
+		&pgproto3.Sync{},
+	)
+	require.Equal(t, []string{"ParseComplete", "BindComplete", "ErrorResponse 54000", "ReadyForQuery"}, msgs)
+}
+
+func TestPortals(t *testing.T) {
+	_, conn := newConn(t)
+
+	// portals executed in batches.
+	msgs := roundTrip(t, conn.PgConn(),
+		&pgproto3.Parse{Query: "SELECT a FROM foo"},
+		&pgproto3.Bind{DestinationPortal: "p"},
+		&pgproto3.Describe{ObjectType: 'P', Name: "p"},
+		&pgproto3.Execute{Portal: "p", MaxRows: 2},
+		&pgproto3.Execute{Portal: "p", MaxRows: 2},
+		&pgproto3.Sync{},
+	)
+	require.Equal(t, []string{
+		"ParseComplete", "BindComplete", "RowDescription a",
+		"DataRow 1", "DataRow 2", "PortalSuspended",
+		"DataRow 3", "CommandComplete SELECT 3",
+		"ReadyForQuery",
+	}, msgs)
+
+	// portals described without being executed by the next message.
+	msgs = roundTrip(t, conn.PgConn(),
+		&pgproto3.Bind{DestinationPortal: "q"},
+		&pgproto3.Describe{ObjectType: 'P', Name: "q"},
+		&pgproto3.Sync{},
+	)
+	require.Equal(t, []string{"BindComplete", "RowDescription a", "ReadyForQuery"}, msgs)
+
+	msgs = roundTrip(t, conn.PgConn(),
+		&pgproto3.Execute{Portal: "q"},
+		&pgproto3.Execute{Portal: "q"},
+		&pgproto3.Sync{},
+	)
+	require.Equal(t, []string{
+		"DataRow 1", "DataRow 2", "DataRow 3", "CommandComplete SELECT 3",
+		"CommandComplete SELECT 0",
+		"ReadyForQuery",
+	}, msgs)
+
+	// portals described and executed by the next message are streamed.
+	msgs = roundTrip(t, conn.PgConn(),
+		&pgproto3.Bind{DestinationPortal: "r"},
+		&pgproto3.Describe{ObjectType: 'P', Name: "r"},
+		&pgproto3.Execute{Portal: "r"},
+		&pgproto3.Sync{},
+	)
+	require.Equal(t, []string{
+		"BindComplete", "RowDescription a",
+		"DataRow 1", "DataRow 2", "DataRow 3", "CommandComplete SELECT 3",
+		"ReadyForQuery",
+	}, msgs)
+}
+
+// roundTrip sends the messages and returns a summary
+// of the messages received until the server is ready for a query.
+func roundTrip(t *testing.T, conn *pgconn.PgConn, msgs ...pgproto3.FrontendMessage) []string {
+	ctx := context.Background()
+
+	var buf []byte
+	for _, msg := range msgs {
+		var err error
+		buf, err = msg.Encode(buf)
+		require.NoError(t, err)
+	}
+	require.NoError(t, conn.SendBytes(ctx, buf))
+
+	var res []string
+	for {
+		msg, err := conn.ReceiveMessage(ctx)
+		require.NoError(t, err)
+
+		switch msg := msg.(type) {
+		case *pgproto3.RowDescription:
+			s := "RowDescription"
+			for _, f := range msg.Fields {
+				s += " " + string(f.Name)
+			}
+			res = append(res, s)
+		case *pgproto3.DataRow:
+			res = append(res, "DataRow "+string(msg.Values[0]))
+		case *pgproto3.CommandComplete:
+			res = append(res, "CommandComplete "+string(msg.CommandTag))
+		case *pgproto3.ErrorResponse:
+			res = append(res, "ErrorResponse "+msg.Code)
+		case *pgproto3.ReadyForQuery:
+			return append(res, "ReadyForQuery")
+		default:
+			res = append(res, strings.TrimPrefix(fmt.Sprintf("%T", msg), "*pgproto3."))
+		}
+	}
+}
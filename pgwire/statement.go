@@ -0,0 +1,116 @@
+package pgwire
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// a statement is a parsed statement of a query.
+type statement struct {
+	stmt query.Statement
+	// command run by the statement, like SELECT or CREATE TABLE.
+	command string
+	// number of parameters referenced by the statement.
+	params int
+}
+
+// returnsRows returns true if the statement returns documents.
+func (s *statement) returnsRows() bool {
+	return s.command == "SELECT" || s.command == "EXPLAIN"
+}
+
+// commandTag returns the tag sent to the client once the statement
+// is executed.
+func (s *statement) commandTag(rows int, rowsAffected int64) string {
+	switch s.command {
+	case "SELECT":
+		return fmt.Sprintf("SELECT %d", rows)
+	case "INSERT":
+		return fmt.Sprintf("INSERT 0 %d", rowsAffected)
+	case "UPDATE", "DELETE":
+		return fmt.Sprintf("%s %d", s.command, rowsAffected)
+	}
+
+	return s.command
+}
+
+// parseStatements splits q into statements and parses them individually.
+// PostgreSQL parameters ($1, $2, ...) are parsed as named parameters,
+// and the positional parameters of Genji (?) are also supported.
+func parseStatements(q string) ([]*statement, error) {
+	var stmts []*statement
+	var buf strings.Builder
+	var toks []scanner.Token
+	var params, positional int
+
+	parse := func() error {
+		defer func() {
+			buf.Reset()
+			toks = toks[:0]
+			params, positional = 0, 0
+		}()
+
+		if len(toks) == 0 {
+			return nil
+		}
+
+		pq, err := parser.ParseQuery(buf.String())
+		if err != nil {
+			return err
+		}
+
+		if positional > params {
+			params = positional
+		}
+		stmts = append(stmts, &statement{stmt: pq.Statements[0], command: commandName(toks), params: params})
+		return nil
+	}
+
+	sc := scanner.NewScanner(strings.NewReader(q))
+	for {
+		ti := sc.Scan()
+		switch ti.Tok {
+		case scanner.EOF:
+			return stmts, parse()
+		case scanner.SEMICOLON:
+			if err := parse(); err != nil {
+				return nil, err
+			}
+			continue
+		case scanner.WS, scanner.COMMENT:
+		case scanner.NAMEDPARAM:
+			if n, err := strconv.Atoi(ti.Lit[1:]); err == nil && n > params {
+				params = n
+			}
+			toks = append(toks, ti.Tok)
+		case scanner.POSITIONALPARAM:
+			positional++
+			toks = append(toks, ti.Tok)
+		default:
+			toks = append(toks, ti.Tok)
+		}
+
+		buf.WriteString(ti.Raw)
+	}
+}
+
+// commandName returns the command run by a statement, given its tokens.
+func commandName(toks []scanner.Token) string {
+	cmd := toks[0].String()
+
+	switch toks[0] {
+	case scanner.CREATE, scanner.DROP, scanner.ALTER:
+		for _, tok := range toks[1:] {
+			if tok != scanner.UNIQUE && tok != scanner.FULLTEXT && tok != scanner.SPATIAL {
+				return cmd + " " + tok.String()
+			}
+		}
+	}
+
+	return cmd
+}
@@ -0,0 +1,352 @@
+package pgwire
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/genjidb/genji/document"
+)
+
+// OIDs of the PostgreSQL types used by the server.
+const (
+	oidBool    = 16
+	oidBytea   = 17
+	oidName    = 19
+	oidInt8    = 20
+	oidInt2    = 21
+	oidInt4    = 23
+	oidText    = 25
+	oidJSON    = 114
+	oidFloat4  = 700
+	oidFloat8  = 701
+	oidUnknown = 705
+	oidBpchar  = 1042
+	oidVarchar = 1043
+	oidNumeric = 1700
+	oidJSONB   = 3802
+)
+
+// Format codes of parameters and columns.
+const (
+	textFormat   = 0
+	binaryFormat = 1
+)
+
+// typeOID returns the PostgreSQL type of the values of type t.
+// Arrays and documents are returned as JSON.
+func typeOID(t document.ValueType) uint32 {
+	switch t {
+	case document.BoolValue:
+		return oidBool
+	case document.IntegerValue:
+		return oidInt8
+	case document.DoubleValue:
+		return oidFloat8
+	case document.BlobValue:
+		return oidBytea
+	case document.ArrayValue, document.DocumentValue:
+		return oidJSON
+	}
+
+	return oidText
+}
+
+// typeSize returns the size of the values of the type, or -1 if their size is variable.
+func typeSize(oid uint32) int16 {
+	switch oid {
+	case oidBool:
+		return 1
+	case oidInt8, oidFloat8:
+		return 8
+	}
+
+	return -1
+}
+
+// a column of the documents returned by a statement.
+type column struct {
+	name   string
+	oid    uint32
+	format int16
+
+	// type of the non-null values of the column.
+	typ   document.ValueType
+	mixed bool
+}
+
+func (c *column) add(t document.ValueType) {
+	switch {
+	case t == document.NullValue || t == c.typ:
+	case c.typ == 0:
+		c.typ = t
+	case isNumber(t) && isNumber(c.typ):
+		c.typ = document.DoubleValue
+	default:
+		c.mixed = true
+	}
+}
+
+func isNumber(t document.ValueType) bool {
+	return t == document.IntegerValue || t == document.DoubleValue
+}
+
+// documentColumns returns the columns of a list of documents: the union of
+// their top-level fields, in order of appearance. The type of a column is
+// the type of its values, double if they are integers and doubles, or JSON
+// if they are of different types.
+func documentColumns(docs []document.Document) ([]column, error) {
+	var cols []column
+	idx := make(map[string]int)
+
+	for _, d := range docs {
+		err := d.Iterate(func(field string, v document.Value) error {
+			i, ok := idx[field]
+			if !ok {
+				i = len(cols)
+				idx[field] = i
+				cols = append(cols, column{name: field})
+			}
+
+			cols[i].add(v.Type)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range cols {
+		if cols[i].mixed {
+			cols[i].oid = oidJSON
+		} else {
+			cols[i].oid = typeOID(cols[i].typ)
+		}
+	}
+
+	return cols, nil
+}
+
+// encodeValue encodes v as a value of the column.
+// It returns nil if v is null.
+func encodeValue(v document.Value, c *column) ([]byte, error) {
+	if v.Type == document.NullValue {
+		return nil, nil
+	}
+
+	if c.oid == oidJSON {
+		return v.MarshalJSON()
+	}
+
+	// the columns are determined by the first documents of a result,
+	// the values of the following ones are converted to the type of their column.
+	t := c.typ
+	if t == 0 {
+		t = document.TextValue
+	}
+	v, err := v.CastAs(t)
+	if err != nil {
+		return nil, &pgError{code: "42804", msg: fmt.Sprintf("column %q: %v", c.name, err)}
+	}
+
+	if c.format == binaryFormat {
+		return encodeBinary(v), nil
+	}
+
+	return encodeText(v), nil
+}
+
+func encodeText(v document.Value) []byte {
+	switch v.Type {
+	case document.BoolValue:
+		if v.V.(bool) {
+			return []byte("t")
+		}
+		return []byte("f")
+	case document.IntegerValue:
+		return strconv.AppendInt(nil, v.V.(int64), 10)
+	case document.DoubleValue:
+		f := v.V.(float64)
+		switch {
+		case math.IsInf(f, 1):
+			return []byte("Infinity")
+		case math.IsInf(f, -1):
+			return []byte("-Infinity")
+		case math.IsNaN(f):
+			return []byte("NaN")
+		}
+		return strconv.AppendFloat(nil, f, 'g', -1, 64)
+	case document.BlobValue:
+		b := v.V.([]byte)
+		buf := make([]byte, 2+hex.EncodedLen(len(b)))
+		copy(buf, `\x`)
+		hex.Encode(buf[2:], b)
+		return buf
+	}
+
+	return []byte(v.V.(string))
+}
+
+func encodeBinary(v document.Value) []byte {
+	switch v.Type {
+	case document.BoolValue:
+		if v.V.(bool) {
+			return []byte{1}
+		}
+		return []byte{0}
+	case document.IntegerValue:
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], uint64(v.V.(int64)))
+		return buf[:]
+	case document.DoubleValue:
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], math.Float64bits(v.V.(float64)))
+		return buf[:]
+	case document.BlobValue:
+		return v.V.([]byte)
+	}
+
+	return []byte(v.V.(string))
+}
+
+// decodeParam decodes the value of a parameter of the given type and format.
+// Parameters whose type wasn't specified by the client are described as JSON:
+// their value is decoded as JSON, or as text if it is not valid JSON.
+func decodeParam(data []byte, oid uint32, format int16) (document.Value, error) {
+	if data == nil {
+		return document.NewNullValue(), nil
+	}
+
+	if format == binaryFormat {
+		return decodeBinary(data, oid)
+	}
+
+	s := string(data)
+	switch oid {
+	case 0:
+		if v, err := decodeJSON(data); err == nil {
+			return v, nil
+		}
+		return document.NewTextValue(s), nil
+	case oidBool:
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "t", "true", "y", "yes", "on", "1":
+			return document.NewBoolValue(true), nil
+		case "f", "false", "n", "no", "off", "0":
+			return document.NewBoolValue(false), nil
+		}
+	case oidInt2, oidInt4, oidInt8:
+		i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err == nil {
+			return document.NewIntegerValue(i), nil
+		}
+	case oidFloat4, oidFloat8, oidNumeric:
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err == nil {
+			return document.NewDoubleValue(f), nil
+		}
+	case oidBytea:
+		if !strings.HasPrefix(s, `\x`) {
+			return document.NewBlobValue(data), nil
+		}
+		b, err := hex.DecodeString(s[2:])
+		if err == nil {
+			return document.NewBlobValue(b), nil
+		}
+	case oidJSON, oidJSONB:
+		return decodeJSON(data)
+	default:
+		return document.NewTextValue(s), nil
+	}
+
+	return document.Value{}, fmt.Errorf("invalid input syntax for type %s: %q", typeName(oid), s)
+}
+
+func decodeBinary(data []byte, oid uint32) (document.Value, error) {
+	switch oid {
+	case oidBool:
+		if len(data) == 1 {
+			return document.NewBoolValue(data[0] != 0), nil
+		}
+	case oidInt2:
+		if len(data) == 2 {
+			return document.NewIntegerValue(int64(int16(binary.BigEndian.Uint16(data)))), nil
+		}
+	case oidInt4:
+		if len(data) == 4 {
+			return document.NewIntegerValue(int64(int32(binary.BigEndian.Uint32(data)))), nil
+		}
+	case oidInt8:
+		if len(data) == 8 {
+			return document.NewIntegerValue(int64(binary.BigEndian.Uint64(data))), nil
+		}
+	case oidFloat4:
+		if len(data) == 4 {
+			return document.NewDoubleValue(float64(math.Float32frombits(binary.BigEndian.Uint32(data)))), nil
+		}
+	case oidFloat8:
+		if len(data) == 8 {
+			return document.NewDoubleValue(math.Float64frombits(binary.BigEndian.Uint64(data))), nil
+		}
+	case oidText, oidVarchar, oidBpchar, oidName, oidUnknown:
+		return document.NewTextValue(string(data)), nil
+	case oidBytea:
+		return document.NewBlobValue(data), nil
+	case 0, oidJSON:
+		return decodeJSON(data)
+	case oidJSONB:
+		// binary jsonb values are prefixed by a version number.
+		if len(data) > 0 && data[0] == 1 {
+			return decodeJSON(data[1:])
+		}
+	default:
+		return document.Value{}, fmt.Errorf("binary format is not supported for type %s", typeName(oid))
+	}
+
+	return document.Value{}, fmt.Errorf("invalid binary representation for type %s", typeName(oid))
+}
+
+// decodeJSON decodes a JSON value.
+func decodeJSON(data []byte) (document.Value, error) {
+	var vb document.ValueBuffer
+
+	buf := make([]byte, 0, len(data)+2)
+	buf = append(buf, '[')
+	buf = append(buf, data...)
+	buf = append(buf, ']')
+	if err := vb.UnmarshalJSON(buf); err != nil || vb.Len() != 1 {
+		return document.Value{}, fmt.Errorf("invalid input syntax for type json: %q", data)
+	}
+
+	return vb.GetByIndex(0)
+}
+
+func typeName(oid uint32) string {
+	switch oid {
+	case oidBool:
+		return "boolean"
+	case oidBytea:
+		return "bytea"
+	case oidInt2:
+		return "smallint"
+	case oidInt4:
+		return "integer"
+	case oidInt8:
+		return "bigint"
+	case oidFloat4:
+		return "real"
+	case oidFloat8:
+		return "double precision"
+	case oidNumeric:
+		return "numeric"
+	case oidJSON:
+		return "json"
+	case oidJSONB:
+		return "jsonb"
+	}
+
+	return "oid " + strconv.FormatUint(uint64(oid), 10)
+}
@@ -10,6 +10,7 @@ import (
 	"sync"
 
 	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/sql/parser"
 	"github.com/genjidb/genji/sql/planner"
@@ -63,7 +64,10 @@ type connector struct {
 }
 
 func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
-	return &conn{db: c.db}, nil
+	return &conn{
+		db:      c.db,
+		session: c.db.DB.NewSession(),
+	}, nil
 }
 
 func (c *connector) Driver() driver.Driver {
@@ -78,11 +82,16 @@ func (c *connector) Close() error {
 	return err
 }
 
+var _ driver.SessionResetter = (*conn)(nil)
+
 // conn represents a connection to the Genji database.
 // It implements the database/sql/driver.Conn interface.
+// Each connection has its own session, which holds the transaction
+// opened with the BEGIN statement, independently of the other connections.
 type conn struct {
-	db *genji.DB
-	tx *genji.Tx
+	db      *genji.DB
+	session *database.Session
+	tx      *genji.Tx
 }
 
 // Prepare returns a prepared statement, bound to this connection.
@@ -98,9 +107,10 @@ func (c *conn) PrepareContext(ctx context.Context, q string) (driver.Stmt, error
 	}
 
 	return stmt{
-		db: c.db,
-		tx: c.tx,
-		q:  pq,
+		session: c.session,
+		tx:      c.tx,
+		q:       pq,
+		text:    q,
 	}, nil
 }
 
@@ -110,6 +120,22 @@ func (c *conn) Close() error {
 		return c.tx.Rollback()
 	}
 
+	if tx := c.session.GetAttachedTx(); tx != nil {
+		return tx.Rollback()
+	}
+
+	return nil
+}
+
+// ResetSession rolls back the transaction opened with the BEGIN statement, if any.
+// It is called by the database/sql package before reusing the connection.
+func (c *conn) ResetSession(ctx context.Context) error {
+	if tx := c.session.GetAttachedTx(); tx != nil {
+		if err := tx.Rollback(); err != nil {
+			return driver.ErrBadConn
+		}
+	}
+
 	return nil
 }
 
@@ -126,14 +152,17 @@ func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, e
 		return nil, errors.New("isolation levels are not supported")
 	}
 
-	db := c.db.WithContext(ctx)
-
 	// if the ReadOnly flag is explicitly specified, create a read-only transaction,
 	// otherwise create a read/write transaction.
-	var err error
-	c.tx, err = db.Begin(!opts.ReadOnly)
+	tx, err := c.session.BeginTx(ctx, &database.TxOptions{
+		ReadOnly: opts.ReadOnly,
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	return c, err
+	c.tx = &genji.Tx{Transaction: tx}
+	return c, nil
 }
 
 func (c *conn) Commit() error {
@@ -151,9 +180,23 @@ func (c *conn) Rollback() error {
 // Stmt is a prepared statement. It is bound to a Conn and not
 // used by multiple goroutines concurrently.
 type stmt struct {
-	db *genji.DB
-	tx *genji.Tx
-	q  query.Query
+	session *database.Session
+	tx      *genji.Tx
+	q       query.Query
+	text    string
+}
+
+// traced returns the query of the statement with a new trace.
+// The statement is parsed when prepared, so the trace has no parse stage.
+func (s stmt) traced(ctx context.Context, args []driver.NamedValue) query.Query {
+	targs := make([]interface{}, len(args))
+	for i := range args {
+		targs[i] = args[i].Value
+	}
+
+	q := s.q
+	q.Trace = s.session.DB().StartQueryTrace(ctx, s.text, targs)
+	return q
 }
 
 // NumInput returns the number of placeholder parameters.
@@ -200,11 +243,12 @@ func (s stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver
 	var err error
 
 	// if calling ExecContext within a transaction, use it,
-	// otherwise use DB.
+	// otherwise use the session of the connection.
+	q := s.traced(ctx, args)
 	if s.tx != nil {
-		res, err = s.q.Exec(s.tx.Transaction, driverNamedValueToParams(args))
+		res, err = q.Exec(s.tx.Transaction, driverNamedValueToParams(args))
 	} else {
-		res, err = s.q.Run(ctx, s.db.DB, driverNamedValueToParams(args))
+		res, err = q.RunSession(ctx, s.session, driverNamedValueToParams(args))
 	}
 
 	if err != nil {
@@ -250,11 +294,12 @@ func (s stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (drive
 	var err error
 
 	// if calling QueryContext within a transaction, use it,
-	// otherwise use DB.
+	// otherwise use the session of the connection.
+	q := s.traced(ctx, args)
 	if s.tx != nil {
-		res, err = s.q.Exec(s.tx.Transaction, driverNamedValueToParams(args))
+		res, err = q.Exec(s.tx.Transaction, driverNamedValueToParams(args))
 	} else {
-		res, err = s.q.Run(ctx, s.db.DB, driverNamedValueToParams(args))
+		res, err = q.RunSession(ctx, s.session, driverNamedValueToParams(args))
 	}
 
 	if err != nil {
@@ -5,7 +5,10 @@ import (
 	"database/sql"
 	"testing"
 
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/memoryengine"
 	"github.com/stretchr/testify/require"
 )
 
@@ -132,6 +135,23 @@ func TestDriver(t *testing.T) {
 		require.Equal(t, 1, count)
 	})
 
+	t.Run("Prepared statement", func(t *testing.T) {
+		_, err := db.Exec("CREATE INDEX idx_a ON test (a); REINDEX idx_a")
+		require.NoError(t, err)
+		defer db.Exec("DROP INDEX idx_a")
+
+		stmt, err := db.Prepare("SELECT a FROM test WHERE a = ?")
+		require.NoError(t, err)
+		defer stmt.Close()
+
+		for i := 0; i < 3; i++ {
+			var a int
+			err = stmt.QueryRow(i).Scan(&a)
+			require.NoError(t, err)
+			require.Equal(t, i, a)
+		}
+	})
+
 	t.Run("Transactions", func(t *testing.T) {
 		tx, err := db.Begin()
 		require.NoError(t, err)
@@ -212,3 +232,142 @@ func TestDriver(t *testing.T) {
 		require.Equal(t, err, engine.ErrTransactionReadOnly)
 	})
 }
+
+func TestDriverSessions(t *testing.T) {
+	db, err := sql.Open("genji", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE test")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	c1, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer c1.Close()
+	c2, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer c2.Close()
+
+	t.Run("Read only", func(t *testing.T) {
+		// each connection has its own transaction.
+		_, err = c1.ExecContext(ctx, "BEGIN READ ONLY")
+		require.NoError(t, err)
+		_, err = c2.ExecContext(ctx, "BEGIN READ ONLY")
+		require.NoError(t, err)
+
+		_, err = c1.ExecContext(ctx, "ROLLBACK")
+		require.NoError(t, err)
+		_, err = c2.ExecContext(ctx, "ROLLBACK")
+		require.NoError(t, err)
+	})
+
+	t.Run("Read write", func(t *testing.T) {
+		_, err = c1.ExecContext(ctx, "BEGIN; INSERT INTO test (a) VALUES (1)")
+		require.NoError(t, err)
+
+		// the second transaction waits for the first one to be closed.
+		done := make(chan error)
+		go func() {
+			_, err := c2.ExecContext(ctx, "BEGIN; INSERT INTO test (a) VALUES (2); COMMIT")
+			done <- err
+		}()
+
+		_, err = c1.ExecContext(ctx, "COMMIT")
+		require.NoError(t, err)
+		require.NoError(t, <-done)
+
+		var n int
+		err = db.QueryRow("SELECT COUNT(*) FROM test").Scan(&n)
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		_, err = c1.ExecContext(ctx, "BEGIN")
+		require.NoError(t, err)
+
+		_, err = c1.ExecContext(ctx, "BEGIN")
+		require.Error(t, err)
+
+		// the transaction of the other connection is not affected.
+		_, err = c2.ExecContext(ctx, "BEGIN READ ONLY")
+		require.NoError(t, err)
+		_, err = c2.ExecContext(ctx, "ROLLBACK")
+		require.NoError(t, err)
+	})
+}
+
+func TestDriverResetSession(t *testing.T) {
+	db, err := sql.Open("genji", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	// a single connection, reused by the pool for every query.
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE test")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	c, err := db.Conn(ctx)
+	require.NoError(t, err)
+
+	_, err = c.ExecContext(ctx, "BEGIN; INSERT INTO test (a) VALUES (1)")
+	require.NoError(t, err)
+
+	// the connection goes back to the pool with its transaction.
+	require.NoError(t, c.Close())
+
+	// the pool resets the session before reusing the connection.
+	var n int
+	err = db.QueryRow("SELECT COUNT(*) FROM test").Scan(&n)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	_, err = db.Exec("BEGIN; INSERT INTO test (a) VALUES (2); COMMIT")
+	require.NoError(t, err)
+
+	err = db.QueryRow("SELECT COUNT(*) FROM test").Scan(&n)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+}
+
+type argsTracer struct {
+	args [][]interface{}
+}
+
+func (tr *argsTracer) StartQuery(ctx context.Context, q string, args []interface{}) database.QueryTrace {
+	tr.args = append(tr.args, args)
+	return tr
+}
+
+func (tr *argsTracer) StartStage(stage database.TraceStage) func(err error) {
+	return func(err error) {}
+}
+
+func (tr *argsTracer) End(err error) {}
+
+func TestDriverTrace(t *testing.T) {
+	var tr argsTracer
+	gdb, err := genji.New(context.Background(), memoryengine.NewEngine(), genji.WithTracer(&tr))
+	require.NoError(t, err)
+
+	db := sql.OpenDB(&connector{db: gdb, driver: sqlDriver{}})
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE test")
+	require.NoError(t, err)
+
+	// the values of the parameters are traced, not their driver wrappers.
+	tr.args = nil
+	_, err = db.Exec("INSERT INTO test (a, b) VALUES (?, ?)", 1, "foo")
+	require.NoError(t, err)
+
+	var n int
+	err = db.QueryRow("SELECT COUNT(*) FROM test WHERE a = ?", 1).Scan(&n)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	require.Equal(t, [][]interface{}{{int64(1), "foo"}, {int64(1)}}, tr.args)
+}
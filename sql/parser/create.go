@@ -1,9 +1,12 @@
 package parser
 
 import (
+	"bytes"
 	"fmt"
+	"strings"
 
 	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/sql/query"
 	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/genjidb/genji/sql/scanner"
@@ -22,11 +25,29 @@ func (p *Parser) parseCreateStatement() (query.Statement, error) {
 		}
 
 		return p.parseCreateIndexStatement(true)
+	case scanner.FULLTEXT:
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.INDEX {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"INDEX"}, pos)
+		}
+
+		stmt, err := p.parseCreateIndexStatement(false)
+		stmt.FullText = true
+		return stmt, err
+	case scanner.SPATIAL:
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.INDEX {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"INDEX"}, pos)
+		}
+
+		stmt, err := p.parseCreateIndexStatement(false)
+		stmt.Spatial = true
+		return stmt, err
 	case scanner.INDEX:
 		return p.parseCreateIndexStatement(false)
+	case scanner.TRIGGER:
+		return p.parseCreateTriggerStatement()
 	}
 
-	return nil, newParseError(scanner.Tokstr(tok, lit), []string{"TABLE", "INDEX"}, pos)
+	return nil, newParseError(scanner.Tokstr(tok, lit), []string{"TABLE", "INDEX", "TRIGGER"}, pos)
 }
 
 // parseCreateTableStatement parses a create table string and returns a Statement AST object.
@@ -53,9 +74,36 @@ func (p *Parser) parseCreateTableStatement() (query.CreateTableStmt, error) {
 		return stmt, err
 	}
 
+	// parse table options
+	stmt.Info.TTLPath, err = p.parseTTL()
+	if err != nil {
+		return stmt, err
+	}
+
 	return stmt, nil
 }
 
+// parseTTL parses the "WITH TTL ON path" clause, if it exists.
+func (p *Parser) parseTTL() (document.Path, error) {
+	// Parse "WITH"
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.WITH {
+		p.Unscan()
+		return nil, nil
+	}
+
+	// Parse "TTL"
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.TTL {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"TTL"}, pos)
+	}
+
+	// Parse "ON"
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.ON {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"ON"}, pos)
+	}
+
+	return p.parsePath()
+}
+
 func (p *Parser) parseIfNotExists() (bool, error) {
 	// Parse "IF"
 	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.IF {
@@ -230,20 +278,140 @@ func (p *Parser) parseCreateIndexStatement(unique bool) (query.CreateIndexStmt,
 		return stmt, err
 	}
 
-	paths, err := p.parsePathList()
+	stmt.Path, stmt.Multikey, err = p.parseIndexedPath()
 	if err != nil {
 		return stmt, err
 	}
-	if len(paths) == 0 {
-		tok, pos, lit := p.ScanIgnoreWhitespace()
-		return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
+
+	return stmt, nil
+}
+
+// parseIndexedPath parses the path of an index, between parentheses.
+// If the path is followed by [*], the index is a multi-key index,
+// which indexes each element of the arrays found at that path.
+func (p *Parser) parseIndexedPath() (path document.Path, multikey bool, err error) {
+	// Parse ( token.
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.LPAREN {
+		return nil, false, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
+	}
+
+	path, err = p.parsePath()
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Parse optional [*].
+	if tok, _, _ := p.Scan(); tok == scanner.LSBRACKET {
+		if tok, pos, lit := p.Scan(); tok != scanner.MUL {
+			return nil, false, newParseError(scanner.Tokstr(tok, lit), []string{"*"}, pos)
+		}
+		if tok, pos, lit := p.Scan(); tok != scanner.RSBRACKET {
+			return nil, false, newParseError(scanner.Tokstr(tok, lit), []string{"]"}, pos)
+		}
+		multikey = true
+	} else {
+		p.Unscan()
+	}
+
+	// Parse required ) token.
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	if tok == scanner.COMMA {
+		return nil, false, &ParseError{Message: "indexes on more than one path are not supported", Pos: pos}
+	}
+	if tok != scanner.RPAREN {
+		return nil, false, newParseError(scanner.Tokstr(tok, lit), []string{")"}, pos)
+	}
+
+	return path, multikey, nil
+}
+
+// parseCreateTriggerStatement parses a create trigger string and returns a Statement AST object.
+// This function assumes the CREATE TRIGGER tokens have already been consumed.
+func (p *Parser) parseCreateTriggerStatement() (query.CreateTriggerStmt, error) {
+	var stmt query.CreateTriggerStmt
+	var err error
+
+	// Parse IF NOT EXISTS
+	stmt.IfNotExists, err = p.parseIfNotExists()
+	if err != nil {
+		return stmt, err
+	}
+
+	// Parse trigger name
+	stmt.TriggerName, err = p.parseIdent()
+	if err != nil {
+		pErr := err.(*ParseError)
+		pErr.Expected = []string{"trigger_name"}
+		return stmt, pErr
 	}
 
-	if len(paths) != 1 {
-		return stmt, &ParseError{Message: "indexes on more than one path are not supported"}
+	// Parse "BEFORE" or "AFTER"
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	switch tok {
+	case scanner.BEFORE:
+		stmt.Timing = database.BeforeTrigger
+	case scanner.AFTER:
+		stmt.Timing = database.AfterTrigger
+	default:
+		return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"BEFORE", "AFTER"}, pos)
 	}
 
-	stmt.Path = paths[0]
+	// Parse "INSERT", "UPDATE" or "DELETE"
+	tok, pos, lit = p.ScanIgnoreWhitespace()
+	switch tok {
+	case scanner.INSERT:
+		stmt.Event = database.InsertChange
+	case scanner.UPDATE:
+		stmt.Event = database.UpdateChange
+	case scanner.DELETE:
+		stmt.Event = database.DeleteChange
+	default:
+		return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"INSERT", "UPDATE", "DELETE"}, pos)
+	}
+
+	// Parse "ON"
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.ON {
+		return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"ON"}, pos)
+	}
+
+	// Parse table name
+	stmt.TableName, err = p.parseIdent()
+	if err != nil {
+		pErr := err.(*ParseError)
+		pErr.Expected = []string{"table_name"}
+		return stmt, pErr
+	}
+
+	// Parse optional "FOR EACH ROW"
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.FOR {
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.EACH {
+			return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"EACH"}, pos)
+		}
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.ROW {
+			return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"ROW"}, pos)
+		}
+	} else {
+		p.Unscan()
+	}
+
+	// Parse the statement run by the trigger and store its
+	// literal representation.
+	p.buf = new(bytes.Buffer)
+	defer func() { p.buf = nil }()
+
+	tok, pos, lit = p.ScanIgnoreWhitespace()
+	switch tok {
+	case scanner.INSERT, scanner.UPDATE, scanner.DELETE, scanner.SELECT:
+	default:
+		return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"INSERT", "UPDATE", "DELETE", "SELECT"}, pos)
+	}
+	p.Unscan()
+
+	_, err = p.ParseStatement()
+	if err != nil {
+		return stmt, err
+	}
 
+	stmt.Statement = strings.TrimSpace(p.buf.String())
 	return stmt, nil
 }
@@ -167,6 +167,20 @@ func TestParserCreateTable(t *testing.T) {
 					},
 				},
 			}, false},
+		{"With TTL", "CREATE TABLE test(expires_at INTEGER) WITH TTL ON expires_at",
+			query.CreateTableStmt{
+				TableName: "test",
+				Info: database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "expires_at"), Type: document.IntegerValue},
+					},
+					TTLPath: parsePath(t, "expires_at"),
+				},
+			}, false},
+		{"With TTL and no constraints", "CREATE TABLE test WITH TTL ON a.b",
+			query.CreateTableStmt{TableName: "test", Info: database.TableInfo{TTLPath: parsePath(t, "a.b")}}, false},
+		{"With TTL and no path", "CREATE TABLE test WITH TTL ON", query.CreateTableStmt{}, true},
+		{"With unknown option", "CREATE TABLE test WITH foo", query.CreateTableStmt{}, true},
 
 		{"With errored text aliases types",
 			"CREATE TABLE test(v VARCHAR(1 IN [1, 2, 3] AND foo > 4) )",
@@ -204,6 +218,12 @@ func TestParserCreateIndex(t *testing.T) {
 		{"Basic", "CREATE INDEX idx ON test (foo)", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Path: parsePath(t, "foo")}, false},
 		{"If not exists", "CREATE INDEX IF NOT EXISTS idx ON test (foo.bar[1])", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Path: parsePath(t, "foo.bar[1]"), IfNotExists: true}, false},
 		{"Unique", "CREATE UNIQUE INDEX IF NOT EXISTS idx ON test (foo[3].baz)", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Path: parsePath(t, "foo[3].baz"), IfNotExists: true, Unique: true}, false},
+		{"Full-text", "CREATE FULLTEXT INDEX IF NOT EXISTS idx ON test (foo.bar)", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Path: parsePath(t, "foo.bar"), IfNotExists: true, FullText: true}, false},
+		{"Unique full-text", "CREATE UNIQUE FULLTEXT INDEX idx ON test (foo)", nil, true},
+		{"Spatial", "CREATE SPATIAL INDEX idx ON test (location)", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Path: parsePath(t, "location"), Spatial: true}, false},
+		{"Multi-key", "CREATE INDEX idx ON test (foo.bar[*])", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Path: parsePath(t, "foo.bar"), Multikey: true}, false},
+		{"Multi-key with array index", "CREATE UNIQUE INDEX idx ON test (foo[1][*])", query.CreateIndexStmt{IndexName: "idx", TableName: "test", Path: parsePath(t, "foo[1]"), Unique: true, Multikey: true}, false},
+		{"Invalid wildcard", "CREATE INDEX idx ON test (foo[*].bar)", nil, true},
 		{"No fields", "CREATE INDEX idx ON test", nil, true},
 		{"More than 1 path", "CREATE INDEX idx ON test (foo, bar)", nil, true},
 	}
@@ -221,3 +241,37 @@ func TestParserCreateIndex(t *testing.T) {
 		})
 	}
 }
+
+func TestParserCreateTrigger(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		expected query.Statement
+		errored  bool
+	}{
+		{"Basic", "CREATE TRIGGER tr AFTER INSERT ON test INSERT INTO log (a) VALUES ($new.a)",
+			query.CreateTriggerStmt{TriggerName: "tr", TableName: "test", Timing: database.AfterTrigger, Event: database.InsertChange, Statement: "INSERT INTO log (a) VALUES ($new.a)"}, false},
+		{"For each row", "CREATE TRIGGER IF NOT EXISTS tr BEFORE UPDATE ON test FOR EACH ROW UPDATE counters SET n = n + 1",
+			query.CreateTriggerStmt{TriggerName: "tr", TableName: "test", IfNotExists: true, Timing: database.BeforeTrigger, Event: database.UpdateChange, Statement: "UPDATE counters SET n = n + 1"}, false},
+		{"Delete", "CREATE TRIGGER tr AFTER DELETE ON test DELETE FROM log WHERE a = $old.a",
+			query.CreateTriggerStmt{TriggerName: "tr", TableName: "test", Timing: database.AfterTrigger, Event: database.DeleteChange, Statement: "DELETE FROM log WHERE a = $old.a"}, false},
+		{"Missing timing", "CREATE TRIGGER tr INSERT ON test DELETE FROM log", nil, true},
+		{"Missing event", "CREATE TRIGGER tr AFTER ON test DELETE FROM log", nil, true},
+		{"Missing table", "CREATE TRIGGER tr AFTER INSERT DELETE FROM log", nil, true},
+		{"Missing statement", "CREATE TRIGGER tr AFTER INSERT ON test", nil, true},
+		{"Transaction statement", "CREATE TRIGGER tr AFTER INSERT ON test COMMIT", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := ParseQuery(test.s)
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, q.Statements, 1)
+			require.EqualValues(t, test.expected, q.Statements[0])
+		})
+	}
+}
@@ -9,6 +9,14 @@ import (
 // parseExplainStatement parses any statement and returns an ExplainStmt object.
 // This function assumes the EXPLAIN token has already been consumed.
 func (p *Parser) parseExplainStatement() (query.Statement, error) {
+	// parse optional ANALYZE keyword
+	var analyze bool
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.ANALYZE {
+		analyze = true
+	} else {
+		p.Unscan()
+	}
+
 	// ensure we don't have multiple EXPLAIN keywords
 	tok, pos, lit := p.ScanIgnoreWhitespace()
 	if tok == scanner.EXPLAIN {
@@ -21,5 +29,5 @@ func (p *Parser) parseExplainStatement() (query.Statement, error) {
 		return nil, err
 	}
 
-	return &planner.ExplainStmt{Statement: innerStmt}, nil
+	return &planner.ExplainStmt{Statement: innerStmt, Analyze: analyze}, nil
 }
@@ -178,6 +178,14 @@ func (p *Parser) parseUnaryExpr() (expr.Expr, error) {
 			return nil, &ParseError{Message: "cannot mix positional arguments with named arguments"}
 		}
 		p.namedParams++
+		// a document passed as a parameter can be followed by a path
+		path, err := p.parsePathFragments(nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(path) > 0 {
+			return expr.NamedParamPath{Param: expr.NamedParam(lit[1:]), Path: path}, nil
+		}
 		return expr.NamedParam(lit[1:]), nil
 	case scanner.POSITIONALPARAM:
 		if p.namedParams > 0 {
@@ -421,6 +429,12 @@ func (p *Parser) parsePath() (document.Path, error) {
 		FieldName: chunk,
 	})
 
+	return p.parsePathFragments(path)
+}
+
+// parsePathFragments parses the fragments following the beginning of a path
+// and appends them to path.
+func (p *Parser) parsePathFragments(path document.Path) (document.Path, error) {
 LOOP:
 	for {
 		// scan the very next token.
@@ -440,6 +454,13 @@ LOOP:
 		case scanner.LSBRACKET:
 			// scan the next token for an integer
 			tok, pos, lit := p.Scan()
+			// [*] is not part of the path: it is left to the caller,
+			// which may accept it, like CREATE INDEX.
+			if tok == scanner.MUL {
+				p.Unscan()
+				p.Unscan()
+				break LOOP
+			}
 			if tok != scanner.INTEGER || lit[0] == '-' {
 				return nil, newParseError(lit, []string{"array index"}, pos)
 			}
@@ -221,6 +221,32 @@ func TestParsePath(t *testing.T) {
 	}
 }
 
+func TestParseExpr(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		expected expr.Expr
+		fails    bool
+	}{
+		{"literal", `10`, expr.IntegerValue(10), false},
+		{"operation", `a > 1`, expr.Gt(expr.Path(parsePath(t, "a")), expr.IntegerValue(1)), false},
+		{"trailing tokens", `10 20`, nil, true},
+		{"invalid", `>`, nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e, err := ParseExpr(test.s)
+			if test.fails {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.EqualValues(t, test.expected, e)
+			}
+		})
+	}
+}
+
 func TestParserParams(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -240,6 +266,8 @@ func TestParserParams(t *testing.T) {
 				expr.Eq(expr.Path(parsePath(t, "age")), expr.NamedParam("foo")),
 				expr.Eq(expr.Path(parsePath(t, "age")), expr.NamedParam("bar")),
 			), false},
+		{"named with path", "age = $doc.a[1]",
+			expr.Eq(expr.Path(parsePath(t, "age")), expr.NamedParamPath{Param: "doc", Path: parsePath(t, "a[1]")}), false},
 		{"mixed", "age >= ? AND age > $foo OR age < ?", nil, true},
 	}
 
@@ -42,7 +42,58 @@ func (p *Parser) parseRollbackStatement() (query.Statement, error) {
 		p.Unscan()
 	}
 
-	return query.RollbackStmt{}, nil
+	// parse optional TO token
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.TO {
+		p.Unscan()
+		return query.RollbackStmt{}, nil
+	}
+
+	name, err := p.parseSavepointName()
+	if err != nil {
+		return nil, err
+	}
+
+	return query.RollbackToSavepointStmt{SavepointName: name}, nil
+}
+
+// parseSavepointStatement parses a SAVEPOINT statement.
+// This function assumes the SAVEPOINT token has already been consumed.
+func (p *Parser) parseSavepointStatement() (query.Statement, error) {
+	name, err := p.parseIdent()
+	if err != nil {
+		pErr := err.(*ParseError)
+		pErr.Expected = []string{"savepoint_name"}
+		return nil, pErr
+	}
+
+	return query.SavepointStmt{SavepointName: name}, nil
+}
+
+// parseReleaseStatement parses a RELEASE statement.
+// This function assumes the RELEASE token has already been consumed.
+func (p *Parser) parseReleaseStatement() (query.Statement, error) {
+	name, err := p.parseSavepointName()
+	if err != nil {
+		return nil, err
+	}
+
+	return query.ReleaseSavepointStmt{SavepointName: name}, nil
+}
+
+// parseSavepointName parses a savepoint name, optionally preceded by the SAVEPOINT token.
+func (p *Parser) parseSavepointName() (string, error) {
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.SAVEPOINT {
+		p.Unscan()
+	}
+
+	name, err := p.parseIdent()
+	if err != nil {
+		pErr := err.(*ParseError)
+		pErr.Expected = []string{"savepoint_name"}
+		return "", pErr
+	}
+
+	return name, nil
 }
 
 // parseCommitStatement parses a COMMIT statement.
@@ -21,6 +21,14 @@ func TestParserTransactions(t *testing.T) {
 		{"BEGIN WRITE", query.BeginStmt{}, true},
 		{"ROLLBACK", query.RollbackStmt{}, false},
 		{"ROLLBACK TRANSACTION", query.RollbackStmt{}, false},
+		{"ROLLBACK TO sp", query.RollbackToSavepointStmt{SavepointName: "sp"}, false},
+		{"ROLLBACK TRANSACTION TO SAVEPOINT sp", query.RollbackToSavepointStmt{SavepointName: "sp"}, false},
+		{"ROLLBACK TO", nil, true},
+		{"SAVEPOINT sp", query.SavepointStmt{SavepointName: "sp"}, false},
+		{"SAVEPOINT", nil, true},
+		{"RELEASE sp", query.ReleaseSavepointStmt{SavepointName: "sp"}, false},
+		{"RELEASE SAVEPOINT sp", query.ReleaseSavepointStmt{SavepointName: "sp"}, false},
+		{"RELEASE", nil, true},
 		{"COMMIT", query.CommitStmt{}, false},
 		{"COMMIT TRANSACTION", query.CommitStmt{}, false},
 	}
@@ -2,6 +2,7 @@ package planner
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
@@ -9,11 +10,17 @@ import (
 	"github.com/genjidb/genji/sql/query/expr"
 )
 
+// name of the savepoint used to cancel the writes of EXPLAIN ANALYZE.
+const explainSavepoint = "__genji_explain_analyze"
+
 // ExplainStmt is a query.Statement that
 // displays information about how a statement
 // is going to be executed, without executing it.
+// If Analyze is true, the statement is executed and
+// its writes are cancelled.
 type ExplainStmt struct {
 	Statement query.Statement
+	Analyze   bool
 }
 
 // Run analyses the inner statement and displays its execution plan.
@@ -23,14 +30,13 @@ type ExplainStmt struct {
 func (s *ExplainStmt) Run(tx *database.Transaction, params []expr.Param) (query.Result, error) {
 	switch t := s.Statement.(type) {
 	case *Tree:
-		err := Bind(t, tx, params)
+		t, err := t.prepare(tx, params)
 		if err != nil {
 			return query.Result{}, err
 		}
 
-		t, err = Optimize(t)
-		if err != nil {
-			return query.Result{}, err
+		if s.Analyze {
+			return s.analyze(tx, t)
 		}
 
 		return s.createResult(t.String())
@@ -48,8 +54,74 @@ func (s *ExplainStmt) createResult(text string) (query.Result, error) {
 	}, nil
 }
 
+// analyze executes the tree and returns one document per node,
+// from the input to the root, with the number of documents received
+// and returned by the node and the time spent in it.
+// Writes are rolled back once the tree is executed.
+func (s *ExplainStmt) analyze(tx *database.Transaction, t *Tree) (query.Result, error) {
+	if t.Root == nil {
+		return query.Result{}, nil
+	}
+
+	if !t.IsReadOnly() {
+		err := tx.Savepoint(explainSavepoint)
+		if err != nil {
+			return query.Result{}, err
+		}
+	}
+
+	var qs queryStats
+	st, err := qs.nodeToStream(t.Root)
+	if err == nil {
+		err = st.Iterate(func(d document.Document) error {
+			return nil
+		})
+	}
+
+	if !t.IsReadOnly() {
+		rerr := tx.RollbackToSavepoint(explainSavepoint)
+		if rerr == nil {
+			rerr = tx.ReleaseSavepoint(explainSavepoint)
+		}
+		if err == nil {
+			err = rerr
+		}
+	}
+	if err != nil {
+		return query.Result{}, err
+	}
+
+	docs := make([]document.Document, 0, len(qs.nodes))
+	for i, ns := range qs.nodes {
+		fb := document.NewFieldBuffer().
+			Add("node", document.NewTextValue(fmt.Sprintf("%v", ns.node)))
+
+		switch {
+		case ns.node.Operation() == Input:
+			access := "table"
+			if ns.index {
+				access = "index"
+			}
+			fb.Add("access", document.NewTextValue(access))
+		case i > 0:
+			fb.Add("documents_in", document.NewIntegerValue(qs.nodes[i-1].documents))
+		default:
+			fb.Add("documents_in", document.NewIntegerValue(0))
+		}
+
+		fb.Add("documents_out", document.NewIntegerValue(ns.documents)).
+			Add("elapsed", document.NewTextValue(qs.elapsed(i).String()))
+
+		docs = append(docs, fb)
+	}
+
+	return query.Result{
+		Stream: document.NewStream(document.NewIterator(docs...)),
+	}, nil
+}
+
 // IsReadOnly indicates that this statement doesn't write anything into
-// the database.
+// the database, unless it analyzes a statement that does.
 func (s *ExplainStmt) IsReadOnly() bool {
-	return true
+	return !s.Analyze || s.Statement.IsReadOnly()
 }
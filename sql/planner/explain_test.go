@@ -2,8 +2,10 @@ package planner_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
 	"github.com/stretchr/testify/require"
 )
 
@@ -61,3 +63,98 @@ func TestExplainStmt(t *testing.T) {
 		})
 	}
 }
+
+func TestExplainAnalyzeStmt(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(`
+		CREATE TABLE test;
+		CREATE INDEX idx_a ON test (a);
+		INSERT INTO test (a, b) VALUES (1, 1), (2, 2), (3, 3), (4, 4);
+	`)
+	require.NoError(t, err)
+
+	analyze := func(t *testing.T, q string) []string {
+		t.Helper()
+
+		res, err := db.Query(q)
+		require.NoError(t, err)
+		defer res.Close()
+
+		var nodes []string
+		err = res.Iterate(func(d document.Document) error {
+			var fb document.FieldBuffer
+			err := fb.Copy(d)
+			if err != nil {
+				return err
+			}
+
+			v, err := fb.GetByField("elapsed")
+			require.NoError(t, err)
+			_, err = time.ParseDuration(v.V.(string))
+			require.NoError(t, err)
+			require.NoError(t, fb.Delete(document.Path{document.PathFragment{FieldName: "elapsed"}}))
+
+			data, err := document.MarshalJSON(&fb)
+			nodes = append(nodes, string(data))
+			return err
+		})
+		require.NoError(t, err)
+		return nodes
+	}
+
+	count := func(t *testing.T) int {
+		t.Helper()
+
+		d, err := db.QueryDocument("SELECT COUNT(*) FROM test WHERE b > 0")
+		require.NoError(t, err)
+		var n int
+		require.NoError(t, document.Scan(d, &n))
+		return n
+	}
+
+	t.Run("Select", func(t *testing.T) {
+		require.Equal(t, []string{
+			`{"node": "Table(test)", "access": "table", "documents_out": 4}`,
+			`{"node": "σ(cond: b > 2)", "documents_in": 4, "documents_out": 2}`,
+			`{"node": "∏(a)", "documents_in": 2, "documents_out": 2}`,
+		}, analyze(t, "EXPLAIN ANALYZE SELECT a FROM test WHERE b > 2"))
+
+		require.Equal(t, []string{
+			`{"node": "Index(idx_a)", "access": "index", "documents_out": 1}`,
+			`{"node": "∏(a)", "documents_in": 1, "documents_out": 1}`,
+		}, analyze(t, "EXPLAIN ANALYZE SELECT a FROM test WHERE a = 2"))
+
+		require.Equal(t, []string{
+			`{"node": "∏(1)", "documents_in": 0, "documents_out": 1}`,
+		}, analyze(t, "EXPLAIN ANALYZE SELECT 1"))
+	})
+
+	t.Run("Writes are rolled back", func(t *testing.T) {
+		require.Equal(t, []string{
+			`{"node": "Table(test)", "access": "table", "documents_out": 4}`,
+			`{"node": "σ(cond: b > 2)", "documents_in": 4, "documents_out": 2}`,
+			`{"node": "Delete(test)", "documents_in": 2, "documents_out": 0}`,
+		}, analyze(t, "EXPLAIN ANALYZE DELETE FROM test WHERE b > 2"))
+		require.Equal(t, 4, count(t))
+
+		analyze(t, "EXPLAIN ANALYZE UPDATE test SET b = 0")
+		require.Equal(t, 4, count(t))
+
+		// writes made before EXPLAIN ANALYZE in the same transaction are kept.
+		err := db.Update(func(tx *genji.Tx) error {
+			err := tx.Exec("INSERT INTO test (a, b) VALUES (5, 5)")
+			require.NoError(t, err)
+			return tx.Exec("EXPLAIN ANALYZE DELETE FROM test")
+		})
+		require.NoError(t, err)
+		require.Equal(t, 5, count(t))
+	})
+
+	t.Run("Invalid statement", func(t *testing.T) {
+		_, err := db.Query("EXPLAIN ANALYZE CREATE TABLE foo")
+		require.Error(t, err)
+	})
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/index/spatial"
 	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/genjidb/genji/sql/scanner"
 )
@@ -122,7 +123,8 @@ func (n *indexInputNode) Bind(tx *database.Transaction, params []expr.Param) (er
 
 		shouldBeConverted := true
 		for _, fc := range info.FieldConstraints {
-			if fc.Path.IsEqual(n.path) && fc.Type != 0 {
+			// the elements of multi-key indexes are not typed.
+			if fc.Path.IsEqual(n.path) && fc.Type != 0 && !n.index.Opts.Multikey {
 				shouldBeConverted = false
 				break
 			}
@@ -160,6 +162,20 @@ type IndexIteratorOperator interface {
 	IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error
 }
 
+// elementOperator reads the documents whose array contains a value
+// from a multi-key index, which associates each element of arrays
+// with the key of their document.
+type elementOperator struct{}
+
+func (elementOperator) IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error {
+	// like the IN operator, null values match no documents.
+	if v.Type == document.NullValue {
+		return nil
+	}
+
+	return expr.Eq(nil, nil).(IndexIteratorOperator).IterateIndex(idx, tb, v, fn)
+}
+
 type indexIterator struct {
 	tx               *database.Transaction
 	tb               *database.Table
@@ -179,21 +195,11 @@ func (it indexIterator) Iterate(fn func(d document.Document) error) error {
 
 		if it.orderByDirection == scanner.DESC {
 			err = it.index.DescendLessOrEqual(document.Value{}, func(val, key []byte, isEqual bool) error {
-				d, err := it.tb.GetDocument(key)
-				if err != nil {
-					return err
-				}
-
-				return fn(d)
+				return it.tb.VisitIndexedDocument(key, fn)
 			})
 		} else {
 			err = it.index.AscendGreaterOrEqual(document.Value{}, func(val, key []byte, isEqual bool) error {
-				d, err := it.tb.GetDocument(key)
-				if err != nil {
-					return err
-				}
-
-				return fn(d)
+				return it.tb.VisitIndexedDocument(key, fn)
 			})
 		}
 
@@ -202,3 +208,209 @@ func (it indexIterator) Iterate(fn func(d document.Document) error) error {
 
 	return it.iop.IterateIndex(it.index, it.tb, it.filter, fn)
 }
+
+type fullTextInputNode struct {
+	node
+
+	tableName string
+	indexName string
+	query     expr.Expr
+
+	tx             *database.Transaction
+	params         []expr.Param
+	table          *database.Table
+	index          *database.Index
+	evaluatedQuery string
+}
+
+var _ inputNode = (*fullTextInputNode)(nil)
+
+// NewFullTextInputNode creates a node that reads the documents matching a full-text query
+// from a full-text index, by descending relevance.
+func NewFullTextInputNode(tableName, indexName string, query expr.Expr) Node {
+	return &fullTextInputNode{
+		node: node{
+			op: Input,
+		},
+		tableName: tableName,
+		indexName: indexName,
+		query:     query,
+	}
+}
+
+func (n *fullTextInputNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
+	if n.table == nil {
+		n.table, err = tx.GetTable(n.tableName)
+		if err != nil {
+			return
+		}
+	}
+
+	if n.index == nil {
+		n.index, err = tx.GetIndex(n.indexName)
+		if err != nil {
+			return
+		}
+	}
+
+	n.tx = tx
+	n.params = params
+
+	// evaluate the query expression
+	v, err := n.query.Eval(expr.EvalStack{
+		Tx:     n.tx,
+		Params: n.params,
+	})
+	if err != nil {
+		return
+	}
+	if v.Type != document.TextValue {
+		return errors.New("MATCH() query must be a text")
+	}
+
+	n.evaluatedQuery = v.V.(string)
+	return
+}
+
+func (n *fullTextInputNode) buildStream() (document.Stream, error) {
+	return document.NewStream(&fullTextIterator{
+		tb:    n.table,
+		index: n.index,
+		query: n.evaluatedQuery,
+	}), nil
+}
+
+func (n *fullTextInputNode) String() string {
+	return fmt.Sprintf("FullTextIndex(%s)", n.indexName)
+}
+
+type fullTextIterator struct {
+	tb    *database.Table
+	index *database.Index
+	query string
+}
+
+func (it fullTextIterator) Iterate(fn func(d document.Document) error) error {
+	return it.index.FullText.Search(it.query, func(key []byte, score float64) error {
+		return it.tb.VisitIndexedDocument(key, func(d document.Document) error {
+			return fn(scoredDocument{Document: d, key: key, score: score})
+		})
+	})
+}
+
+// scoredDocument is a document read from a full-text index,
+// whose relevance can be returned by the score() function.
+type scoredDocument struct {
+	document.Document
+
+	key   []byte
+	score float64
+}
+
+var _ expr.Scorer = scoredDocument{}
+
+func (d scoredDocument) Key() []byte {
+	return d.key
+}
+
+func (d scoredDocument) Score() float64 {
+	return d.score
+}
+
+type spatialInputNode struct {
+	node
+
+	tableName string
+	indexName string
+	// cond is either a ST_WITHIN_RADIUS or a ST_WITHIN_BOX function.
+	cond expr.Expr
+
+	tx     *database.Transaction
+	params []expr.Param
+	table  *database.Table
+	index  *database.Index
+	center spatial.Point
+	radius float64
+	box    spatial.Box
+}
+
+var _ inputNode = (*spatialInputNode)(nil)
+
+// NewSpatialInputNode creates a node that reads the documents whose point is within
+// a radius or a box from a spatial index. The condition must be a ST_WITHIN_RADIUS
+// or a ST_WITHIN_BOX function.
+func NewSpatialInputNode(tableName, indexName string, cond expr.Expr) Node {
+	return &spatialInputNode{
+		node: node{
+			op: Input,
+		},
+		tableName: tableName,
+		indexName: indexName,
+		cond:      cond,
+	}
+}
+
+func (n *spatialInputNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
+	if n.table == nil {
+		n.table, err = tx.GetTable(n.tableName)
+		if err != nil {
+			return
+		}
+	}
+
+	if n.index == nil {
+		n.index, err = tx.GetIndex(n.indexName)
+		if err != nil {
+			return
+		}
+	}
+
+	n.tx = tx
+	n.params = params
+
+	// evaluate the area
+	stack := expr.EvalStack{
+		Tx:     n.tx,
+		Params: n.params,
+	}
+	switch f := n.cond.(type) {
+	case expr.WithinRadiusFunc:
+		n.center, n.radius, err = f.Circle(stack)
+	case expr.WithinBoxFunc:
+		n.box, err = f.Box(stack)
+	default:
+		err = fmt.Errorf("unsupported spatial condition %v", n.cond)
+	}
+
+	return
+}
+
+func (n *spatialInputNode) buildStream() (document.Stream, error) {
+	return document.NewStream(&spatialIterator{
+		tb:   n.table,
+		node: n,
+	}), nil
+}
+
+func (n *spatialInputNode) String() string {
+	return fmt.Sprintf("SpatialIndex(%s)", n.indexName)
+}
+
+type spatialIterator struct {
+	tb   *database.Table
+	node *spatialInputNode
+}
+
+func (it spatialIterator) Iterate(fn func(d document.Document) error) error {
+	getDocument := func(key []byte) error {
+		return it.tb.VisitIndexedDocument(key, fn)
+	}
+
+	if _, ok := it.node.cond.(expr.WithinRadiusFunc); ok {
+		return it.node.index.Spatial.SearchRadius(it.node.center, it.node.radius, func(key []byte, _ float64) error {
+			return getDocument(key)
+		})
+	}
+
+	return it.node.index.Spatial.SearchBox(it.node.box, getDocument)
+}
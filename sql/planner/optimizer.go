@@ -12,6 +12,8 @@ var optimizerRules = []func(t *Tree) (*Tree, error){
 	PrecalculateExprRule,
 	RemoveUnnecessarySelectionNodesRule,
 	RemoveUnnecessaryDedupNodeRule,
+	UseFullTextIndexRule,
+	UseSpatialIndexRule,
 	UseIndexBasedOnSelectionNodeRule,
 }
 
@@ -115,7 +117,7 @@ func PrecalculateExprRule(t *Tree) (*Tree, error) {
 	for n != nil {
 		if n.Operation() == Selection {
 			sn := n.(*selectionNode)
-			sn.cond = precalculateExpr(sn.cond)
+			sn.cond, _ = precalculateExpr(sn.cond)
 		}
 
 		n = n.Left()
@@ -127,19 +129,23 @@ func PrecalculateExprRule(t *Tree) (*Tree, error) {
 // precalculateExpr is a recursive function that tries to precalculate
 // expression nodes when possible.
 // it returns a new expression with simplified nodes.
-// if no simplification is possible it returns the same expression.
-func precalculateExpr(e expr.Expr) expr.Expr {
+// if no simplification is possible it returns the same expression and false.
+// Sub-expressions are only replaced if they change, to let already
+// optimized expressions be shared by concurrent executions.
+func precalculateExpr(e expr.Expr) (expr.Expr, bool) {
 	switch t := e.(type) {
 	case expr.LiteralExprList:
 		// we assume that the list of expressions contains only literals
 		// until proven wrong.
 		literalsOnly := true
 		for i, te := range t {
-			newExpr := precalculateExpr(te)
+			newExpr, changed := precalculateExpr(te)
 			if _, ok := newExpr.(expr.LiteralValue); !ok {
 				literalsOnly = false
 			}
-			t[i] = newExpr
+			if changed {
+				t[i] = newExpr
+			}
 		}
 
 		// if literalsOnly is still true, it means we have a list of constant expressions
@@ -150,7 +156,7 @@ func precalculateExpr(e expr.Expr) expr.Expr {
 				values[i] = document.Value(t[i].(expr.LiteralValue))
 			}
 
-			return expr.ArrayValue(document.NewValueBuffer(values...))
+			return expr.ArrayValue(document.NewValueBuffer(values...)), true
 		}
 
 	case expr.KVPairs:
@@ -159,11 +165,13 @@ func precalculateExpr(e expr.Expr) expr.Expr {
 		literalsOnly := true
 
 		for i, kv := range t {
-			kv.V = precalculateExpr(kv.V)
-			if _, ok := kv.V.(expr.LiteralValue); !ok {
+			v, changed := precalculateExpr(kv.V)
+			if _, ok := v.(expr.LiteralValue); !ok {
 				literalsOnly = false
 			}
-			t[i] = kv
+			if changed {
+				t[i].V = v
+			}
 		}
 
 		// if literalsOnly is still true, it means we have a list of kvpairs
@@ -175,7 +183,7 @@ func precalculateExpr(e expr.Expr) expr.Expr {
 				fb.Add(t[i].K, document.Value(t[i].V.(expr.LiteralValue)))
 			}
 
-			return expr.LiteralValue(document.NewDocumentValue(&fb))
+			return expr.LiteralValue(document.NewDocumentValue(&fb)), true
 		}
 	case expr.Operator:
 		// since expr.Operator is an interface,
@@ -185,13 +193,17 @@ func precalculateExpr(e expr.Expr) expr.Expr {
 			!expr.IsOrOperator(t) &&
 			!expr.IsArithmeticOperator(t) &&
 			!expr.IsComparisonOperator(t) {
-			return e
+			return e, false
 		}
 
-		lh := precalculateExpr(t.LeftHand())
-		rh := precalculateExpr(t.RightHand())
-		t.SetLeftHandExpr(lh)
-		t.SetRightHandExpr(rh)
+		lh, changed := precalculateExpr(t.LeftHand())
+		if changed {
+			t.SetLeftHandExpr(lh)
+		}
+		rh, changed := precalculateExpr(t.RightHand())
+		if changed {
+			t.SetRightHandExpr(rh)
+		}
 
 		_, leftIsLit := lh.(expr.LiteralValue)
 		_, rightIsLit := rh.(expr.LiteralValue)
@@ -203,11 +215,11 @@ func precalculateExpr(e expr.Expr) expr.Expr {
 				panic(err)
 			}
 			// we replace this expression with the result of its evaluation
-			return expr.LiteralValue(v)
+			return expr.LiteralValue(v), true
 		}
 	}
 
-	return e
+	return e, false
 }
 
 // RemoveUnnecessarySelectionNodesRule removes any selection node whose
@@ -311,6 +323,175 @@ func isProjectionUnique(indexes map[string]database.Index, pn *ProjectionNode) b
 	return true
 }
 
+// UseFullTextIndexRule scans the tree for the first selection node whose condition
+// is a MATCH function whose path is indexed by a full-text index, and whose query
+// is a literal value or a parameter.
+// If found, it removes the selection node and replaces the input node by a fullTextInputNode
+// reading the matching documents from the index, by descending relevance.
+// It is applied before the other index rules: the score() function
+// requires the documents to be read from a full-text index.
+func UseFullTextIndexRule(t *Tree) (*Tree, error) {
+	return replaceSelectionByInputNode(t, selectionNodeValidForFullTextIndex)
+}
+
+// UseSpatialIndexRule scans the tree for the first selection node whose condition
+// is a ST_WITHIN_RADIUS or ST_WITHIN_BOX function whose path is indexed by a spatial index,
+// and whose other arguments are literal values, parameters or points built from them.
+// If found, it removes the selection node and replaces the input node by a spatialInputNode
+// reading the matching documents from the index. Documents within a radius are read
+// by ascending distance, which allows to select the nearest ones with LIMIT.
+func UseSpatialIndexRule(t *Tree) (*Tree, error) {
+	return replaceSelectionByInputNode(t, selectionNodeValidForSpatialIndex)
+}
+
+// replaceSelectionByInputNode looks for the first selection node for which valid returns
+// an input node. If found, it removes the selection node and replaces the table input node
+// by the returned input node, whose results are expected to satisfy the condition
+// of the selection node.
+func replaceSelectionByInputNode(t *Tree, valid func(sn *selectionNode, tableName string, indexes map[string]database.Index) Node) (*Tree, error) {
+	n := t.Root
+	var prev Node
+	var inpn *tableInputNode
+
+	// first we lookup for the input node
+	for n != nil {
+		if n.Operation() == Input {
+			inpn, _ = n.(*tableInputNode)
+			break
+		}
+
+		n = n.Left()
+	}
+
+	if inpn == nil {
+		return t, nil
+	}
+
+	var in Node
+	n = t.Root
+	for n != nil {
+		if n.Operation() == Selection {
+			in = valid(n.(*selectionNode), inpn.tableName, inpn.indexes)
+			if in != nil {
+				break
+			}
+		}
+
+		prev = n
+		n = n.Left()
+	}
+
+	if in == nil {
+		return t, nil
+	}
+
+	// we make sure the new input node is bound
+	if err := in.Bind(inpn.tx, inpn.params); err != nil {
+		return nil, err
+	}
+
+	// we remove the selection node from the tree
+	if prev == nil {
+		t.Root = n.Left()
+	} else {
+		prev.SetLeft(n.Left())
+	}
+
+	n = t.Root
+	prev = nil
+	// we lookup again for the input node and the node that is right before.
+	for n != nil {
+		if n.Operation() == Input {
+			break
+		}
+
+		prev = n
+		n = n.Left()
+	}
+
+	// we replace the table input node by the new input node
+	if prev == nil {
+		t.Root = in
+	} else {
+		prev.SetLeft(in)
+	}
+
+	return t, nil
+}
+
+func selectionNodeValidForFullTextIndex(sn *selectionNode, tableName string, indexes map[string]database.Index) Node {
+	m, ok := sn.cond.(expr.MatchFunc)
+	if !ok {
+		return nil
+	}
+
+	path, ok := m.Path.(expr.Path)
+	if !ok || !isLiteralOrParam(m.Query) {
+		return nil
+	}
+
+	for _, idx := range indexes {
+		if idx.FullText != nil && idx.Opts.Path.IsEqual(document.Path(path)) {
+			in := NewFullTextInputNode(tableName, idx.Opts.IndexName, m.Query).(*fullTextInputNode)
+			in.index = &idx
+			return in
+		}
+	}
+
+	return nil
+}
+
+func selectionNodeValidForSpatialIndex(sn *selectionNode, tableName string, indexes map[string]database.Index) Node {
+	var path expr.Expr
+	var cond expr.Expr
+	var args []expr.Expr
+
+	// document literals, like {lat: 48.8, lon: 2.3}, are turned into literal values.
+	switch f := sn.cond.(type) {
+	case expr.WithinRadiusFunc:
+		f.Center, _ = precalculateExpr(f.Center)
+		f.Radius, _ = precalculateExpr(f.Radius)
+		path, cond, args = f.Path, f, []expr.Expr{f.Center, f.Radius}
+	case expr.WithinBoxFunc:
+		f.SW, _ = precalculateExpr(f.SW)
+		f.NE, _ = precalculateExpr(f.NE)
+		path, cond, args = f.Path, f, []expr.Expr{f.SW, f.NE}
+	default:
+		return nil
+	}
+
+	p, ok := path.(expr.Path)
+	if !ok {
+		return nil
+	}
+
+	for _, arg := range args {
+		if !isSpatialArg(arg) {
+			return nil
+		}
+	}
+
+	for _, idx := range indexes {
+		if idx.Spatial != nil && idx.Opts.Path.IsEqual(document.Path(p)) {
+			in := NewSpatialInputNode(tableName, idx.Opts.IndexName, cond).(*spatialInputNode)
+			in.index = &idx
+			return in
+		}
+	}
+
+	return nil
+}
+
+// isSpatialArg returns true if e is a literal value, a parameter,
+// or a ST_POINT function whose arguments are literal values or parameters.
+func isSpatialArg(e expr.Expr) bool {
+	if f, ok := e.(expr.PointFunc); ok {
+		return isLiteralOrParam(f.Lat) && isLiteralOrParam(f.Lon)
+	}
+
+	return isLiteralOrParam(e)
+}
+
 // UseIndexBasedOnSelectionNodeRule scans the tree for the first selection node whose condition is an
 // operator that satisfies the following criterias:
 // - implements the indexIteratorOperator interface
@@ -336,9 +517,11 @@ func UseIndexBasedOnSelectionNodeRule(t *Tree) (*Tree, error) {
 		return t, nil
 	}
 
-	// Here we will assume that at this point
-	// inputNodes can only be instances of tableInputNode.
-	inpn := inputNode.(*tableInputNode)
+	// the input node may already read from an index.
+	inpn, ok := inputNode.(*tableInputNode)
+	if !ok {
+		return t, nil
+	}
 
 	type candidate struct {
 		prevNode, nextNode Node
@@ -434,6 +617,13 @@ func selectionNodeValidForIndex(sn *selectionNode, tableName string, indexes map
 		return nil
 	}
 
+	// "expr IN path" can read from a multi-key index on path.
+	if expr.IsInOperator(op) {
+		if in := inOperatorValidForMultikeyIndex(op, tableName, indexes); in != nil {
+			return in
+		}
+	}
+
 	// determine if the operator can read from the index
 	iop, ok := op.(IndexIteratorOperator)
 	if !ok {
@@ -463,6 +653,27 @@ func selectionNodeValidForIndex(sn *selectionNode, tableName string, indexes map
 	return in
 }
 
+// inOperatorValidForMultikeyIndex returns an index input node reading the documents
+// whose array contains the left operand of the IN operator, if the right operand
+// is a path indexed by a multi-key index and the left operand is a literal or a param.
+func inOperatorValidForMultikeyIndex(op expr.Operator, tableName string, indexes map[string]database.Index) *indexInputNode {
+	path, ok := op.RightHand().(expr.Path)
+	if !ok || !isLiteralOrParam(op.LeftHand()) {
+		return nil
+	}
+
+	// multi-key indexes are listed under the path followed by "[*]"
+	idx, ok := indexes[path.String()+"[*]"]
+	if !ok {
+		return nil
+	}
+
+	in := NewIndexInputNode(tableName, idx.Opts.IndexName, elementOperator{}, path, op.LeftHand(), scanner.ASC).(*indexInputNode)
+	in.index = &idx
+
+	return in
+}
+
 func opCanUseIndex(op expr.Operator) (bool, expr.Path, expr.Expr) {
 	lf, leftIsField := op.LeftHand().(expr.Path)
 	rf, rightIsField := op.RightHand().(expr.Path)
@@ -491,6 +491,70 @@ func TestUseIndexBasedOnSelectionNodeRule(t *testing.T) {
 				),
 			),
 		},
+		{
+			"FROM foo WHERE 1 IN e",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.In(
+					expr.IntegerValue(1),
+					expr.Path(parsePath(t, "e")),
+				),
+			),
+			planner.NewIndexInputNode(
+				"foo",
+				"idx_foo_e",
+				expr.Eq(nil, nil).(planner.IndexIteratorOperator),
+				expr.Path(parsePath(t, "e")),
+				expr.IntegerValue(1),
+				scanner.ASC,
+			),
+		},
+		{
+			"FROM foo WHERE ? IN e",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.In(
+					expr.PositionalParam(1),
+					expr.Path(parsePath(t, "e")),
+				),
+			),
+			planner.NewIndexInputNode(
+				"foo",
+				"idx_foo_e",
+				expr.Eq(nil, nil).(planner.IndexIteratorOperator),
+				expr.Path(parsePath(t, "e")),
+				expr.PositionalParam(1),
+				scanner.ASC,
+			),
+		},
+		{
+			"FROM foo WHERE a IN e",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.In(
+					expr.Path(parsePath(t, "a")),
+					expr.Path(parsePath(t, "e")),
+				),
+			),
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.In(
+					expr.Path(parsePath(t, "a")),
+					expr.Path(parsePath(t, "e")),
+				),
+			),
+		},
+		{
+			"FROM foo WHERE e = 1",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.Eq(
+					expr.Path(parsePath(t, "e")),
+					expr.IntegerValue(1),
+				),
+			),
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.Eq(
+					expr.Path(parsePath(t, "e")),
+					expr.IntegerValue(1),
+				),
+			),
+		},
 	}
 
 	for _, test := range tests {
@@ -508,6 +572,7 @@ func TestUseIndexBasedOnSelectionNodeRule(t *testing.T) {
 				CREATE INDEX idx_foo_a ON foo(a);
 				CREATE INDEX idx_foo_b ON foo(b);
 				CREATE UNIQUE INDEX idx_foo_c ON foo(c);
+				CREATE INDEX idx_foo_e ON foo(e[*]);
 				INSERT INTO foo (a, b, c, d) VALUES
 					(1, 1, 1, 1),
 					(2, 2, 2, 2),
@@ -531,3 +596,171 @@ func TestUseIndexBasedOnSelectionNodeRule(t *testing.T) {
 		})
 	}
 }
+
+func TestUseFullTextIndexRule(t *testing.T) {
+	match := func(path string, query expr.Expr) expr.Expr {
+		return expr.MatchFunc{Path: expr.Path(parsePath(t, path)), Query: query}
+	}
+
+	tests := []struct {
+		name           string
+		root, expected planner.Node
+	}{
+		{
+			"non-indexed path",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"), match("b", expr.TextValue("hello"))),
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"), match("b", expr.TextValue("hello"))),
+		},
+		{
+			"query is not a literal",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"), match("a", expr.Path(parsePath(t, "b")))),
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"), match("a", expr.Path(parsePath(t, "b")))),
+		},
+		{
+			"FROM foo WHERE MATCH(a, 'hello')",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"), match("a", expr.TextValue("hello"))),
+			planner.NewFullTextInputNode("foo", "idx_foo_a", expr.TextValue("hello")),
+		},
+		{
+			"FROM foo WHERE b = 2 AND MATCH(a, ?)",
+			planner.NewSelectionNode(
+				planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+					expr.Eq(
+						expr.Path(parsePath(t, "b")),
+						expr.IntegerValue(2),
+					),
+				),
+				match("a", expr.PositionalParam(1)),
+			),
+			planner.NewSelectionNode(
+				planner.NewFullTextInputNode("foo", "idx_foo_a", expr.PositionalParam(1)),
+				expr.Eq(
+					expr.Path(parsePath(t, "b")),
+					expr.IntegerValue(2),
+				),
+			),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			tx, err := db.Begin(true)
+			require.NoError(t, err)
+			defer tx.Rollback()
+
+			err = tx.Exec(`
+				CREATE TABLE foo;
+				CREATE FULLTEXT INDEX idx_foo_a ON foo(a);
+				CREATE INDEX idx_foo_b ON foo(b);
+				INSERT INTO foo (a, b) VALUES ('hello world', 1), ('hello', 2)
+			`)
+			require.NoError(t, err)
+
+			params := []expr.Param{{Value: "hello"}}
+			err = planner.Bind(planner.NewTree(test.root), tx.Transaction, params)
+			require.NoError(t, err)
+
+			res, err := planner.UseFullTextIndexRule(planner.NewTree(test.root))
+			require.NoError(t, err)
+			// the full-text index takes precedence over the other indexes.
+			res, err = planner.UseIndexBasedOnSelectionNodeRule(res)
+			require.NoError(t, err)
+			require.Equal(t, planner.NewTree(test.expected).String(), res.String())
+		})
+	}
+}
+
+func TestUseSpatialIndexRule(t *testing.T) {
+	point := func(lat, lon float64) expr.Expr {
+		return expr.PointFunc{Lat: expr.DoubleValue(lat), Lon: expr.DoubleValue(lon)}
+	}
+	radius := func(path string, center, radius expr.Expr) expr.Expr {
+		return expr.WithinRadiusFunc{Path: expr.Path(parsePath(t, path)), Center: center, Radius: radius}
+	}
+	box := func(path string, sw, ne expr.Expr) expr.Expr {
+		return expr.WithinBoxFunc{Path: expr.Path(parsePath(t, path)), SW: sw, NE: ne}
+	}
+
+	tests := []struct {
+		name           string
+		root, expected planner.Node
+	}{
+		{
+			"non-indexed path",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"), radius("b", point(1, 2), expr.IntegerValue(10))),
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"), radius("b", point(1, 2), expr.IntegerValue(10))),
+		},
+		{
+			"center is not a literal",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"), radius("a", expr.Path(parsePath(t, "b")), expr.IntegerValue(10))),
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"), radius("a", expr.Path(parsePath(t, "b")), expr.IntegerValue(10))),
+		},
+		{
+			"FROM foo WHERE ST_WITHIN_RADIUS(a, ST_POINT(1, 2), 10)",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"), radius("a", point(1, 2), expr.IntegerValue(10))),
+			planner.NewSpatialInputNode("foo", "idx_foo_a", radius("a", point(1, 2), expr.IntegerValue(10))),
+		},
+		{
+			"FROM foo WHERE ST_WITHIN_RADIUS(a, {lat: 1, lon: 2}, ?)",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"), radius("a",
+				expr.KVPairs{{K: "lat", V: expr.IntegerValue(1)}, {K: "lon", V: expr.IntegerValue(2)}},
+				expr.PositionalParam(1),
+			)),
+			planner.NewSpatialInputNode("foo", "idx_foo_a", radius("a", point(1, 2), expr.PositionalParam(1))),
+		},
+		{
+			"FROM foo WHERE b = 2 AND ST_WITHIN_BOX(a, ?, ST_POINT(1, 2))",
+			planner.NewSelectionNode(
+				planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+					expr.Eq(
+						expr.Path(parsePath(t, "b")),
+						expr.IntegerValue(2),
+					),
+				),
+				box("a", expr.PositionalParam(2), point(1, 2)),
+			),
+			planner.NewSelectionNode(
+				planner.NewSpatialInputNode("foo", "idx_foo_a", box("a", expr.PositionalParam(2), point(1, 2))),
+				expr.Eq(
+					expr.Path(parsePath(t, "b")),
+					expr.IntegerValue(2),
+				),
+			),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			tx, err := db.Begin(true)
+			require.NoError(t, err)
+			defer tx.Rollback()
+
+			err = tx.Exec(`
+				CREATE TABLE foo;
+				CREATE SPATIAL INDEX idx_foo_a ON foo(a);
+				CREATE INDEX idx_foo_b ON foo(b);
+				INSERT INTO foo (a, b) VALUES ({lat: 1, lon: 2}, 1), ({lat: 1.5, lon: 2.5}, 2)
+			`)
+			require.NoError(t, err)
+
+			params := []expr.Param{{Value: 1000}, {Value: map[string]float64{"lat": 0, "lon": 0}}}
+			err = planner.Bind(planner.NewTree(test.root), tx.Transaction, params)
+			require.NoError(t, err)
+
+			res, err := planner.UseSpatialIndexRule(planner.NewTree(test.root))
+			require.NoError(t, err)
+			// the spatial index takes precedence over the other indexes.
+			res, err = planner.UseIndexBasedOnSelectionNodeRule(res)
+			require.NoError(t, err)
+			require.Equal(t, planner.NewTree(test.expected).String(), res.String())
+		})
+	}
+}
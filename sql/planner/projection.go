@@ -19,8 +19,9 @@ type ProjectionNode struct {
 	Expressions []ProjectedField
 	tableName   string
 
-	info *database.TableInfo
-	tx   *database.Transaction
+	info   *database.TableInfo
+	tx     *database.Transaction
+	params []expr.Param
 }
 
 var _ operationNode = (*ProjectionNode)(nil)
@@ -40,6 +41,7 @@ func NewProjectionNode(n Node, expressions []ProjectedField, tableName string) N
 // Bind database resources to this node.
 func (n *ProjectionNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
 	n.tx = tx
+	n.params = params
 	if n.tableName == "" {
 		return
 	}
@@ -56,6 +58,7 @@ func (n *ProjectionNode) Bind(tx *database.Transaction, params []expr.Param) (er
 func (n *ProjectionNode) toStream(st document.Stream) (document.Stream, error) {
 	if st.IsEmpty() {
 		d := documentMask{
+			params:       n.params,
 			resultFields: n.Expressions,
 		}
 		var fb document.FieldBuffer
@@ -69,6 +72,7 @@ func (n *ProjectionNode) toStream(st document.Stream) (document.Stream, error) {
 		var dm documentMask
 		st = st.Map(func(d document.Document) (document.Document, error) {
 			dm.info = n.info
+			dm.params = n.params
 			dm.d = d
 			dm.resultFields = n.Expressions
 
@@ -94,6 +98,7 @@ func (n *ProjectionNode) String() string {
 
 type documentMask struct {
 	info         *database.TableInfo
+	params       []expr.Param
 	d            document.Document
 	resultFields []ProjectedField
 }
@@ -111,6 +116,7 @@ func (r documentMask) GetByField(field string) (v document.Value, err error) {
 			stack := expr.EvalStack{
 				Document: r.d,
 				Info:     r.info,
+				Params:   r.params,
 			}
 			var found bool
 			err = rf.Iterate(stack, func(f string, value document.Value) error {
@@ -135,6 +141,7 @@ func (r documentMask) Iterate(fn func(field string, value document.Value) error)
 	stack := expr.EvalStack{
 		Document: r.d,
 		Info:     r.info,
+		Params:   r.params,
 	}
 
 	for _, rf := range r.resultFields {
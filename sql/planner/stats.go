@@ -0,0 +1,172 @@
+package planner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+)
+
+// queryStats measures the execution of a tree
+// and reports it to the database.
+type queryStats struct {
+	db   *database.Database
+	plan string
+	// if true, the time spent in each node is measured,
+	// which requires reading the clock around each document.
+	timeNodes bool
+	// stats of each node, from the input to the root.
+	nodes []*nodeStats
+}
+
+type nodeStats struct {
+	node  Node
+	index bool
+
+	// number of documents output by the node.
+	documents int64
+	// number of iterations over the stream of the node.
+	iterations int64
+	// time spent building the stream of the node, including the iteration
+	// of its input by nodes which consume it eagerly.
+	build time.Duration
+	// time spent iterating the stream of the node, including its input.
+	// If the nodes are timed, it excludes the downstream nodes.
+	iter time.Duration
+}
+
+// nodeToStream builds the stream of n and of its input, and wraps
+// each of them to measure them.
+func (qs *queryStats) nodeToStream(n Node) (st document.Stream, err error) {
+	l := n.Left()
+	if l != nil {
+		st, err = qs.nodeToStream(l)
+		if err != nil {
+			return
+		}
+	}
+
+	ns := nodeStats{node: n}
+	start := time.Now()
+
+	switch t := n.(type) {
+	case inputNode:
+		switch t.(type) {
+		case *indexInputNode, *fullTextInputNode, *spatialInputNode:
+			ns.index = true
+		}
+		st, err = t.buildStream()
+	case operationNode:
+		st, err = t.toStream(st)
+	default:
+		panic(fmt.Sprintf("incorrect node type %#v", n))
+	}
+	if err != nil {
+		return
+	}
+
+	ns.build = time.Since(start)
+	qs.nodes = append(qs.nodes, &ns)
+
+	return qs.wrap(&ns, st), nil
+}
+
+// wrap returns a stream that measures the iterations of st.
+// Empty streams are returned as is.
+func (qs *queryStats) wrap(ns *nodeStats, st document.Stream) document.Stream {
+	if st.IsEmpty() {
+		return st
+	}
+
+	if !qs.timeNodes {
+		return document.NewStream(document.IteratorFunc(func(fn func(d document.Document) error) error {
+			ns.iterations++
+			start := time.Now()
+			err := st.Iterate(func(d document.Document) error {
+				ns.documents++
+				return fn(d)
+			})
+			ns.iter += time.Since(start)
+
+			return err
+		}))
+	}
+
+	return document.NewStream(document.IteratorFunc(func(fn func(d document.Document) error) error {
+		var downstream time.Duration
+
+		ns.iterations++
+		start := time.Now()
+		err := st.Iterate(func(d document.Document) error {
+			ns.documents++
+
+			t := time.Now()
+			err := fn(d)
+			downstream += time.Since(t)
+			return err
+		})
+		ns.iter += time.Since(start) - downstream
+
+		return err
+	}))
+}
+
+// elapsed returns the time spent in the i-th node, excluding the other nodes.
+func (qs *queryStats) elapsed(i int) time.Duration {
+	d := qs.nodes[i].build + qs.nodes[i].iter
+	if i > 0 {
+		d -= qs.nodes[i-1].iter
+	}
+
+	return d
+}
+
+// report sends the statistics measured since the last report to the database.
+func (qs *queryStats) report() {
+	stats := database.QueryStats{
+		Plan:  qs.plan,
+		Nodes: make([]database.NodeStats, 0, len(qs.nodes)),
+	}
+
+	for i, ns := range qs.nodes {
+		op := ns.node.Operation()
+
+		var d time.Duration
+		if qs.timeNodes {
+			d = qs.elapsed(i)
+			stats.Duration += d
+		} else {
+			stats.Duration += ns.build
+		}
+
+		stats.Nodes = append(stats.Nodes, database.NodeStats{
+			Operation: op.String(),
+			Documents: ns.documents,
+			Duration:  d,
+		})
+
+		if op == Input {
+			stats.DocumentsScanned += ns.documents
+		}
+		if ns.index {
+			stats.IndexLookups += ns.iterations
+		}
+	}
+
+	if len(qs.nodes) > 0 {
+		root := qs.nodes[len(qs.nodes)-1]
+		stats.DocumentsReturned = root.documents
+
+		// the iteration of the root includes the one of the other nodes.
+		if !qs.timeNodes {
+			stats.Duration += root.iter
+		}
+	}
+
+	for _, ns := range qs.nodes {
+		*ns = nodeStats{node: ns.node, index: ns.index}
+	}
+
+	qs.db.ReportQuery(stats)
+}
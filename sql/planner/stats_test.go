@@ -0,0 +1,171 @@
+package planner_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+type queryHook struct {
+	mu      sync.Mutex
+	queries []database.QueryStats
+}
+
+func (h *queryHook) TransactionBegun(writable bool)      {}
+func (h *queryHook) TransactionCommitted(writable bool)  {}
+func (h *queryHook) TransactionRolledBack(writable bool) {}
+
+func (h *queryHook) QueryDone(qs database.QueryStats) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.queries = append(h.queries, qs)
+}
+
+func (h *queryHook) last() database.QueryStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.queries[len(h.queries)-1]
+}
+
+func TestQueryStats(t *testing.T) {
+	var h queryHook
+	db, err := genji.New(context.Background(), memoryengine.NewEngine(), genji.WithStatsHook(&h))
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(`
+		CREATE TABLE test;
+		CREATE INDEX idx_a ON test (a);
+		INSERT INTO test (a, b) VALUES (1, 1), (2, 2), (3, 3), (4, 4), (5, 5);
+	`)
+	require.NoError(t, err)
+
+	operations := func(qs database.QueryStats) []string {
+		var ops []string
+		for _, ns := range qs.Nodes {
+			ops = append(ops, ns.Operation)
+		}
+		return ops
+	}
+
+	documents := func(qs database.QueryStats) []int64 {
+		var docs []int64
+		for _, ns := range qs.Nodes {
+			docs = append(docs, ns.Documents)
+		}
+		return docs
+	}
+
+	t.Run("Table scan", func(t *testing.T) {
+		before := db.Stats()
+
+		res, err := db.Query("SELECT a FROM test WHERE b > 3")
+		require.NoError(t, err)
+		n, err := res.Count()
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+		require.NoError(t, res.Close())
+
+		qs := h.last()
+		require.Equal(t, "Table(test) -> σ(cond: b > 3) -> ∏(a)", qs.Plan)
+		require.Equal(t, []string{"Input", "Selection", "Projection"}, operations(qs))
+		require.Equal(t, []int64{5, 2, 2}, documents(qs))
+		require.EqualValues(t, 5, qs.DocumentsScanned)
+		require.EqualValues(t, 2, qs.DocumentsReturned)
+		require.Zero(t, qs.IndexLookups)
+
+		// nodes are only timed when enabled.
+		for _, ns := range qs.Nodes {
+			require.Zero(t, ns.Duration)
+		}
+		require.True(t, qs.Duration > 0)
+
+		stats := db.Stats()
+		require.Equal(t, before.Queries+1, stats.Queries)
+		require.Equal(t, before.DocumentsScanned+5, stats.DocumentsScanned)
+		require.Equal(t, before.DocumentsReturned+2, stats.DocumentsReturned)
+		require.Equal(t, before.QueryDuration.Count+1, stats.QueryDuration.Count)
+		require.Equal(t, before.ReadTransactions.Begun+1, stats.ReadTransactions.Begun)
+		require.Equal(t, before.ReadTransactions.RolledBack+1, stats.ReadTransactions.RolledBack)
+		require.Equal(t, before.Nodes["Selection"].Documents+2, stats.Nodes["Selection"].Documents)
+	})
+
+	t.Run("Index", func(t *testing.T) {
+		before := db.Stats()
+
+		d, err := db.QueryDocument("SELECT a FROM test WHERE a = 2")
+		require.NoError(t, err)
+		require.NotNil(t, d)
+
+		qs := h.last()
+		require.Equal(t, []string{"Input", "Projection"}, operations(qs))
+		require.EqualValues(t, 1, qs.DocumentsScanned)
+		require.EqualValues(t, 1, qs.IndexLookups)
+		require.Equal(t, before.IndexLookups+1, db.Stats().IndexLookups)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		before := db.Stats()
+
+		err := db.Exec("UPDATE test SET b = 10 WHERE b < 3")
+		require.NoError(t, err)
+
+		// statements writing documents are reported once executed.
+		qs := h.last()
+		require.Equal(t, []string{"Input", "Selection", "Set", "Replacement"}, operations(qs))
+		require.Equal(t, []int64{5, 2, 2, 0}, documents(qs))
+		require.EqualValues(t, 5, qs.DocumentsScanned)
+		require.Zero(t, qs.DocumentsReturned)
+
+		stats := db.Stats()
+		require.Equal(t, before.WriteTransactions.Begun+1, stats.WriteTransactions.Begun)
+		require.Equal(t, before.WriteTransactions.Committed+1, stats.WriteTransactions.Committed)
+	})
+
+	t.Run("Without table", func(t *testing.T) {
+		d, err := db.QueryDocument("SELECT 1 + 1")
+		require.NoError(t, err)
+		require.NotNil(t, d)
+
+		qs := h.last()
+		require.Equal(t, []string{"Projection"}, operations(qs))
+		require.Zero(t, qs.DocumentsScanned)
+		require.EqualValues(t, 1, qs.DocumentsReturned)
+	})
+
+	t.Run("Node timings", func(t *testing.T) {
+		var h queryHook
+		db, err := genji.New(context.Background(), memoryengine.NewEngine(), genji.WithStatsHook(&h), genji.WithNodeTimings())
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(`
+			CREATE TABLE test;
+			INSERT INTO test (a, b) VALUES (1, 1), (2, 2), (3, 3), (4, 4), (5, 5);
+		`)
+		require.NoError(t, err)
+
+		res, err := db.Query("SELECT a FROM test WHERE b > 3")
+		require.NoError(t, err)
+		n, err := res.Count()
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+		require.NoError(t, res.Close())
+
+		qs := h.last()
+		require.Equal(t, []int64{5, 2, 2}, documents(qs))
+
+		var total int64
+		for _, ns := range qs.Nodes {
+			require.True(t, ns.Duration >= 0)
+			total += int64(ns.Duration)
+		}
+		require.True(t, total > 0)
+		require.EqualValues(t, total, qs.Duration)
+	})
+}
@@ -7,6 +7,7 @@ package planner
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
@@ -53,6 +54,12 @@ const (
 // Each node will manipulate the stream using relational algebra operations.
 type Tree struct {
 	Root Node
+
+	// optimized copy of the tree, reused by Run
+	// as long as the schema of the database doesn't change.
+	mu          sync.Mutex
+	plan        *Tree
+	planVersion uint64
 }
 
 // NewTree creates a new tree with n as root.
@@ -62,41 +69,103 @@ func NewTree(n Node) *Tree {
 
 // Run implements the query.Statement interface.
 // It binds the tree to the database resources and executes it.
+// The tree itself is not modified, which allows it to be run multiple times,
+// concurrently.
 func (t *Tree) Run(tx *database.Transaction, params []expr.Param) (query.Result, error) {
-	err := Bind(t, tx, params)
+	pt, err := t.prepare(tx, params)
 	if err != nil {
 		return query.Result{}, err
 	}
 
-	t, err = Optimize(t)
+	return pt.execute(tx)
+}
+
+// prepare returns an optimized copy of the tree, bound to tx.
+// The optimized tree is cached and only optimized again when
+// the tables or indexes of the database change.
+func (t *Tree) prepare(tx *database.Transaction, params []expr.Param) (*Tree, error) {
+	trace := tx.Trace()
+	version, known := tx.SchemaVersion()
+
+	t.mu.Lock()
+	if known && t.plan != nil && t.planVersion == version {
+		plan := t.plan
+		t.mu.Unlock()
+
+		pt := plan.clone()
+		end := trace.StartStage(database.BindStage)
+		err := Bind(pt, tx, params)
+		end(err)
+		if err != nil {
+			return nil, err
+		}
+
+		return pt, nil
+	}
+	// the tree is optimized by one goroutine at a time, since the optimizer
+	// rewrites shared expressions the first time.
+	defer t.mu.Unlock()
+
+	pt := t.clone()
+
+	end := trace.StartStage(database.BindStage)
+	err := Bind(pt, tx, params)
+	end(err)
 	if err != nil {
-		return query.Result{}, err
+		return nil, err
 	}
 
-	return t.execute()
+	end = trace.StartStage(database.OptimizeStage)
+	pt, err = Optimize(pt)
+	end(err)
+	if err != nil {
+		return nil, err
+	}
+
+	if known {
+		t.plan = pt.clone()
+		t.planVersion = version
+	}
+
+	return pt, nil
 }
 
-func (t *Tree) execute() (query.Result, error) {
+// clone returns a copy of the tree whose nodes can be bound and optimized
+// without modifying the original ones. Expressions are shared.
+func (t *Tree) clone() *Tree {
+	return &Tree{Root: cloneNode(t.Root)}
+}
+
+// execute builds the stream of the tree. Its statistics are reported
+// after each iteration of the stream or, if the stream is empty,
+// once it is built.
+func (t *Tree) execute(tx *database.Transaction) (query.Result, error) {
 	if t.Root == nil {
 		return query.Result{}, nil
 	}
-	var st document.Stream
-	var err error
 
-	if t.Root.Left() != nil {
-		st, err = nodeToStream(t.Root.Left())
-		if err != nil {
-			return query.Result{}, err
-		}
+	qs := queryStats{
+		db:        tx.DB(),
+		plan:      t.String(),
+		timeNodes: tx.DB().NodeTimings(),
 	}
 
-	st, err = t.Root.(operationNode).toStream(st)
+	st, err := qs.nodeToStream(t.Root)
 	if err != nil {
 		return query.Result{}, err
 	}
 
+	if st.IsEmpty() {
+		qs.report()
+		return query.Result{}, nil
+	}
+
 	return query.Result{
-		Stream: st,
+		Stream: document.NewStream(document.IteratorFunc(func(fn func(d document.Document) error) error {
+			defer qs.report()
+
+			return st.Iterate(fn)
+		})),
 	}, nil
 }
 
@@ -125,29 +194,92 @@ func nodeToString(n Node) string {
 }
 
 // IsReadOnly implements the query.Statement interface.
+// A tree is read-only if none of its nodes deletes or replaces documents.
 func (t *Tree) IsReadOnly() bool {
-	return false
+	return isReadOnly(t.Root)
 }
 
-func nodeToStream(n Node) (st document.Stream, err error) {
-	l := n.Left()
-	if l != nil {
-		st, err = nodeToStream(l)
-		if err != nil {
-			return
-		}
+func isReadOnly(n Node) bool {
+	if n == nil {
+		return true
+	}
+
+	switch n.Operation() {
+	case Deletion, Replacement:
+		return false
 	}
 
+	return isReadOnly(n.Left()) && isReadOnly(n.Right())
+}
+
+func cloneNode(n Node) Node {
+	if n == nil {
+		return nil
+	}
+
+	var c Node
 	switch t := n.(type) {
-	case inputNode:
-		st, err = t.buildStream()
-	case operationNode:
-		st, err = t.toStream(st)
+	case *tableInputNode:
+		cp := *t
+		c = &cp
+	case *indexInputNode:
+		cp := *t
+		// the table and the index are bound to a transaction.
+		cp.table, cp.index = nil, nil
+		c = &cp
+	case *fullTextInputNode:
+		cp := *t
+		// the table and the index are bound to a transaction.
+		cp.table, cp.index = nil, nil
+		c = &cp
+	case *spatialInputNode:
+		cp := *t
+		// the table and the index are bound to a transaction.
+		cp.table, cp.index = nil, nil
+		c = &cp
+	case *selectionNode:
+		cp := *t
+		c = &cp
+	case *limitNode:
+		cp := *t
+		c = &cp
+	case *offsetNode:
+		cp := *t
+		c = &cp
+	case *setNode:
+		cp := *t
+		c = &cp
+	case *unsetNode:
+		cp := *t
+		c = &cp
+	case *GroupingNode:
+		cp := *t
+		c = &cp
+	case *AggregationNode:
+		cp := *t
+		c = &cp
+	case *dedupNode:
+		cp := *t
+		c = &cp
+	case *ProjectionNode:
+		cp := *t
+		c = &cp
+	case *sortNode:
+		cp := *t
+		c = &cp
+	case *replacementNode:
+		cp := *t
+		c = &cp
+	case *deletionNode:
+		cp := *t
+		c = &cp
 	default:
 		panic(fmt.Sprintf("incorrect node type %#v", n))
 	}
 
-	return
+	c.SetLeft(cloneNode(n.Left()))
+	c.SetRight(cloneNode(n.Right()))
+	return c
 }
 
 // A Node represents an operation on the stream.
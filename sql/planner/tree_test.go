@@ -0,0 +1,60 @@
+package planner_test
+
+import (
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreeIsReadOnly(t *testing.T) {
+	tests := []struct {
+		query    string
+		readOnly bool
+	}{
+		{"SELECT * FROM test", true},
+		{"SELECT a FROM test WHERE a > 10 GROUP BY a ORDER BY a LIMIT 10", true},
+		{"UPDATE test SET a = 10", false},
+		{"UPDATE test UNSET a WHERE b = 1", false},
+		{"DELETE FROM test WHERE a > 10", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			stmt, err := parser.ParseQuery(test.query)
+			require.NoError(t, err)
+			require.Len(t, stmt.Statements, 1)
+			require.Equal(t, test.readOnly, stmt.Statements[0].IsReadOnly())
+		})
+	}
+}
+
+func TestTreeRun(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec("CREATE TABLE test; CREATE INDEX idx_a ON test (a); INSERT INTO test (a, b) VALUES (1, 1), (2, 2)")
+	require.NoError(t, err)
+
+	q, err := parser.ParseQuery("SELECT b FROM test WHERE a = 1 + 1 AND b > 0")
+	require.NoError(t, err)
+	tree := q.Statements[0].(*planner.Tree)
+
+	// running the tree doesn't modify its nodes, so it can be run again.
+	for i := 0; i < 2; i++ {
+		tx, err := db.Begin(false)
+		require.NoError(t, err)
+
+		res, err := tree.Run(tx.Transaction, nil)
+		require.NoError(t, err)
+		n, err := res.Count()
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+		require.NoError(t, tx.Rollback())
+		// constant expressions may have been precalculated.
+		require.Equal(t, "Table(test) -> σ(cond: a = 2 AND b > 0) -> ∏(b)", tree.String())
+	}
+}
@@ -50,6 +50,9 @@ type CreateIndexStmt struct {
 	Path        document.Path
 	IfNotExists bool
 	Unique      bool
+	FullText    bool
+	Multikey    bool
+	Spatial     bool
 }
 
 // IsReadOnly always returns false. It implements the Statement interface.
@@ -79,6 +82,9 @@ func (stmt CreateIndexStmt) Run(tx *database.Transaction, args []expr.Param) (Re
 		IndexName: stmt.IndexName,
 		TableName: stmt.TableName,
 		Path:      stmt.Path,
+		FullText:  stmt.FullText,
+		Multikey:  stmt.Multikey,
+		Spatial:   stmt.Spatial,
 	})
 	if stmt.IfNotExists && err == database.ErrIndexAlreadyExists {
 		err = nil
@@ -86,3 +92,46 @@ func (stmt CreateIndexStmt) Run(tx *database.Transaction, args []expr.Param) (Re
 
 	return res, err
 }
+
+// CreateTriggerStmt is a DSL that allows creating a full CREATE TRIGGER statement.
+type CreateTriggerStmt struct {
+	TriggerName string
+	TableName   string
+	IfNotExists bool
+	Timing      database.TriggerTiming
+	Event       database.ChangeType
+	// Statement run every time the trigger is fired.
+	Statement string
+}
+
+// IsReadOnly always returns false. It implements the Statement interface.
+func (stmt CreateTriggerStmt) IsReadOnly() bool {
+	return false
+}
+
+// Run runs the Create trigger statement in the given transaction.
+// It implements the Statement interface.
+func (stmt CreateTriggerStmt) Run(tx *database.Transaction, args []expr.Param) (Result, error) {
+	var res Result
+
+	if stmt.TriggerName == "" {
+		return res, errors.New("missing trigger name")
+	}
+
+	if stmt.TableName == "" {
+		return res, errors.New("missing table name")
+	}
+
+	err := tx.CreateTrigger(database.TriggerConfig{
+		TriggerName: stmt.TriggerName,
+		TableName:   stmt.TableName,
+		Timing:      stmt.Timing,
+		Event:       stmt.Event,
+		Statement:   stmt.Statement,
+	})
+	if stmt.IfNotExists && err == database.ErrTriggerAlreadyExists {
+		err = nil
+	}
+
+	return res, err
+}
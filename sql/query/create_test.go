@@ -213,6 +213,11 @@ func TestCreateIndex(t *testing.T) {
 		{"Basic", "CREATE INDEX idx ON test (foo)", false},
 		{"If not exists", "CREATE INDEX IF NOT EXISTS idx ON test (foo.bar)", false},
 		{"Unique", "CREATE UNIQUE INDEX IF NOT EXISTS idx ON test (foo[1])", false},
+		{"Full-text", "CREATE FULLTEXT INDEX idx ON test (foo.bar)", false},
+		{"Multi-key", "CREATE INDEX idx ON test (foo.bar[*])", false},
+		{"Multi-key full-text", "CREATE FULLTEXT INDEX idx ON test (foo[*])", true},
+		{"Spatial", "CREATE SPATIAL INDEX idx ON test (foo.bar)", false},
+		{"Multi-key spatial", "CREATE SPATIAL INDEX idx ON test (foo[*])", true},
 		{"No fields", "CREATE INDEX idx ON test", true},
 		{"More than 1 field", "CREATE INDEX idx ON test (foo, bar)", true},
 	}
@@ -235,3 +240,38 @@ func TestCreateIndex(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateTrigger(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		fails bool
+	}{
+		{"Basic", "CREATE TRIGGER tr AFTER INSERT ON test INSERT INTO log (a) VALUES ($new.a)", false},
+		{"If not exists", "CREATE TRIGGER IF NOT EXISTS tr AFTER INSERT ON test FOR EACH ROW INSERT INTO log (a) VALUES ($new.a)", false},
+		{"Already exists", "CREATE TRIGGER existing AFTER INSERT ON test INSERT INTO log (a) VALUES ($new.a)", true},
+		{"If not exists on existing", "CREATE TRIGGER IF NOT EXISTS existing AFTER INSERT ON test INSERT INTO log (a) VALUES ($new.a)", false},
+		{"Unknown table", "CREATE TRIGGER tr AFTER INSERT ON unknown INSERT INTO log (a) VALUES ($new.a)", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(`
+				CREATE TABLE test; CREATE TABLE log;
+				CREATE TRIGGER existing AFTER DELETE ON test DELETE FROM log
+			`)
+			require.NoError(t, err)
+
+			err = db.Exec(test.query)
+			if test.fails {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -76,3 +76,47 @@ func TestDropIndex(t *testing.T) {
 	require.Equal(t, "idx_test1_foo", indexes[0].IndexName)
 	require.Equal(t, false, indexes[0].Unique)
 }
+
+func TestDropTrigger(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(`
+		CREATE TABLE test; CREATE TABLE log;
+		CREATE TRIGGER tr1 AFTER INSERT ON test INSERT INTO log (a) VALUES ($new.a);
+		CREATE TRIGGER tr2 AFTER DELETE ON test INSERT INTO log (a) VALUES ($old.a);
+	`)
+	require.NoError(t, err)
+
+	err = db.Exec("DROP TRIGGER tr1")
+	require.NoError(t, err)
+
+	err = db.Exec("DROP TRIGGER IF EXISTS tr1")
+	require.NoError(t, err)
+
+	// Dropping a trigger that doesn't exist without "IF EXISTS"
+	// should return an error.
+	err = db.Exec("DROP TRIGGER tr1")
+	require.Error(t, err)
+
+	// Assert that only tr1 has been dropped.
+	var triggers []*database.TriggerConfig
+	err = db.View(func(tx *genji.Tx) error {
+		var err error
+		triggers, err = tx.ListTriggers()
+		return err
+	})
+	require.NoError(t, err)
+	require.Len(t, triggers, 1)
+	require.Equal(t, "tr2", triggers[0].TriggerName)
+
+	// The dropped trigger must not be fired anymore.
+	err = db.Exec("INSERT INTO test (a) VALUES (1)")
+	require.NoError(t, err)
+	d, err := db.QueryDocument("SELECT COUNT(*) AS n FROM log")
+	require.NoError(t, err)
+	v, err := d.GetByField("n")
+	require.NoError(t, err)
+	require.Equal(t, document.NewIntegerValue(0), v)
+}
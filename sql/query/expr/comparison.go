@@ -35,12 +35,7 @@ var errStop = errors.New("errStop")
 func (op eqOp) IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error {
 	err := idx.AscendGreaterOrEqual(v, func(val, key []byte, isEqual bool) error {
 		if isEqual {
-			d, err := tb.GetDocument(key)
-			if err != nil {
-				return err
-			}
-
-			return fn(d)
+			return tb.VisitIndexedDocument(key, fn)
 		}
 
 		return errStop
@@ -108,12 +103,7 @@ func (op gtOp) IterateIndex(idx *database.Index, tb *database.Table, v document.
 			return nil
 		}
 
-		d, err := tb.GetDocument(key)
-		if err != nil {
-			return err
-		}
-
-		return fn(d)
+		return tb.VisitIndexedDocument(key, fn)
 	})
 
 	if err != nil && err != errStop {
@@ -176,12 +166,7 @@ func Gte(a, b Expr) Expr {
 
 func (op gteOp) IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error {
 	err := idx.AscendGreaterOrEqual(v, func(val, key []byte, isEqual bool) error {
-		d, err := tb.GetDocument(key)
-		if err != nil {
-			return err
-		}
-
-		return fn(d)
+		return tb.VisitIndexedDocument(key, fn)
 	})
 
 	if err != nil && err != errStop {
@@ -250,12 +235,7 @@ func (op ltOp) IterateIndex(idx *database.Index, tb *database.Table, v document.
 			return errStop
 		}
 
-		d, err := tb.GetDocument(key)
-		if err != nil {
-			return err
-		}
-
-		return fn(d)
+		return tb.VisitIndexedDocument(key, fn)
 	})
 
 	if err != nil && err != errStop {
@@ -327,12 +307,7 @@ func (op lteOp) IterateIndex(idx *database.Index, tb *database.Table, v document
 			return errStop
 		}
 
-		d, err := tb.GetDocument(key)
-		if err != nil {
-			return err
-		}
-
-		return fn(d)
+		return tb.VisitIndexedDocument(key, fn)
 	})
 
 	if err != nil && err != errStop {
@@ -53,6 +53,42 @@ func BuiltinFunctions() map[string]func(args ...Expr) (Expr, error) {
 			}
 			return &AvgFunc{Expr: args[0]}, nil
 		},
+		"match": func(args ...Expr) (Expr, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("MATCH() takes 2 arguments")
+			}
+			return MatchFunc{Path: args[0], Query: args[1]}, nil
+		},
+		"score": func(args ...Expr) (Expr, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("score() takes no arguments")
+			}
+			return ScoreFunc{}, nil
+		},
+		"st_point": func(args ...Expr) (Expr, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("ST_POINT() takes 2 arguments")
+			}
+			return PointFunc{Lat: args[0], Lon: args[1]}, nil
+		},
+		"st_distance": func(args ...Expr) (Expr, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("ST_DISTANCE() takes 2 arguments")
+			}
+			return DistanceFunc{A: args[0], B: args[1]}, nil
+		},
+		"st_within_radius": func(args ...Expr) (Expr, error) {
+			if len(args) != 3 {
+				return nil, fmt.Errorf("ST_WITHIN_RADIUS() takes 3 arguments")
+			}
+			return WithinRadiusFunc{Path: args[0], Center: args[1], Radius: args[2]}, nil
+		},
+		"st_within_box": func(args ...Expr) (Expr, error) {
+			if len(args) != 3 {
+				return nil, fmt.Errorf("ST_WITHIN_BOX() takes 3 arguments")
+			}
+			return WithinBoxFunc{Path: args[0], SW: args[1], NE: args[2]}, nil
+		},
 	}
 }
 
@@ -0,0 +1,91 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/index/fulltext"
+)
+
+// MatchFunc represents the MATCH(path, query) function.
+// It returns true if the text at the given path contains all the terms of the query.
+// If the path is indexed by a full-text index, the planner reads the matching
+// documents from the index instead of evaluating the function for each document.
+type MatchFunc struct {
+	Path  Expr
+	Query Expr
+}
+
+// Eval analyzes the text and the query and returns true if
+// the text contains all the terms of the query.
+func (m MatchFunc) Eval(ctx EvalStack) (document.Value, error) {
+	q, err := m.Query.Eval(ctx)
+	if err != nil {
+		return nullLitteral, err
+	}
+	if q.Type != document.TextValue {
+		return nullLitteral, errors.New("MATCH() query must be a text")
+	}
+
+	v, err := m.Path.Eval(ctx)
+	if err != nil {
+		return nullLitteral, err
+	}
+	if v.Type != document.TextValue {
+		return falseLitteral, nil
+	}
+
+	if fulltext.Match(v.V.(string), q.V.(string)) {
+		return trueLitteral, nil
+	}
+
+	return falseLitteral, nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (m MatchFunc) IsEqual(other Expr) bool {
+	o, ok := other.(MatchFunc)
+	if !ok {
+		return false
+	}
+
+	return Equal(m.Path, o.Path) && Equal(m.Query, o.Query)
+}
+
+func (m MatchFunc) String() string {
+	return fmt.Sprintf("MATCH(%v, %v)", m.Path, m.Query)
+}
+
+// A Scorer is a document whose relevance to a full-text query is known.
+// Documents read from a full-text index implement this interface.
+type Scorer interface {
+	Score() float64
+}
+
+// ScoreFunc represents the score() function.
+// It returns the relevance of the current document to the MATCH condition
+// that selected it, which requires a full-text index.
+type ScoreFunc struct{}
+
+// Eval returns the score of the current document.
+func (s ScoreFunc) Eval(ctx EvalStack) (document.Value, error) {
+	sc, ok := ctx.Document.(Scorer)
+	if !ok {
+		return nullLitteral, errors.New("score() requires a MATCH condition on a path with a full-text index")
+	}
+
+	return document.NewDoubleValue(sc.Score()), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (s ScoreFunc) IsEqual(other Expr) bool {
+	_, ok := other.(ScoreFunc)
+	return ok
+}
+
+func (s ScoreFunc) String() string {
+	return "score()"
+}
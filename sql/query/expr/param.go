@@ -51,6 +51,50 @@ func (p NamedParam) String() string {
 	return fmt.Sprintf("$%s", string(p))
 }
 
+// NamedParamPath is an expression which represents a path to a value
+// of a document passed as a named parameter, i.e. $new.a.b.
+type NamedParamPath struct {
+	Param NamedParam
+	Path  document.Path
+}
+
+// Eval looks up for the document passed as the named parameter and returns
+// the value found at the path. If there is no value at the path, it returns NULL.
+func (p NamedParamPath) Eval(stack EvalStack) (document.Value, error) {
+	v, err := p.Param.Eval(stack)
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	if v.Type != document.DocumentValue {
+		return nullLitteral, nil
+	}
+
+	v, err = p.Path.GetValue(v.V.(document.Document))
+	if err == document.ErrFieldNotFound || err == document.ErrValueNotFound {
+		return nullLitteral, nil
+	}
+
+	return v, err
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (p NamedParamPath) IsEqual(other Expr) bool {
+	o, ok := other.(NamedParamPath)
+	return ok && p.Param == o.Param && p.Path.IsEqual(o.Path)
+}
+
+// String implements the fmt.Stringer interface.
+func (p NamedParamPath) String() string {
+	s := p.Path.String()
+	if len(p.Path) > 0 && p.Path[0].FieldName == "" {
+		return p.Param.String() + s
+	}
+
+	return p.Param.String() + "." + s
+}
+
 // PositionalParam is an expression which represents the position of a parameter.
 type PositionalParam int
 
@@ -0,0 +1,255 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/index/spatial"
+)
+
+// PointFunc represents the ST_POINT(lat, lon) function.
+// It returns a point, which is a document with lat and lon fields.
+type PointFunc struct {
+	Lat Expr
+	Lon Expr
+}
+
+// Eval returns the point located at the given latitude and longitude.
+func (f PointFunc) Eval(ctx EvalStack) (document.Value, error) {
+	lat, err := evalNumber(ctx, f.Lat, "ST_POINT() latitude")
+	if err != nil {
+		return nullLitteral, err
+	}
+	if lat < -90 || lat > 90 {
+		return nullLitteral, fmt.Errorf("ST_POINT() latitude must be between -90 and 90, got %v", lat)
+	}
+
+	lon, err := evalNumber(ctx, f.Lon, "ST_POINT() longitude")
+	if err != nil {
+		return nullLitteral, err
+	}
+	if lon < -180 || lon > 180 {
+		return nullLitteral, fmt.Errorf("ST_POINT() longitude must be between -180 and 180, got %v", lon)
+	}
+
+	return spatial.Point{Lat: lat, Lon: lon}.Value(), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (f PointFunc) IsEqual(other Expr) bool {
+	o, ok := other.(PointFunc)
+	if !ok {
+		return false
+	}
+
+	return Equal(f.Lat, o.Lat) && Equal(f.Lon, o.Lon)
+}
+
+func (f PointFunc) String() string {
+	return fmt.Sprintf("ST_POINT(%v, %v)", f.Lat, f.Lon)
+}
+
+// DistanceFunc represents the ST_DISTANCE(a, b) function.
+// It returns the distance between two points, in meters,
+// or NULL if one of the values is not a point.
+type DistanceFunc struct {
+	A Expr
+	B Expr
+}
+
+// Eval returns the great-circle distance between the two points.
+func (f DistanceFunc) Eval(ctx EvalStack) (document.Value, error) {
+	a, err := f.A.Eval(ctx)
+	if err != nil {
+		return nullLitteral, err
+	}
+	pa, ok := spatial.PointFromValue(a)
+	if !ok {
+		return nullLitteral, nil
+	}
+
+	b, err := f.B.Eval(ctx)
+	if err != nil {
+		return nullLitteral, err
+	}
+	pb, ok := spatial.PointFromValue(b)
+	if !ok {
+		return nullLitteral, nil
+	}
+
+	return document.NewDoubleValue(spatial.Distance(pa, pb)), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (f DistanceFunc) IsEqual(other Expr) bool {
+	o, ok := other.(DistanceFunc)
+	if !ok {
+		return false
+	}
+
+	return Equal(f.A, o.A) && Equal(f.B, o.B)
+}
+
+func (f DistanceFunc) String() string {
+	return fmt.Sprintf("ST_DISTANCE(%v, %v)", f.A, f.B)
+}
+
+// WithinRadiusFunc represents the ST_WITHIN_RADIUS(path, center, radius) function.
+// It returns true if the point at the given path is at most radius meters away
+// from the center. If the path is indexed by a spatial index, the planner reads
+// the matching documents from the index, by ascending distance.
+type WithinRadiusFunc struct {
+	Path   Expr
+	Center Expr
+	Radius Expr
+}
+
+// Circle evaluates the center and the radius of the circle.
+func (f WithinRadiusFunc) Circle(ctx EvalStack) (center spatial.Point, radius float64, err error) {
+	center, err = evalPoint(ctx, f.Center, "ST_WITHIN_RADIUS() center")
+	if err != nil {
+		return
+	}
+
+	radius, err = evalNumber(ctx, f.Radius, "ST_WITHIN_RADIUS() radius")
+	if err == nil && radius < 0 {
+		err = fmt.Errorf("ST_WITHIN_RADIUS() radius must be positive, got %v", radius)
+	}
+	return
+}
+
+// Eval returns true if the point is inside the circle.
+// Values that are not points are never inside the circle.
+func (f WithinRadiusFunc) Eval(ctx EvalStack) (document.Value, error) {
+	center, radius, err := f.Circle(ctx)
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	v, err := f.Path.Eval(ctx)
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	p, ok := spatial.PointFromValue(v)
+	if !ok || spatial.Distance(center, p) > radius {
+		return falseLitteral, nil
+	}
+
+	return trueLitteral, nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (f WithinRadiusFunc) IsEqual(other Expr) bool {
+	o, ok := other.(WithinRadiusFunc)
+	if !ok {
+		return false
+	}
+
+	return Equal(f.Path, o.Path) && Equal(f.Center, o.Center) && Equal(f.Radius, o.Radius)
+}
+
+func (f WithinRadiusFunc) String() string {
+	return fmt.Sprintf("ST_WITHIN_RADIUS(%v, %v, %v)", f.Path, f.Center, f.Radius)
+}
+
+// WithinBoxFunc represents the ST_WITHIN_BOX(path, sw, ne) function.
+// It returns true if the point at the given path is inside the box delimited
+// by the south-west and north-east corners. If the longitude of the south-west
+// corner is greater than the one of the north-east corner, the box crosses
+// the antimeridian. If the path is indexed by a spatial index, the planner
+// reads the matching documents from the index.
+type WithinBoxFunc struct {
+	Path Expr
+	SW   Expr
+	NE   Expr
+}
+
+// Box evaluates the corners of the box.
+func (f WithinBoxFunc) Box(ctx EvalStack) (spatial.Box, error) {
+	sw, err := evalPoint(ctx, f.SW, "ST_WITHIN_BOX() south-west corner")
+	if err != nil {
+		return spatial.Box{}, err
+	}
+
+	ne, err := evalPoint(ctx, f.NE, "ST_WITHIN_BOX() north-east corner")
+	if err != nil {
+		return spatial.Box{}, err
+	}
+
+	if sw.Lat > ne.Lat {
+		return spatial.Box{}, errors.New("ST_WITHIN_BOX() south-west corner must be south of the north-east corner")
+	}
+
+	return spatial.Box{SW: sw, NE: ne}, nil
+}
+
+// Eval returns true if the point is inside the box.
+// Values that are not points are never inside the box.
+func (f WithinBoxFunc) Eval(ctx EvalStack) (document.Value, error) {
+	b, err := f.Box(ctx)
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	v, err := f.Path.Eval(ctx)
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	p, ok := spatial.PointFromValue(v)
+	if !ok || !b.Contains(p) {
+		return falseLitteral, nil
+	}
+
+	return trueLitteral, nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (f WithinBoxFunc) IsEqual(other Expr) bool {
+	o, ok := other.(WithinBoxFunc)
+	if !ok {
+		return false
+	}
+
+	return Equal(f.Path, o.Path) && Equal(f.SW, o.SW) && Equal(f.NE, o.NE)
+}
+
+func (f WithinBoxFunc) String() string {
+	return fmt.Sprintf("ST_WITHIN_BOX(%v, %v, %v)", f.Path, f.SW, f.NE)
+}
+
+func evalPoint(ctx EvalStack, e Expr, name string) (spatial.Point, error) {
+	v, err := e.Eval(ctx)
+	if err != nil {
+		return spatial.Point{}, err
+	}
+
+	p, ok := spatial.PointFromValue(v)
+	if !ok {
+		return spatial.Point{}, fmt.Errorf("%s must be a point, got %v", name, v)
+	}
+
+	return p, nil
+}
+
+func evalNumber(ctx EvalStack, e Expr, name string) (float64, error) {
+	v, err := e.Eval(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	switch v.Type {
+	case document.IntegerValue:
+		return float64(v.V.(int64)), nil
+	case document.DoubleValue:
+		return v.V.(float64), nil
+	}
+
+	return 0, fmt.Errorf("%s must be a number, got %v", name, v)
+}
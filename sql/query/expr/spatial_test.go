@@ -0,0 +1,55 @@
+package expr_test
+
+import (
+	"testing"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/index/spatial"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+func TestSpatialFuncs(t *testing.T) {
+	paris := spatial.Point{Lat: 48.8566, Lon: 2.3522}
+	london := spatial.Point{Lat: 51.5074, Lon: -0.1278}
+
+	stack := expr.EvalStack{
+		Document: document.NewFromJSON([]byte(`{
+			"loc": {"lat": 48.8566, "lon": 2.3522},
+			"name": "paris"
+		}`)),
+	}
+
+	tests := []struct {
+		expr  string
+		res   document.Value
+		fails bool
+	}{
+		{"ST_POINT(48.8566, 2.3522)", paris.Value(), false},
+		{"ST_POINT(-90, 180)", spatial.Point{Lat: -90, Lon: 180}.Value(), false},
+		{"ST_POINT(91, 0)", nullLitteral, true},
+		{"ST_POINT(0, -181)", nullLitteral, true},
+		{"ST_POINT('1', 0)", nullLitteral, true},
+		{"ST_DISTANCE(loc, loc)", document.NewDoubleValue(0), false},
+		{"ST_DISTANCE(loc, {lat: 51.5074, lon: -0.1278})", document.NewDoubleValue(spatial.Distance(paris, london)), false},
+		{"ST_DISTANCE(ST_POINT(51.5074, -0.1278), loc)", document.NewDoubleValue(spatial.Distance(london, paris)), false},
+		{"ST_DISTANCE(name, loc)", nullLitteral, false},
+		{"ST_DISTANCE(loc, unknown)", nullLitteral, false},
+		{"ST_WITHIN_RADIUS(loc, ST_POINT(51.5074, -0.1278), 400000)", document.NewBoolValue(true), false},
+		{"ST_WITHIN_RADIUS(loc, ST_POINT(51.5074, -0.1278), 300000)", document.NewBoolValue(false), false},
+		{"ST_WITHIN_RADIUS(name, ST_POINT(51.5074, -0.1278), 400000)", document.NewBoolValue(false), false},
+		{"ST_WITHIN_RADIUS(loc, name, 400000)", nullLitteral, true},
+		{"ST_WITHIN_RADIUS(loc, loc, -1)", nullLitteral, true},
+		{"ST_WITHIN_RADIUS(loc, loc, '1')", nullLitteral, true},
+		{"ST_WITHIN_BOX(loc, ST_POINT(40, -5), ST_POINT(50, 10))", document.NewBoolValue(true), false},
+		{"ST_WITHIN_BOX(loc, ST_POINT(40, 5), ST_POINT(50, 10))", document.NewBoolValue(false), false},
+		{"ST_WITHIN_BOX(loc, ST_POINT(40, 10), ST_POINT(50, 5))", document.NewBoolValue(true), false},
+		{"ST_WITHIN_BOX(unknown, ST_POINT(40, -5), ST_POINT(50, 10))", document.NewBoolValue(false), false},
+		{"ST_WITHIN_BOX(loc, ST_POINT(50, -5), ST_POINT(40, 10))", nullLitteral, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			testExpr(t, test.expr, stack, test.res, test.fails)
+		})
+	}
+}
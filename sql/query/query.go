@@ -17,22 +17,52 @@ var ErrResultClosed = errors.New("result already closed")
 // Results are returned as streams.
 type Query struct {
 	Statements []Statement
+	// Trace of the query, if any. It is ended when the result is closed
+	// or if the query fails.
+	Trace      database.QueryTrace
 	tx         *database.Transaction
 	autoCommit bool
 }
 
 // Run executes all the statements in their own transaction and returns the last result.
+// It uses the default session of the database: see RunSession.
 func (q Query) Run(ctx context.Context, db *database.Database, args []expr.Param) (*Result, error) {
+	return q.RunSession(ctx, db.DefaultSession(), args)
+}
+
+// RunSession executes all the statements in their own transaction and returns the last result.
+// If the session has an attached transaction, the statements are run within it.
+// Transactions opened by the BEGIN statement are attached to the session.
+func (q Query) RunSession(ctx context.Context, s *database.Session, args []expr.Param) (*Result, error) {
+	res, err := q.run(ctx, s, args)
+	if err != nil {
+		if q.Trace != nil {
+			q.Trace.End(err)
+		}
+		return nil, err
+	}
+
+	res.trace = q.Trace
+	return res, nil
+}
+
+func (q Query) run(ctx context.Context, s *database.Session, args []expr.Param) (*Result, error) {
 	var res Result
 	var err error
 
-	q.tx = db.GetAttachedTx()
+	q.tx = s.GetAttachedTx()
 	if q.tx == nil {
 		q.autoCommit = true
 	}
 
 	type queryAlterer interface {
-		alterQuery(ctx context.Context, db *database.Database, q *Query) error
+		alterQuery(ctx context.Context, s *database.Session, q *Query) error
+	}
+
+	// savepoint statements fail like regular statements:
+	// the active transaction is left open.
+	type savepointStmt interface {
+		isSavepointStmt()
 	}
 
 	for i, stmt := range q.Statements {
@@ -43,9 +73,15 @@ func (q Query) Run(ctx context.Context, db *database.Database, args []expr.Param
 		}
 
 		if qa, ok := stmt.(queryAlterer); ok {
-			err = qa.alterQuery(ctx, db, &q)
+			end := startStage(q.Trace, database.ExecuteStage)
+			err = qa.alterQuery(ctx, s, &q)
+			end(err)
 			if err != nil {
-				if tx := db.GetAttachedTx(); tx != nil {
+				if _, ok := stmt.(savepointStmt); ok {
+					return nil, err
+				}
+
+				if tx := s.GetAttachedTx(); tx != nil {
 					tx.Rollback()
 				}
 				return nil, err
@@ -55,7 +91,7 @@ func (q Query) Run(ctx context.Context, db *database.Database, args []expr.Param
 		}
 
 		if q.tx == nil {
-			q.tx, err = db.BeginTx(ctx, &database.TxOptions{
+			q.tx, err = s.BeginTx(ctx, &database.TxOptions{
 				ReadOnly: stmt.IsReadOnly(),
 			})
 			if err != nil {
@@ -63,7 +99,7 @@ func (q Query) Run(ctx context.Context, db *database.Database, args []expr.Param
 			}
 		}
 
-		res, err = stmt.Run(q.tx, args)
+		res, err = q.runStatement(q.tx, stmt, args)
 		if err != nil {
 			if q.autoCommit {
 				q.tx.Rollback()
@@ -76,7 +112,9 @@ func (q Query) Run(ctx context.Context, db *database.Database, args []expr.Param
 		// to be executed, close the current transaction.
 		if q.tx != nil && q.autoCommit && i+1 < len(q.Statements) {
 			if q.tx.Writable() {
+				end := startStage(q.Trace, database.CommitStage)
 				err := q.tx.Commit()
+				end(err)
 				if err != nil {
 					return nil, err
 				}
@@ -105,15 +143,41 @@ func (q Query) Exec(tx *database.Transaction, args []expr.Param) (*Result, error
 	var err error
 
 	for _, stmt := range q.Statements {
-		res, err = stmt.Run(tx, args)
+		res, err = q.runStatement(tx, stmt, args)
 		if err != nil {
+			if q.Trace != nil {
+				q.Trace.End(err)
+			}
 			return nil, err
 		}
 	}
 
+	res.trace = q.Trace
 	return &res, nil
 }
 
+// runStatement runs stmt within tx, which follows the trace of the query
+// during the execution of the statement.
+func (q *Query) runStatement(tx *database.Transaction, stmt Statement, args []expr.Param) (Result, error) {
+	prev := tx.SetTrace(q.Trace)
+	defer tx.SetTrace(prev)
+
+	end := tx.Trace().StartStage(database.ExecuteStage)
+	res, err := stmt.Run(tx, args)
+	end(err)
+
+	return res, err
+}
+
+// startStage starts a stage of the trace t, if not nil.
+func startStage(t database.QueryTrace, stage database.TraceStage) func(err error) {
+	if t == nil {
+		return func(err error) {}
+	}
+
+	return t.StartStage(stage)
+}
+
 // New creates a new query with the given statements.
 func New(statements ...Statement) Query {
 	return Query{Statements: statements}
@@ -132,6 +196,7 @@ type Result struct {
 	LastInsertKey []byte
 	Tx            *database.Transaction
 	closed        bool
+	trace         database.QueryTrace
 }
 
 // Close the result stream.
@@ -151,12 +216,18 @@ func (r *Result) Close() (err error) {
 
 	if r.Tx != nil {
 		if r.Tx.Writable() {
+			end := startStage(r.trace, database.CommitStage)
 			err = r.Tx.Commit()
+			end(err)
 		} else {
 			err = r.Tx.Rollback()
 		}
 	}
 
+	if r.trace != nil {
+		r.trace.End(err)
+	}
+
 	return err
 }
 
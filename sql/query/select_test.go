@@ -8,7 +8,9 @@ import (
 	"testing"
 
 	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/index/spatial"
 	"github.com/stretchr/testify/require"
 )
 
@@ -74,6 +76,9 @@ func TestSelectStmt(t *testing.T) {
 		{"With offset then limit", "SELECT * FROM test WHERE size = 10 OFFSET 1 LIMIT 1", true, "", nil},
 		{"With positional params", "SELECT * FROM test WHERE color = ? OR height = ?", false, `[{"k":1,"color":"red","size":10,"shape":"square"},{"k":3,"height":100,"weight":200}]`, []interface{}{"red", 100}},
 		{"With named params", "SELECT * FROM test WHERE color = $a OR height = $d", false, `[{"k":1,"color":"red","size":10,"shape":"square"},{"k":3,"height":100,"weight":200}]`, []interface{}{sql.Named("a", "red"), sql.Named("d", 100)}},
+		{"With named params in projection", "SELECT color, $a AS a FROM test WHERE k = 1", false, `[{"color":"red","a":"foo"}]`, []interface{}{sql.Named("a", "foo")}},
+		{"With params and no table", "SELECT ? + 1 AS a, ?", false, `[{"a":2,"?":"foo"}]`, []interface{}{1, "foo"}},
+		{"With named params and no table", "SELECT $a AS a", false, `[{"a":"foo"}]`, []interface{}{sql.Named("a", "foo")}},
 		{"With pk()", "SELECT pk(), color FROM test", false, `[{"pk()":1,"color":"red"},{"pk()":2,"color":"blue"},{"pk()":3,"color":null}]`, []interface{}{sql.Named("a", "red"), sql.Named("d", 100)}},
 		{"With pk in cond, gt", "SELECT * FROM test WHERE k > 0 AND weight = 100", false, `[{"k":2,"color":"blue","size":10,"weight":100,"k":2}]`, nil},
 		{"With pk in cond, =", "SELECT * FROM test WHERE k = 2.0 AND weight = 100", false, `[{"k":2,"color":"blue","size":10,"weight":100,"k":2}]`, nil},
@@ -335,3 +340,323 @@ func TestDistinct(t *testing.T) {
 		})
 	}
 }
+
+func TestFullTextSearch(t *testing.T) {
+	newDB := func(t *testing.T, withIndex bool) *genji.DB {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		err = db.Exec("CREATE TABLE posts(id INTEGER PRIMARY KEY, body TEXT)")
+		require.NoError(t, err)
+		if withIndex {
+			err = db.Exec("CREATE FULLTEXT INDEX idx_body ON posts (body)")
+			require.NoError(t, err)
+		}
+
+		err = db.Exec(`INSERT INTO posts (id, body) VALUES
+			(1, 'Genji is an embedded document database written in Go.'),
+			(2, 'Documents are stored in tables. Indexing documents makes queries faster.'),
+			(3, 'The Go programming language.'),
+			(4, 'Databases store documents.')`)
+		require.NoError(t, err)
+
+		return db
+	}
+
+	ids := func(t *testing.T, db *genji.DB, q string, args ...interface{}) []int64 {
+		st, err := db.Query(q, args...)
+		require.NoError(t, err)
+		defer st.Close()
+
+		var res []int64
+		err = st.Iterate(func(d document.Document) error {
+			v, err := d.GetByField("id")
+			require.NoError(t, err)
+			res = append(res, v.V.(int64))
+			return nil
+		})
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("Without index", func(t *testing.T) {
+		db := newDB(t, false)
+
+		require.Equal(t, []int64{1, 3}, ids(t, db, "SELECT id FROM posts WHERE MATCH(body, 'go')"))
+		require.Equal(t, []int64{1, 4}, ids(t, db, "SELECT id FROM posts WHERE MATCH(body, ?) AND id < 5", "database DOCUMENTS"))
+
+		// score() requires a full-text index.
+		st, err := db.Query("SELECT score() FROM posts WHERE MATCH(body, 'go')")
+		require.NoError(t, err)
+		defer st.Close()
+		var buf bytes.Buffer
+		require.Error(t, document.IteratorToJSONArray(&buf, st))
+	})
+
+	t.Run("With index", func(t *testing.T) {
+		db := newDB(t, true)
+
+		// documents are returned by descending score.
+		require.Equal(t, []int64{4, 2, 1}, ids(t, db, "SELECT id FROM posts WHERE MATCH(body, 'document')"))
+		require.Equal(t, []int64{4, 1}, ids(t, db, "SELECT id FROM posts WHERE MATCH(body, ?)", "database DOCUMENTS"))
+		require.Equal(t, []int64{1}, ids(t, db, "SELECT id FROM posts WHERE id < 3 AND MATCH(body, 'databases')"))
+		require.Empty(t, ids(t, db, "SELECT id FROM posts WHERE MATCH(body, 'the')"))
+
+		d, err := db.QueryDocument("EXPLAIN SELECT id FROM posts WHERE MATCH(body, 'go')")
+		require.NoError(t, err)
+		v, err := d.GetByField("plan")
+		require.NoError(t, err)
+		require.Equal(t, "FullTextIndex(idx_body) -> ∏(id)", v.V)
+
+		st, err := db.Query("SELECT id, score() AS s FROM posts WHERE MATCH(body, 'go') ORDER BY s")
+		require.NoError(t, err)
+		var scores []float64
+		err = st.Iterate(func(d document.Document) error {
+			v, err := d.GetByField("s")
+			require.NoError(t, err)
+			scores = append(scores, v.V.(float64))
+			return nil
+		})
+		require.NoError(t, err)
+		require.NoError(t, st.Close())
+		require.Len(t, scores, 2)
+		require.Greater(t, scores[0], 0.0)
+		require.Less(t, scores[0], scores[1])
+
+		// the index is updated when documents are modified.
+		err = db.Exec("UPDATE posts SET body = 'Nothing to see here' WHERE id = 3")
+		require.NoError(t, err)
+		err = db.Exec("DELETE FROM posts WHERE MATCH(body, 'tables')")
+		require.NoError(t, err)
+		err = db.Exec("INSERT INTO posts (id, body) VALUES (5, 'Go, go, go!')")
+		require.NoError(t, err)
+		require.Equal(t, []int64{5, 1}, ids(t, db, "SELECT id FROM posts WHERE MATCH(body, 'go')"))
+		require.Equal(t, []int64{4, 1}, ids(t, db, "SELECT id FROM posts WHERE MATCH(body, 'document')"))
+
+		err = db.Exec("REINDEX idx_body")
+		require.NoError(t, err)
+		require.Equal(t, []int64{5, 1}, ids(t, db, "SELECT id FROM posts WHERE MATCH(body, 'go')"))
+	})
+
+	t.Run("Missing field", func(t *testing.T) {
+		db := newDB(t, true)
+
+		err := db.Exec("INSERT INTO posts (id) VALUES (10)")
+		require.NoError(t, err)
+
+		// documents without the text can be modified and deleted.
+		err = db.Exec("UPDATE posts SET title = 'untitled' WHERE id = 10")
+		require.NoError(t, err)
+		err = db.Exec("UPDATE posts SET body = 'Go away' WHERE id = 10")
+		require.NoError(t, err)
+		require.Equal(t, []int64{10, 3, 1}, ids(t, db, "SELECT id FROM posts WHERE MATCH(body, 'go')"))
+		err = db.Exec("UPDATE posts UNSET body WHERE id = 10")
+		require.NoError(t, err)
+		err = db.Exec("DELETE FROM posts WHERE id = 10")
+		require.NoError(t, err)
+		require.Equal(t, []int64{1, 3}, ids(t, db, "SELECT id FROM posts WHERE MATCH(body, 'go') ORDER BY id"))
+	})
+}
+
+func TestMultikeyIndex(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(`
+		CREATE TABLE users(id INTEGER PRIMARY KEY, roles ARRAY);
+		CREATE INDEX idx_roles ON users (roles[*]);
+		CREATE INDEX idx_scores ON users (scores[*]);
+		INSERT INTO users (id, roles, scores) VALUES
+			(1, ['admin', 'dev', 'admin'], [1, 2]),
+			(2, ['dev'], [2.5]),
+			(3, [], 3),
+			(4, ['ops', ['admin']], [2, 1]);
+		INSERT INTO users (id) VALUES (5);
+	`)
+	require.NoError(t, err)
+
+	ids := func(q string, args ...interface{}) []int64 {
+		st, err := db.Query(q, args...)
+		require.NoError(t, err)
+		defer st.Close()
+
+		var res []int64
+		err = st.Iterate(func(d document.Document) error {
+			v, err := d.GetByField("id")
+			require.NoError(t, err)
+			res = append(res, v.V.(int64))
+			return nil
+		})
+		require.NoError(t, err)
+		return res
+	}
+
+	plan := func(q string) string {
+		d, err := db.QueryDocument("EXPLAIN " + q)
+		require.NoError(t, err)
+		v, err := d.GetByField("plan")
+		require.NoError(t, err)
+		return v.V.(string)
+	}
+
+	require.Equal(t, "Index(idx_roles) -> ∏(id)", plan("SELECT id FROM users WHERE 'admin' IN roles"))
+	require.Equal(t, `Table(users) -> σ(cond: roles IN ["admin"]) -> ∏(id)`, plan("SELECT id FROM users WHERE roles IN ['admin']"))
+
+	// documents are returned once, even if the value appears more than once in their array.
+	require.Equal(t, []int64{1}, ids("SELECT id FROM users WHERE 'admin' IN roles"))
+	require.Equal(t, []int64{1, 2}, ids("SELECT id FROM users WHERE ? IN roles", "dev"))
+	require.Equal(t, []int64{4}, ids("SELECT id FROM users WHERE ['admin'] IN roles"))
+	require.Equal(t, []int64{1, 4}, ids("SELECT id FROM users WHERE 1 IN scores"))
+	require.Equal(t, []int64{1, 4}, ids("SELECT id FROM users WHERE 2 IN scores AND 1 IN scores"))
+	require.Equal(t, []int64{2}, ids("SELECT id FROM users WHERE 2.5 IN scores"))
+	require.Empty(t, ids("SELECT id FROM users WHERE 3 IN scores"))
+	require.Empty(t, ids("SELECT id FROM users WHERE NULL IN roles"))
+
+	// the index is updated when documents are modified.
+	err = db.Exec("UPDATE users SET roles = ['admin'] WHERE id = 2")
+	require.NoError(t, err)
+	err = db.Exec("DELETE FROM users WHERE 'ops' IN roles")
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2}, ids("SELECT id FROM users WHERE 'admin' IN roles"))
+	require.Empty(t, ids("SELECT id FROM users WHERE 'dev' IN roles AND id > 1"))
+
+	err = db.Exec("REINDEX idx_roles")
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2}, ids("SELECT id FROM users WHERE 'admin' IN roles"))
+
+	t.Run("Unique", func(t *testing.T) {
+		err = db.Exec("CREATE UNIQUE INDEX idx_tags ON users (tags[*])")
+		require.NoError(t, err)
+
+		err = db.Exec("INSERT INTO users (id, tags) VALUES (10, ['a', 'b', 'a'])")
+		require.NoError(t, err)
+		err = db.Exec("INSERT INTO users (id, tags) VALUES (11, ['c', 'b'])")
+		require.Equal(t, database.ErrDuplicateDocument, err)
+	})
+
+	t.Run("Missing field", func(t *testing.T) {
+		err = db.Exec("CREATE TABLE m; CREATE INDEX idx_m ON m (tags[*]); INSERT INTO m (a) VALUES (1)")
+		require.NoError(t, err)
+
+		// documents without the array can be modified and deleted.
+		err = db.Exec("UPDATE m SET a = 2")
+		require.NoError(t, err)
+		err = db.Exec("UPDATE m SET tags = ['x']")
+		require.NoError(t, err)
+		err = db.Exec("UPDATE m UNSET tags")
+		require.NoError(t, err)
+		err = db.Exec("DELETE FROM m")
+		require.NoError(t, err)
+
+		d, err := db.QueryDocument("SELECT COUNT(*) AS n FROM m")
+		require.NoError(t, err)
+		v, err := d.GetByField("n")
+		require.NoError(t, err)
+		require.EqualValues(t, 0, v.V)
+	})
+}
+
+func TestSpatialIndex(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(`
+		CREATE TABLE places(name TEXT PRIMARY KEY, loc DOCUMENT);
+		INSERT INTO places (name, loc) VALUES
+			('paris', {lat: 48.8566, lon: 2.3522}),
+			('london', {lat: 51.5074, lon: -0.1278}),
+			('berlin', {lat: 52.52, lon: 13.405}),
+			('fiji', {lat: -17.7134, lon: 178.065}),
+			('samoa', {lat: -13.759, lon: -172.1046}),
+			('nowhere', {lat: 100, lon: 0});
+		INSERT INTO places (name) VALUES ('unknown');
+	`)
+	require.NoError(t, err)
+
+	names := func(q string, args ...interface{}) []string {
+		st, err := db.Query(q, args...)
+		require.NoError(t, err)
+		defer st.Close()
+
+		var res []string
+		err = st.Iterate(func(d document.Document) error {
+			v, err := d.GetByField("name")
+			require.NoError(t, err)
+			res = append(res, v.V.(string))
+			return nil
+		})
+		require.NoError(t, err)
+		return res
+	}
+
+	plan := func(q string) string {
+		d, err := db.QueryDocument("EXPLAIN " + q)
+		require.NoError(t, err)
+		v, err := d.GetByField("plan")
+		require.NoError(t, err)
+		return v.V.(string)
+	}
+
+	radiusQuery := "SELECT name FROM places WHERE ST_WITHIN_RADIUS(loc, ST_POINT(48.8566, 2.3522), 1000000)"
+	boxQuery := "SELECT name FROM places WHERE ST_WITHIN_BOX(loc, {lat: -30, lon: 170}, {lat: 0, lon: -170})"
+
+	// without index, documents are returned in primary key order.
+	require.Equal(t, []string{"berlin", "london", "paris"}, names(radiusQuery))
+	require.Equal(t, []string{"fiji", "samoa"}, names(boxQuery))
+
+	// existing documents are indexed by REINDEX.
+	err = db.Exec("CREATE SPATIAL INDEX idx_loc ON places (loc); REINDEX idx_loc")
+	require.NoError(t, err)
+
+	require.Equal(t, "SpatialIndex(idx_loc) -> ∏(name)", plan(radiusQuery))
+	require.Equal(t, "SpatialIndex(idx_loc) -> ∏(name)", plan(boxQuery))
+
+	// with the index, documents within a radius are returned by ascending distance.
+	require.Equal(t, []string{"paris", "london", "berlin"}, names(radiusQuery))
+	require.Equal(t, []string{"paris", "london"}, names(radiusQuery+" LIMIT 2"))
+	require.Equal(t, []string{"fiji", "samoa"}, names(boxQuery))
+	require.Equal(t, []string{"london"}, names(
+		"SELECT name FROM places WHERE ST_WITHIN_RADIUS(loc, ?, ?) AND name != 'paris' LIMIT 1",
+		spatial.Point{Lat: 48.8566, Lon: 2.3522}, 1000000,
+	))
+
+	d, err := db.QueryDocument("SELECT ST_DISTANCE(loc, ST_POINT(51.5074, -0.1278)) AS d FROM places WHERE name = 'paris'")
+	require.NoError(t, err)
+	v, err := d.GetByField("d")
+	require.NoError(t, err)
+	require.InDelta(t, 343.5e3, v.V.(float64), 1e3)
+
+	// invalid areas are reported when the plan is bound.
+	_, err = db.QueryDocument("SELECT name FROM places WHERE ST_WITHIN_RADIUS(loc, ?, 10)", "paris")
+	require.Error(t, err)
+
+	// the index is updated when documents are modified.
+	err = db.Exec("UPDATE places SET loc = {lat: 52.3676, lon: 4.9041} WHERE name = 'london'")
+	require.NoError(t, err)
+	require.Equal(t, []string{"paris", "london", "berlin"}, names(radiusQuery))
+	err = db.Exec("DELETE FROM places WHERE ST_WITHIN_RADIUS(loc, ST_POINT(52.52, 13.405), 1)")
+	require.NoError(t, err)
+	require.Equal(t, []string{"paris", "london"}, names(radiusQuery))
+
+	err = db.Exec("REINDEX idx_loc")
+	require.NoError(t, err)
+	require.Equal(t, []string{"paris", "london"}, names(radiusQuery))
+
+	// documents without a point can be modified and deleted.
+	err = db.Exec("UPDATE places SET country = 'none' WHERE name = 'unknown'")
+	require.NoError(t, err)
+	err = db.Exec("UPDATE places SET loc = {lat: 48.8, lon: 2.3} WHERE name = 'unknown'")
+	require.NoError(t, err)
+	require.Equal(t, []string{"paris", "unknown", "london"}, names(radiusQuery))
+	err = db.Exec("UPDATE places UNSET loc WHERE name = 'unknown'")
+	require.NoError(t, err)
+	err = db.Exec("DELETE FROM places WHERE name = 'unknown'")
+	require.NoError(t, err)
+	require.Equal(t, []string{"paris", "london"}, names(radiusQuery))
+
+	err = db.Exec("CREATE SPATIAL INDEX idx_name ON places (name)")
+	require.Error(t, err)
+}
@@ -13,13 +13,13 @@ type BeginStmt struct {
 	Writable bool
 }
 
-func (stmt BeginStmt) alterQuery(ctx context.Context, db *database.Database, q *Query) error {
+func (stmt BeginStmt) alterQuery(ctx context.Context, s *database.Session, q *Query) error {
 	if q.tx != nil {
 		return errors.New("cannot begin a transaction within a transaction")
 	}
 
 	var err error
-	q.tx, err = db.BeginTx(ctx, &database.TxOptions{
+	q.tx, err = s.BeginTx(ctx, &database.TxOptions{
 		ReadOnly: !stmt.Writable,
 		Attached: true,
 	})
@@ -38,7 +38,7 @@ func (stmt BeginStmt) Run(tx *database.Transaction, args []expr.Param) (Result,
 // RollbackStmt is a statement that rollbacks the current active transaction.
 type RollbackStmt struct{}
 
-func (stmt RollbackStmt) alterQuery(ctx context.Context, db *database.Database, q *Query) error {
+func (stmt RollbackStmt) alterQuery(ctx context.Context, s *database.Session, q *Query) error {
 	if q.tx == nil || q.autoCommit == true {
 		return errors.New("cannot rollback with no active transaction")
 	}
@@ -62,12 +62,18 @@ func (stmt RollbackStmt) Run(tx *database.Transaction, args []expr.Param) (Resul
 // CommitStmt is a statement that commits the current active transaction.
 type CommitStmt struct{}
 
-func (stmt CommitStmt) alterQuery(ctx context.Context, db *database.Database, q *Query) error {
+func (stmt CommitStmt) alterQuery(ctx context.Context, s *database.Session, q *Query) error {
 	if q.tx == nil || q.autoCommit == true {
 		return errors.New("cannot commit with no active transaction")
 	}
 
-	err := q.tx.Commit()
+	// read-only transactions can't be committed, they are released by a rollback.
+	var err error
+	if q.tx.Writable() {
+		err = q.tx.Commit()
+	} else {
+		err = q.tx.Rollback()
+	}
 	if err != nil {
 		return err
 	}
@@ -82,3 +88,76 @@ func (stmt CommitStmt) IsReadOnly() bool {
 func (stmt CommitStmt) Run(tx *database.Transaction, args []expr.Param) (Result, error) {
 	return Result{}, errors.New("cannot commit with no active transaction")
 }
+
+// SavepointStmt is a statement that creates a savepoint in the current active transaction.
+type SavepointStmt struct {
+	SavepointName string
+}
+
+func (stmt SavepointStmt) alterQuery(ctx context.Context, s *database.Session, q *Query) error {
+	if q.tx == nil || q.autoCommit == true {
+		return errors.New("cannot create a savepoint with no active transaction")
+	}
+
+	_, err := stmt.Run(q.tx, nil)
+	return err
+}
+
+func (stmt SavepointStmt) isSavepointStmt() {}
+
+func (stmt SavepointStmt) IsReadOnly() bool {
+	return false
+}
+
+func (stmt SavepointStmt) Run(tx *database.Transaction, args []expr.Param) (Result, error) {
+	return Result{}, tx.Savepoint(stmt.SavepointName)
+}
+
+// RollbackToSavepointStmt is a statement that cancels the writes made in the current
+// active transaction since the creation of a savepoint.
+type RollbackToSavepointStmt struct {
+	SavepointName string
+}
+
+func (stmt RollbackToSavepointStmt) alterQuery(ctx context.Context, s *database.Session, q *Query) error {
+	if q.tx == nil || q.autoCommit == true {
+		return errors.New("cannot rollback to a savepoint with no active transaction")
+	}
+
+	_, err := stmt.Run(q.tx, nil)
+	return err
+}
+
+func (stmt RollbackToSavepointStmt) isSavepointStmt() {}
+
+func (stmt RollbackToSavepointStmt) IsReadOnly() bool {
+	return false
+}
+
+func (stmt RollbackToSavepointStmt) Run(tx *database.Transaction, args []expr.Param) (Result, error) {
+	return Result{}, tx.RollbackToSavepoint(stmt.SavepointName)
+}
+
+// ReleaseSavepointStmt is a statement that removes a savepoint from the current active transaction.
+type ReleaseSavepointStmt struct {
+	SavepointName string
+}
+
+func (stmt ReleaseSavepointStmt) alterQuery(ctx context.Context, s *database.Session, q *Query) error {
+	if q.tx == nil || q.autoCommit == true {
+		return errors.New("cannot release a savepoint with no active transaction")
+	}
+
+	_, err := stmt.Run(q.tx, nil)
+	return err
+}
+
+func (stmt ReleaseSavepointStmt) isSavepointStmt() {}
+
+func (stmt ReleaseSavepointStmt) IsReadOnly() bool {
+	return false
+}
+
+func (stmt ReleaseSavepointStmt) Run(tx *database.Transaction, args []expr.Param) (Result, error) {
+	return Result{}, tx.ReleaseSavepoint(stmt.SavepointName)
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
 	"github.com/stretchr/testify/require"
 )
 
@@ -16,6 +17,7 @@ func TestTransactionRun(t *testing.T) {
 		{"Same exec/ Basic", []string{`BEGIN`}, false},
 		{"Same exec/ Nested transaction", []string{`BEGIN;BEGIN`}, true},
 		{"Same exec/ Begin then commit", []string{`BEGIN;COMMIT`}, false},
+		{"Same exec/ Begin read-only then commit", []string{`BEGIN READ ONLY;SELECT 1;COMMIT`}, false},
 		{"Same exec/ Begin then rollback", []string{`BEGIN;ROLLBACK`}, false},
 		{"Same exec/ Begin, select, then rollback", []string{`BEGIN;SELECT 1;ROLLBACK`}, false},
 		{"Multiple execs/ Begin then rollback", []string{`BEGIN`, `ROLLBACK`}, false},
@@ -23,6 +25,12 @@ func TestTransactionRun(t *testing.T) {
 		{"Multiple execs/ Double", []string{`BEGIN`, `COMMIT`, `BEGIN`, `COMMIT`}, false},
 		{"Multiple execs/ Begin then begin", []string{`BEGIN`, `BEGIN`}, true},
 		{"Multiple execs/ Nested", []string{`BEGIN`, `BEGIN`, `COMMIT`, `COMMIT`}, true},
+		{"Savepoint/ Begin, savepoint then release", []string{`BEGIN;SAVEPOINT a;RELEASE a;COMMIT`}, false},
+		{"Savepoint/ Rollback to", []string{`BEGIN`, `SAVEPOINT a`, `ROLLBACK TO a`, `ROLLBACK TO a`, `COMMIT`}, false},
+		{"Savepoint/ No transaction", []string{`SAVEPOINT a`}, true},
+		{"Savepoint/ Rollback with no transaction", []string{`ROLLBACK TO a`}, true},
+		{"Savepoint/ Unknown", []string{`BEGIN;SAVEPOINT a;RELEASE b`}, true},
+		{"Savepoint/ Released", []string{`BEGIN;SAVEPOINT a;RELEASE a;ROLLBACK TO a`}, true},
 	}
 
 	for _, test := range tests {
@@ -46,3 +54,72 @@ func TestTransactionRun(t *testing.T) {
 		})
 	}
 }
+
+func TestSavepointRun(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(`
+		CREATE TABLE test(a INTEGER PRIMARY KEY);
+		CREATE UNIQUE INDEX idx_b ON test (b);
+		BEGIN;
+		INSERT INTO test (a, b) VALUES (1, 1);
+		SAVEPOINT sp;
+	`)
+	require.NoError(t, err)
+
+	// the failing statement partially wrote to the table.
+	err = db.Exec("INSERT INTO test (a, b) VALUES (2, 2), (3, 1)")
+	require.Error(t, err)
+
+	err = db.Exec("ROLLBACK TO sp; INSERT INTO test (a, b) VALUES (2, 2); RELEASE sp; COMMIT")
+	require.NoError(t, err)
+
+	res, err := db.Query("SELECT a FROM test WHERE b = 2")
+	require.NoError(t, err)
+	defer res.Close()
+
+	var keys []int
+	err = res.Iterate(func(d document.Document) error {
+		var a int
+		err := document.Scan(d, &a)
+		keys = append(keys, a)
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{2}, keys)
+}
+
+func TestSavepointUnknown(t *testing.T) {
+	for _, q := range []string{"ROLLBACK TO nope", "RELEASE nope"} {
+		t.Run(q, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(`
+				CREATE TABLE test;
+				BEGIN;
+				INSERT INTO test (a) VALUES (1);
+				SAVEPOINT s;
+				INSERT INTO test (a) VALUES (2);
+			`)
+			require.NoError(t, err)
+
+			// the error must leave the transaction and its savepoints intact.
+			err = db.Exec(q)
+			require.Error(t, err)
+
+			err = db.Exec("INSERT INTO test (a) VALUES (3); ROLLBACK TO s; COMMIT")
+			require.NoError(t, err)
+
+			d, err := db.QueryDocument("SELECT COUNT(*) FROM test")
+			require.NoError(t, err)
+			var n int
+			err = document.Scan(d, &n)
+			require.NoError(t, err)
+			require.Equal(t, 1, n)
+		})
+	}
+}
@@ -119,6 +119,7 @@ func TestScanner_Scan(t *testing.T) {
 		// Keywords
 		{s: `ADD`, tok: scanner.ADD_KEYWORD, raw: `ADD`},
 		{s: `ALTER`, tok: scanner.ALTER, raw: `ALTER`},
+		{s: `ANALYZE`, tok: scanner.ANALYZE, raw: `ANALYZE`},
 		{s: `AS`, tok: scanner.AS, raw: `AS`},
 		{s: `ASC`, tok: scanner.ASC, raw: `ASC`},
 		{s: `BY`, tok: scanner.BY, raw: `BY`},
@@ -293,3 +294,25 @@ func TestScanRegex(t *testing.T) {
 		}
 	}
 }
+
+func TestKeywords(t *testing.T) {
+	kws := scanner.Keywords()
+
+	for _, kw := range []string{"SELECT", "FROM", "AND", "NULL", "TEXT"} {
+		var found bool
+		for _, k := range kws {
+			if k == kw {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("keyword %q not found", kw)
+		}
+	}
+
+	for _, kw := range kws {
+		if tok := scanner.Lookup(kw); tok == scanner.IDENT {
+			t.Errorf("keyword %q scanned as an identifier", kw)
+		}
+	}
+}
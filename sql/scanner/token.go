@@ -74,9 +74,12 @@ const (
 	keywordBeg
 	// ALL and the following are Genji SQL Keywords
 	ADD_KEYWORD
+	AFTER
 	ALTER
+	ANALYZE
 	AS
 	ASC
+	BEFORE
 	BEGIN
 	BY
 	CAST
@@ -87,10 +90,13 @@ const (
 	DESC
 	DISTINCT
 	DROP
+	EACH
 	EXISTS
 	EXPLAIN
 	FIELD
+	FOR
 	FROM
+	FULLTEXT
 	GROUP
 	IF
 	INDEX
@@ -107,18 +113,25 @@ const (
 	PRIMARY
 	READ
 	REINDEX
+	RELEASE
 	RENAME
 	ROLLBACK
+	ROW
+	SAVEPOINT
 	SELECT
 	SET
+	SPATIAL
 	TABLE
 	TO
 	TRANSACTION
+	TRIGGER
+	TTL
 	UNIQUE
 	UNSET
 	UPDATE
 	VALUES
 	WHERE
+	WITH
 	WRITE
 
 	// Aliases
@@ -197,9 +210,12 @@ var tokens = [...]string{
 	DOT:         ".",
 
 	ADD_KEYWORD: "ADD",
+	AFTER:       "AFTER",
 	ALTER:       "ALTER",
+	ANALYZE:     "ANALYZE",
 	AS:          "AS",
 	ASC:         "ASC",
+	BEFORE:      "BEFORE",
 	BEGIN:       "BEGIN",
 	COMMIT:      "COMMIT",
 	GROUP:       "GROUP",
@@ -211,11 +227,14 @@ var tokens = [...]string{
 	DESC:        "DESC",
 	DISTINCT:    "DISTINCT",
 	DROP:        "DROP",
+	EACH:        "EACH",
 	EXISTS:      "EXISTS",
 	EXPLAIN:     "EXPLAIN",
 	KEY:         "KEY",
 	FIELD:       "FIELD",
+	FOR:         "FOR",
 	FROM:        "FROM",
+	FULLTEXT:    "FULLTEXT",
 	IF:          "IF",
 	INDEX:       "INDEX",
 	INSERT:      "INSERT",
@@ -230,18 +249,25 @@ var tokens = [...]string{
 	PRIMARY:     "PRIMARY",
 	READ:        "READ",
 	REINDEX:     "REINDEX",
+	RELEASE:     "RELEASE",
 	RENAME:      "RENAME",
 	ROLLBACK:    "ROLLBACK",
+	ROW:         "ROW",
+	SAVEPOINT:   "SAVEPOINT",
 	SELECT:      "SELECT",
 	SET:         "SET",
+	SPATIAL:     "SPATIAL",
 	TABLE:       "TABLE",
 	TO:          "TO",
 	TRANSACTION: "TRANSACTION",
+	TRIGGER:     "TRIGGER",
+	TTL:         "TTL",
 	UNIQUE:      "UNIQUE",
 	UNSET:       "UNSET",
 	UPDATE:      "UPDATE",
 	VALUES:      "VALUES",
 	WHERE:       "WHERE",
+	WITH:        "WITH",
 	WRITE:       "WRITE",
 
 	TYPEARRAY:     "ARRAY",
@@ -268,12 +294,30 @@ var keywords map[string]Token
 
 func initKeywords() {
 	keywords = make(map[string]Token)
-	for tok := keywordBeg + 1; tok < keywordEnd; tok++ {
+	for _, tok := range keywordTokens() {
 		keywords[strings.ToLower(tokens[tok])] = tok
 	}
-	for _, tok := range []Token{AND, OR, TRUE, FALSE, NULL, IN, IS, LIKE} {
-		keywords[strings.ToLower(tokens[tok])] = tok
+}
+
+// keywordTokens returns the tokens that are scanned from keywords.
+func keywordTokens() []Token {
+	toks := []Token{AND, OR, TRUE, FALSE, NULL, IN, IS, LIKE}
+	for tok := keywordBeg + 1; tok < keywordEnd; tok++ {
+		toks = append(toks, tok)
 	}
+
+	return toks
+}
+
+// Keywords returns the list of Genji SQL keywords, in upper case.
+func Keywords() []string {
+	toks := keywordTokens()
+	kws := make([]string, len(toks))
+	for i, tok := range toks {
+		kws[i] = tokens[tok]
+	}
+
+	return kws
 }
 
 // String returns the string representation of the token.
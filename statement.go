@@ -0,0 +1,61 @@
+package genji
+
+import (
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/query"
+)
+
+// A Statement is a prepared query. It is parsed once and can be run multiple times,
+// concurrently. The optimized plans of its statements are reused until the tables
+// or indexes of the database change.
+type Statement struct {
+	db   *DB
+	text string
+	q    query.Query
+}
+
+// Prepare parses the query and returns a statement that can be run multiple times.
+func (db *DB) Prepare(q string) (*Statement, error) {
+	pq, err := parser.ParseQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Statement{
+		db:   db,
+		text: q,
+		q:    pq,
+	}, nil
+}
+
+// Query runs the statement and returns the result.
+// The returned result must always be closed after usage.
+func (s *Statement) Query(args ...interface{}) (*query.Result, error) {
+	q := s.q
+	q.Trace = s.db.DB.StartQueryTrace(s.db.ctx, s.text, args)
+
+	return q.Run(s.db.ctx, s.db.DB, argsToParams(args))
+}
+
+// QueryDocument runs the statement and returns the first document.
+// If the query returns no error, QueryDocument returns database.ErrDocumentNotFound.
+func (s *Statement) QueryDocument(args ...interface{}) (document.Document, error) {
+	res, err := s.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	return firstDocument(res)
+}
+
+// Exec runs the statement without returning the result.
+func (s *Statement) Exec(args ...interface{}) error {
+	res, err := s.Query(args...)
+	if err != nil {
+		return err
+	}
+
+	return res.Close()
+}
@@ -0,0 +1,140 @@
+package genji_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+// stageCounter counts the stages of the traces of the queries.
+type stageCounter struct {
+	mu     sync.Mutex
+	stages map[database.TraceStage]int
+}
+
+func (c *stageCounter) StartQuery(ctx context.Context, q string, args []interface{}) database.QueryTrace {
+	return c
+}
+
+func (c *stageCounter) StartStage(stage database.TraceStage) func(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stages[stage]++
+	return func(err error) {}
+}
+
+func (c *stageCounter) End(err error) {}
+
+func (c *stageCounter) count(stage database.TraceStage) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stages[stage]
+}
+
+func TestPrepare(t *testing.T) {
+	c := stageCounter{stages: make(map[database.TraceStage]int)}
+	db, err := genji.New(context.Background(), memoryengine.NewEngine(), genji.WithTracer(&c))
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(`
+		CREATE TABLE test(a INTEGER);
+		INSERT INTO test (a, b) VALUES (1, 'a'), (2, 'b'), (3, 'c');
+	`)
+	require.NoError(t, err)
+
+	_, err = db.Prepare("SELEC")
+	require.Error(t, err)
+
+	stmt, err := db.Prepare("SELECT b FROM test WHERE a = ?")
+	require.NoError(t, err)
+
+	get := func(a int) string {
+		d, err := stmt.QueryDocument(a)
+		require.NoError(t, err)
+		var b string
+		require.NoError(t, document.Scan(d, &b))
+		return b
+	}
+
+	optimized := c.count(database.OptimizeStage)
+	require.Equal(t, "a", get(1))
+	require.Equal(t, "b", get(2))
+	require.Equal(t, "c", get(3))
+	// the statement is parsed and optimized once.
+	require.Equal(t, optimized+1, c.count(database.OptimizeStage))
+	require.Equal(t, 1, c.count(database.ParseStage))
+
+	_, err = stmt.QueryDocument(4)
+	require.Equal(t, database.ErrDocumentNotFound, err)
+
+	// the statement is optimized again when an index is created.
+	err = db.Exec("CREATE INDEX idx_a ON test (a); REINDEX idx_a")
+	require.NoError(t, err)
+	optimized = c.count(database.OptimizeStage)
+	lookups := db.Stats().IndexLookups
+	require.Equal(t, "b", get(2))
+	require.Equal(t, "c", get(3))
+	require.Equal(t, optimized+1, c.count(database.OptimizeStage))
+	require.Equal(t, lookups+2, db.Stats().IndexLookups)
+
+	// and when it is dropped.
+	err = db.Exec("DROP INDEX idx_a")
+	require.NoError(t, err)
+	require.Equal(t, "a", get(1))
+	require.Equal(t, lookups+2, db.Stats().IndexLookups)
+
+	// statements can be run within a transaction changing the schema.
+	err = db.Exec("BEGIN; CREATE INDEX idx_a ON test (a); REINDEX idx_a")
+	require.NoError(t, err)
+	require.Equal(t, "b", get(2))
+	require.Equal(t, lookups+3, db.Stats().IndexLookups)
+	require.NoError(t, db.Exec("ROLLBACK"))
+	require.Equal(t, "b", get(2))
+	require.Equal(t, lookups+3, db.Stats().IndexLookups)
+
+	t.Run("Exec", func(t *testing.T) {
+		insert, err := db.Prepare("INSERT INTO test (a, b) VALUES (?, ?)")
+		require.NoError(t, err)
+
+		for i := 10; i < 20; i++ {
+			require.NoError(t, insert.Exec(i, "x"))
+		}
+
+		d, err := db.QueryDocument("SELECT COUNT(*) FROM test WHERE b = 'x'")
+		require.NoError(t, err)
+		var n int
+		require.NoError(t, document.Scan(d, &n))
+		require.Equal(t, 10, n)
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				for j := 0; j < 20; j++ {
+					d, err := stmt.QueryDocument(3)
+					require.NoError(t, err)
+					var b string
+					require.NoError(t, document.Scan(d, &b))
+					require.Equal(t, "c", b)
+				}
+			}()
+		}
+
+		err := db.Update(func(tx *genji.Tx) error {
+			return tx.Exec("CREATE INDEX idx_a ON test (a); REINDEX idx_a")
+		})
+		require.NoError(t, err)
+		wg.Wait()
+	})
+}
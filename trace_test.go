@@ -0,0 +1,148 @@
+package genji_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTracer records the events of the traces of the queries.
+type recordingTracer struct {
+	events []string
+}
+
+func (r *recordingTracer) StartQuery(ctx context.Context, q string, args []interface{}) database.QueryTrace {
+	r.events = append(r.events, fmt.Sprintf("start %s %v", q, args))
+	return r
+}
+
+func (r *recordingTracer) StartStage(stage database.TraceStage) func(err error) {
+	r.events = append(r.events, stage.String())
+	return func(err error) {
+		r.events = append(r.events, fmt.Sprintf("end %s %v", stage, err))
+	}
+}
+
+func (r *recordingTracer) End(err error) {
+	r.events = append(r.events, fmt.Sprintf("end %v", err))
+}
+
+func TestTracer(t *testing.T) {
+	var tr recordingTracer
+	db, err := genji.New(context.Background(), memoryengine.NewEngine(), genji.WithTracer(&tr))
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec("CREATE TABLE test")
+	require.NoError(t, err)
+
+	t.Run("Exec", func(t *testing.T) {
+		tr.events = nil
+		err = db.Exec("INSERT INTO test (a) VALUES (?); UPDATE test SET a = 2", 1)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"start INSERT INTO test (a) VALUES (?); UPDATE test SET a = 2 [1]",
+			"parse", "end parse <nil>",
+			"execute", "end execute <nil>",
+			"commit", "end commit <nil>",
+			"execute",
+			"bind", "end bind <nil>",
+			"optimize", "end optimize <nil>",
+			"end execute <nil>",
+			"commit", "end commit <nil>",
+			"end <nil>",
+		}, tr.events)
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		tr.events = nil
+		res, err := db.Query("SELECT * FROM test")
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"start SELECT * FROM test []",
+			"parse", "end parse <nil>",
+			"execute",
+			"bind", "end bind <nil>",
+			"optimize", "end optimize <nil>",
+			"end execute <nil>",
+		}, tr.events)
+
+		// the trace is ended once the result is closed.
+		require.NoError(t, res.Close())
+		require.Equal(t, "end <nil>", tr.events[len(tr.events)-1])
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		tr.events = nil
+		err = db.Exec("SELEC")
+		require.Error(t, err)
+		require.Len(t, tr.events, 4)
+		require.True(t, strings.HasPrefix(tr.events[2], "end parse "))
+		require.Equal(t, fmt.Sprintf("end %v", err), tr.events[3])
+
+		tr.events = nil
+		err = db.Exec("SELECT * FROM unknown")
+		require.Error(t, err)
+		require.Equal(t, []string{
+			"start SELECT * FROM unknown []",
+			"parse", "end parse <nil>",
+			"execute",
+			"bind", fmt.Sprintf("end bind %v", err),
+			fmt.Sprintf("end execute %v", err),
+			fmt.Sprintf("end %v", err),
+		}, tr.events)
+	})
+
+	t.Run("Transaction", func(t *testing.T) {
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		tr.events = nil
+		err = tx.Exec("INSERT INTO test (a) VALUES (1)")
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"start INSERT INTO test (a) VALUES (1) []",
+			"parse", "end parse <nil>",
+			"execute", "end execute <nil>",
+			"end <nil>",
+		}, tr.events)
+	})
+}
+
+func TestSlowQueryLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := database.NewSlowQueryLogger(&buf, 10*time.Millisecond)
+
+	db, err := genji.New(context.Background(), memoryengine.NewEngine(), genji.WithTracer(l))
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec("CREATE TABLE test")
+	require.NoError(t, err)
+	require.Empty(t, buf.String())
+
+	res, err := db.Query("SELECT * FROM test WHERE a = ?", 1)
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, res.Close())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 1)
+	require.Contains(t, lines[0], ` query="SELECT * FROM test WHERE a = ?" args=[1]`)
+	require.NotContains(t, lines[0], "error=")
+
+	tr := l.StartQuery(context.Background(), "SELECT 1", nil)
+	time.Sleep(20 * time.Millisecond)
+	tr.End(errors.New("boom"))
+	require.Contains(t, buf.String(), ` query="SELECT 1" error="boom"`)
+}
@@ -0,0 +1,43 @@
+package genji
+
+import (
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// RegisterTrigger registers a Go function that is called every time a document of
+// the given table is inserted, updated or deleted, depending on the event.
+// BEFORE triggers can veto the write by returning an error or rewrite the
+// document by replacing the New field of the trigger context.
+// Triggers are run within the transaction that writes the document.
+func (db *DB) RegisterTrigger(name, table string, timing database.TriggerTiming, event database.ChangeType, fn database.TriggerFunc) error {
+	return db.DB.RegisterTrigger(name, table, timing, event, fn)
+}
+
+// UnregisterTrigger removes a trigger registered with RegisterTrigger.
+func (db *DB) UnregisterTrigger(name string) error {
+	return db.DB.UnregisterTrigger(name)
+}
+
+// prepareTrigger parses the statement of a SQL trigger and returns a function
+// that runs it within the transaction that fired the trigger. The old and new
+// versions of the document are passed as the $old and $new parameters.
+func prepareTrigger(stmt string) (database.TriggerFunc, error) {
+	q, err := parser.ParseQuery(stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(tc *database.TriggerContext) error {
+		res, err := q.Exec(tc.Tx, []expr.Param{
+			{Name: "old", Value: tc.Old},
+			{Name: "new", Value: tc.New},
+		})
+		if err != nil {
+			return err
+		}
+
+		return res.Close()
+	}, nil
+}